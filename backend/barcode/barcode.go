@@ -0,0 +1,92 @@
+// SPDX-License-Identifier: GPL-3.0
+
+// Package barcode validates the GS1 product codes items and boxes are
+// labelled with and normalizes them to GTIN-14 for storage. It reads GS1
+// scans and prints labels in ZPL, PNG and PDF.
+package barcode
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	EAN8   = "EAN-8"
+	UPCA   = "UPC-A"
+	EAN13  = "EAN-13"
+	GTIN14 = "GTIN-14"
+)
+
+// kinds names the symbologies by their number of digits.
+var kinds = map[int]string{8: EAN8, 12: UPCA, 13: EAN13, 14: GTIN14}
+
+var ErrInvalid = errors.New("invalid barcode")
+
+// Error describes why a code is not a valid barcode. It matches
+// ErrInvalid.
+type Error struct {
+	Code   string
+	Reason string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("barcode %q %s", e.Code, e.Reason)
+}
+
+func (e *Error) Is(target error) bool { return target == ErrInvalid }
+
+// CheckDigit computes the GS1 check digit of a code without its last
+// digit. Digits are weighted 3 and 1 alternately from the right.
+func CheckDigit(digits string) byte {
+	sum := 0
+	for i := 0; i < len(digits); i++ {
+		d := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 0 {
+			d *= 3
+		}
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// Kind returns the symbology of a code by its length.
+func Kind(code string) (string, error) {
+	code = strings.TrimSpace(code)
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return "", &Error{Code: code, Reason: "must contain only digits"}
+		}
+	}
+	kind, ok := kinds[len(code)]
+	if !ok {
+		return "", &Error{Code: code, Reason: "must be 8, 12, 13 or 14 digits long"}
+	}
+	return kind, nil
+}
+
+// Validate checks that a code is an EAN-8, UPC-A, EAN-13 or GTIN-14 with a
+// correct check digit.
+func Validate(code string) error {
+	code = strings.TrimSpace(code)
+	kind, err := Kind(code)
+	if err != nil {
+		return err
+	}
+	last := len(code) - 1
+	if want := CheckDigit(code[:last]); code[last] != want {
+		return &Error{Code: code, Reason: fmt.Sprintf("has check digit %c, the %s check digit is %c", code[last], kind, want)}
+	}
+	return nil
+}
+
+// Normalize validates a code and pads it with zeros to the 14 digits of a
+// GTIN-14, so the same product reads the same whichever way it was
+// scanned.
+func Normalize(code string) (string, error) {
+	code = strings.TrimSpace(code)
+	if err := Validate(code); err != nil {
+		return "", err
+	}
+	return strings.Repeat("0", 14-len(code)) + code, nil
+}
@@ -0,0 +1,38 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package barcode
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	for code, gtin := range map[string]string{
+		"96385074":       "00000096385074",
+		"036000291452":   "00036000291452",
+		"4006381333931":  "04006381333931",
+		"10036000291459": "10036000291459",
+		" 036000291452 ": "00036000291452",
+	} {
+		normalized, err := Normalize(code)
+		assert.Nil(t, err, code)
+		assert.Equal(t, gtin, normalized, code)
+	}
+
+	for _, code := range []string{"", "123456", "036000291453", "4006381333932", "03600029145X", "0360-0029-1452"} {
+		_, err := Normalize(code)
+		assert.ErrorIs(t, err, ErrInvalid, code)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	err := Validate("036000291453")
+	assert.EqualError(t, err, `barcode "036000291453" has check digit 3, the UPC-A check digit is 2`)
+
+	kind, err := Kind("4006381333931")
+	assert.Nil(t, err)
+	assert.Equal(t, EAN13, kind)
+	assert.Equal(t, byte('2'), CheckDigit("03600029145"))
+}
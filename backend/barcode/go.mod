@@ -0,0 +1,18 @@
+module github.com/KNG-Hollow/WMS/barcode
+
+go 1.25.6
+
+require (
+	github.com/boombuler/barcode v1.1.0
+	github.com/jung-kurt/gofpdf v1.16.2
+	github.com/stretchr/testify v1.11.1
+	golang.org/x/image v0.25.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/WMS/barcode => .
@@ -0,0 +1,197 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package barcode
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GS is the group separator scanners send for FNC1 to end a variable
+// length element.
+const GS = "\x1d"
+
+// Application identifiers read from GS1-128 scans.
+const (
+	AISSCC       = "00"
+	AIGTIN       = "01"
+	AIContent    = "02"
+	AILot        = "10"
+	AIProduction = "11"
+	AIBestBefore = "15"
+	AIExpiry     = "17"
+	AISerial     = "21"
+	AICount      = "37"
+)
+
+// aiLengths holds the data length of each known application identifier.
+// Negative lengths are the maximum of a variable length element.
+var aiLengths = map[string]int{
+	AISSCC:       18,
+	AIGTIN:       14,
+	AIContent:    14,
+	AILot:        -20,
+	AIProduction: 6,
+	AIBestBefore: 6,
+	AIExpiry:     6,
+	AISerial:     -20,
+	AICount:      -8,
+}
+
+// symbologyIDs are the prefixes scanners put in front of GS1 data.
+var symbologyIDs = []string{"]C1", "]e0", "]d2", "]Q3"}
+
+var bracketed = regexp.MustCompile(`^(\(\d{2,4}\)[^()]+)+$`)
+
+// Element is one application identifier of a GS1 scan and its data.
+type Element struct {
+	AI    string `json:"ai"`
+	Value string `json:"value"`
+}
+
+// IsGS1 reports whether scanned data carries GS1 application identifiers,
+// marked by a symbology identifier, a leading FNC1 or the bracketed
+// human-readable form such as "(01)00036000291452(10)L42".
+func IsGS1(data string) bool {
+	for _, id := range symbologyIDs {
+		if strings.HasPrefix(data, id) {
+			return true
+		}
+	}
+	return strings.HasPrefix(data, GS) || bracketed.MatchString(data)
+}
+
+// ParseGS1 splits GS1 data into its elements. Fixed length elements may be
+// followed directly by the next one, variable length elements end at a
+// group separator or the end of the data. GTIN and SSCC check digits and
+// dates are validated.
+func ParseGS1(data string) ([]Element, error) {
+	for _, id := range symbologyIDs {
+		data = strings.TrimPrefix(data, id)
+	}
+	data = strings.TrimPrefix(data, GS)
+
+	if bracketed.MatchString(data) {
+		var elements []Element
+		for _, part := range strings.Split(data, "(")[1:] {
+			ai, value, _ := strings.Cut(part, ")")
+			element, err := checkElement(ai, value)
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, element)
+		}
+		return elements, nil
+	}
+
+	var elements []Element
+	for len(data) > 0 {
+		if len(data) < 2 {
+			return nil, fmt.Errorf("%w: truncated application identifier %q", ErrInvalid, data)
+		}
+		ai := data[:2]
+		length, ok := aiLengths[ai]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown application identifier %q", ErrInvalid, ai)
+		}
+		data = data[2:]
+		var value string
+		if length > 0 {
+			if len(data) < length {
+				return nil, fmt.Errorf("%w: (%s) needs %d characters", ErrInvalid, ai, length)
+			}
+			value, data = data[:length], data[length:]
+			data = strings.TrimPrefix(data, GS)
+		} else {
+			value, data, _ = strings.Cut(data, GS)
+		}
+		element, err := checkElement(ai, value)
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, element)
+	}
+	if len(elements) == 0 {
+		return nil, fmt.Errorf("%w: no GS1 data", ErrInvalid)
+	}
+	return elements, nil
+}
+
+// checkElement validates the data of one element against its application
+// identifier.
+func checkElement(ai string, value string) (Element, error) {
+	length, ok := aiLengths[ai]
+	if !ok {
+		return Element{}, fmt.Errorf("%w: unknown application identifier %q", ErrInvalid, ai)
+	}
+	switch {
+	case length > 0 && len(value) != length:
+		return Element{}, fmt.Errorf("%w: (%s) needs %d characters", ErrInvalid, ai, length)
+	case length < 0 && (value == "" || len(value) > -length):
+		return Element{}, fmt.Errorf("%w: (%s) takes 1 to %d characters", ErrInvalid, ai, -length)
+	}
+
+	var err error
+	switch ai {
+	case AISSCC:
+		err = ValidateSSCC(value)
+	case AIGTIN, AIContent:
+		err = Validate(value)
+	case AIProduction, AIBestBefore, AIExpiry:
+		_, err = ParseDate(value)
+	case AICount:
+		if _, convErr := strconv.ParseUint(value, 10, 64); convErr != nil {
+			err = fmt.Errorf("%w: (%s) count %q is not a number", ErrInvalid, ai, value)
+		}
+	}
+	if err != nil {
+		return Element{}, err
+	}
+	return Element{AI: ai, Value: value}, nil
+}
+
+// ParseDate reads a GS1 YYMMDD date. A day of 00 means the last day of the
+// month. Years are taken to be in this century.
+func ParseDate(value string) (time.Time, error) {
+	date, err := time.Parse("060102", value)
+	if err == nil {
+		return time.Date(2000+date.Year()%100, date.Month(), date.Day(), 0, 0, 0, 0, time.UTC), nil
+	}
+	if len(value) == 6 && strings.HasSuffix(value, "00") {
+		month, err := time.Parse("0601", value[:4])
+		if err == nil {
+			return time.Date(2000+month.Year()%100, month.Month()+1, 0, 0, 0, 0, 0, time.UTC), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: date %q must be YYMMDD", ErrInvalid, value)
+}
+
+// ValidateSSCC checks the 18 digits and check digit of a serial shipping
+// container code.
+func ValidateSSCC(code string) error {
+	if len(code) != 18 || strings.Trim(code, "0123456789") != "" {
+		return &Error{Code: code, Reason: "must be an 18 digit SSCC"}
+	}
+	if want := CheckDigit(code[:17]); code[17] != want {
+		return &Error{Code: code, Reason: fmt.Sprintf("has check digit %c, the SSCC check digit is %c", code[17], want)}
+	}
+	return nil
+}
+
+// SSCC builds the serial shipping container code of a container from the
+// GS1 company prefix of the warehouse and a serial number.
+func SSCC(prefix string, serial int64) (string, error) {
+	if len(prefix) < 6 || len(prefix) > 12 || strings.Trim(prefix, "0123456789") != "" {
+		return "", fmt.Errorf("%w: company prefix %q must be 6 to 12 digits", ErrInvalid, prefix)
+	}
+	digits := 16 - len(prefix)
+	reference := strconv.FormatInt(serial, 10)
+	if serial < 0 || len(reference) > digits {
+		return "", fmt.Errorf("%w: serial %d does not fit %d digits", ErrInvalid, serial, digits)
+	}
+	code := "0" + prefix + strings.Repeat("0", digits-len(reference)) + reference
+	return code + string(CheckDigit(code)), nil
+}
@@ -0,0 +1,63 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package barcode
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGS1(t *testing.T) {
+	want := []Element{
+		{AI: AIGTIN, Value: "10036000291459"},
+		{AI: AIExpiry, Value: "261231"},
+		{AI: AILot, Value: "L42"},
+		{AI: AISerial, Value: "SN7"},
+	}
+	for _, data := range []string{
+		"]C101100360002914591726123110L42" + GS + "21SN7",
+		GS + "011003600029145917261231" + "10L42" + GS + "21SN7",
+		"(01)10036000291459(17)261231(10)L42(21)SN7",
+	} {
+		assert.True(t, IsGS1(data), data)
+		elements, err := ParseGS1(data)
+		assert.Nil(t, err, data)
+		assert.Equal(t, want, elements, data)
+	}
+	assert.False(t, IsGS1("036000291452"))
+	assert.False(t, IsGS1("A-01-02"))
+
+	for _, data := range []string{
+		"]C1011003600029145817261231",   // GTIN check digit
+		"]C10110036000291459171313",     // short date
+		"]C101100360002914591713131310", // impossible date
+		"]C199ABC",                      // unknown identifier
+		"]C110" + GS,                    // empty lot
+		"]C1",                           // nothing
+	} {
+		_, err := ParseGS1(data)
+		assert.ErrorIs(t, err, ErrInvalid, data)
+	}
+}
+
+func TestSSCC(t *testing.T) {
+	code, err := SSCC("0614141", 123456789)
+	assert.Nil(t, err)
+	assert.Equal(t, "006141411234567890", code)
+	assert.Nil(t, ValidateSSCC(code))
+	elements, err := ParseGS1("]C100" + code)
+	assert.Nil(t, err)
+	assert.Equal(t, []Element{{AI: AISSCC, Value: code}}, elements)
+
+	_, err = SSCC("12", 1)
+	assert.ErrorIs(t, err, ErrInvalid)
+	_, err = SSCC("0614141", 1_000_000_000)
+	assert.ErrorIs(t, err, ErrInvalid)
+	assert.ErrorIs(t, ValidateSSCC("006141410123456788"), ErrInvalid)
+
+	date, err := ParseDate("270200")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2027, time.February, 28, 0, 0, 0, 0, time.UTC), date, "Day 00 is the end of the month")
+}
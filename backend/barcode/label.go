@@ -0,0 +1,207 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package barcode
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	symbol "github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/qr"
+)
+
+// Symbologies a label can be printed with. GS1-128 label data is written
+// in the bracketed form, "(00)006141411234567890".
+const (
+	Code128 = "code128"
+	GS1128  = "gs1-128"
+	QR      = "qr"
+)
+
+// DPI is the resolution labels are laid out in, that of most thermal
+// label printers.
+const DPI = 203
+
+// Label is one printed label: a title and lines of text above a barcode,
+// with the barcode data printed under it.
+type Label struct {
+	Title     string   `json:"title"`
+	Lines     []string `json:"lines,omitempty"`
+	Symbology string   `json:"symbology"`
+	Data      string   `json:"data"`
+}
+
+// Template is the size of a label stock and how labels are laid out on it.
+// Sizes are in inches.
+type Template struct {
+	Name   string  `json:"name"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+	// Lines prints the lines of text of a label, small labels only have
+	// room for the title and barcode.
+	Lines bool `json:"lines"`
+	// QR prints the label data as a QR code whatever its symbology.
+	QR bool `json:"qr"`
+}
+
+var Templates = map[string]Template{
+	"standard": {Name: "standard", Width: 4, Height: 2, Lines: true},
+	"small":    {Name: "small", Width: 2, Height: 1},
+	"qr":       {Name: "qr", Width: 2, Height: 2, QR: true},
+	"shipping": {Name: "shipping", Width: 4, Height: 6, Lines: true},
+}
+
+// TemplateNames lists the templates in a stable order for error messages.
+func TemplateNames() []string {
+	names := make([]string, 0, len(Templates))
+	for name := range Templates {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (t Template) dots() (int, int) {
+	return int(t.Width * DPI), int(t.Height * DPI)
+}
+
+// mark is one thing printed on a label, in dots from its top left corner.
+// Marks with a module size are the barcode, the others a line of text.
+type mark struct {
+	X, Y, Width, Height int
+	Text                string
+	Bold                bool
+	Module              int
+}
+
+// symbology returns how a label is printed on a template.
+func (l Label) symbology(t Template) string {
+	if t.QR {
+		return QR
+	}
+	return l.Symbology
+}
+
+// encode builds the barcode of a label. GS1-128 data starts with FNC1 and
+// has FNC1 after each variable length element but the last.
+func (l Label) encode(t Template) (symbol.Barcode, error) {
+	switch l.symbology(t) {
+	case QR:
+		return qr.Encode(l.Data, qr.M, qr.Auto)
+	case GS1128:
+		elements, err := ParseGS1(l.Data)
+		if err != nil {
+			return nil, err
+		}
+		var content strings.Builder
+		content.WriteRune(code128.FNC1)
+		for i, element := range elements {
+			content.WriteString(element.AI + element.Value)
+			if aiLengths[element.AI] < 0 && i < len(elements)-1 {
+				content.WriteRune(code128.FNC1)
+			}
+		}
+		return code128.Encode(content.String())
+	case Code128:
+		return code128.Encode(l.Data)
+	}
+	return nil, &Error{Code: l.Data, Reason: fmt.Sprintf("has unknown symbology %q", l.Symbology)}
+}
+
+// fit cuts text to the characters that fit a width at a text height,
+// taking characters to be about 0.6 of their height wide.
+func fit(text string, width int, height int) string {
+	runes := []rune(text)
+	if n := width * 10 / (height * 6); len(runes) > n {
+		return string(runes[:n])
+	}
+	return text
+}
+
+// layout places the title and the lines that fit at the top of a label
+// and the barcode with its data printed under it at the bottom. Linear
+// barcodes are at most an inch tall.
+func layout(label Label, t Template, code symbol.Barcode) ([]mark, error) {
+	width, height := t.dots()
+	margin := DPI / 10
+	inner := width - 2*margin
+	titleSize, lineSize := DPI*22/100, DPI*13/100
+	if t.Height < 1.5 {
+		titleSize, lineSize = DPI*15/100, DPI*11/100
+	}
+
+	var marks []mark
+	y := margin
+	text := func(s string, size int, bold bool) {
+		marks = append(marks, mark{X: margin, Y: y, Width: inner, Height: size, Text: fit(s, inner, size), Bold: bold})
+		y += size + size/4
+	}
+	text(label.Title, titleSize, true)
+
+	// The barcode gets the room left over the barcode data, but no less
+	// than minBar: lines that would squeeze it are not printed.
+	bottom := height - margin - lineSize - lineSize/4
+	minBar := DPI * 4 / 10
+	if t.Lines {
+		for _, line := range label.Lines {
+			if y+lineSize+lineSize/4+minBar > bottom {
+				break
+			}
+			text(line, lineSize, false)
+		}
+	}
+
+	modules := code.Bounds().Dx()
+	avail := bottom - y
+	bar := mark{Width: inner, Height: min(avail, DPI)}
+	if label.symbology(t) == QR {
+		bar.Module = min(inner, avail) / modules
+		bar.Width, bar.Height = modules*bar.Module, modules*bar.Module
+	} else {
+		bar.Module = min(inner/modules, 4)
+		bar.Width = modules * bar.Module
+	}
+	if bar.Module < 1 || bar.Height < minBar/2 {
+		return nil, &Error{Code: label.Data, Reason: fmt.Sprintf("does not fit a %s label", t.Name)}
+	}
+	bar.X, bar.Y = margin+(inner-bar.Width)/2, bottom-bar.Height
+	marks = append(marks, bar)
+	y = bottom + lineSize/4
+
+	text(label.Data, lineSize, false)
+	return marks, nil
+}
+
+// Formats are the label formats with their content type: ZPL for thermal
+// printers, PNG and PDF for office printers.
+var Formats = map[string]string{
+	"zpl": "application/zpl",
+	"png": "image/png",
+	"pdf": "application/pdf",
+}
+
+// Render prints labels in a format, one after the other: ZPL labels are
+// concatenated, PNG labels stacked into one sheet and PDF labels each get a
+// page.
+func Render(format string, labels []Label, t Template) ([]byte, error) {
+	if len(labels) == 0 {
+		return nil, fmt.Errorf("%w: no labels to print", ErrInvalid)
+	}
+	switch format {
+	case "zpl":
+		return ZPL(labels, t)
+	case "png":
+		return PNG(labels, t)
+	case "pdf":
+		return PDF(labels, t)
+	}
+	return nil, fmt.Errorf("%w: unknown label format %q", ErrInvalid, format)
+}
+
+// points converts a text height in dots to a font size.
+func points(dots int) float64 {
+	return math.Round(float64(dots)*72/DPI*10) / 10
+}
@@ -0,0 +1,99 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package barcode
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	itemLabel   = Label{Title: "Baked beans", Lines: []string{"400 g tin", "UPC 00036000291452"}, Symbology: GS1128, Data: "(01)00036000291452"}
+	cartonLabel = Label{Title: "Order 4 carton 1 of 2", Lines: []string{"Ship to:", "J. Doe", "12345 N. Test Ln."}, Symbology: GS1128, Data: "(00)006141411234567890"}
+	binLabel    = Label{Title: "A-01-02", Lines: []string{"Zone A"}, Symbology: Code128, Data: "A-01-02"}
+)
+
+func TestLayout(t *testing.T) {
+	for _, label := range []Label{itemLabel, cartonLabel, binLabel} {
+		for _, template := range Templates {
+			code, err := label.encode(template)
+			assert.Nil(t, err, label.Data)
+			marks, err := layout(label, template, code)
+			assert.Nil(t, err, label.Data, template.Name)
+
+			width, height := template.dots()
+			for _, m := range marks {
+				assert.True(t, m.X >= 0 && m.X+m.Width <= width, "%s %s %+v", label.Data, template.Name, m)
+				assert.True(t, m.Y >= 0 && m.Y+m.Height <= height, "%s %s %+v", label.Data, template.Name, m)
+			}
+			assert.Equal(t, label.Data, marks[len(marks)-1].Text, "The data is printed under the barcode")
+			if template.QR {
+				bar := marks[len(marks)-2]
+				assert.Equal(t, bar.Width, bar.Height, "QR codes are square")
+			}
+		}
+	}
+
+	// Small labels have no room for lines of text
+	code, _ := itemLabel.encode(Templates["small"])
+	marks, err := layout(itemLabel, Templates["small"], code)
+	assert.Nil(t, err)
+	assert.Len(t, marks, 3)
+
+	long := Label{Title: "long", Symbology: Code128, Data: strings.Repeat("X-", 40)}
+	code, _ = long.encode(Templates["small"])
+	_, err = layout(long, Templates["small"], code)
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	_, err = Label{Symbology: GS1128, Data: "(01)00036000291453"}.encode(Templates["standard"])
+	assert.ErrorIs(t, err, ErrInvalid)
+	_, err = Label{Symbology: "ean-13", Data: "4006381333931"}.encode(Templates["standard"])
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+func TestRender(t *testing.T) {
+	labels := []Label{itemLabel, binLabel}
+
+	zpl, err := Render("zpl", labels, Templates["standard"])
+	assert.Nil(t, err)
+	assert.Equal(t, 2, strings.Count(string(zpl), "^XA"))
+	assert.Contains(t, string(zpl), "^PW812^LL406")
+	assert.Contains(t, string(zpl), "^BCN,203,N,N,N,D^FD(01)00036000291452^FS")
+	assert.Contains(t, string(zpl), "^FDA-01-02^FS")
+
+	zpl, err = Render("zpl", []Label{{Title: "^XZ", Symbology: Code128, Data: "a>b"}}, Templates["qr"])
+	assert.Nil(t, err)
+	assert.Contains(t, string(zpl), "^FD_5EXZ^FS", "Field data cannot end the format")
+	assert.Contains(t, string(zpl), "^BQN,2,")
+	assert.Equal(t, "(01)00036000291452(10)L42>8(21)SN7", zplGS1("(01)00036000291452(10)L42(21)SN7"))
+
+	img, err := Render("png", labels, Templates["standard"])
+	assert.Nil(t, err)
+	sheet, err := png.Decode(bytes.NewReader(img))
+	assert.Nil(t, err)
+	assert.Equal(t, 812, sheet.Bounds().Dx())
+	assert.Equal(t, 2*406, sheet.Bounds().Dy(), "Labels are stacked on one sheet")
+
+	pdf, err := Render("pdf", labels, Templates["shipping"])
+	assert.Nil(t, err)
+	assert.True(t, bytes.HasPrefix(pdf, []byte("%PDF-")))
+	assert.Equal(t, 2, bytes.Count(pdf, []byte("/Type /Page\n")), "Each label gets a page")
+
+	_, err = Render("gif", labels, Templates["standard"])
+	assert.ErrorIs(t, err, ErrInvalid)
+	_, err = Render("zpl", nil, Templates["standard"])
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+func TestQRCode(t *testing.T) {
+	img, err := QRCode("otpauth://totp/WMS:jo?secret=JBSWY3DPEHPK3PXP", 200)
+	assert.Nil(t, err)
+	code, err := png.Decode(bytes.NewReader(img))
+	assert.Nil(t, err)
+	assert.Equal(t, 200, code.Bounds().Dx())
+	assert.Equal(t, 200, code.Bounds().Dy())
+}
@@ -0,0 +1,158 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package barcode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"unicode/utf8"
+
+	symbol "github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/qr"
+	"github.com/jung-kurt/gofpdf"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// bars draws a barcode at the size of its mark in 8-bit gray, the depth
+// PDF images take.
+func bars(code symbol.Barcode, m mark) (*image.Gray, error) {
+	scaled, err := symbol.Scale(code, m.Width, m.Height)
+	if err != nil {
+		return nil, err
+	}
+	img := image.NewGray(scaled.Bounds())
+	draw.Draw(img, img.Rect, scaled, image.Point{}, draw.Src)
+	return img, nil
+}
+
+// drawLabel draws a label on an image at an offset in dots. Text is drawn
+// in a bitmap font scaled up to the height of its line.
+func drawLabel(dst draw.Image, at image.Point, code symbol.Barcode, marks []mark) error {
+	face := basicfont.Face7x13
+	for _, m := range marks {
+		rect := image.Rect(m.X, m.Y, m.X+m.Width, m.Y+m.Height).Add(at)
+		if m.Module > 0 {
+			img, err := bars(code, m)
+			if err != nil {
+				return err
+			}
+			draw.Draw(dst, rect, img, image.Point{}, draw.Src)
+			continue
+		}
+
+		line := image.NewGray(image.Rect(0, 0, utf8.RuneCountInString(m.Text)*face.Advance, face.Height))
+		draw.Draw(line, line.Bounds(), image.White, image.Point{}, draw.Src)
+		drawer := font.Drawer{Dst: line, Src: image.Black, Face: face, Dot: fixed.P(0, face.Ascent)}
+		drawer.DrawString(m.Text)
+		rect.Max.X = rect.Min.X + line.Rect.Dx()*m.Height/face.Height
+		draw.NearestNeighbor.Scale(dst, rect, line, line.Bounds(), draw.Src, nil)
+	}
+	return nil
+}
+
+// PNG prints labels as one image at DPI, labels stacked top to bottom with
+// a line between them to cut along.
+func PNG(labels []Label, t Template) ([]byte, error) {
+	width, height := t.dots()
+	sheet := image.NewGray(image.Rect(0, 0, width, height*len(labels)))
+	draw.Draw(sheet, sheet.Rect, image.White, image.Point{}, draw.Src)
+	for i, label := range labels {
+		code, err := label.encode(t)
+		if err != nil {
+			return nil, err
+		}
+		marks, err := layout(label, t, code)
+		if err != nil {
+			return nil, err
+		}
+		if err := drawLabel(sheet, image.Pt(0, i*height), code, marks); err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			for x := 0; x < width; x += 4 {
+				sheet.SetGray(x, i*height, color.Gray{Y: 0x80})
+			}
+		}
+	}
+
+	var b bytes.Buffer
+	if err := png.Encode(&b, sheet); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// PDF prints labels one per page of the size of the template. Text is set
+// in Helvetica, the barcode is embedded as an image.
+func PDF(labels []Label, t Template) ([]byte, error) {
+	pdf := gofpdf.NewCustom(&gofpdf.InitType{UnitStr: "in", Size: gofpdf.SizeType{Wd: t.Width, Ht: t.Height}})
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	translate := pdf.UnicodeTranslatorFromDescriptor("")
+	inches := func(dots int) float64 { return float64(dots) / DPI }
+
+	for i, label := range labels {
+		code, err := label.encode(t)
+		if err != nil {
+			return nil, err
+		}
+		marks, err := layout(label, t, code)
+		if err != nil {
+			return nil, err
+		}
+
+		pdf.AddPage()
+		for _, m := range marks {
+			if m.Module > 0 {
+				symbol, err := bars(code, m)
+				if err != nil {
+					return nil, err
+				}
+				var img bytes.Buffer
+				if err := png.Encode(&img, symbol); err != nil {
+					return nil, err
+				}
+				name := fmt.Sprintf("barcode%d", i)
+				options := gofpdf.ImageOptions{ImageType: "PNG"}
+				pdf.RegisterImageOptionsReader(name, options, &img)
+				pdf.ImageOptions(name, inches(m.X), inches(m.Y), inches(m.Width), inches(m.Height), false, options, 0, "")
+				continue
+			}
+			style := ""
+			if m.Bold {
+				style = "B"
+			}
+			pdf.SetFont("Helvetica", style, points(m.Height))
+			pdf.Text(inches(m.X), inches(m.Y+m.Height*8/10), translate(m.Text))
+		}
+	}
+
+	var b bytes.Buffer
+	if err := pdf.Output(&b); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// QRCode draws text alone as a QR code PNG of size pixels square.
+func QRCode(text string, size int) ([]byte, error) {
+	code, err := qr.Encode(text, qr.M, qr.Auto)
+	if err != nil {
+		return nil, err
+	}
+	img, err := bars(code, mark{Width: size, Height: size})
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
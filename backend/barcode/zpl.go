@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package barcode
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// zplEscaper hex escapes the characters ZPL reads as commands in field
+// data written after ^FH.
+var zplEscaper = strings.NewReplacer("_", "_5F", "^", "_5E", "~", "_7E")
+
+// ZPL prints labels for Zebra thermal printers, one ^XA...^XZ format per
+// label. Text is UTF-8 in the scalable font 0.
+func ZPL(labels []Label, t Template) ([]byte, error) {
+	width, height := t.dots()
+	var b bytes.Buffer
+	for _, label := range labels {
+		code, err := label.encode(t)
+		if err != nil {
+			return nil, err
+		}
+		marks, err := layout(label, t, code)
+		if err != nil {
+			return nil, err
+		}
+
+		fmt.Fprintf(&b, "^XA^CI28^PW%d^LL%d\n", width, height)
+		for _, m := range marks {
+			fmt.Fprintf(&b, "^FO%d,%d", m.X, m.Y)
+			switch {
+			case m.Module == 0:
+				fmt.Fprintf(&b, "^A0N,%d,%d^FH^FD%s^FS\n", m.Height, m.Height*3/4, zplEscaper.Replace(m.Text))
+			case label.symbology(t) == QR:
+				fmt.Fprintf(&b, "^BQN,2,%d^FH^FDMA,%s^FS\n", min(m.Module, 10), zplEscaper.Replace(label.Data))
+			case label.Symbology == GS1128:
+				fmt.Fprintf(&b, "^BY%d^BCN,%d,N,N,N,D^FD%s^FS\n", m.Module, m.Height, zplGS1(label.Data))
+			default:
+				// ">" starts an invocation code in the data of ^BC, "><" is
+				// a literal ">".
+				data := strings.ReplaceAll(label.Data, ">", "><")
+				fmt.Fprintf(&b, "^BY%d^BCN,%d,N,N,N^FH^FD%s^FS\n", m.Module, m.Height, zplEscaper.Replace(data))
+			}
+		}
+		b.WriteString("^XZ\n")
+	}
+	return b.Bytes(), nil
+}
+
+// zplGS1 writes GS1 data for the UCC/EAN mode of ^BC, which adds the
+// leading FNC1 itself and needs >8 for FNC1 after variable length elements.
+func zplGS1(data string) string {
+	elements, _ := ParseGS1(data)
+	var b strings.Builder
+	for i, element := range elements {
+		fmt.Fprintf(&b, "(%s)%s", element.AI, element.Value)
+		if aiLengths[element.AI] < 0 && i < len(elements)-1 {
+			b.WriteString(">8")
+		}
+	}
+	return b.String()
+}
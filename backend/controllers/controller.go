@@ -3,16 +3,132 @@
 package controllers
 
 import (
+	"cmp"
 	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/WMS/barcode"
 	"github.com/WMS/models"
 	"github.com/WMS/services"
 	"github.com/labstack/echo/v5"
 )
 
-func AuthorizeLogin(c *echo.Context) error {
+type Controller struct {
+	store services.Repository
+}
+
+func NewController(store services.Repository) *Controller {
+	return &Controller{store: store}
+}
+
+// storeError maps the typed errors returned by the store to HTTP responses,
+// leaving anything else to the default error handler.
+func storeError(err error) error {
+	var validation *services.ValidationError
+	switch {
+	case errors.As(err, &validation):
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, validation.Error()).Wrap(err)
+	case errors.Is(err, services.ErrNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, err.Error()).Wrap(err)
+	case errors.Is(err, services.ErrConflict):
+		return echo.NewHTTPError(http.StatusConflict, err.Error()).Wrap(err)
+	}
+	return err
+}
+
+// unitSystem reads the units query parameter, "metric" or "imperial".
+// Without it measurements are returned in the units they were entered in.
+func unitSystem(c *echo.Context) (string, error) {
+	system := strings.ToLower(c.QueryParam("units"))
+	if system != "" && !slices.Contains(models.UnitSystems, system) {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "invalid units")
+	}
+	return system, nil
+}
+
+// inSystem converts the measurements of each row to a unit system.
+func inSystem[T interface{ System(string) T }](rows []T, system string) []T {
+	for i := range rows {
+		rows[i] = rows[i].System(system)
+	}
+	return rows
+}
+
+// listQuery reads how a listing is paged and sorted from the query string.
+// Every other parameter but units filters the rows by the field it names.
+func listQuery(c *echo.Context) (models.ListQuery, error) {
+	var query models.ListQuery
+	for param, values := range c.QueryParams() {
+		var err error
+		switch param {
+		case "limit":
+			query.Limit, err = strconv.Atoi(values[0])
+		case "offset":
+			query.Offset, err = strconv.Atoi(values[0])
+		case "sort":
+			for _, value := range values {
+				query.Sort = append(query.Sort, strings.Split(value, ",")...)
+			}
+		case "units":
+		default:
+			if query.Filters == nil {
+				query.Filters = map[string][]string{}
+			}
+			query.Filters[param] = values
+		}
+		if err != nil {
+			return models.ListQuery{}, echo.NewHTTPError(http.StatusBadRequest, "invalid "+param)
+		}
+	}
+	return query, nil
+}
+
+// accountResponses leaves the password hashes out of a page of accounts.
+func accountResponses(accounts models.Page[models.Account], err error) (models.Page[models.AccountResponse], error) {
+	page := models.Page[models.AccountResponse]{Data: make([]models.AccountResponse, len(accounts.Data)), Page: accounts.Page}
+	for i, account := range accounts.Data {
+		page.Data[i] = account.Response()
+	}
+	return page, err
+}
+
+// listError maps store errors of a listing to responses. A listing only
+// fails validation on its query string.
+func listError(err error) error {
+	var validation *services.ValidationError
+	if errors.As(err, &validation) {
+		return echo.NewHTTPError(http.StatusBadRequest, validation.Error()).Wrap(err)
+	}
+	return storeError(err)
+}
+
+// groupsToBase converts item lines entered in a unit of measure to the
+// eaches they are stored in.
+func (ctl *Controller) groupsToBase(c *echo.Context, groups []models.ItemGroup) error {
+	units, err := ctl.store.GetUnitsOfMeasure(c.Request().Context(), services.ItemIDs(groups))
+	if err != nil {
+		return storeError(err)
+	}
+	return storeError(services.GroupsToBase(groups, units))
+}
+
+// inventoryToBase converts location counts entered in a unit of measure to
+// the eaches they are stored in.
+func (ctl *Controller) inventoryToBase(c *echo.Context, inv *models.Inventory) error {
+	units, err := ctl.store.GetUnitsOfMeasure(c.Request().Context(), []int64{inv.Item.ID})
+	if err != nil {
+		return storeError(err)
+	}
+	return storeError(services.LocationsToBase(inv.Item.ID, inv.Locations, units))
+}
+
+func (ctl *Controller) AuthorizeLogin(c *echo.Context) error {
 	var loginDetails models.LoginDetails
 	loginDetails.Username = c.FormValue("username")
 	loginDetails.Password = c.FormValue("password")
@@ -23,514 +139,1577 @@ func AuthorizeLogin(c *echo.Context) error {
 		}
 	}
 
-	acc, err := services.ValidateLogin(loginDetails.Username, loginDetails.Password)
-	if acc == nil {
-		return errors.New("account is empty")
+	acc, err := services.AttemptLogin(c.Request().Context(), ctl.store, loginDetails.Username, loginDetails.Password, c.RealIP())
+	if err != nil {
+		return loginError(c, err)
 	}
+
+	challenge, err := services.LoginChallenge(c.Request().Context(), ctl.store, *acc)
 	if err != nil {
 		return err
 	}
+	if challenge != nil {
+		return c.JSON(http.StatusAccepted, challenge)
+	}
 
-	token, err := services.InitJWT(acc)
+	tokens, err := services.IssueTokens(c.Request().Context(), ctl.store, *acc)
 	if err != nil {
 		return err
 	}
 
-	return c.JSON(http.StatusAccepted, token)
+	return c.JSON(http.StatusAccepted, tokens)
 }
 
-//  Basic Functionality  //
+// loginError answers a failed login step alike whatever the cause, so it
+// does not reveal which usernames exist.
+func loginError(c *echo.Context, err error) error {
+	var lockout *services.LockoutError
+	if errors.As(err, &lockout) {
+		c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(lockout.RetryAfter.Seconds()))))
+		return c.JSON(http.StatusTooManyRequests, models.AuthError{
+			Error:   "too many requests",
+			Message: "too many failed logins, try again later",
+		})
+	}
+	if errors.Is(err, services.ErrCredentials) {
+		return c.JSON(http.StatusUnauthorized, models.AuthError{
+			Error:   "unauthorized",
+			Message: "invalid credentials",
+		})
+	}
+	return err
+}
 
-func AddAccount(c *echo.Context) error {
-	/*
-		err := services.AuthorizeRequest(c)
-		if err != nil {
-			return err
-		}
-	*/
-	var account models.Account
-	if err := c.Bind(&account); err != nil {
-		return err
+// VerifySecondFactor completes a login challenge with a TOTP or recovery
+// code.
+func (ctl *Controller) VerifySecondFactor(c *echo.Context) error {
+	var request models.SecondFactorRequest
+	if err := c.Bind(&request); err != nil || request.PreAuthToken == "" {
+		return c.JSON(http.StatusBadRequest, "bad request")
+	}
+	tokens, err := services.VerifySecondFactor(c.Request().Context(), ctl.store, request, c.RealIP())
+	if err != nil {
+		return loginError(c, err)
+	}
+	return c.JSON(http.StatusAccepted, tokens)
+}
+
+// EnrollAtLogin enrolls the TOTP secret of an account whose login challenge
+// asks it to. The first code entered at /auth/totp confirms it.
+func (ctl *Controller) EnrollAtLogin(c *echo.Context) error {
+	var request models.SecondFactorRequest
+	if err := c.Bind(&request); err != nil || request.PreAuthToken == "" {
+		return c.JSON(http.StatusBadRequest, "bad request")
+	}
+	enrollment, err := services.EnrollWithPreAuth(c.Request().Context(), ctl.store, request.PreAuthToken)
+	if errors.Is(err, services.ErrCredentials) {
+		return loginError(c, err)
+	}
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusCreated, enrollment)
+}
+
+// RefreshTokens trades a refresh token for a new token pair.
+func (ctl *Controller) RefreshTokens(c *echo.Context) error {
+	var request models.RefreshRequest
+	if err := c.Bind(&request); err != nil || request.RefreshToken == "" {
+		return c.JSON(http.StatusBadRequest, "bad request")
+	}
+	tokens, err := services.RefreshTokens(c.Request().Context(), ctl.store, request.RefreshToken)
+	if errors.Is(err, services.ErrCredentials) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "refresh token is invalid or expired")
 	}
-	err := services.AddAccount(account)
 	if err != nil {
 		return err
 	}
-	return c.JSON(http.StatusCreated, account)
+	return c.JSON(http.StatusAccepted, tokens)
 }
 
-func AddItem(c *echo.Context) error {
-	/*
-		err := services.AuthorizeRequest(c)
-		if err != nil {
-			return err
+// Logout revokes the access token of the request and, when given, the
+// refresh token logged in with.
+func (ctl *Controller) Logout(c *echo.Context) error {
+	claims, err := services.GetClaims(c)
+	if err != nil {
+		return err
+	}
+	var request models.RefreshRequest
+	if c.Request().ContentLength > 0 {
+		if err := c.Bind(&request); err != nil {
+			return c.JSON(http.StatusBadRequest, "bad request")
 		}
-	*/
+	}
+	if err := services.Logout(c.Request().Context(), ctl.store, claims, request.RefreshToken); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, map[string]string{"status": "Logged out"})
+}
+
+//  Basic Functionality  //
+
+func (ctl *Controller) AddAccount(c *echo.Context) error {
+	var request models.NewAccount
+	if err := c.Bind(&request); err != nil {
+		return err
+	}
+	if err := services.CheckPassword("password", request.Password); err != nil {
+		return storeError(err)
+	}
+	if err := services.CheckRole("role", request.Role); err != nil {
+		return storeError(err)
+	}
+	account := request.Account(time.Now())
+	err := ctl.store.AddAccount(c.Request().Context(), account)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusCreated, account.Response())
+}
+
+func (ctl *Controller) AddItem(c *echo.Context) error {
 	var item models.Item
 	if err := c.Bind(&item); err != nil {
 		return err
 	}
-	err := services.AddItem(item)
+	if err := services.NormalizeItem(&item); err != nil {
+		return storeError(err)
+	}
+	err := ctl.store.AddItem(c.Request().Context(), item)
 	if err != nil {
-		return err
+		return storeError(err)
 	}
 	return c.JSON(http.StatusCreated, item)
 }
 
-func AddOrder(c *echo.Context) error {
-	/*
-		err := services.AuthorizeRequest(c)
-		if err != nil {
-			return err
-		}
-	*/
+// orderOwner returns the customer whose orders the caller is limited to,
+// or 0 for staff and API keys.
+func orderOwner(c *echo.Context) (int64, error) {
+	if _, ok := services.APIKeyFrom(c); ok {
+		return 0, nil
+	}
+	claims, err := services.GetClaims(c)
+	if err != nil {
+		return 0, err
+	}
+	return services.OrderOwner(claims.Role.Value, claims.ID), nil
+}
+
+// AddOrder places an order. Customers always order for themselves.
+func (ctl *Controller) AddOrder(c *echo.Context) error {
 	var order models.Order
 	if err := c.Bind(&order); err != nil {
 		return err
 	}
-	err := services.AddOrder(order)
+	owner, err := orderOwner(c)
 	if err != nil {
 		return err
 	}
+	if owner != 0 {
+		customer, err := ctl.store.GetAccount(c.Request().Context(), int(owner))
+		if err != nil {
+			return storeError(err)
+		}
+		order.Customer = customer
+	}
+	if err := ctl.groupsToBase(c, order.Payload); err != nil {
+		return err
+	}
+	if err := ctl.store.AddOrder(c.Request().Context(), order); err != nil {
+		return storeError(err)
+	}
 	return c.JSON(http.StatusCreated, order)
 }
 
-func AddBox(c *echo.Context) error {
-	/*
-		err := services.AuthorizeRequest(c)
-		if err != nil {
-			return err
-		}
-	*/
+func (ctl *Controller) AddBox(c *echo.Context) error {
 	var box models.Box
 	if err := c.Bind(&box); err != nil {
 		return err
 	}
-	err := services.AddBox(box)
+	if err := services.NormalizeBox(&box); err != nil {
+		return storeError(err)
+	}
+	err := ctl.store.AddBox(c.Request().Context(), box)
 	if err != nil {
-		return err
+		return storeError(err)
 	}
 	return c.JSON(http.StatusCreated, box)
 }
 
-func AddInventory(c *echo.Context) error {
-	/*
-		err := services.AuthorizeRequest(c)
-		if err != nil {
-			return err
-		}
-	*/
+func (ctl *Controller) AddInventory(c *echo.Context) error {
 	var inv models.Inventory
 	if err := c.Bind(&inv); err != nil {
 		return err
 	}
-	err := services.AddInventory(inv)
-	if err != nil {
+	if err := ctl.inventoryToBase(c, &inv); err != nil {
 		return err
 	}
+	err := ctl.store.AddInventory(c.Request().Context(), inv)
+	if err != nil {
+		return storeError(err)
+	}
 	return c.JSON(http.StatusCreated, inv)
 }
 
-func GetAccounts(c *echo.Context) error {
-	/*
-		err := services.AuthorizeRequest(c)
-		if err != nil {
-			return err
-		}
-	*/
-	accounts, err := services.GetAccounts()
+func (ctl *Controller) AddShipment(c *echo.Context) error {
+	var shipment models.Shipment
+	if err := c.Bind(&shipment); err != nil {
+		return err
+	}
+	if err := ctl.groupsToBase(c, shipment.Payload); err != nil {
+		return err
+	}
+	err := ctl.store.AddShipment(c.Request().Context(), shipment)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusCreated, shipment)
+}
+
+func (ctl *Controller) GetAccounts(c *echo.Context) error {
+	query, err := listQuery(c)
 	if err != nil {
 		return err
 	}
+	accounts, err := accountResponses(ctl.store.GetAccounts(c.Request().Context(), query))
+	if err != nil {
+		return listError(err)
+	}
 	return c.JSON(http.StatusOK, accounts)
 }
 
-func GetAccount(c *echo.Context) error {
-	/*
-		err := services.AuthorizeRequest(c)
-		if err != nil {
-			return err
-		}
-	*/
+func (ctl *Controller) GetAccount(c *echo.Context) error {
 	idParam := c.Param("id")
 	id, err := strconv.Atoi(idParam)
 	if err != nil {
 		return err
 	}
 
-	account, err := services.GetAccount(id)
+	account, err := ctl.store.GetAccount(c.Request().Context(), id)
 	if err != nil {
-		return err
+		return storeError(err)
 	}
-	return c.JSON(http.StatusOK, account)
+	return c.JSON(http.StatusOK, account.Response())
 }
 
-func GetOrders(c *echo.Context) error {
-	/*
-		err := services.AuthorizeRequest(c)
-		if err != nil {
-			return err
-		}
-	*/
-	orders, err := services.GetOrders()
+// GetOrders lists orders, only their own to customers.
+func (ctl *Controller) GetOrders(c *echo.Context) error {
+	system, err := unitSystem(c)
+	if err != nil {
+		return err
+	}
+	query, err := listQuery(c)
+	if err != nil {
+		return err
+	}
+	owner, err := orderOwner(c)
 	if err != nil {
 		return err
 	}
+	if owner != 0 {
+		if query.Filters == nil {
+			query.Filters = map[string][]string{}
+		}
+		query.Filters["customerId"] = []string{strconv.FormatInt(owner, 10)}
+	}
+	orders, err := ctl.store.GetOrders(c.Request().Context(), query)
+	if err != nil {
+		return listError(err)
+	}
+	orders.Data = inSystem(orders.Data, system)
 	return c.JSON(http.StatusOK, orders)
 }
 
-func GetOrder(c *echo.Context) error {
-	/*
-		err := services.AuthorizeRequest(c)
-		if err != nil {
-			return err
-		}
-	*/
+// GetOrder returns an order, to customers only when it is theirs.
+func (ctl *Controller) GetOrder(c *echo.Context) error {
 	idParam := c.Param("id")
 	id, err := strconv.Atoi(idParam)
 	if err != nil {
 		return err
 	}
-
-	order, err := services.GetOrder(id)
+	system, err := unitSystem(c)
 	if err != nil {
 		return err
 	}
-	return c.JSON(http.StatusOK, order)
-}
 
-func GetItems(c *echo.Context) error {
-	/*
-		err := services.AuthorizeRequest(c)
-		if err != nil {
-			return err
-		}
-	*/
-	items, err := services.GetItems()
+	owner, err := orderOwner(c)
 	if err != nil {
 		return err
 	}
-	return c.JSON(http.StatusOK, items)
-}
 
-func GetItemsList(c *echo.Context) error {
-	/*
-		err := services.AuthorizeRequest(c)
-		if err != nil {
-			return err
-		}
-	*/
-	items, err := services.GetItemsList()
+	order, err := ctl.store.GetOrder(c.Request().Context(), id)
 	if err != nil {
-		return err
+		return storeError(err)
 	}
-	return c.JSON(http.StatusOK, items)
+	// Other customers' orders are hidden rather than forbidden
+	if owner != 0 && order.Customer.ID != owner {
+		return storeError(fmt.Errorf("order %d: %w", id, services.ErrNotFound))
+	}
+	return c.JSON(http.StatusOK, order.System(system))
 }
 
-func GetItem(c *echo.Context) error {
-	/*
-		err := services.AuthorizeRequest(c)
-		if err != nil {
-			return err
-		}
-	*/
-	idParam := c.Param("id")
-	id, err := strconv.Atoi(idParam)
+func (ctl *Controller) GetItems(c *echo.Context) error {
+	system, err := unitSystem(c)
 	if err != nil {
 		return err
 	}
-
-	item, err := services.GetItem(id)
+	query, err := listQuery(c)
 	if err != nil {
 		return err
 	}
-	return c.JSON(http.StatusOK, item)
+	items, err := ctl.store.GetItems(c.Request().Context(), query)
+	if err != nil {
+		return listError(err)
+	}
+	items.Data = inSystem(items.Data, system)
+	return c.JSON(http.StatusOK, items)
 }
 
-func GetBoxes(c *echo.Context) error {
-	/*
-		err := services.AuthorizeRequest(c)
-		if err != nil {
-			return err
-		}
-	*/
-	boxes, err := services.GetBoxes()
+func (ctl *Controller) GetItemsList(c *echo.Context) error {
+	query, err := listQuery(c)
 	if err != nil {
 		return err
 	}
-	return c.JSON(http.StatusOK, boxes)
+	items, err := ctl.store.GetItemsList(c.Request().Context(), query)
+	if err != nil {
+		return listError(err)
+	}
+	return c.JSON(http.StatusOK, items)
 }
 
-func GetBox(c *echo.Context) error {
-	/*
-		err := services.AuthorizeRequest(c)
-		if err != nil {
-			return err
-		}
-	*/
+func (ctl *Controller) GetItem(c *echo.Context) error {
 	idParam := c.Param("id")
 	id, err := strconv.Atoi(idParam)
 	if err != nil {
 		return err
 	}
-
-	box, err := services.GetBox(id)
+	system, err := unitSystem(c)
 	if err != nil {
 		return err
 	}
-	return c.JSON(http.StatusOK, box)
-}
 
-func GetAllInventory(c *echo.Context) error {
-	/*
-		err := services.AuthorizeRequest(c)
-		if err != nil {
-			return err
-		}
-	*/
-	allInventory, err := services.GetAllInventory()
+	item, err := ctl.store.GetItem(c.Request().Context(), id)
 	if err != nil {
-		return err
+		return storeError(err)
 	}
-	return c.JSON(http.StatusOK, allInventory)
+	return c.JSON(http.StatusOK, item.System(system))
 }
 
-func GetInventory(c *echo.Context) error {
-	/*
-		err := services.AuthorizeRequest(c)
-		if err != nil {
-			return err
-		}
-	*/
+func (ctl *Controller) GetItemUnits(c *echo.Context) error {
 	idParam := c.Param("id")
 	id, err := strconv.Atoi(idParam)
 	if err != nil {
 		return err
 	}
 
-	inv, err := services.GetInventory(id)
+	units, err := ctl.store.GetUnitsOfMeasure(c.Request().Context(), []int64{int64(id)})
 	if err != nil {
-		return err
+		return storeError(err)
+	}
+	itemUnits, ok := units[int64(id)]
+	if !ok {
+		return storeError(fmt.Errorf("item %d: %w", id, services.ErrNotFound))
 	}
-	return c.JSON(http.StatusOK, inv)
+	return c.JSON(http.StatusOK, itemUnits)
 }
 
-func UpdateAccount(c *echo.Context) error {
-	/*
-		err := services.AuthorizeRequest(c)
-		if err != nil {
-			return err
-		}
-	*/
-	idParam := c.Param("id")
-	id, err := strconv.Atoi(idParam)
+func (ctl *Controller) GetBoxes(c *echo.Context) error {
+	system, err := unitSystem(c)
 	if err != nil {
 		return err
 	}
-
-	var account models.Account
-	if err := c.Bind(&account); err != nil {
+	query, err := listQuery(c)
+	if err != nil {
 		return err
 	}
-	err = services.UpdateAccount(id, account)
+	boxes, err := ctl.store.GetBoxes(c.Request().Context(), query)
 	if err != nil {
-		return err
+		return listError(err)
 	}
-	return c.JSON(http.StatusAccepted, account)
+	boxes.Data = inSystem(boxes.Data, system)
+	return c.JSON(http.StatusOK, boxes)
 }
 
-func UpdateItem(c *echo.Context) error {
-	/*
-		err := services.AuthorizeRequest(c)
-		if err != nil {
-			return err
-		}
-	*/
+func (ctl *Controller) GetBox(c *echo.Context) error {
 	idParam := c.Param("id")
 	id, err := strconv.Atoi(idParam)
 	if err != nil {
 		return err
 	}
-
-	var item models.Item
-	if err := c.Bind(&item); err != nil {
+	system, err := unitSystem(c)
+	if err != nil {
 		return err
 	}
-	err = services.UpdateItem(id, item)
+
+	box, err := ctl.store.GetBox(c.Request().Context(), id)
 	if err != nil {
-		return err
+		return storeError(err)
 	}
-	return c.JSON(http.StatusAccepted, item)
+	return c.JSON(http.StatusOK, box.System(system))
 }
 
-func UpdateOrder(c *echo.Context) error {
-	/*
-		err := services.AuthorizeRequest(c)
-		if err != nil {
-			return err
-		}
-	*/
-	idParam := c.Param("id")
-	id, err := strconv.Atoi(idParam)
+func (ctl *Controller) GetAllInventory(c *echo.Context) error {
+	system, err := unitSystem(c)
 	if err != nil {
 		return err
 	}
-
-	var order models.Order
-	if err := c.Bind(&order); err != nil {
+	query, err := listQuery(c)
+	if err != nil {
 		return err
 	}
-	err = services.UpdateOrder(id, order)
+	allInventory, err := ctl.store.GetAllInventory(c.Request().Context(), query)
 	if err != nil {
-		return err
+		return listError(err)
 	}
-	return c.JSON(http.StatusAccepted, order)
+	allInventory.Data = inSystem(allInventory.Data, system)
+	return c.JSON(http.StatusOK, allInventory)
 }
 
-func UpdateBox(c *echo.Context) error {
-	/*
-		err := services.AuthorizeRequest(c)
-		if err != nil {
-			return err
-		}
-	*/
+func (ctl *Controller) GetInventory(c *echo.Context) error {
 	idParam := c.Param("id")
 	id, err := strconv.Atoi(idParam)
 	if err != nil {
 		return err
 	}
-
-	var box models.Box
-	if err := c.Bind(&box); err != nil {
+	system, err := unitSystem(c)
+	if err != nil {
 		return err
 	}
-	err = services.UpdateBox(id, box)
+
+	inv, err := ctl.store.GetInventory(c.Request().Context(), id)
 	if err != nil {
-		return err
+		return storeError(err)
 	}
-	return c.JSON(http.StatusAccepted, box)
+	return c.JSON(http.StatusOK, inv.System(system))
 }
 
-func UpdateInventory(c *echo.Context) error {
-	/*
-		err := services.AuthorizeRequest(c)
-		if err != nil {
-			return err
-		}
-	*/
+func (ctl *Controller) GetAvailability(c *echo.Context) error {
 	idParam := c.Param("id")
 	id, err := strconv.Atoi(idParam)
 	if err != nil {
 		return err
 	}
 
-	var inv models.Inventory
-	if err := c.Bind(&inv); err != nil {
-		return err
-	}
-	err = services.UpdateInventory(id, inv)
+	available, err := ctl.store.GetAvailability(c.Request().Context(), id)
 	if err != nil {
-		return err
+		return storeError(err)
 	}
-	return c.JSON(http.StatusAccepted, inv)
+	return c.JSON(http.StatusOK, available)
 }
 
-func DeleteAccount(c *echo.Context) error {
-	/*
-		err := services.AuthorizeRequest(c)
-		if err != nil {
-			return err
-		}
-	*/
-	idParam := c.Param("id")
-	id, err := strconv.Atoi(idParam)
+func (ctl *Controller) GetShipments(c *echo.Context) error {
+	system, err := unitSystem(c)
 	if err != nil {
 		return err
 	}
-
-	err = services.DeleteAccount(id)
+	query, err := listQuery(c)
 	if err != nil {
 		return err
 	}
-	return c.JSON(http.StatusAccepted, id)
+	shipments, err := ctl.store.GetShipments(c.Request().Context(), query)
+	if err != nil {
+		return listError(err)
+	}
+	shipments.Data = inSystem(shipments.Data, system)
+	return c.JSON(http.StatusOK, shipments)
 }
 
-func DeleteItem(c *echo.Context) error {
-	/*
-		err := services.AuthorizeRequest(c)
-		if err != nil {
-			return err
-		}
-	*/
+func (ctl *Controller) GetShipment(c *echo.Context) error {
 	idParam := c.Param("id")
 	id, err := strconv.Atoi(idParam)
 	if err != nil {
 		return err
 	}
-
-	err = services.DeleteItem(id)
+	system, err := unitSystem(c)
 	if err != nil {
 		return err
 	}
-	return c.JSON(http.StatusAccepted, id)
+
+	shipment, err := ctl.store.GetShipment(c.Request().Context(), id)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusOK, shipment.System(system))
 }
 
-func DeleteOrder(c *echo.Context) error {
-	/*
-		err := services.AuthorizeRequest(c)
-		if err != nil {
-			return err
-		}
-	*/
+func (ctl *Controller) UpdateAccount(c *echo.Context) error {
 	idParam := c.Param("id")
 	id, err := strconv.Atoi(idParam)
 	if err != nil {
 		return err
 	}
 
-	err = services.DeleteOrder(id)
-	if err != nil {
+	var update models.AccountUpdate
+	if err := c.Bind(&update); err != nil {
 		return err
 	}
-	return c.JSON(http.StatusAccepted, id)
-}
-
-func DeleteBox(c *echo.Context) error {
-	/*
-		err := services.AuthorizeRequest(c)
+	current, err := ctl.store.GetAccount(c.Request().Context(), id)
+	if err != nil {
+		return storeError(err)
+	}
+
+	// Self-service edits may not change the role or active flag
+	claims, err := services.GetClaims(c)
+	if err == nil && claims.Role.Value != models.RoleAdmin {
+		update.Role = current.Role
+		update.Active = current.Active
+	}
+	if err := services.CheckRole("role", update.Role); err != nil {
+		return storeError(err)
+	}
+
+	account := update.Apply(current)
+	err = ctl.store.UpdateAccount(c.Request().Context(), id, account)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusAccepted, account.Response())
+}
+
+// ChangePassword replaces the password of an account, which takes its
+// current password even for an admin.
+func (ctl *Controller) ChangePassword(c *echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid id")
+	}
+	var change models.PasswordChange
+	if err := c.Bind(&change); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid password change")
+	}
+
+	err = services.ChangePassword(c.Request().Context(), ctl.store, id, change)
+	var lockout *services.LockoutError
+	if errors.As(err, &lockout) {
+		return loginError(c, err)
+	}
+	if errors.Is(err, services.ErrCredentials) {
+		return echo.NewHTTPError(http.StatusForbidden, "current password is incorrect").Wrap(err)
+	}
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusAccepted, id)
+}
+
+// ownAccount returns the :id of a request made by that account itself.
+func ownAccount(c *echo.Context) (int64, error) {
+	claims, err := services.GetClaims(c)
+	if err != nil {
+		return 0, err
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "invalid id")
+	}
+	if id != claims.ID {
+		return 0, echo.NewHTTPError(http.StatusForbidden, "accounts enroll their own second factor")
+	}
+	return id, nil
+}
+
+// EnrollTOTP gives the caller's account a new TOTP secret to confirm.
+func (ctl *Controller) EnrollTOTP(c *echo.Context) error {
+	id, err := ownAccount(c)
+	if err != nil {
+		return err
+	}
+	acc, err := ctl.store.GetAccount(c.Request().Context(), int(id))
+	if err != nil {
+		return storeError(err)
+	}
+	enrollment, err := services.EnrollTOTP(c.Request().Context(), ctl.store, acc)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusCreated, enrollment)
+}
+
+// ConfirmTOTP confirms the TOTP secret of the caller's account with a code
+// from it.
+func (ctl *Controller) ConfirmTOTP(c *echo.Context) error {
+	id, err := ownAccount(c)
+	if err != nil {
+		return err
+	}
+	var request models.TOTPCode
+	if err := c.Bind(&request); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid code")
+	}
+	err = services.ConfirmTOTP(c.Request().Context(), ctl.store, id, request.Code)
+	if errors.Is(err, services.ErrCredentials) {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "code is incorrect").Wrap(err)
+	}
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusAccepted, id)
+}
+
+// DisableTOTP removes the TOTP secret of an account, for one whose
+// authenticator is lost.
+func (ctl *Controller) DisableTOTP(c *echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid id")
+	}
+	if err := ctl.store.DeleteTwoFactor(c.Request().Context(), id); err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusAccepted, id)
+}
+
+// UnlockAccount lifts the lockout of an account after failed logins.
+func (ctl *Controller) UnlockAccount(c *echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid id")
+	}
+	if err := services.UnlockAccount(c.Request().Context(), ctl.store, id); err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusAccepted, id)
+}
+
+func (ctl *Controller) UpdateItem(c *echo.Context) error {
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return err
+	}
+
+	var item models.Item
+	if err := c.Bind(&item); err != nil {
+		return err
+	}
+	if err := services.NormalizeItem(&item); err != nil {
+		return storeError(err)
+	}
+	err = ctl.store.UpdateItem(c.Request().Context(), id, item)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusAccepted, item)
+}
+
+// UpdateItemUnits replaces the pack levels defined for an item and returns
+// its complete unit-of-measure hierarchy.
+func (ctl *Controller) UpdateItemUnits(c *echo.Context) error {
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return err
+	}
+
+	var units []models.UnitOfMeasure
+	if err := c.Bind(&units); err != nil {
+		return err
+	}
+	if err := services.NormalizeUnitsOfMeasure(int64(id), units); err != nil {
+		return storeError(err)
+	}
+	err = ctl.store.SetUnitsOfMeasure(c.Request().Context(), id, units)
+	if err != nil {
+		return storeError(err)
+	}
+	all, err := ctl.store.GetUnitsOfMeasure(c.Request().Context(), []int64{int64(id)})
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusAccepted, all[int64(id)])
+}
+
+func (ctl *Controller) UpdateOrder(c *echo.Context) error {
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return err
+	}
+
+	var order models.Order
+	if err := c.Bind(&order); err != nil {
+		return err
+	}
+	if err := ctl.groupsToBase(c, order.Payload); err != nil {
+		return err
+	}
+	err = ctl.store.UpdateOrder(c.Request().Context(), id, order)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusAccepted, order)
+}
+
+func (ctl *Controller) UpdateBox(c *echo.Context) error {
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return err
+	}
+
+	var box models.Box
+	if err := c.Bind(&box); err != nil {
+		return err
+	}
+	if err := services.NormalizeBox(&box); err != nil {
+		return storeError(err)
+	}
+	err = ctl.store.UpdateBox(c.Request().Context(), id, box)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusAccepted, box)
+}
+
+func (ctl *Controller) UpdateInventory(c *echo.Context) error {
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return err
+	}
+
+	var inv models.Inventory
+	if err := c.Bind(&inv); err != nil {
+		return err
+	}
+	if err := ctl.inventoryToBase(c, &inv); err != nil {
+		return err
+	}
+	err = ctl.store.UpdateInventory(c.Request().Context(), id, inv)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusAccepted, inv)
+}
+
+func (ctl *Controller) UpdateShipment(c *echo.Context) error {
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return err
+	}
+
+	var shipment models.Shipment
+	if err := c.Bind(&shipment); err != nil {
+		return err
+	}
+	if err := ctl.groupsToBase(c, shipment.Payload); err != nil {
+		return err
+	}
+	err = ctl.store.UpdateShipment(c.Request().Context(), id, shipment)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusAccepted, shipment)
+}
+
+func (ctl *Controller) DeleteAccount(c *echo.Context) error {
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return err
+	}
+
+	err = ctl.store.DeleteAccount(c.Request().Context(), id)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusAccepted, id)
+}
+
+func (ctl *Controller) DeleteItem(c *echo.Context) error {
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return err
+	}
+
+	err = ctl.store.DeleteItem(c.Request().Context(), id)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusAccepted, id)
+}
+
+func (ctl *Controller) DeleteOrder(c *echo.Context) error {
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return err
+	}
+
+	err = ctl.store.DeleteOrder(c.Request().Context(), id)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusAccepted, id)
+}
+
+func (ctl *Controller) DeleteBox(c *echo.Context) error {
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return err
+	}
+
+	err = ctl.store.DeleteBox(c.Request().Context(), id)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusAccepted, id)
+}
+
+func (ctl *Controller) DeleteInventory(c *echo.Context) error {
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return err
+	}
+
+	err = ctl.store.DeleteInventory(c.Request().Context(), id)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusAccepted, id)
+}
+
+func (ctl *Controller) DeleteShipment(c *echo.Context) error {
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return err
+	}
+
+	err = ctl.store.DeleteShipment(c.Request().Context(), id)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusAccepted, id)
+}
+
+//  Locations  //
+
+func (ctl *Controller) AddLocation(c *echo.Context) error {
+	var loc models.Location
+	if err := c.Bind(&loc); err != nil {
+		return err
+	}
+	if err := services.NormalizeLocation(&loc); err != nil {
+		return storeError(err)
+	}
+	err := ctl.store.AddLocation(c.Request().Context(), loc)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusCreated, loc)
+}
+
+func (ctl *Controller) GetLocations(c *echo.Context) error {
+	query, err := listQuery(c)
+	if err != nil {
+		return err
+	}
+	locations, err := ctl.store.GetLocations(c.Request().Context(), query)
+	if err != nil {
+		return listError(err)
+	}
+	return c.JSON(http.StatusOK, locations)
+}
+
+func (ctl *Controller) GetLocation(c *echo.Context) error {
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return err
+	}
+
+	loc, err := ctl.store.GetLocation(c.Request().Context(), id)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusOK, loc)
+}
+
+func (ctl *Controller) GetLocationInventory(c *echo.Context) error {
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return err
+	}
+
+	stock, err := ctl.store.GetLocationStock(c.Request().Context(), id)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusOK, stock)
+}
+
+func (ctl *Controller) UpdateLocation(c *echo.Context) error {
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return err
+	}
+
+	var loc models.Location
+	if err := c.Bind(&loc); err != nil {
+		return err
+	}
+	if err := services.NormalizeLocation(&loc); err != nil {
+		return storeError(err)
+	}
+	err = ctl.store.UpdateLocation(c.Request().Context(), id, loc)
+	if err != nil {
+		return storeError(err)
+	}
+	loc.ID = int64(id)
+	return c.JSON(http.StatusAccepted, loc)
+}
+
+func (ctl *Controller) DeleteLocation(c *echo.Context) error {
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return err
+	}
+
+	err = ctl.store.DeleteLocation(c.Request().Context(), id)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusAccepted, id)
+}
+
+//  Stock  //
+
+func (ctl *Controller) MoveStock(c *echo.Context) error {
+	var move models.StockMoveRequest
+	if err := c.Bind(&move); err != nil {
+		return err
+	}
+	posted, err := ctl.store.MoveStock(c.Request().Context(), move)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusCreated, posted)
+}
+
+func (ctl *Controller) TransferStock(c *echo.Context) error {
+	var transfer models.StockTransferRequest
+	if err := c.Bind(&transfer); err != nil {
+		return err
+	}
+	posted, err := ctl.store.TransferStock(c.Request().Context(), transfer)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusCreated, posted)
+}
+
+func (ctl *Controller) AdjustStock(c *echo.Context) error {
+	var adj models.StockAdjustmentRequest
+	if err := c.Bind(&adj); err != nil {
+		return err
+	}
+	posted, err := ctl.store.AdjustStock(c.Request().Context(), adj)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusCreated, posted)
+}
+
+func (ctl *Controller) GetStockTransactions(c *echo.Context) error {
+	query, err := listQuery(c)
+	if err != nil {
+		return err
+	}
+	entries, err := ctl.store.GetStockTransactions(c.Request().Context(), query)
+	if err != nil {
+		return listError(err)
+	}
+	return c.JSON(http.StatusOK, entries)
+}
+
+//  Order Lifecycle  //
+
+func (ctl *Controller) TransitionOrder(c *echo.Context) error {
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return err
+	}
+
+	var req models.TransitionRequest
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	status := strings.ToUpper(strings.TrimSpace(req.Status))
+	order, err := ctl.store.TransitionOrder(c.Request().Context(), id, status)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusOK, order)
+}
+
+//  Receiving  //
+
+func (ctl *Controller) ArriveShipment(c *echo.Context) error {
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return err
+	}
+
+	shipment, err := ctl.store.ArriveShipment(c.Request().Context(), id)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusOK, shipment)
+}
+
+func (ctl *Controller) ReceiveShipment(c *echo.Context) error {
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return err
+	}
+
+	var req models.ReceiveRequest
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	if slices.ContainsFunc(req.Lines, func(line models.ReceiptLine) bool { return line.UOM != "" }) {
+		shipment, err := ctl.store.GetShipment(c.Request().Context(), id)
+		if err != nil {
+			return storeError(err)
+		}
+		units, err := ctl.store.GetUnitsOfMeasure(c.Request().Context(), services.ItemIDs(shipment.Payload))
 		if err != nil {
 			return err
 		}
-	*/
+		if err := services.ReceiptToBase(shipment, req.Lines, units); err != nil {
+			return storeError(err)
+		}
+	}
+	shipment, err := ctl.store.ReceiveShipment(c.Request().Context(), id, req)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusOK, shipment)
+}
+
+//  Picking  //
+
+func (ctl *Controller) CreateWave(c *echo.Context) error {
+	var req models.WaveRequest
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	wave, err := ctl.store.CreateWave(c.Request().Context(), req)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusCreated, wave)
+}
+
+func (ctl *Controller) GetWaves(c *echo.Context) error {
+	query, err := listQuery(c)
+	if err != nil {
+		return err
+	}
+	waves, err := ctl.store.GetWaves(c.Request().Context(), query)
+	if err != nil {
+		return listError(err)
+	}
+	return c.JSON(http.StatusOK, waves)
+}
+
+func (ctl *Controller) GetWave(c *echo.Context) error {
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return err
+	}
+
+	wave, err := ctl.store.GetWave(c.Request().Context(), id)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusOK, wave)
+}
+
+func (ctl *Controller) GetPickTasks(c *echo.Context) error {
+	query, err := listQuery(c)
+	if err != nil {
+		return err
+	}
+	tasks, err := ctl.store.GetPickTasks(c.Request().Context(), query)
+	if err != nil {
+		return listError(err)
+	}
+	return c.JSON(http.StatusOK, tasks)
+}
+
+func (ctl *Controller) ClaimPickTask(c *echo.Context) error {
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return err
+	}
+
+	task, err := ctl.store.ClaimPickTask(c.Request().Context(), id)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusOK, task)
+}
+
+func (ctl *Controller) ConfirmPickTask(c *echo.Context) error {
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return err
+	}
+
+	task, err := ctl.store.ConfirmPickTask(c.Request().Context(), id)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusOK, task)
+}
+
+func (ctl *Controller) ShortPickTask(c *echo.Context) error {
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return err
+	}
+
+	var req models.ShortPickRequest
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	task, err := ctl.store.ShortPickTask(c.Request().Context(), id, req)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusOK, task)
+}
+
+//  Packing  //
+
+func (ctl *Controller) AddCarton(c *echo.Context) error {
+	var carton models.Carton
+	if err := c.Bind(&carton); err != nil {
+		return err
+	}
+	if err := services.NormalizeCarton(&carton); err != nil {
+		return storeError(err)
+	}
+	err := ctl.store.AddCarton(c.Request().Context(), carton)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusCreated, carton)
+}
+
+func (ctl *Controller) GetCartons(c *echo.Context) error {
+	system, err := unitSystem(c)
+	if err != nil {
+		return err
+	}
+	query, err := listQuery(c)
+	if err != nil {
+		return err
+	}
+	cartons, err := ctl.store.GetCartons(c.Request().Context(), query)
+	if err != nil {
+		return listError(err)
+	}
+	cartons.Data = inSystem(cartons.Data, system)
+	return c.JSON(http.StatusOK, cartons)
+}
+
+func (ctl *Controller) GetCarton(c *echo.Context) error {
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return err
+	}
+	system, err := unitSystem(c)
+	if err != nil {
+		return err
+	}
+
+	carton, err := ctl.store.GetCarton(c.Request().Context(), id)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusOK, carton.System(system))
+}
+
+func (ctl *Controller) UpdateCarton(c *echo.Context) error {
 	idParam := c.Param("id")
 	id, err := strconv.Atoi(idParam)
 	if err != nil {
 		return err
 	}
 
-	err = services.DeleteBox(id)
+	var carton models.Carton
+	if err := c.Bind(&carton); err != nil {
+		return err
+	}
+	if err := services.NormalizeCarton(&carton); err != nil {
+		return storeError(err)
+	}
+	err = ctl.store.UpdateCarton(c.Request().Context(), id, carton)
+	if err != nil {
+		return storeError(err)
+	}
+	carton.ID = int64(id)
+	return c.JSON(http.StatusAccepted, carton)
+}
+
+func (ctl *Controller) DeleteCarton(c *echo.Context) error {
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
 	if err != nil {
 		return err
 	}
+
+	err = ctl.store.DeleteCarton(c.Request().Context(), id)
+	if err != nil {
+		return storeError(err)
+	}
 	return c.JSON(http.StatusAccepted, id)
 }
 
-func DeleteInventory(c *echo.Context) error {
-	/*
-		err := services.AuthorizeRequest(c)
-		if err != nil {
-			return err
-		}
-	*/
+func (ctl *Controller) SuggestCartons(c *echo.Context) error {
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return err
+	}
+	system, err := unitSystem(c)
+	if err != nil {
+		return err
+	}
+
+	packing, err := ctl.store.SuggestCartons(c.Request().Context(), id)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusOK, packing.System(system))
+}
+
+func (ctl *Controller) PackOrder(c *echo.Context) error {
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return err
+	}
+
+	system, err := unitSystem(c)
+	if err != nil {
+		return err
+	}
+
+	var req models.PackRequest
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	packing, err := ctl.store.PackOrder(c.Request().Context(), id, req)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusCreated, packing.System(system))
+}
+
+func (ctl *Controller) GetPacking(c *echo.Context) error {
 	idParam := c.Param("id")
 	id, err := strconv.Atoi(idParam)
 	if err != nil {
 		return err
 	}
+	system, err := unitSystem(c)
+	if err != nil {
+		return err
+	}
+
+	packing, err := ctl.store.GetPacking(c.Request().Context(), id)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusOK, packing.System(system))
+}
+
+//  Scanning  //
+
+func (ctl *Controller) ResolveScan(c *echo.Context) error {
+	system, err := unitSystem(c)
+	if err != nil {
+		return err
+	}
+
+	var req models.ScanRequest
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	scan, err := services.ParseScan(req.Data)
+	if err != nil {
+		return storeError(err)
+	}
+	result, err := ctl.store.ResolveScan(c.Request().Context(), scan)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusOK, result.System(system))
+}
+
+//  Labels  //
+
+// maxLabels caps how many records one batch prints labels for.
+const maxLabels = 500
+
+// labelIDs reads the comma separated ids query parameter of a batch.
+func labelIDs(c *echo.Context) ([]int, error) {
+	var ids []int
+	for param := range strings.SplitSeq(c.QueryParam("ids"), ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(param))
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "invalid ids")
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) > maxLabels {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("at most %d labels per batch", maxLabels))
+	}
+	return ids, nil
+}
+
+func (ctl *Controller) GetLabels(c *echo.Context) error {
+	kind := c.Param("kind")
+	defaultTemplate, ok := services.LabelKinds[kind]
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotFound, "unknown label kind")
+	}
+	template, ok := barcode.Templates[cmp.Or(strings.ToLower(c.QueryParam("template")), defaultTemplate)]
+	if !ok {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid template, use one of "+strings.Join(barcode.TemplateNames(), ", "))
+	}
+	format := cmp.Or(strings.ToLower(c.QueryParam("format")), "zpl")
+	contentType, ok := barcode.Formats[format]
+	if !ok {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid format")
+	}
+	system, err := unitSystem(c)
+	if err != nil {
+		return err
+	}
+	ids, err := labelIDs(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	var labels []barcode.Label
+	for _, id := range ids {
+		switch kind {
+		case "items":
+			item, err := ctl.store.GetItem(ctx, id)
+			if err != nil {
+				return storeError(err)
+			}
+			labels = append(labels, services.ItemLabel(item.System(system)))
+		case "boxes":
+			box, err := ctl.store.GetBox(ctx, id)
+			if err != nil {
+				return storeError(err)
+			}
+			labels = append(labels, services.BoxLabel(box.System(system)))
+		case "locations":
+			loc, err := ctl.store.GetLocation(ctx, id)
+			if err != nil {
+				return storeError(err)
+			}
+			labels = append(labels, services.LocationLabel(loc))
+		case "packing":
+			order, err := ctl.store.GetOrder(ctx, id)
+			if err != nil {
+				return storeError(err)
+			}
+			packing, err := ctl.store.GetPacking(ctx, id)
+			if err != nil {
+				return storeError(err)
+			}
+			cartons, err := services.CartonLabels(order, packing.System(system))
+			if err != nil {
+				return storeError(err)
+			}
+			labels = append(labels, cartons...)
+		}
+	}
+
+	content, err := barcode.Render(format, labels, template)
+	if errors.Is(err, barcode.ErrInvalid) {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error()).Wrap(err)
+	}
+	if err != nil {
+		return err
+	}
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("inline; filename=%q", kind+"."+format))
+	return c.Blob(http.StatusOK, contentType, content)
+}
+
+//  Searching  //
+
+// Search finds records of the kinds the caller may read by the words in
+// the q parameter. Customers only find their own orders.
+func (ctl *Controller) Search(c *echo.Context) error {
+	claims, err := services.GetClaims(c)
+	if err != nil {
+		return err
+	}
+	query := models.SearchQuery{Text: c.QueryParam("q")}
+	if param := c.QueryParam("kinds"); param != "" {
+		query.Kinds = strings.Split(param, ",")
+	}
+	if param := c.QueryParam("limit"); param != "" {
+		query.Limit, err = strconv.Atoi(param)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid limit")
+		}
+	}
+
+	scoped, ok := services.ScopeSearch(query, claims.Role.Value, claims.ID)
+	if !ok {
+		return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("role %q may not search %s", claims.Role.Value, strings.Join(query.Kinds, ", ")))
+	}
+	query = scoped
+	results, err := ctl.store.Search(c.Request().Context(), query)
+	if err != nil {
+		return listError(err)
+	}
+	return c.JSON(http.StatusOK, results)
+}
+
+//  Querying  //
+
+// queryResult wraps the page a query found.
+func queryResult[T any](page models.Page[T], err error) (models.QueryResult, error) {
+	return models.QueryResult{Data: page.Data, Page: page.Page}, err
+}
+
+// Query runs a query in the query language against the collection it
+// names, which the role must be allowed to read. Queries that do not parse
+// or do not fit their collection are answered with the position of the
+// mistake.
+func (ctl *Controller) Query(c *echo.Context) error {
+	claims, err := services.GetClaims(c)
+	if err != nil {
+		return err
+	}
+	var request models.QueryRequest
+	if err := c.Bind(&request); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid query")
+	}
+	entity, err := services.QueryEntity(request.Query)
+	if err != nil {
+		return queryError(c, err)
+	}
+	if !services.IsAllowed(claims.Role.Value, entity, services.VerbRead) {
+		return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("role %q may not read %s", claims.Role.Value, entity))
+	}
+
+	ctx := c.Request().Context()
+	query := models.ListQuery{Query: request.Query}
+	var result models.QueryResult
+	switch entity {
+	case "accounts":
+		result, err = queryResult(accountResponses(ctl.store.GetAccounts(ctx, query)))
+	case "items":
+		result, err = queryResult(ctl.store.GetItems(ctx, query))
+	case "boxes":
+		result, err = queryResult(ctl.store.GetBoxes(ctx, query))
+	case "inventory":
+		result, err = queryResult(ctl.store.GetAllInventory(ctx, query))
+	case "orders":
+		result, err = queryResult(ctl.store.GetOrders(ctx, query))
+	case "shipments":
+		result, err = queryResult(ctl.store.GetShipments(ctx, query))
+	case "locations":
+		result, err = queryResult(ctl.store.GetLocations(ctx, query))
+	case "cartons":
+		result, err = queryResult(ctl.store.GetCartons(ctx, query))
+	case "waves":
+		result, err = queryResult(ctl.store.GetWaves(ctx, query))
+	case "picks":
+		result, err = queryResult(ctl.store.GetPickTasks(ctx, query))
+	}
+	if err != nil {
+		return queryError(c, err)
+	}
+	result.Entity = entity
+	return c.JSON(http.StatusOK, result)
+}
+
+// queryError answers a query that went wrong at a position with the
+// position, leaving other errors to listError.
+func queryError(c *echo.Context, err error) error {
+	var queryErr *services.QueryError
+	if errors.As(err, &queryErr) {
+		return c.JSON(http.StatusBadRequest, queryErr)
+	}
+	return listError(err)
+}
+
+//  API Keys  //
+
+func (ctl *Controller) GetAPIKeys(c *echo.Context) error {
+	query, err := listQuery(c)
+	if err != nil {
+		return err
+	}
+	keys, err := ctl.store.GetAPIKeys(c.Request().Context(), query)
+	if err != nil {
+		return listError(err)
+	}
+	return c.JSON(http.StatusOK, keys)
+}
 
-	err = services.DeleteInventory(id)
+func (ctl *Controller) AddAPIKey(c *echo.Context) error {
+	claims, err := services.GetClaims(c)
 	if err != nil {
 		return err
 	}
+	var request models.NewAPIKey
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, "bad request")
+	}
+	key, err := services.NewAPIKey(c.Request().Context(), ctl.store, request, claims.ID)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusCreated, key)
+}
+
+func (ctl *Controller) DeleteAPIKey(c *echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid id")
+	}
+	if err := ctl.store.DeleteAPIKey(c.Request().Context(), id); err != nil {
+		return storeError(err)
+	}
 	return c.JSON(http.StatusAccepted, id)
 }
+
+//  Monitoring  //
+
+func (ctl *Controller) GetPoolStats(c *echo.Context) error {
+	reporter, ok := ctl.store.(services.StatsReporter)
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotImplemented, "store does not report pool stats")
+	}
+	return c.JSON(http.StatusOK, reporter.Stats())
+}
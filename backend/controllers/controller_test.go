@@ -3,17 +3,21 @@
 package controllers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"image/png"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/WMS/models"
 	"github.com/WMS/services"
-	"github.com/joho/godotenv"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v5"
 	"github.com/labstack/echo/v5/echotest"
 	"github.com/stretchr/testify/assert"
@@ -34,25 +38,25 @@ var (
 	}
 	mockItem models.Item = models.Item{
 		ID:          66,
-		UPC:         "123456",
+		UPC:         "00036000291452",
 		Name:        "test",
 		Description: "test item",
-		Weight:      1.0,
+		Weight:      models.Weight{Value: 1.0, Unit: models.UnitPound},
 		Image:       models.ImageData{Name: "test.png", Data: nil, Valid: true},
 	}
 	mockOrder models.Order = models.Order{ID: 66, Customer: models.Account{ID: 66, Firstname: "test", Lastname: "test", Email: "test@test.com", Phone: "123-456-7890", Username: "test", Password: "test", Role: models.Role{Value: "CUSTOMER"}, Active: true, Created: time.Now()}, Address: "12345 N. test ln.", TimeOrdered: time.Now(), Payload: []models.ItemGroup{models.ItemGroup{Item: models.Item{
 		ID:          66,
-		UPC:         "123456",
+		UPC:         "00036000291452",
 		Name:        "test",
 		Description: "test item",
-		Weight:      1.0,
+		Weight:      models.Weight{Value: 1.0, Unit: models.UnitPound},
 		Image:       models.ImageData{Name: "test.png", Data: nil, Valid: true}}, Count: 55}},
 	}
 	mockBox models.Box = models.Box{
 		ID:         66,
-		UPC:        "123456",
+		UPC:        "10036000291459",
 		Item:       mockItem,
-		Dimensions: "2x2x4",
+		Dimensions: models.Dimensions{Length: 2, Width: 2, Height: 4, Unit: models.UnitInch},
 		Count:      66,
 	}
 	mockInv models.Inventory = models.Inventory{
@@ -80,10 +84,10 @@ var (
 	}
 	mockItem1 models.Item = models.Item{
 		ID:          66,
-		UPC:         "234567",
+		UPC:         "04006381333931",
 		Name:        "test1",
 		Description: "test item1",
-		Weight:      2.0,
+		Weight:      models.Weight{Value: 2.0, Unit: models.UnitPound},
 		Image:       models.ImageData{Name: "test.png", Data: nil, Valid: true},
 	}
 	mockOrder1 models.Order = models.Order{
@@ -108,10 +112,10 @@ var (
 			{
 				Item: models.Item{
 					ID:          66,
-					UPC:         "234567",
+					UPC:         "04006381333931",
 					Name:        "test1",
 					Description: "test item1",
-					Weight:      1.0,
+					Weight:      models.Weight{Value: 1.0, Unit: models.UnitPound},
 					Image:       models.ImageData{Name: "test.png", Data: nil, Valid: true},
 				},
 				Count: 55,
@@ -120,9 +124,9 @@ var (
 	}
 	mockBox1 models.Box = models.Box{
 		ID:         66,
-		UPC:        "234567",
+		UPC:        "14006381333938",
 		Item:       mockItem1,
-		Dimensions: "4x4x8",
+		Dimensions: models.Dimensions{Length: 4, Width: 4, Height: 8, Unit: models.UnitInch},
 		Count:      667,
 	}
 	mockInv1 models.Inventory = models.Inventory{
@@ -138,13 +142,34 @@ var (
 	}
 )
 
-func TestAccountController(t *testing.T) {
-	err := godotenv.Load("../.env")
-	if err != nil {
-		fmt.Println("failed to load .env file")
+func testController(t *testing.T) *Controller {
+	t.Helper()
+	return NewController(services.NewMemoryStore())
+}
+
+// as serves a handler as the account signed in with the claims given.
+func as(claims models.JwtCustomClaims, handler echo.HandlerFunc) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		c.Set("user", &jwt.Token{Claims: &claims})
+		return handler(c)
 	}
+}
+
+func TestAccountController(t *testing.T) {
+	ctl := testController(t)
+
 	// Mock Accounts In JSON
-	jsonAcc, err := json.Marshal(mockAccount)
+	jsonAcc, err := json.Marshal(models.NewAccount{
+		ID:        mockAccount.ID,
+		Firstname: mockAccount.Firstname,
+		Lastname:  mockAccount.Lastname,
+		Email:     mockAccount.Email,
+		Phone:     mockAccount.Phone,
+		Username:  mockAccount.Username,
+		Password:  mockAccount.Password,
+		Role:      mockAccount.Role,
+		Active:    mockAccount.Active,
+	})
 	if err != nil {
 		fmt.Println("Error marshaling account to JSON:", err)
 		return
@@ -153,6 +178,7 @@ func TestAccountController(t *testing.T) {
 	if err != nil {
 		fmt.Println("Error marshaling account1 to JSON:", err)
 	}
+	assert.NotContains(t, string(jsonAcc1), "password", "Stored accounts never encode their password")
 
 	// AddAccount
 	rec := echotest.ContextConfig{
@@ -160,10 +186,25 @@ func TestAccountController(t *testing.T) {
 			echo.HeaderContentType: {echo.MIMEApplicationJSON},
 		},
 		JSONBody: jsonAcc,
-	}.ServeWithHandler(t, AddAccount)
+	}.ServeWithHandler(t, ctl.AddAccount)
 
 	assert.Equal(t, http.StatusCreated, rec.Code)
-	assert.Equal(t, string(jsonAcc)+"\n", rec.Body.String())
+	var created models.AccountResponse
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	assert.Equal(t, mockAccount.Username, created.Username)
+	assert.False(t, created.Created.IsZero())
+	assert.NotContains(t, rec.Body.String(), "password")
+
+	rec = echotest.ContextConfig{
+		Headers:  map[string][]string{echo.HeaderContentType: {echo.MIMEApplicationJSON}},
+		JSONBody: []byte(`{"id": 67, "username": "nopass"}`),
+	}.ServeWithHandler(t, ctl.AddAccount)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, "Accounts are created with a password")
+	rec = echotest.ContextConfig{
+		Headers:  map[string][]string{echo.HeaderContentType: {echo.MIMEApplicationJSON}},
+		JSONBody: []byte(`{"id": 67, "username": "norole", "password": "secret", "role": {"Value": "OWNER"}}`),
+	}.ServeWithHandler(t, ctl.AddAccount)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, "Accounts are created with a known role")
 
 	// GetAccounts
 	rec = echotest.ContextConfig{
@@ -171,9 +212,10 @@ func TestAccountController(t *testing.T) {
 			echo.HeaderContentType: {echo.MIMEApplicationJSON},
 		},
 		JSONBody: jsonAcc,
-	}.ServeWithHandler(t, GetAccounts)
+	}.ServeWithHandler(t, ctl.GetAccounts)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "argon2", "Listings leave out password hashes")
 
 	// GetAccount
 	rec = echotest.ContextConfig{
@@ -184,7 +226,7 @@ func TestAccountController(t *testing.T) {
 			echo.HeaderContentType: {echo.MIMEApplicationJSON},
 		},
 		JSONBody: jsonAcc,
-	}.ServeWithHandler(t, GetAccount)
+	}.ServeWithHandler(t, ctl.GetAccount)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 
@@ -197,9 +239,35 @@ func TestAccountController(t *testing.T) {
 			echo.HeaderContentType: {echo.MIMEApplicationJSON},
 		},
 		JSONBody: jsonAcc1,
-	}.ServeWithHandler(t, UpdateAccount)
+	}.ServeWithHandler(t, ctl.UpdateAccount)
 
 	assert.Equal(t, http.StatusAccepted, rec.Code)
+	_, err = services.ValidateLogin(context.Background(), ctl.store, mockAccount1.Username, mockAccount.Password)
+	assert.Nil(t, err, "Profile edits keep the password")
+	rec = echotest.ContextConfig{
+		PathValues: echo.PathValues{{Name: "id", Value: "66"}},
+		Headers:    map[string][]string{echo.HeaderContentType: {echo.MIMEApplicationJSON}},
+		JSONBody:   []byte(`{"username": "test1", "role": {"Value": "owner"}}`),
+	}.ServeWithHandler(t, ctl.UpdateAccount)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, "Accounts keep a known role")
+
+	// ChangePassword
+	changePassword := func(change models.PasswordChange) int {
+		body, err := json.Marshal(change)
+		assert.Nil(t, err)
+		return echotest.ContextConfig{
+			PathValues: echo.PathValues{{Name: "id", Value: "66"}},
+			Headers:    map[string][]string{echo.HeaderContentType: {echo.MIMEApplicationJSON}},
+			JSONBody:   body,
+		}.ServeWithHandler(t, ctl.ChangePassword).Code
+	}
+	assert.Equal(t, http.StatusForbidden, changePassword(models.PasswordChange{CurrentPassword: "wrong", NewPassword: "next"}))
+	assert.Equal(t, http.StatusTooManyRequests, changePassword(models.PasswordChange{CurrentPassword: mockAccount.Password, NewPassword: "next"}), "Wrong current passwords count as failed logins")
+	assert.Nil(t, services.UnlockAccount(context.Background(), ctl.store, 66))
+	assert.Equal(t, http.StatusUnprocessableEntity, changePassword(models.PasswordChange{CurrentPassword: mockAccount.Password}))
+	assert.Equal(t, http.StatusAccepted, changePassword(models.PasswordChange{CurrentPassword: mockAccount.Password, NewPassword: "next"}))
+	_, err = services.ValidateLogin(context.Background(), ctl.store, mockAccount1.Username, "next")
+	assert.Nil(t, err)
 
 	// DeleteAccount
 	rec = echotest.ContextConfig{
@@ -210,12 +278,14 @@ func TestAccountController(t *testing.T) {
 			echo.HeaderContentType: {echo.MIMEApplicationJSON},
 		},
 		JSONBody: jsonAcc1,
-	}.ServeWithHandler(t, DeleteAccount)
+	}.ServeWithHandler(t, ctl.DeleteAccount)
 
 	assert.Equal(t, http.StatusAccepted, rec.Code)
 }
 
 func TestItemController(t *testing.T) {
+	ctl := testController(t)
+
 	// Scan PNG Image As Byte Array Into mockItem & mockItem1
 	testPng, err := os.Open("../services/sample.png")
 	if err != nil {
@@ -248,7 +318,7 @@ func TestItemController(t *testing.T) {
 			echo.HeaderContentType: {echo.MIMEApplicationJSON},
 		},
 		JSONBody: jsonItem,
-	}.ServeWithHandler(t, AddItem)
+	}.ServeWithHandler(t, ctl.AddItem)
 
 	assert.Equal(t, http.StatusCreated, rec.Code)
 	assert.Equal(t, string(jsonItem)+"\n", rec.Body.String())
@@ -259,9 +329,27 @@ func TestItemController(t *testing.T) {
 			echo.HeaderContentType: {echo.MIMEApplicationJSON},
 		},
 		JSONBody: jsonItem,
-	}.ServeWithHandler(t, GetItems)
+	}.ServeWithHandler(t, ctl.GetItems)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
+	var items models.Page[models.Item]
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &items))
+	assert.Len(t, items.Data, 1)
+	assert.Equal(t, models.PageInfo{Limit: models.DefaultPageLimit, Total: 1, Sort: []string{"id"}}, items.Page)
+
+	rec = echotest.ContextConfig{
+		QueryValues: url.Values{"limit": {"1"}, "sort": {"-name"}, "name": {"nobody"}, "units": {"metric"}},
+	}.ServeWithHandler(t, ctl.GetItems)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &items))
+	assert.Empty(t, items.Data)
+	assert.Equal(t, []string{"-name", "id"}, items.Page.Sort)
+
+	for _, query := range []url.Values{{"limit": {"all"}}, {"limit": {"1000"}}, {"sort": {"image"}}, {"weight": {"1"}}} {
+		rec = echotest.ContextConfig{QueryValues: query}.ServeWithHandler(t, ctl.GetItems)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query.Encode())
+	}
 
 	// GetItem
 	rec = echotest.ContextConfig{
@@ -272,7 +360,7 @@ func TestItemController(t *testing.T) {
 			echo.HeaderContentType: {echo.MIMEApplicationJSON},
 		},
 		JSONBody: jsonItem,
-	}.ServeWithHandler(t, GetItem)
+	}.ServeWithHandler(t, ctl.GetItem)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 
@@ -285,7 +373,7 @@ func TestItemController(t *testing.T) {
 			echo.HeaderContentType: {echo.MIMEApplicationJSON},
 		},
 		JSONBody: jsonItem1,
-	}.ServeWithHandler(t, UpdateItem)
+	}.ServeWithHandler(t, ctl.UpdateItem)
 
 	assert.Equal(t, http.StatusAccepted, rec.Code)
 
@@ -298,12 +386,15 @@ func TestItemController(t *testing.T) {
 			echo.HeaderContentType: {echo.MIMEApplicationJSON},
 		},
 		JSONBody: jsonItem1,
-	}.ServeWithHandler(t, DeleteItem)
+	}.ServeWithHandler(t, ctl.DeleteItem)
 
 	assert.Equal(t, http.StatusAccepted, rec.Code)
 }
 
 func TestOrderController(t *testing.T) {
+	ctl := testController(t)
+	manager := models.JwtCustomClaims{ID: 1, Role: models.Role{Value: models.RoleManager}}
+
 	// Mock Orders In JSON
 	jsonOrder, err := json.Marshal(mockOrder)
 	if err != nil {
@@ -321,7 +412,7 @@ func TestOrderController(t *testing.T) {
 			echo.HeaderContentType: {echo.MIMEApplicationJSON},
 		},
 		JSONBody: jsonOrder,
-	}.ServeWithHandler(t, AddOrder)
+	}.ServeWithHandler(t, as(manager, ctl.AddOrder))
 
 	assert.Equal(t, http.StatusCreated, rec.Code)
 	assert.Equal(t, string(jsonOrder)+"\n", rec.Body.String())
@@ -332,7 +423,7 @@ func TestOrderController(t *testing.T) {
 			echo.HeaderContentType: {echo.MIMEApplicationJSON},
 		},
 		JSONBody: jsonOrder,
-	}.ServeWithHandler(t, GetOrders)
+	}.ServeWithHandler(t, as(manager, ctl.GetOrders))
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 
@@ -345,7 +436,7 @@ func TestOrderController(t *testing.T) {
 			echo.HeaderContentType: {echo.MIMEApplicationJSON},
 		},
 		JSONBody: jsonOrder1,
-	}.ServeWithHandler(t, GetOrder)
+	}.ServeWithHandler(t, as(manager, ctl.GetOrder))
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 
@@ -358,10 +449,51 @@ func TestOrderController(t *testing.T) {
 			echo.HeaderContentType: {echo.MIMEApplicationJSON},
 		},
 		JSONBody: jsonOrder1,
-	}.ServeWithHandler(t, UpdateOrder)
+	}.ServeWithHandler(t, ctl.UpdateOrder)
 
 	assert.Equal(t, http.StatusAccepted, rec.Code)
 
+	// TransitionOrder
+	for _, step := range []struct {
+		status string
+		code   int
+	}{
+		{"placed", http.StatusOK},
+		{models.OrderShipped, http.StatusConflict},
+		{"LOST", http.StatusUnprocessableEntity},
+		{models.OrderAllocated, http.StatusConflict}, // nothing in stock
+		{models.OrderCancelled, http.StatusOK},
+	} {
+		rec = echotest.ContextConfig{
+			PathValues: echo.PathValues{
+				{Name: "id", Value: "66"},
+			},
+			Headers: map[string][]string{
+				echo.HeaderContentType: {echo.MIMEApplicationJSON},
+			},
+			JSONBody: []byte(`{"status":"` + step.status + `"}`),
+		}.ServeWithHandler(t, ctl.TransitionOrder)
+
+		assert.Equal(t, step.code, rec.Code, step.status)
+	}
+	var order models.Order
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &order))
+	assert.Equal(t, models.OrderCancelled, order.Status)
+	assert.Equal(t, []string{models.OrderDraft, models.OrderPlaced}, []string{order.History[0].From, order.History[1].From})
+
+	// UpdateOrder is refused once the order left the placed state
+	rec = echotest.ContextConfig{
+		PathValues: echo.PathValues{
+			{Name: "id", Value: "66"},
+		},
+		Headers: map[string][]string{
+			echo.HeaderContentType: {echo.MIMEApplicationJSON},
+		},
+		JSONBody: jsonOrder1,
+	}.ServeWithHandler(t, ctl.UpdateOrder)
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
 	// DeleteOrder
 	rec = echotest.ContextConfig{
 		PathValues: echo.PathValues{
@@ -371,12 +503,79 @@ func TestOrderController(t *testing.T) {
 			echo.HeaderContentType: {echo.MIMEApplicationJSON},
 		},
 		JSONBody: jsonOrder1,
-	}.ServeWithHandler(t, DeleteOrder)
+	}.ServeWithHandler(t, ctl.DeleteOrder)
 
 	assert.Equal(t, http.StatusAccepted, rec.Code)
 }
 
+func TestOrderOwnership(t *testing.T) {
+	ctl := testController(t)
+	ctx := context.Background()
+	grace := models.Account{ID: 66, Firstname: "Grace", Lastname: "Hopper", Username: "grace", Role: models.Role{Value: models.RoleCustomer}, Active: true}
+	assert.Nil(t, ctl.store.AddAccount(ctx, grace))
+	assert.Nil(t, ctl.store.AddOrder(ctx, models.Order{ID: 1, Customer: models.Account{ID: 67, Firstname: "Ada", Lastname: "Lovelace"}, Address: "2 Bean Lane"}))
+	customer := models.JwtCustomClaims{ID: 66, Role: models.Role{Value: models.RoleCustomer}}
+
+	rec := echotest.ContextConfig{
+		Headers:  map[string][]string{echo.HeaderContentType: {echo.MIMEApplicationJSON}},
+		JSONBody: []byte(`{"id":2,"customer":{"id":67,"firstname":"Ada"},"address":"1 Bean Lane"}`),
+	}.ServeWithHandler(t, as(customer, ctl.AddOrder))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	order, err := ctl.store.GetOrder(ctx, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, "Grace", order.Customer.Firstname, "Customers order for themselves")
+
+	rec = echotest.ContextConfig{}.ServeWithHandler(t, as(customer, ctl.GetOrders))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var orders models.Page[models.Order]
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &orders))
+	if assert.Len(t, orders.Data, 1) {
+		assert.Equal(t, int64(2), orders.Data[0].ID)
+	}
+	rec = echotest.ContextConfig{QueryValues: url.Values{"customerId": {"67"}}}.ServeWithHandler(t, as(customer, ctl.GetOrders))
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &orders))
+	assert.Len(t, orders.Data, 1, "Customers cannot filter for other customers")
+
+	for id, code := range map[string]int{"1": http.StatusNotFound, "2": http.StatusOK, "3": http.StatusNotFound} {
+		rec = echotest.ContextConfig{PathValues: echo.PathValues{{Name: "id", Value: id}}}.ServeWithHandler(t, as(customer, ctl.GetOrder))
+		assert.Equal(t, code, rec.Code, id)
+	}
+	rec = echotest.ContextConfig{PathValues: echo.PathValues{{Name: "id", Value: "1"}}}.ServeWithHandler(t, as(models.JwtCustomClaims{ID: 2, Role: models.Role{Value: models.RoleEmployee}}, ctl.GetOrder))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestUnknownIDs(t *testing.T) {
+	ctl := testController(t)
+	for name, handler := range map[string]echo.HandlerFunc{
+		"GetAccount":     ctl.GetAccount,
+		"UpdateAccount":  ctl.UpdateAccount,
+		"DeleteAccount":  ctl.DeleteAccount,
+		"GetItem":        ctl.GetItem,
+		"DeleteItem":     ctl.DeleteItem,
+		"GetBox":         ctl.GetBox,
+		"DeleteBox":      ctl.DeleteBox,
+		"GetInventory":   ctl.GetInventory,
+		"DeleteOrder":    ctl.DeleteOrder,
+		"DeleteLocation": ctl.DeleteLocation,
+		"DeleteCarton":   ctl.DeleteCarton,
+	} {
+		rec := echotest.ContextConfig{
+			PathValues: echo.PathValues{{Name: "id", Value: "999"}},
+			Headers:    map[string][]string{echo.HeaderContentType: {echo.MIMEApplicationJSON}},
+			JSONBody:   []byte(`{}`),
+		}.ServeWithHandler(t, as(models.JwtCustomClaims{ID: 1, Role: models.Role{Value: models.RoleAdmin}}, handler))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code, name)
+	}
+}
+
 func TestBoxController(t *testing.T) {
+	ctl := testController(t)
+
+	// Box and inventory rows reference an existing item
+	err := ctl.store.AddItem(context.Background(), mockItem)
+	assert.Nil(t, err)
+
 	// Mock Boxes In JSON
 	jsonBox, err := json.Marshal(mockBox)
 	if err != nil {
@@ -394,7 +593,7 @@ func TestBoxController(t *testing.T) {
 			echo.HeaderContentType: {echo.MIMEApplicationJSON},
 		},
 		JSONBody: jsonBox,
-	}.ServeWithHandler(t, AddBox)
+	}.ServeWithHandler(t, ctl.AddBox)
 
 	assert.Equal(t, http.StatusCreated, rec.Code)
 	assert.Equal(t, string(jsonBox)+"\n", rec.Body.String())
@@ -405,7 +604,7 @@ func TestBoxController(t *testing.T) {
 			echo.HeaderContentType: {echo.MIMEApplicationJSON},
 		},
 		JSONBody: jsonBox,
-	}.ServeWithHandler(t, GetBoxes)
+	}.ServeWithHandler(t, ctl.GetBoxes)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 
@@ -418,7 +617,7 @@ func TestBoxController(t *testing.T) {
 			echo.HeaderContentType: {echo.MIMEApplicationJSON},
 		},
 		JSONBody: jsonBox,
-	}.ServeWithHandler(t, GetBox)
+	}.ServeWithHandler(t, ctl.GetBox)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 
@@ -431,7 +630,7 @@ func TestBoxController(t *testing.T) {
 			echo.HeaderContentType: {echo.MIMEApplicationJSON},
 		},
 		JSONBody: jsonBox1,
-	}.ServeWithHandler(t, UpdateBox)
+	}.ServeWithHandler(t, ctl.UpdateBox)
 
 	assert.Equal(t, http.StatusAccepted, rec.Code)
 
@@ -444,12 +643,24 @@ func TestBoxController(t *testing.T) {
 			echo.HeaderContentType: {echo.MIMEApplicationJSON},
 		},
 		JSONBody: jsonBox1,
-	}.ServeWithHandler(t, DeleteBox)
+	}.ServeWithHandler(t, ctl.DeleteBox)
 
 	assert.Equal(t, http.StatusAccepted, rec.Code)
 }
 
 func TestInventoryController(t *testing.T) {
+	ctl := testController(t)
+
+	// Box and inventory rows reference an existing item
+	err := ctl.store.AddItem(context.Background(), mockItem)
+	assert.Nil(t, err)
+
+	// Stock is kept at existing locations
+	for _, code := range []string{"A12", "C4"} {
+		err = ctl.store.AddLocation(context.Background(), models.Location{Code: code, Zone: code, Type: models.LocationReserve, Active: true})
+		assert.Nil(t, err)
+	}
+
 	// Mock Inventory In JSON
 	jsonInv, err := json.Marshal(mockInv)
 	if err != nil {
@@ -467,7 +678,7 @@ func TestInventoryController(t *testing.T) {
 			echo.HeaderContentType: {echo.MIMEApplicationJSON},
 		},
 		JSONBody: jsonInv,
-	}.ServeWithHandler(t, AddInventory)
+	}.ServeWithHandler(t, ctl.AddInventory)
 
 	assert.Equal(t, http.StatusCreated, rec.Code)
 	assert.Equal(t, string(jsonInv)+"\n", rec.Body.String())
@@ -478,7 +689,7 @@ func TestInventoryController(t *testing.T) {
 			echo.HeaderContentType: {echo.MIMEApplicationJSON},
 		},
 		JSONBody: jsonInv,
-	}.ServeWithHandler(t, GetAllInventory)
+	}.ServeWithHandler(t, ctl.GetAllInventory)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 
@@ -491,9 +702,32 @@ func TestInventoryController(t *testing.T) {
 			echo.HeaderContentType: {echo.MIMEApplicationJSON},
 		},
 		JSONBody: jsonInv,
-	}.ServeWithHandler(t, GetInventory)
+	}.ServeWithHandler(t, ctl.GetInventory)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var inv models.Inventory
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &inv))
+	assert.Equal(t, int64(2345), inv.TotalCount)
+
+	// GetAvailability
+	rec = echotest.ContextConfig{
+		PathValues: echo.PathValues{
+			{Name: "id", Value: "66"},
+		},
+	}.ServeWithHandler(t, ctl.GetAvailability)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
+	var available models.Availability
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &available))
+	assert.Equal(t, int64(2345), available.Available)
+
+	rec = echotest.ContextConfig{
+		PathValues: echo.PathValues{
+			{Name: "id", Value: "67"},
+		},
+	}.ServeWithHandler(t, ctl.GetAvailability)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
 
 	// UpdateInventory
 	rec = echotest.ContextConfig{
@@ -504,7 +738,7 @@ func TestInventoryController(t *testing.T) {
 			echo.HeaderContentType: {echo.MIMEApplicationJSON},
 		},
 		JSONBody: jsonInv1,
-	}.ServeWithHandler(t, UpdateInventory)
+	}.ServeWithHandler(t, ctl.UpdateInventory)
 
 	assert.Equal(t, http.StatusAccepted, rec.Code)
 
@@ -517,7 +751,905 @@ func TestInventoryController(t *testing.T) {
 			echo.HeaderContentType: {echo.MIMEApplicationJSON},
 		},
 		JSONBody: jsonInv1,
-	}.ServeWithHandler(t, DeleteInventory)
+	}.ServeWithHandler(t, ctl.DeleteInventory)
 
 	assert.Equal(t, http.StatusAccepted, rec.Code)
 }
+
+func TestLocationController(t *testing.T) {
+	ctl := testController(t)
+
+	// AddLocation
+	rec := echotest.ContextConfig{
+		Headers: map[string][]string{
+			echo.HeaderContentType: {echo.MIMEApplicationJSON},
+		},
+		JSONBody: []byte(`{"id":66,"code":"a-21-03","type":"pick_face","capacity":5,"active":true}`),
+	}.ServeWithHandler(t, ctl.AddLocation)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	var loc models.Location
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &loc))
+	assert.Equal(t, "A-21-03", loc.Code)
+	assert.Equal(t, "21", loc.Aisle)
+	assert.Equal(t, models.LocationPickFace, loc.Type)
+
+	// Invalid codes are rejected
+	rec = echotest.ContextConfig{
+		Headers: map[string][]string{
+			echo.HeaderContentType: {echo.MIMEApplicationJSON},
+		},
+		JSONBody: []byte(`{"code":"A--1"}`),
+	}.ServeWithHandler(t, ctl.AddLocation)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	// GetLocations
+	rec = echotest.ContextConfig{}.ServeWithHandler(t, ctl.GetLocations)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// GetLocation
+	rec = echotest.ContextConfig{
+		PathValues: echo.PathValues{
+			{Name: "id", Value: "66"},
+		},
+	}.ServeWithHandler(t, ctl.GetLocation)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = echotest.ContextConfig{
+		PathValues: echo.PathValues{
+			{Name: "id", Value: "67"},
+		},
+	}.ServeWithHandler(t, ctl.GetLocation)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// Stocking the location beyond its capacity fails
+	err := ctl.store.AddItem(context.Background(), mockItem)
+	assert.Nil(t, err)
+	over := models.Inventory{ID: 1, Item: mockItem, Locations: []models.LocationData{{LocationID: 66, Count: 6}}}
+	assert.ErrorAs(t, ctl.store.AddInventory(context.Background(), over), new(*services.ValidationError))
+	over.Locations[0].Count = 5
+	assert.Nil(t, ctl.store.AddInventory(context.Background(), over))
+
+	// GetLocationInventory
+	rec = echotest.ContextConfig{
+		PathValues: echo.PathValues{
+			{Name: "id", Value: "66"},
+		},
+	}.ServeWithHandler(t, ctl.GetLocationInventory)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var stock []models.StockLevel
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &stock))
+	assert.Equal(t, []models.StockLevel{{Item: models.ItemInfo{ID: mockItem.ID, Name: mockItem.Name}, LocationID: 66, LocationCode: "A-21-03", Quantity: 5}}, stock)
+
+	// UpdateLocation
+	rec = echotest.ContextConfig{
+		PathValues: echo.PathValues{
+			{Name: "id", Value: "66"},
+		},
+		Headers: map[string][]string{
+			echo.HeaderContentType: {echo.MIMEApplicationJSON},
+		},
+		JSONBody: []byte(`{"zone":"A","aisle":"21","rack":"03","type":"RESERVE","capacity":50,"active":true}`),
+	}.ServeWithHandler(t, ctl.UpdateLocation)
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+
+	// DeleteLocation is refused once the location has stock history
+	rec = echotest.ContextConfig{
+		PathValues: echo.PathValues{
+			{Name: "id", Value: "66"},
+		},
+	}.ServeWithHandler(t, ctl.DeleteLocation)
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	err = ctl.store.AddLocation(context.Background(), models.Location{ID: 67, Code: "B", Zone: "B", Type: models.LocationDock})
+	assert.Nil(t, err)
+	rec = echotest.ContextConfig{
+		PathValues: echo.PathValues{
+			{Name: "id", Value: "67"},
+		},
+	}.ServeWithHandler(t, ctl.DeleteLocation)
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+}
+
+func TestStockController(t *testing.T) {
+	ctl := testController(t)
+	ctx := context.Background()
+
+	assert.Nil(t, ctl.store.AddItem(ctx, mockItem))
+	for _, code := range []string{"A12", "C4"} {
+		assert.Nil(t, ctl.store.AddLocation(ctx, models.Location{Code: code, Zone: code, Type: models.LocationReserve, Active: true}))
+	}
+	assert.Nil(t, ctl.store.AddInventory(ctx, mockInv))
+
+	// MoveStock
+	rec := echotest.ContextConfig{
+		Headers: map[string][]string{
+			echo.HeaderContentType: {echo.MIMEApplicationJSON},
+		},
+		JSONBody: []byte(`{"itemId":66,"fromLocationId":1,"toLocationId":2,"quantity":45}`),
+	}.ServeWithHandler(t, ctl.MoveStock)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	rec = echotest.ContextConfig{
+		Headers: map[string][]string{
+			echo.HeaderContentType: {echo.MIMEApplicationJSON},
+		},
+		JSONBody: []byte(`{"itemId":66,"fromLocationId":1,"toLocationId":2,"quantity":9999}`),
+	}.ServeWithHandler(t, ctl.MoveStock)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	// AdjustStock
+	rec = echotest.ContextConfig{
+		Headers: map[string][]string{
+			echo.HeaderContentType: {echo.MIMEApplicationJSON},
+		},
+		JSONBody: []byte(`{"itemId":66,"locationId":2,"counted":40,"reason":"cycle_count"}`),
+	}.ServeWithHandler(t, ctl.AdjustStock)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	// TransferStock
+	rec = echotest.ContextConfig{
+		Headers: map[string][]string{
+			echo.HeaderContentType: {echo.MIMEApplicationJSON},
+		},
+		JSONBody: []byte(`{"fromLocationId":2,"toLocationId":1}`),
+	}.ServeWithHandler(t, ctl.TransferStock)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	// GetStockTransactions
+	rec = echotest.ContextConfig{
+		QueryValues: url.Values{"itemId": {"66"}, "locationId": {"2"}},
+	}.ServeWithHandler(t, ctl.GetStockTransactions)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var ledger models.Page[models.StockTransaction]
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &ledger))
+	assert.Equal(t, []string{models.StockMove, models.StockCount, models.StockMove}, []string{ledger.Data[0].Type, ledger.Data[1].Type, ledger.Data[2].Type})
+
+	inv, err := ctl.store.GetInventory(ctx, 66)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2340), inv.TotalCount)
+
+	// DeleteItem is refused once the item has stock history
+	rec = echotest.ContextConfig{
+		PathValues: echo.PathValues{
+			{Name: "id", Value: "66"},
+		},
+	}.ServeWithHandler(t, ctl.DeleteItem)
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+}
+
+func TestShipmentController(t *testing.T) {
+	ctl := testController(t)
+	ctx := context.Background()
+
+	assert.Nil(t, ctl.store.AddItem(ctx, mockItem))
+	assert.Nil(t, ctl.store.AddLocation(ctx, models.Location{ID: 1, Code: "DOCK1", Zone: "DOCK1", Type: models.LocationDock, Active: true}))
+	shipment := models.Shipment{ID: 66, Distributor: "beans united", ETA: time.Now(), Payload: []models.ItemGroup{{Item: mockItem, Count: 10}}}
+	jsonShipment, err := json.Marshal(shipment)
+	assert.Nil(t, err)
+
+	// AddShipment
+	rec := echotest.ContextConfig{
+		Headers: map[string][]string{
+			echo.HeaderContentType: {echo.MIMEApplicationJSON},
+		},
+		JSONBody: jsonShipment,
+	}.ServeWithHandler(t, ctl.AddShipment)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	// GetShipments
+	rec = echotest.ContextConfig{}.ServeWithHandler(t, ctl.GetShipments)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Receiving needs the shipment to have arrived
+	receive := []byte(`{"locationId":1,"lines":[{"line":0,"received":8,"damaged":1}]}`)
+	rec = echotest.ContextConfig{
+		PathValues: echo.PathValues{
+			{Name: "id", Value: "66"},
+		},
+		Headers: map[string][]string{
+			echo.HeaderContentType: {echo.MIMEApplicationJSON},
+		},
+		JSONBody: receive,
+	}.ServeWithHandler(t, ctl.ReceiveShipment)
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	// ArriveShipment
+	rec = echotest.ContextConfig{
+		PathValues: echo.PathValues{
+			{Name: "id", Value: "66"},
+		},
+	}.ServeWithHandler(t, ctl.ArriveShipment)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// UpdateShipment
+	rec = echotest.ContextConfig{
+		PathValues: echo.PathValues{
+			{Name: "id", Value: "66"},
+		},
+		Headers: map[string][]string{
+			echo.HeaderContentType: {echo.MIMEApplicationJSON},
+		},
+		JSONBody: jsonShipment,
+	}.ServeWithHandler(t, ctl.UpdateShipment)
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+
+	// ReceiveShipment
+	rec = echotest.ContextConfig{
+		PathValues: echo.PathValues{
+			{Name: "id", Value: "66"},
+		},
+		Headers: map[string][]string{
+			echo.HeaderContentType: {echo.MIMEApplicationJSON},
+		},
+		JSONBody: receive,
+	}.ServeWithHandler(t, ctl.ReceiveShipment)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var received models.Shipment
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &received))
+	assert.Equal(t, models.ShipmentReceived, received.Status)
+	assert.Equal(t, []string{models.DiscrepancyShort, models.DiscrepancyDamaged}, received.Receipt[0].Discrepancies)
+
+	// Good units are posted into inventory at the receiving location
+	inventory, err := ctl.store.GetAllInventory(ctx, models.ListQuery{})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(8), inventory.Data[0].TotalCount)
+	assert.Equal(t, "DOCK1", inventory.Data[0].Locations[0].Area)
+
+	// DeleteShipment keeps received shipments
+	rec = echotest.ContextConfig{
+		PathValues: echo.PathValues{
+			{Name: "id", Value: "66"},
+		},
+	}.ServeWithHandler(t, ctl.DeleteShipment)
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	// Unknown shipments are not found
+	for _, handler := range []echo.HandlerFunc{ctl.GetShipment, ctl.UpdateShipment, ctl.DeleteShipment} {
+		rec = echotest.ContextConfig{
+			PathValues: echo.PathValues{{Name: "id", Value: "999"}},
+			Headers:    map[string][]string{echo.HeaderContentType: {echo.MIMEApplicationJSON}},
+			JSONBody:   []byte(`{}`),
+		}.ServeWithHandler(t, handler)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestPickingController(t *testing.T) {
+	ctl := testController(t)
+	ctx := context.Background()
+
+	// CreateWave has nothing to pick yet
+	rec := echotest.ContextConfig{
+		Headers: map[string][]string{
+			echo.HeaderContentType: {echo.MIMEApplicationJSON},
+		},
+		JSONBody: []byte(`{"zone":"A12"}`),
+	}.ServeWithHandler(t, ctl.CreateWave)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	assert.Nil(t, ctl.store.AddItem(ctx, mockItem))
+	assert.Nil(t, ctl.store.AddLocation(ctx, models.Location{Code: "A12", Zone: "A12", Type: models.LocationPickFace, Active: true}))
+	assert.Nil(t, ctl.store.AddInventory(ctx, mockInv))
+	assert.Nil(t, ctl.store.AddOrder(ctx, models.Order{ID: 66, Status: models.OrderPlaced, Payload: []models.ItemGroup{{Item: mockItem, Count: 10}}}))
+
+	// CreateWave
+	rec = echotest.ContextConfig{
+		Headers: map[string][]string{
+			echo.HeaderContentType: {echo.MIMEApplicationJSON},
+		},
+		JSONBody: []byte(`{"zone":"A12"}`),
+	}.ServeWithHandler(t, ctl.CreateWave)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	var wave models.Wave
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &wave))
+	assert.Len(t, wave.Tasks, 1)
+	id := strconv.FormatInt(wave.Tasks[0].ID, 10)
+
+	// GetPickTasks
+	rec = echotest.ContextConfig{
+		QueryValues: url.Values{"status": {"open"}},
+	}.ServeWithHandler(t, ctl.GetPickTasks)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var tasks models.Page[models.PickTask]
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &tasks))
+	assert.Len(t, tasks.Data, 1)
+
+	rec = echotest.ContextConfig{
+		QueryValues: url.Values{"status": {"LOST"}},
+	}.ServeWithHandler(t, ctl.GetPickTasks)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// ClaimPickTask, ShortPickTask, ConfirmPickTask
+	for _, step := range []struct {
+		handler echo.HandlerFunc
+		body    string
+		code    int
+	}{
+		{ctl.ConfirmPickTask, ``, http.StatusConflict},
+		{ctl.ClaimPickTask, ``, http.StatusOK},
+		{ctl.ShortPickTask, `{"quantity":10}`, http.StatusUnprocessableEntity},
+		{ctl.ConfirmPickTask, ``, http.StatusOK},
+	} {
+		rec = echotest.ContextConfig{
+			PathValues: echo.PathValues{
+				{Name: "id", Value: id},
+			},
+			Headers: map[string][]string{
+				echo.HeaderContentType: {echo.MIMEApplicationJSON},
+			},
+			JSONBody: []byte(step.body),
+		}.ServeWithHandler(t, step.handler)
+
+		assert.Equal(t, step.code, rec.Code)
+	}
+
+	// GetWave
+	rec = echotest.ContextConfig{
+		PathValues: echo.PathValues{
+			{Name: "id", Value: strconv.FormatInt(wave.ID, 10)},
+		},
+	}.ServeWithHandler(t, ctl.GetWave)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &wave))
+	assert.Equal(t, models.WaveCompleted, wave.Status)
+
+	inv, err := ctl.store.GetInventory(ctx, 66)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2335), inv.TotalCount)
+}
+
+func TestPackingController(t *testing.T) {
+	ctl := testController(t)
+	ctx := context.Background()
+
+	assert.Nil(t, ctl.store.AddItem(ctx, mockItem))
+
+	// AddBox rejects dimensions it cannot pack by
+	for _, step := range []struct {
+		body string
+		code int
+	}{
+		{`{"id":1,"item":{"id":66},"dimensions":"2 by 4","count":1}`, http.StatusBadRequest},
+		{`{"id":1,"item":{"id":66},"dimensions":{"length":2,"width":0,"height":4},"count":1}`, http.StatusUnprocessableEntity},
+		{`{"id":1,"item":{"id":66},"dimensions":{"length":2,"width":2,"height":4,"unit":"yd"},"count":1}`, http.StatusUnprocessableEntity},
+	} {
+		rec := echotest.ContextConfig{
+			Headers: map[string][]string{
+				echo.HeaderContentType: {echo.MIMEApplicationJSON},
+			},
+			JSONBody: []byte(step.body),
+		}.ServeWithHandler(t, ctl.AddBox)
+
+		assert.Equal(t, step.code, rec.Code, step.body)
+	}
+	assert.Nil(t, ctl.store.AddBox(ctx, mockBox))
+
+	// AddCarton
+	for _, step := range []struct {
+		body string
+		code int
+	}{
+		{`{"id":1,"name":" ","dimensions":{"length":12,"width":12,"height":12}}`, http.StatusUnprocessableEntity},
+		{`{"id":1,"name":"S","dimensions":{"length":12,"width":12,"height":12,"unit":"ft"}}`, http.StatusUnprocessableEntity},
+		{`{"id":1,"name":"S","dimensions":{"length":30,"width":30,"height":30,"unit":"cm"},"tareWeight":0.5,"active":true}`, http.StatusCreated},
+		{`{"id":2,"name":"S","dimensions":{"length":12,"width":12,"height":12}}`, http.StatusConflict},
+	} {
+		rec := echotest.ContextConfig{
+			Headers: map[string][]string{
+				echo.HeaderContentType: {echo.MIMEApplicationJSON},
+			},
+			JSONBody: []byte(step.body),
+		}.ServeWithHandler(t, ctl.AddCarton)
+
+		assert.Equal(t, step.code, rec.Code, step.body)
+	}
+
+	// GetCarton
+	rec := echotest.ContextConfig{
+		PathValues: echo.PathValues{
+			{Name: "id", Value: "1"},
+		},
+	}.ServeWithHandler(t, ctl.GetCarton)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var carton models.Carton
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &carton))
+	assert.Equal(t, models.UnitCentimetre, carton.Dimensions.Unit)
+
+	rec = echotest.ContextConfig{
+		PathValues: echo.PathValues{
+			{Name: "id", Value: "1"},
+		},
+		QueryValues: url.Values{"units": {"imperial"}},
+	}.ServeWithHandler(t, ctl.GetCarton)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &carton))
+	assert.Equal(t, models.Dimensions{Length: 11.811024, Width: 11.811024, Height: 11.811024, Unit: models.UnitInch}, carton.Dimensions)
+
+	rec = echotest.ContextConfig{
+		PathValues: echo.PathValues{
+			{Name: "id", Value: "1"},
+		},
+		QueryValues: url.Values{"units": {"furlongs"}},
+	}.ServeWithHandler(t, ctl.GetCarton)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// SuggestCartons
+	assert.Nil(t, ctl.store.AddOrder(ctx, models.Order{ID: 66, Payload: []models.ItemGroup{{Item: mockItem, Count: 10}}}))
+	rec = echotest.ContextConfig{
+		PathValues: echo.PathValues{
+			{Name: "id", Value: "66"},
+		},
+	}.ServeWithHandler(t, ctl.SuggestCartons)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var packing models.Packing
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &packing))
+	assert.Len(t, packing.Cartons, 1)
+	assert.Equal(t, models.Weight{Value: 10.5, Unit: models.UnitPound}, packing.Weight)
+
+	rec = echotest.ContextConfig{
+		PathValues: echo.PathValues{
+			{Name: "id", Value: "66"},
+		},
+		QueryValues: url.Values{"units": {"metric"}},
+	}.ServeWithHandler(t, ctl.SuggestCartons)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &packing))
+	assert.Equal(t, models.Weight{Value: 4.762720, Unit: models.UnitKilogram}, packing.Weight)
+	assert.Equal(t, models.UnitKilogram, packing.Cartons[0].Contents[0].Item.Weight.Unit)
+
+	// PackOrder before the order is picked
+	rec = echotest.ContextConfig{
+		PathValues: echo.PathValues{
+			{Name: "id", Value: "66"},
+		},
+		Headers: map[string][]string{
+			echo.HeaderContentType: {echo.MIMEApplicationJSON},
+		},
+		JSONBody: []byte(`{"cartons":[{"cartonId":1,"contents":[{"item":{"id":66},"count":10}]}]}`),
+	}.ServeWithHandler(t, ctl.PackOrder)
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	// GetPacking
+	rec = echotest.ContextConfig{
+		PathValues: echo.PathValues{
+			{Name: "id", Value: "67"},
+		},
+	}.ServeWithHandler(t, ctl.GetPacking)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestBarcodeController(t *testing.T) {
+	ctl := testController(t)
+
+	for _, step := range []struct {
+		body string
+		code int
+	}{
+		{`{"id":1,"upc":"036000291453","name":"typo"}`, http.StatusUnprocessableEntity},
+		{`{"id":1,"upc":"","name":"no code"}`, http.StatusUnprocessableEntity},
+		{`{"id":1,"upc":"036000291452","name":"beans"}`, http.StatusCreated},
+		{`{"id":2,"upc":"00036000291452","name":"same beans"}`, http.StatusConflict},
+		{`{"id":2,"upc":"4006381333931","name":"pens"}`, http.StatusCreated},
+	} {
+		rec := echotest.ContextConfig{
+			Headers: map[string][]string{
+				echo.HeaderContentType: {echo.MIMEApplicationJSON},
+			},
+			JSONBody: []byte(step.body),
+		}.ServeWithHandler(t, ctl.AddItem)
+
+		assert.Equal(t, step.code, rec.Code, step.body)
+	}
+	item, err := ctl.store.GetItem(context.Background(), 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "00036000291452", item.UPC)
+
+	// UpdateItem and UpdateBox
+	for _, step := range []struct {
+		handler echo.HandlerFunc
+		body    string
+		code    int
+	}{
+		{ctl.UpdateItem, `{"upc":"036000291452","name":"pens"}`, http.StatusConflict},
+		{ctl.UpdateItem, `{"upc":"4006381333932","name":"pens"}`, http.StatusUnprocessableEntity},
+		{ctl.AddBox, `{"id":2,"upc":"1234","item":{"id":2},"dimensions":{"length":1,"width":1,"height":1},"count":10}`, http.StatusUnprocessableEntity},
+		{ctl.AddBox, `{"id":2,"upc":"14006381333938","item":{"id":2},"dimensions":{"length":1,"width":1,"height":1},"count":10}`, http.StatusCreated},
+		{ctl.UpdateBox, `{"upc":"14006381333939","item":{"id":2},"dimensions":{"length":1,"width":1,"height":1},"count":10}`, http.StatusUnprocessableEntity},
+	} {
+		rec := echotest.ContextConfig{
+			PathValues: echo.PathValues{
+				{Name: "id", Value: "2"},
+			},
+			Headers: map[string][]string{
+				echo.HeaderContentType: {echo.MIMEApplicationJSON},
+			},
+			JSONBody: []byte(step.body),
+		}.ServeWithHandler(t, step.handler)
+
+		assert.Equal(t, step.code, rec.Code, step.body)
+	}
+}
+
+func TestUnitOfMeasureController(t *testing.T) {
+	ctl := testController(t)
+	ctx := context.Background()
+
+	assert.Nil(t, ctl.store.AddItem(ctx, mockItem))
+	assert.Nil(t, ctl.store.AddBox(ctx, mockBox))
+
+	// UpdateItemUnits
+	for _, step := range []struct {
+		id   string
+		body string
+		code int
+	}{
+		{"66", `[{"uom":"pallet","quantity":660}]`, http.StatusAccepted},
+		{"66", `[{"uom":"case","quantity":66},{"uom":"inner","quantity":66}]`, http.StatusUnprocessableEntity},
+		{"66", `[{"uom":"each","quantity":2}]`, http.StatusUnprocessableEntity},
+		{"67", `[]`, http.StatusNotFound},
+	} {
+		rec := echotest.ContextConfig{
+			PathValues: echo.PathValues{
+				{Name: "id", Value: step.id},
+			},
+			Headers: map[string][]string{
+				echo.HeaderContentType: {echo.MIMEApplicationJSON},
+			},
+			JSONBody: []byte(step.body),
+		}.ServeWithHandler(t, ctl.UpdateItemUnits)
+
+		assert.Equal(t, step.code, rec.Code, step.body)
+	}
+
+	// GetItemUnits
+	rec := echotest.ContextConfig{
+		PathValues: echo.PathValues{
+			{Name: "id", Value: "66"},
+		},
+	}.ServeWithHandler(t, ctl.GetItemUnits)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var units []models.UnitOfMeasure
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &units))
+	assert.Equal(t, []models.UnitOfMeasure{
+		{ItemID: 66, UOM: models.UOMEach, Quantity: 1, UPC: "00036000291452"},
+		{ItemID: 66, UOM: models.UOMCase, Quantity: 66, UPC: "10036000291459"},
+		{ItemID: 66, UOM: models.UOMPallet, Quantity: 660},
+	}, units, "The box is the case")
+
+	rec = echotest.ContextConfig{
+		PathValues: echo.PathValues{
+			{Name: "id", Value: "67"},
+		},
+	}.ServeWithHandler(t, ctl.GetItemUnits)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// AddInventory in cases
+	assert.Nil(t, ctl.store.AddLocation(ctx, models.Location{ID: 1, Code: "DOCK-1", Type: models.LocationDock, Active: true}))
+	rec = echotest.ContextConfig{
+		Headers: map[string][]string{
+			echo.HeaderContentType: {echo.MIMEApplicationJSON},
+		},
+		JSONBody: []byte(`{"id":66,"item":{"id":66},"locations":[{"locationId":1,"count":2,"uom":"CASE"},{"locationId":1,"count":5}]}`),
+	}.ServeWithHandler(t, ctl.AddInventory)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	inv, err := ctl.store.GetInventory(ctx, 66)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(137), inv.TotalCount)
+
+	// AddOrder in pallets, and in a unit the item is not handled in
+	for _, step := range []struct {
+		body string
+		code int
+	}{
+		{`{"id":66,"payload":[{"item":{"id":66},"count":1,"uom":"pallet"}]}`, http.StatusCreated},
+		{`{"id":67,"payload":[{"item":{"id":66},"count":1,"uom":"inner"}]}`, http.StatusUnprocessableEntity},
+	} {
+		rec := echotest.ContextConfig{
+			Headers: map[string][]string{
+				echo.HeaderContentType: {echo.MIMEApplicationJSON},
+			},
+			JSONBody: []byte(step.body),
+		}.ServeWithHandler(t, as(models.JwtCustomClaims{ID: 1, Role: models.Role{Value: models.RoleManager}}, ctl.AddOrder))
+
+		assert.Equal(t, step.code, rec.Code, step.body)
+	}
+	order, err := ctl.store.GetOrder(ctx, 66)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(660), order.Payload[0].Count)
+	assert.Empty(t, order.Payload[0].UOM)
+
+	// ReceiveShipment counted in cases
+	rec = echotest.ContextConfig{
+		Headers: map[string][]string{
+			echo.HeaderContentType: {echo.MIMEApplicationJSON},
+		},
+		JSONBody: []byte(`{"id":66,"payload":[{"item":{"id":66},"count":10,"uom":"case"}]}`),
+	}.ServeWithHandler(t, ctl.AddShipment)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	_, err = ctl.store.ArriveShipment(ctx, 66)
+	assert.Nil(t, err)
+	rec = echotest.ContextConfig{
+		PathValues: echo.PathValues{
+			{Name: "id", Value: "66"},
+		},
+		Headers: map[string][]string{
+			echo.HeaderContentType: {echo.MIMEApplicationJSON},
+		},
+		JSONBody: []byte(`{"locationId":1,"lines":[{"line":0,"received":9,"damaged":1,"uom":"case"}]}`),
+	}.ServeWithHandler(t, ctl.ReceiveShipment)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	shipment, err := ctl.store.GetShipment(ctx, 66)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(660), shipment.Payload[0].Count)
+	assert.Equal(t, int64(594), shipment.Receipt[0].Received)
+	assert.Equal(t, int64(66), shipment.Receipt[0].Damaged)
+}
+
+func TestScanController(t *testing.T) {
+	ctl := testController(t)
+	ctx := context.Background()
+
+	assert.Nil(t, ctl.store.AddItem(ctx, mockItem))
+	assert.Nil(t, ctl.store.AddBox(ctx, mockBox))
+
+	for _, step := range []struct {
+		body string
+		code int
+		kind string
+	}{
+		{`{"data":"036000291452"}`, http.StatusOK, models.ScanItem},
+		{`{"data":"]C1011003600029145910L42\u001d17261231"}`, http.StatusOK, models.ScanBox},
+		{`{"data":"(01)10036000291459(37)6"}`, http.StatusOK, models.ScanBox},
+		{`{"data":"4006381333931"}`, http.StatusNotFound, ""},
+		{`{"data":"A-01-01"}`, http.StatusNotFound, ""},
+		{`{"data":"(10)L42"}`, http.StatusUnprocessableEntity, ""},
+		{`{"data":"]C101100360002914"}`, http.StatusUnprocessableEntity, ""},
+		{`{"data":" "}`, http.StatusUnprocessableEntity, ""},
+	} {
+		rec := echotest.ContextConfig{
+			Headers: map[string][]string{
+				echo.HeaderContentType: {echo.MIMEApplicationJSON},
+			},
+			JSONBody: []byte(step.body),
+		}.ServeWithHandler(t, ctl.ResolveScan)
+
+		assert.Equal(t, step.code, rec.Code, step.body)
+		if step.code == http.StatusOK {
+			var result models.ScanResult
+			assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &result))
+			assert.Equal(t, step.kind, result.Kind, step.body)
+			assert.Equal(t, int64(66), result.Item.ID)
+			assert.NotEmpty(t, result.Actions)
+		}
+	}
+
+	// Lot, expiry and count come back with the scan
+	rec := echotest.ContextConfig{
+		Headers: map[string][]string{
+			echo.HeaderContentType: {echo.MIMEApplicationJSON},
+		},
+		JSONBody:    []byte(`{"data":"]C1011003600029145917261200\u001d10L42\u001d376"}`),
+		QueryValues: url.Values{"units": {"metric"}},
+	}.ServeWithHandler(t, ctl.ResolveScan)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var result models.ScanResult
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Equal(t, "L42", result.Scan.Lot)
+	assert.Equal(t, int64(6), result.Scan.Count)
+	assert.Equal(t, time.Date(2026, time.December, 31, 0, 0, 0, 0, time.UTC), *result.Scan.Expiry)
+	assert.Equal(t, models.UnitCentimetre, result.Box.Dimensions.Unit)
+	assert.Equal(t, models.UnitKilogram, result.Item.Weight.Unit)
+}
+
+func TestLabelController(t *testing.T) {
+	ctl := testController(t)
+	ctx := context.Background()
+
+	assert.Nil(t, ctl.store.AddItem(ctx, mockItem))
+	assert.Nil(t, ctl.store.AddBox(ctx, mockBox))
+	assert.Nil(t, ctl.store.AddLocation(ctx, models.Location{ID: 66, Code: "A-01-02", Zone: "A", Type: models.LocationReserve, Active: true}))
+	assert.Nil(t, ctl.store.AddOrder(ctx, models.Order{ID: 66, Payload: []models.ItemGroup{{Item: mockItem, Count: 1}}}))
+
+	for _, step := range []struct {
+		kind        string
+		query       url.Values
+		code        int
+		contentType string
+	}{
+		{"items", url.Values{"ids": {"66"}}, http.StatusOK, "application/zpl"},
+		{"boxes", url.Values{"ids": {"66"}, "format": {"png"}, "template": {"small"}}, http.StatusOK, "image/png"},
+		{"locations", url.Values{"ids": {"66,66"}, "format": {"PDF"}, "template": {"qr"}}, http.StatusOK, "application/pdf"},
+		{"locations", url.Values{"ids": {"66,67"}}, http.StatusNotFound, ""},
+		{"packing", url.Values{"ids": {"66"}}, http.StatusUnprocessableEntity, ""},
+		{"accounts", url.Values{"ids": {"66"}}, http.StatusNotFound, ""},
+		{"items", url.Values{"ids": {"66"}, "template": {"8x10"}}, http.StatusBadRequest, ""},
+		{"items", url.Values{"ids": {"66"}, "format": {"gif"}}, http.StatusBadRequest, ""},
+		{"items", url.Values{"ids": {"66,"}}, http.StatusBadRequest, ""},
+		{"items", url.Values{}, http.StatusBadRequest, ""},
+	} {
+		rec := echotest.ContextConfig{
+			PathValues: echo.PathValues{
+				{Name: "kind", Value: step.kind},
+			},
+			QueryValues: step.query,
+		}.ServeWithHandler(t, ctl.GetLabels)
+
+		assert.Equal(t, step.code, rec.Code, step.kind, step.query)
+		if step.code == http.StatusOK {
+			assert.Equal(t, step.contentType, rec.Header().Get(echo.HeaderContentType))
+			assert.NotEmpty(t, rec.Body.Bytes())
+		}
+	}
+
+	rec := echotest.ContextConfig{
+		PathValues: echo.PathValues{
+			{Name: "kind", Value: "boxes"},
+		},
+		QueryValues: url.Values{"ids": {"66"}, "units": {"metric"}},
+	}.ServeWithHandler(t, ctl.GetLabels)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "^FD(01)10036000291459^FS")
+	assert.Contains(t, rec.Body.String(), "^FDBox of 66^FS")
+	assert.Contains(t, rec.Body.String(), "^FD5.08 x 5.08 x 10.16 cm^FS", "Labels print in the unit system asked for")
+	assert.Equal(t, `inline; filename="boxes.zpl"`, rec.Header().Get(echo.HeaderContentDisposition))
+}
+
+func TestSearchController(t *testing.T) {
+	ctl := testController(t)
+	ctx := context.Background()
+
+	assert.Nil(t, ctl.store.AddItem(ctx, models.Item{ID: 1, UPC: "00036000291452", Name: "Baked beans", Description: "in tomato sauce"}))
+	assert.Nil(t, ctl.store.AddAccount(ctx, models.Account{ID: 66, Firstname: "Grace", Lastname: "Hopper", Username: "grace", Role: models.Role{Value: models.RoleCustomer}}))
+	assert.Nil(t, ctl.store.AddOrder(ctx, models.Order{ID: 1, Customer: models.Account{ID: 66, Firstname: "Grace", Lastname: "Hopper"}, Address: "1 Bean Lane"}))
+	assert.Nil(t, ctl.store.AddOrder(ctx, models.Order{ID: 2, Customer: models.Account{ID: 67, Firstname: "Ada", Lastname: "Lovelace"}, Address: "2 Bean Lane"}))
+	admin := models.JwtCustomClaims{ID: 1, Role: models.Role{Value: models.RoleAdmin}}
+	customer := models.JwtCustomClaims{ID: 66, Role: models.Role{Value: models.RoleCustomer}}
+
+	search := func(claims models.JwtCustomClaims, query url.Values) (int, models.SearchResults) {
+		rec := echotest.ContextConfig{QueryValues: query}.ServeWithHandler(t, as(claims, ctl.Search))
+		var results models.SearchResults
+		if rec.Code == http.StatusOK {
+			assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &results))
+		}
+		return rec.Code, results
+	}
+	hits := func(results models.SearchResults) []string {
+		var found []string
+		for _, hit := range results.Hits {
+			found = append(found, fmt.Sprintf("%s %d", hit.Kind, hit.ID))
+		}
+		return found
+	}
+
+	// Words match as prefixes, best match first
+	code, results := search(admin, url.Values{"q": {"bea"}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"items 1", "orders 1", "orders 2"}, hits(results))
+	assert.Equal(t, "Baked beans", results.Hits[0].Title)
+
+	code, results = search(admin, url.Values{"q": {"grace hop"}, "kinds": {"accounts"}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"accounts 66"}, hits(results))
+
+	// Misspelled words still match
+	code, results = search(admin, url.Values{"q": {"lovelase"}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"orders 2"}, hits(results))
+
+	// Customers only find their own orders and cannot search accounts
+	code, results = search(customer, url.Values{"q": {"lane"}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"orders 1"}, hits(results))
+	assert.Equal(t, []string{models.SearchItems, models.SearchOrders}, results.Kinds)
+
+	code, _ = search(customer, url.Values{"q": {"grace"}, "kinds": {"accounts"}})
+	assert.Equal(t, http.StatusForbidden, code)
+
+	for _, query := range []url.Values{{"q": {"  "}}, {"q": {"bean"}, "limit": {"x"}}, {"q": {"bean"}, "limit": {"1000"}}, {"q": {"bean"}, "kinds": {"boxes"}}} {
+		code, _ = search(admin, query)
+		assert.Equal(t, http.StatusBadRequest, code, query.Encode())
+	}
+
+	rec := echotest.ContextConfig{QueryValues: url.Values{"q": {"bean"}}}.ServeWithHandler(t, ctl.Search)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestQueryController(t *testing.T) {
+	ctl := testController(t)
+	ctx := context.Background()
+
+	for i, name := range []string{"Baked beans", "Rice", "Bean sprouts"} {
+		assert.Nil(t, ctl.store.AddItem(ctx, models.Item{ID: int64(i + 1), UPC: fmt.Sprint(i + 1), Name: name}))
+	}
+	admin := models.JwtCustomClaims{ID: 1, Role: models.Role{Value: models.RoleAdmin}}
+
+	query := func(claims models.JwtCustomClaims, text string) *httptest.ResponseRecorder {
+		body, err := json.Marshal(models.QueryRequest{Query: text})
+		assert.Nil(t, err)
+		return echotest.ContextConfig{
+			Headers:  map[string][]string{echo.HeaderContentType: {echo.MIMEApplicationJSON}},
+			JSONBody: body,
+		}.ServeWithHandler(t, as(claims, ctl.Query))
+	}
+
+	rec := query(admin, `items where name contains "bean" or id in (2) order by name desc limit 2`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var result struct {
+		Entity string          `json:"entity"`
+		Data   []models.Item   `json:"data"`
+		Page   models.PageInfo `json:"page"`
+	}
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Equal(t, "items", result.Entity)
+	if assert.Len(t, result.Data, 2) {
+		assert.Equal(t, []string{"Rice", "Bean sprouts"}, []string{result.Data[0].Name, result.Data[1].Name})
+	}
+	assert.Equal(t, models.PageInfo{Limit: 2, Total: 3, Next: 2, Sort: []string{"-name", "id"}}, result.Page)
+
+	// Mistakes are answered with where they are
+	for text, position := range map[string]int{
+		`items where name startswith`: 28,
+		`items where colour = "red"`:  13,
+		`pallets`:                     1,
+	} {
+		rec := query(admin, text)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, text)
+		var queryErr services.QueryError
+		assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &queryErr))
+		assert.Equal(t, position, queryErr.Position, text)
+		assert.NotEmpty(t, queryErr.Message, text)
+	}
+
+	rec = query(admin, "items limit 1000")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = query(models.JwtCustomClaims{ID: 2, Role: models.Role{Value: models.RoleManager}}, "accounts where active = true")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = query(models.JwtCustomClaims{ID: 3, Role: models.Role{Value: models.RoleSupplier}}, "inventory")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = echotest.ContextConfig{JSONBody: []byte(`{"query": "items"}`)}.ServeWithHandler(t, ctl.Query)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
@@ -3,6 +3,7 @@ module github.com/KNG-Hollow/WMS
 go 1.25.6
 
 require (
+	github.com/WMS/controllers v0.0.0-00010101000000-000000000000
 	github.com/WMS/models v0.0.0-00010101000000-000000000000
 	github.com/WMS/routers v0.0.0-00010101000000-000000000000
 	github.com/WMS/services v0.0.0-00010101000000-000000000000
@@ -13,11 +14,16 @@ require (
 )
 
 require (
-	github.com/WMS/controllers v0.0.0-00010101000000-000000000000 // indirect
+	github.com/WMS/barcode v0.0.0-00010101000000-000000000000 // indirect
+	github.com/boombuler/barcode v1.1.0 // indirect
 	github.com/jackc/pgpassfile v1.0.0 // indirect
 	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
 	github.com/jackc/pgx/v5 v5.8.0 // indirect
+	github.com/jackc/puddle/v2 v2.2.2 // indirect
+	github.com/jung-kurt/gofpdf v1.16.2 // indirect
 	golang.org/x/crypto v0.47.0 // indirect
+	golang.org/x/image v0.25.0 // indirect
+	golang.org/x/sync v0.19.0 // indirect
 	golang.org/x/sys v0.40.0 // indirect
 	golang.org/x/text v0.33.0 // indirect
 	golang.org/x/time v0.14.0 // indirect
@@ -30,3 +36,5 @@ replace github.com/WMS/services => ./services
 replace github.com/WMS/routers => ./routers
 
 replace github.com/WMS/controllers => ./controllers
+
+replace github.com/WMS/barcode => ./barcode
@@ -6,7 +6,9 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 
+	ctrl "github.com/WMS/controllers"
 	"github.com/WMS/models"
 	"github.com/WMS/routers"
 	"github.com/WMS/services"
@@ -25,6 +27,10 @@ func main() {
 		fmt.Println("Error loading .env file")
 	}
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:]))
+	}
+
 	jwtKey, err := services.LoadRSAPublicKey(os.Getenv("JWTPUBKEY"))
 	if err != nil {
 		fmt.Println("Failed to open JWT Key at:", os.Getenv("JWTKEY"))
@@ -52,7 +58,28 @@ func main() {
 		}
 	}
 
+	// TOTPROLES lists the roles that must log in with a second factor,
+	// "none" for no role
+	if roles := os.Getenv("TOTPROLES"); roles != "" {
+		services.TwoFactorRoles = nil
+		for _, role := range strings.Split(strings.ToUpper(roles), ",") {
+			if role = strings.TrimSpace(role); role != "NONE" {
+				services.TwoFactorRoles = append(services.TwoFactorRoles, role)
+			}
+		}
+	}
+
+	store, err := services.NewRepository(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer store.Close()
+
 	e := echo.New()
+	// The server terminates TLS itself, so the peer is the client and
+	// forwarding headers cannot pick the address counted for failed logins
+	e.IPExtractor = echo.ExtractIPDirect()
 
 	e.Pre(middleware.HTTPSRedirect())
 
@@ -60,13 +87,14 @@ func main() {
 		NewClaimsFunc: func(c *echo.Context) jwt.Claims {
 			return new(models.JwtCustomClaims)
 		},
-		SigningKey:    jwtKey,
-		SigningMethod: "RS256",
+		SigningKey:     jwtKey,
+		SigningMethod:  "RS256",
+		SuccessHandler: services.RejectRevoked(store),
 	})
 
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins: []string{"*"},
-		AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderXRequestedWith, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAccessControlAllowHeaders, echo.HeaderAuthorization},
+		AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderXRequestedWith, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAccessControlAllowHeaders, echo.HeaderAuthorization, services.APIKeyHeader},
 		AllowMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 	}))
 	e.Use(middleware.RateLimiter(middleware.NewRateLimiterMemoryStore(20.0)))
@@ -74,7 +102,9 @@ func main() {
 	e.Use(middleware.Recover())
 
 	e.Static("/", "./public")
-	routers.InitRouter(e, jwtConfig)
+	// Integrations may send an API key instead of a token
+	auth := services.AcceptAPIKeys(store, jwtConfig)
+	routers.InitRouter(e, auth, ctrl.NewController(store))
 
 	sc := echo.StartConfig{Address: ":1323"}
 	if err := sc.StartTLS(
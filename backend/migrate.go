@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/WMS/services"
+)
+
+const migrateUsage = "usage: server migrate up|down [steps]|status"
+
+// runMigrate handles the `migrate` subcommand and returns the exit code.
+func runMigrate(args []string) int {
+	if len(args) < 1 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	ctx := context.Background()
+	store, err := services.NewPostgresStore(ctx, services.DatabaseURL())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer store.Close()
+
+	switch args[0] {
+	case "up":
+		applied, err := store.MigrateUp(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Printf("Applied %d migration(s)\n", len(applied))
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil {
+				fmt.Fprintln(os.Stderr, migrateUsage)
+				return 2
+			}
+		}
+		reverted, err := store.MigrateDown(ctx, steps)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Printf("Reverted %d migration(s)\n", len(reverted))
+	case "status":
+		migrations, err := store.MigrationStatus(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		for _, m := range migrations {
+			status := "pending"
+			if m.AppliedAt != nil {
+				status = "applied " + m.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s\t%s\n", m.Version, m.Name, status)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,93 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package models
+
+import "time"
+
+// NewAccount is the request that creates an account. Its password is
+// write-only: it is hashed when stored and never returned.
+type NewAccount struct {
+	ID        int64  `json:"id"`
+	Firstname string `json:"firstname"`
+	Lastname  string `json:"lastname"`
+	Email     string `json:"email"`
+	Phone     string `json:"phone"`
+	Username  string `json:"username"`
+	Password  string `json:"password"`
+	Role      Role   `json:"role"`
+	Active    bool   `json:"active"`
+}
+
+// Account returns the account a request creates.
+func (a NewAccount) Account(created time.Time) Account {
+	return Account{
+		ID:        a.ID,
+		Firstname: a.Firstname,
+		Lastname:  a.Lastname,
+		Email:     a.Email,
+		Phone:     a.Phone,
+		Username:  a.Username,
+		Password:  a.Password,
+		Role:      a.Role,
+		Active:    a.Active,
+		Created:   created,
+	}
+}
+
+// AccountUpdate is the request that edits the profile of an account. It
+// leaves the password alone, that takes a PasswordChange.
+type AccountUpdate struct {
+	Firstname string `json:"firstname"`
+	Lastname  string `json:"lastname"`
+	Email     string `json:"email"`
+	Phone     string `json:"phone"`
+	Username  string `json:"username"`
+	Role      Role   `json:"role"`
+	Active    bool   `json:"active"`
+}
+
+// Apply returns an account with its profile replaced by the update.
+func (u AccountUpdate) Apply(account Account) Account {
+	account.Firstname = u.Firstname
+	account.Lastname = u.Lastname
+	account.Email = u.Email
+	account.Phone = u.Phone
+	account.Username = u.Username
+	account.Role = u.Role
+	account.Active = u.Active
+	return account
+}
+
+// AccountResponse is an account as it is returned, without its password.
+type AccountResponse struct {
+	ID        int64     `json:"id"`
+	Firstname string    `json:"firstname"`
+	Lastname  string    `json:"lastname"`
+	Email     string    `json:"email"`
+	Phone     string    `json:"phone"`
+	Username  string    `json:"username"`
+	Role      Role      `json:"role"`
+	Active    bool      `json:"active"`
+	Created   time.Time `json:"created"`
+}
+
+func (a Account) Response() AccountResponse {
+	return AccountResponse{
+		ID:        a.ID,
+		Firstname: a.Firstname,
+		Lastname:  a.Lastname,
+		Email:     a.Email,
+		Phone:     a.Phone,
+		Username:  a.Username,
+		Role:      a.Role,
+		Active:    a.Active,
+		Created:   a.Created,
+	}
+}
+
+// PasswordChange replaces the password of an account, proven by its
+// current password.
+type PasswordChange struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
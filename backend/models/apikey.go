@@ -0,0 +1,42 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package models
+
+import "time"
+
+// API key scopes grant a resource of the permission matrix to read, or to
+// write (create, update and delete), e.g. "inventory:read".
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
+
+// APIKey lets a machine call the API within its scopes, sent in the
+// X-API-Key header. The key is shown once when created. It is stored by the
+// SHA-256 of its value and found by its Prefix, which is not secret.
+type APIKey struct {
+	ID        int64      `json:"id"`
+	Name      string     `json:"name"`
+	Prefix    string     `json:"prefix"`
+	Hash      string     `json:"-"`
+	Scopes    []string   `json:"scopes"`
+	CreatedBy int64      `json:"createdBy"`
+	Created   time.Time  `json:"created"`
+	Expires   time.Time  `json:"expires"`
+	LastUsed  *time.Time `json:"lastUsed,omitempty"`
+}
+
+// NewAPIKey is the request that creates an API key. Without Expires the
+// key expires after the default lifetime.
+type NewAPIKey struct {
+	Name    string    `json:"name"`
+	Scopes  []string  `json:"scopes"`
+	Expires time.Time `json:"expires"`
+}
+
+// CreatedAPIKey answers the creation of an API key with the key itself,
+// which cannot be read again.
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
@@ -0,0 +1,53 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package models
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 500
+)
+
+// ListQuery pages, filters and sorts a collection. Filters hold the values
+// a field may take, a row matches when it has any of them for every
+// filtered field. Sort names fields in order of precedence, a leading "-"
+// sorts a field descending. Query is a query in the query language, it
+// takes the place of Limit, Offset and Sort and its condition is met along
+// with the filters.
+type ListQuery struct {
+	Limit   int
+	Offset  int
+	Sort    []string
+	Filters map[string][]string
+	Query   string
+}
+
+// Page is the envelope collections are returned in: one page of rows and
+// where it is in the whole collection.
+type Page[T any] struct {
+	Data []T      `json:"data"`
+	Page PageInfo `json:"page"`
+}
+
+// PageInfo describes a page. Total counts the rows matching the filters
+// on every page, Next is the offset of the following page when there is
+// one.
+type PageInfo struct {
+	Limit  int      `json:"limit"`
+	Offset int      `json:"offset"`
+	Total  int64    `json:"total"`
+	Next   int      `json:"next,omitempty"`
+	Sort   []string `json:"sort"`
+}
+
+// QueryRequest is a query in the query language, e.g.
+// `inventory where total < 50 and location startswith "A"`.
+type QueryRequest struct {
+	Query string `json:"query"`
+}
+
+// QueryResult is the page of rows a query found in its collection.
+type QueryResult struct {
+	Entity string   `json:"entity"`
+	Data   any      `json:"data"`
+	Page   PageInfo `json:"page"`
+}
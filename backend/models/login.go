@@ -0,0 +1,12 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package models
+
+import "time"
+
+// LoginAttempts counts the failed logins of a username or client address
+// since the last success.
+type LoginAttempts struct {
+	Failures    int
+	LastFailure time.Time
+}
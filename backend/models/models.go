@@ -11,6 +11,9 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// Account is an account as it is stored, its password as an argon2 hash
+// that is never encoded. Requests and responses use the account types of
+// account.go.
 type Account struct {
 	ID        int64     `json:"id" db:"id"`
 	Firstname string    `json:"firstname" db:"firstname"`
@@ -18,7 +21,7 @@ type Account struct {
 	Email     string    `json:"email" db:"email"`
 	Phone     string    `json:"phone" db:"phone"`
 	Username  string    `json:"username" db:"username"`
-	Password  string    `json:"password" db:"password"`
+	Password  string    `json:"-" db:"password"`
 	Role      Role      `json:"role" db:"role"`
 	Active    bool      `json:"active" db:"active"`
 	Created   time.Time `json:"created" db:"created"`
@@ -33,6 +36,16 @@ type Role struct {
 	Value    string
 }
 
+const (
+	RoleAdmin    = "ADMIN"
+	RoleManager  = "MANAGER"
+	RoleEmployee = "EMPLOYEE"
+	RoleSupplier = "SUPPLIER"
+	RoleCustomer = "CUSTOMER"
+)
+
+var Roles = []string{RoleAdmin, RoleManager, RoleEmployee, RoleSupplier, RoleCustomer}
+
 func (r *Role) Scan(value any) error {
 	v, ok := value.(string)
 	if !ok {
@@ -47,7 +60,7 @@ type Item struct {
 	UPC         string    `json:"upc" db:"upc"`
 	Name        string    `json:"name" db:"name"`
 	Description string    `json:"description" db:"description"`
-	Weight      float64   `json:"weight" db:"weight"`
+	Weight      Weight    `json:"weight" db:"weight"`
 	Image       ImageData `json:"image" db:"image"`
 }
 
@@ -95,11 +108,81 @@ func (i *ImageData) Value() (driver.Value, error) {
 }
 
 type Box struct {
-	ID         int64  `json:"id" db:"id"`
-	UPC        string `json:"upc" db:"upc"`
-	Item       Item   `json:"item" db:"item"`
-	Dimensions string `json:"dimensions" db:"dimensions"`
-	Count      int64  `json:"count" db:"count"`
+	ID         int64      `json:"id" db:"id"`
+	UPC        string     `json:"upc" db:"upc"`
+	Item       Item       `json:"item" db:"item"`
+	Dimensions Dimensions `json:"dimensions" db:"dimensions"`
+	Count      int64      `json:"count" db:"count"`
+}
+
+const (
+	UOMEach   = "EACH"
+	UOMInner  = "INNER"
+	UOMCase   = "CASE"
+	UOMPallet = "PALLET"
+)
+
+// UOMLevels lists the units of measure an item is handled in, smallest
+// first.
+var UOMLevels = []string{UOMEach, UOMInner, UOMCase, UOMPallet}
+
+// UnitOfMeasure is one pack level of an item and how many eaches, the base
+// unit every quantity is stored in, it holds.
+type UnitOfMeasure struct {
+	ItemID   int64  `json:"itemId" db:"item_id"`
+	UOM      string `json:"uom" db:"uom"`
+	Quantity int64  `json:"quantity" db:"quantity"`
+	UPC      string `json:"upc" db:"upc"`
+}
+
+// Carton is a shipping container orders are packed into. A zero max
+// weight puts no limit on what it holds.
+type Carton struct {
+	ID         int64      `json:"id" db:"id"`
+	Name       string     `json:"name" db:"name"`
+	Dimensions Dimensions `json:"dimensions" db:"dimensions"`
+	MaxWeight  Weight     `json:"maxWeight" db:"max_weight"`
+	TareWeight Weight     `json:"tareWeight" db:"tare_weight"`
+	Active     bool       `json:"active" db:"active"`
+}
+
+// PackedCarton is one carton of an order, either suggested or packed.
+// Weight includes the carton itself, DimWeight is the dimensional weight a
+// carrier bills for its size. Packed cartons carry an SSCC, the license
+// plate they are scanned by.
+type PackedCarton struct {
+	ID         int64       `json:"id" db:"id"`
+	SSCC       string      `json:"sscc,omitempty" db:"sscc"`
+	OrderID    int64       `json:"orderId" db:"order_id"`
+	CartonID   int64       `json:"cartonId" db:"carton_id"`
+	Carton     string      `json:"carton" db:"carton"`
+	Dimensions Dimensions  `json:"dimensions" db:"dimensions"`
+	Contents   []ItemGroup `json:"contents" db:"contents"`
+	Weight     Weight      `json:"weight" db:"weight"`
+	DimWeight  Weight      `json:"dimWeight" db:"dim_weight"`
+	AccountID  int64       `json:"accountId" db:"account_id"`
+	Created    time.Time   `json:"created" db:"created"`
+}
+
+// Packing lists the cartons of an order with the shipment totals. The
+// billable weight is the greater of actual and dimensional weight per
+// carton.
+type Packing struct {
+	OrderID        int64          `json:"orderId"`
+	Cartons        []PackedCarton `json:"cartons"`
+	Weight         Weight         `json:"weight"`
+	DimWeight      Weight         `json:"dimWeight"`
+	BillableWeight Weight         `json:"billableWeight"`
+}
+
+// PackRequest records the cartons an order was actually packed into.
+type PackRequest struct {
+	Cartons []PackRequestCarton `json:"cartons"`
+}
+
+type PackRequestCarton struct {
+	CartonID int64       `json:"cartonId"`
+	Contents []ItemGroup `json:"contents"`
 }
 
 type Inventory struct {
@@ -110,29 +193,358 @@ type Inventory struct {
 }
 
 type LocationData struct {
-	Area  string `json:"area" db:"area"`
-	Count int64  `json:"count" db:"count"`
+	LocationID int64  `json:"locationId,omitempty" db:"location_id"`
+	Area       string `json:"area" db:"area"`
+	Count      int64  `json:"count" db:"count"`
+	UOM        string `json:"uom,omitempty"`
+}
+
+type Location struct {
+	ID        int64   `json:"id" db:"id"`
+	Code      string  `json:"code" db:"code"`
+	Zone      string  `json:"zone" db:"zone"`
+	Aisle     string  `json:"aisle" db:"aisle"`
+	Rack      string  `json:"rack" db:"rack"`
+	Shelf     string  `json:"shelf" db:"shelf"`
+	Bin       string  `json:"bin" db:"bin"`
+	Type      string  `json:"type" db:"type"`
+	Capacity  int64   `json:"capacity" db:"capacity"`
+	MaxWeight float64 `json:"maxWeight" db:"max_weight"`
+	Active    bool    `json:"active" db:"active"`
+}
+
+const (
+	LocationPickFace = "PICK_FACE"
+	LocationReserve  = "RESERVE"
+	LocationDock     = "DOCK"
+	LocationStaging  = "STAGING"
+)
+
+var LocationTypes = []string{LocationPickFace, LocationReserve, LocationDock, LocationStaging}
+
+type StockLevel struct {
+	Item         ItemInfo `json:"item"`
+	LocationID   int64    `json:"locationId"`
+	LocationCode string   `json:"locationCode"`
+	Quantity     int64    `json:"quantity"`
+}
+
+// StockTransaction is one entry of the append-only stock ledger. Units
+// leave FromLocationID and arrive at ToLocationID; one side is zero when
+// stock enters or leaves the warehouse. Quantity is always positive.
+type StockTransaction struct {
+	ID             int64     `json:"id" db:"id"`
+	Type           string    `json:"type" db:"type"`
+	ItemID         int64     `json:"itemId" db:"item_id"`
+	FromLocationID int64     `json:"fromLocationId,omitempty" db:"from_location_id"`
+	ToLocationID   int64     `json:"toLocationId,omitempty" db:"to_location_id"`
+	Quantity       int64     `json:"quantity" db:"quantity"`
+	Reason         string    `json:"reason" db:"reason"`
+	Reference      string    `json:"reference,omitempty" db:"reference"`
+	AccountID      int64     `json:"accountId,omitempty" db:"account_id"`
+	Created        time.Time `json:"created" db:"created"`
+}
+
+const (
+	StockReceipt = "RECEIPT"
+	StockPick    = "PICK"
+	StockMove    = "MOVE"
+	StockAdjust  = "ADJUST"
+	StockCount   = "COUNT"
+)
+
+var StockTransactionTypes = []string{StockReceipt, StockPick, StockMove, StockAdjust, StockCount}
+
+const (
+	ReasonReceived   = "RECEIVED"
+	ReasonPicked     = "PICKED"
+	ReasonPutaway    = "PUTAWAY"
+	ReasonReplenish  = "REPLENISHMENT"
+	ReasonRelocation = "RELOCATION"
+	ReasonDamaged    = "DAMAGED"
+	ReasonExpired    = "EXPIRED"
+	ReasonLost       = "LOST"
+	ReasonFound      = "FOUND"
+	ReasonCycleCount = "CYCLE_COUNT"
+	ReasonCorrection = "CORRECTION"
+	ReasonOpening    = "OPENING_BALANCE"
+)
+
+var ReasonCodes = []string{
+	ReasonReceived, ReasonPicked, ReasonPutaway, ReasonReplenish, ReasonRelocation, ReasonDamaged,
+	ReasonExpired, ReasonLost, ReasonFound, ReasonCycleCount, ReasonCorrection, ReasonOpening,
+}
+
+// StockMoveRequest moves units of one item between two locations.
+type StockMoveRequest struct {
+	ItemID         int64  `json:"itemId"`
+	FromLocationID int64  `json:"fromLocationId"`
+	ToLocationID   int64  `json:"toLocationId"`
+	Quantity       int64  `json:"quantity"`
+	Reason         string `json:"reason"`
+	Reference      string `json:"reference"`
+}
+
+// StockTransferRequest moves everything stored at one location to another.
+type StockTransferRequest struct {
+	FromLocationID int64  `json:"fromLocationId"`
+	ToLocationID   int64  `json:"toLocationId"`
+	Reason         string `json:"reason"`
+	Reference      string `json:"reference"`
+}
+
+// StockAdjustmentRequest changes the quantity of an item at a location by
+// Quantity, or sets it to Counted after a physical count.
+type StockAdjustmentRequest struct {
+	ItemID     int64  `json:"itemId"`
+	LocationID int64  `json:"locationId"`
+	Quantity   int64  `json:"quantity"`
+	Counted    *int64 `json:"counted"`
+	Reason     string `json:"reason"`
+	Reference  string `json:"reference"`
 }
 
 type Order struct {
-	ID          int64       `json:"id" db:"id"`
-	Customer    Account     `json:"customer" db:"customer"`
-	Address     string      `json:"address" db:"address"`
-	TimeOrdered time.Time   `json:"timeOrdered" db:"timeOrdered"`
-	Payload     []ItemGroup `json:"payload" db:"payload"`
+	ID          int64             `json:"id" db:"id"`
+	Customer    Account           `json:"customer" db:"customer"`
+	Address     string            `json:"address" db:"address"`
+	TimeOrdered time.Time         `json:"timeOrdered" db:"timeOrdered"`
+	Payload     []ItemGroup       `json:"payload" db:"payload"`
+	Status      string            `json:"status" db:"status"`
+	History     []OrderTransition `json:"history,omitempty" db:"history"`
+	Allocation  []LineAllocation  `json:"allocation,omitempty" db:"allocation"`
+}
+
+const (
+	OrderDraft     = "DRAFT"
+	OrderPlaced    = "PLACED"
+	OrderAllocated = "ALLOCATED"
+	OrderPicking   = "PICKING"
+	OrderPacked    = "PACKED"
+	OrderShipped   = "SHIPPED"
+	OrderDelivered = "DELIVERED"
+	OrderCancelled = "CANCELLED"
+)
+
+var OrderStatuses = []string{OrderDraft, OrderPlaced, OrderAllocated, OrderPicking, OrderPacked, OrderShipped, OrderDelivered, OrderCancelled}
+
+// OrderTransition records one status change of an order and who made it.
+type OrderTransition struct {
+	From      string    `json:"from" db:"from_status"`
+	To        string    `json:"to" db:"to_status"`
+	AccountID int64     `json:"accountId,omitempty" db:"account_id"`
+	Created   time.Time `json:"created" db:"created"`
+}
+
+// Reservation holds quantity of an item at a location for one line of an
+// order until it is picked or released.
+type Reservation struct {
+	ID           int64     `json:"id" db:"id"`
+	OrderID      int64     `json:"orderId" db:"order_id"`
+	Line         int       `json:"line" db:"line"`
+	ItemID       int64     `json:"itemId" db:"item_id"`
+	LocationID   int64     `json:"locationId" db:"location_id"`
+	LocationCode string    `json:"locationCode" db:"location_code"`
+	Quantity     int64     `json:"quantity" db:"quantity"`
+	Created      time.Time `json:"created" db:"created"`
+}
+
+// LineAllocation summarises how much of an order line is reserved.
+type LineAllocation struct {
+	Line         int           `json:"line"`
+	ItemID       int64         `json:"itemId"`
+	Ordered      int64         `json:"ordered"`
+	Allocated    int64         `json:"allocated"`
+	Status       string        `json:"status"`
+	Reservations []Reservation `json:"reservations"`
+}
+
+const (
+	AllocationFull        = "ALLOCATED"
+	AllocationPartial     = "PARTIAL"
+	AllocationBackordered = "BACKORDERED"
+)
+
+// Availability is the available-to-promise quantity of an item: on-hand
+// stock less what is reserved for orders.
+type Availability struct {
+	ItemID    int64                  `json:"itemId"`
+	OnHand    int64                  `json:"onHand"`
+	Reserved  int64                  `json:"reserved"`
+	Available int64                  `json:"available"`
+	Locations []LocationAvailability `json:"locations"`
+}
+
+type LocationAvailability struct {
+	LocationID   int64  `json:"locationId"`
+	LocationCode string `json:"locationCode"`
+	Type         string `json:"type"`
+	Active       bool   `json:"active"`
+	OnHand       int64  `json:"onHand"`
+	Reserved     int64  `json:"reserved"`
+	Available    int64  `json:"available"`
+}
+
+type TransitionRequest struct {
+	Status string `json:"status"`
+}
+
+// Wave groups allocated orders released to the floor together.
+type Wave struct {
+	ID        int64      `json:"id" db:"id"`
+	Status    string     `json:"status" db:"status"`
+	Zone      string     `json:"zone" db:"zone"`
+	Cutoff    *time.Time `json:"cutoff" db:"cutoff"`
+	MaxOrders int        `json:"maxOrders" db:"max_orders"`
+	Orders    []int64    `json:"orders" db:"orders"`
+	Tasks     []PickTask `json:"tasks,omitempty" db:"tasks"`
+	AccountID int64      `json:"accountId" db:"account_id"`
+	Created   time.Time  `json:"created" db:"created"`
+	Completed *time.Time `json:"completed" db:"completed"`
+}
+
+const (
+	WaveOpen      = "OPEN"
+	WaveCompleted = "COMPLETED"
+)
+
+// WaveRequest selects the allocated orders of a new wave, oldest first.
+// Every filter is optional: orders placed up to a carrier cutoff, orders
+// picked entirely within one zone, and at most a number of orders.
+type WaveRequest struct {
+	Cutoff    *time.Time `json:"cutoff"`
+	Zone      string     `json:"zone"`
+	MaxOrders int        `json:"maxOrders"`
+}
+
+// PickTask is one reservation to be picked from a location, numbered by
+// its place on the wave's walking path.
+type PickTask struct {
+	ID            int64     `json:"id" db:"id"`
+	WaveID        int64     `json:"waveId" db:"wave_id"`
+	OrderID       int64     `json:"orderId" db:"order_id"`
+	Line          int       `json:"line" db:"line"`
+	ReservationID int64     `json:"reservationId" db:"reservation_id"`
+	ItemID        int64     `json:"itemId" db:"item_id"`
+	LocationID    int64     `json:"locationId" db:"location_id"`
+	LocationCode  string    `json:"locationCode" db:"location_code"`
+	Sequence      int       `json:"sequence" db:"sequence"`
+	Quantity      int64     `json:"quantity" db:"quantity"`
+	Picked        int64     `json:"picked" db:"picked"`
+	Status        string    `json:"status" db:"status"`
+	AccountID     int64     `json:"accountId" db:"account_id"`
+	Updated       time.Time `json:"updated" db:"updated"`
+}
+
+const (
+	PickOpen      = "OPEN"
+	PickClaimed   = "CLAIMED"
+	PickPicked    = "PICKED"
+	PickShort     = "SHORT"
+	PickCancelled = "CANCELLED"
+)
+
+var PickStatuses = []string{PickOpen, PickClaimed, PickPicked, PickShort, PickCancelled}
+
+// ShortPickRequest reports how many units were actually found.
+type ShortPickRequest struct {
+	Quantity int64 `json:"quantity"`
 }
 
 type Shipment struct {
-	ID          int64       `json:"id" db:"id"`
-	Supplier    Account     `json:"supplier" db:"supplier"`
-	Distributor string      `json:"distributor" db:"distributor"`
-	ETA         time.Time   `json:"eta" db:"eta"`
-	Payload     []ItemGroup `json:"payload" db:"payload"`
+	ID                  int64         `json:"id" db:"id"`
+	Supplier            Account       `json:"supplier" db:"supplier"`
+	Distributor         string        `json:"distributor" db:"distributor"`
+	ETA                 time.Time     `json:"eta" db:"eta"`
+	Payload             []ItemGroup   `json:"payload" db:"payload"`
+	Status              string        `json:"status" db:"status"`
+	Arrived             *time.Time    `json:"arrived,omitempty" db:"arrived"`
+	Received            *time.Time    `json:"received,omitempty" db:"received"`
+	ReceivingLocationID int64         `json:"receivingLocationId,omitempty" db:"receiving_location_id"`
+	Receipt             []ReceiptLine `json:"receipt" db:"receipt"`
+}
+
+const (
+	ShipmentExpected = "EXPECTED"
+	ShipmentArrived  = "ARRIVED"
+	ShipmentReceived = "RECEIVED"
+)
+
+// ReceiptLine records what was received against one line of a shipment
+// payload. Received counts good units, Damaged the ones refused.
+type ReceiptLine struct {
+	Line          int      `json:"line"`
+	ItemID        int64    `json:"itemId"`
+	Expected      int64    `json:"expected"`
+	Received      int64    `json:"received"`
+	Damaged       int64    `json:"damaged"`
+	UOM           string   `json:"uom,omitempty"`
+	Discrepancies []string `json:"discrepancies,omitempty"`
+}
+
+const (
+	DiscrepancyOver    = "OVER"
+	DiscrepancyShort   = "SHORT"
+	DiscrepancyDamaged = "DAMAGED"
+)
+
+// ReceiveRequest records the counted lines of an arrived shipment and the
+// location its good units are put into.
+type ReceiveRequest struct {
+	LocationID int64         `json:"locationId"`
+	Lines      []ReceiptLine `json:"lines"`
 }
 
 type ItemGroup struct {
-	Item  Item  `json:"item"`
-	Count int64 `json:"count"`
+	Item  Item   `json:"item"`
+	Count int64  `json:"count"`
+	UOM   string `json:"uom,omitempty"`
+}
+
+const (
+	ScanItem         = "ITEM"
+	ScanBox          = "BOX"
+	ScanLocation     = "LOCATION"
+	ScanLicensePlate = "LICENSE_PLATE"
+	ScanOrder        = "ORDER"
+)
+
+type ScanRequest struct {
+	Data string `json:"data"`
+}
+
+// Scan is what a raw scan says: the code to look up and the GS1 data that
+// came with it. Codes that are neither a GTIN nor an SSCC are kept as text.
+type Scan struct {
+	Data   string     `json:"data"`
+	GTIN   string     `json:"gtin,omitempty"`
+	SSCC   string     `json:"sscc,omitempty"`
+	Lot    string     `json:"lot,omitempty"`
+	Expiry *time.Time `json:"expiry,omitempty"`
+	Serial string     `json:"serial,omitempty"`
+	Count  int64      `json:"count,omitempty"`
+	Code   string     `json:"code,omitempty"`
+}
+
+// ScanAction is a request the scanning device may follow up with.
+type ScanAction struct {
+	Name   string `json:"name"`
+	Method string `json:"method"`
+	Path   string `json:"path"`
+}
+
+// ScanResult is the record a scan resolved to. Unit is the pack level a
+// product code stands for.
+type ScanResult struct {
+	Scan     Scan           `json:"scan"`
+	Kind     string         `json:"kind"`
+	Item     *Item          `json:"item,omitempty"`
+	Unit     *UnitOfMeasure `json:"unit,omitempty"`
+	Box      *Box           `json:"box,omitempty"`
+	Location *Location      `json:"location,omitempty"`
+	Carton   *PackedCarton  `json:"carton,omitempty"`
+	Order    *Order         `json:"order,omitempty"`
+	Actions  []ScanAction   `json:"actions"`
 }
 
 type LoginDetails struct {
@@ -140,10 +552,34 @@ type LoginDetails struct {
 	Password string `form:"password" json:"password" binding:"required"`
 }
 
+type AuthError struct {
+	Error    string `json:"error"`
+	Message  string `json:"message"`
+	Resource string `json:"resource,omitempty"`
+	Verb     string `json:"verb,omitempty"`
+	Role     string `json:"role,omitempty"`
+}
+
+// JwtCustomClaims are the claims of an access token. Generation is the
+// token generation of the account when it was signed, tokens of earlier
+// generations are revoked.
 type JwtCustomClaims struct {
-	ID       int64  `json:"id"`
-	Username string `json:"username"`
-	Password string `json:"password"`
-	Role     Role   `json:"role"`
+	ID         int64  `json:"id"`
+	Username   string `json:"username"`
+	Role       Role   `json:"role"`
+	Generation int64  `json:"gen"`
 	jwt.RegisteredClaims
 }
+
+type PoolStats struct {
+	TotalConns           int32         `json:"totalConns"`
+	AcquiredConns        int32         `json:"acquiredConns"`
+	IdleConns            int32         `json:"idleConns"`
+	ConstructingConns    int32         `json:"constructingConns"`
+	MaxConns             int32         `json:"maxConns"`
+	AcquireCount         int64         `json:"acquireCount"`
+	AcquireDuration      time.Duration `json:"acquireDuration"`
+	CanceledAcquireCount int64         `json:"canceledAcquireCount"`
+	EmptyAcquireCount    int64         `json:"emptyAcquireCount"`
+	NewConnsCount        int64         `json:"newConnsCount"`
+}
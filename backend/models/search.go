@@ -0,0 +1,45 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package models
+
+const (
+	SearchItems     = "items"
+	SearchOrders    = "orders"
+	SearchAccounts  = "accounts"
+	SearchLocations = "locations"
+)
+
+// SearchKinds are the collections searched, named after the resources
+// their records are read from.
+var SearchKinds = []string{SearchItems, SearchOrders, SearchAccounts, SearchLocations}
+
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+// SearchQuery searches the records of some kinds for text. Each word of
+// the text matches words starting with it, misspelled text matches words
+// close to it. A CustomerID limits the orders found to that customer's.
+type SearchQuery struct {
+	Text       string
+	Kinds      []string
+	Limit      int
+	CustomerID int64
+}
+
+// SearchHit is a record that matched a search, ranked by how well it did.
+type SearchHit struct {
+	Kind   string  `json:"kind"`
+	ID     int64   `json:"id"`
+	Title  string  `json:"title"`
+	Detail string  `json:"detail"`
+	Rank   float64 `json:"rank"`
+}
+
+// SearchResults are the best hits of a search across its kinds.
+type SearchResults struct {
+	Query string      `json:"query"`
+	Kinds []string    `json:"kinds"`
+	Hits  []SearchHit `json:"hits"`
+}
@@ -0,0 +1,31 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package models
+
+import "time"
+
+// TokenPair is what logging in or refreshing returns: a short-lived access
+// token for the Authorization header and the refresh token that replaces
+// it once it expires.
+type TokenPair struct {
+	Token        string    `json:"token"`
+	RefreshToken string    `json:"refreshToken"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+}
+
+// RefreshRequest trades a refresh token for a new pair, or revokes it on
+// logout.
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// RefreshToken is a refresh token as it is stored, by the hash of its
+// value. Each refresh replaces the token by a new one of the same family,
+// the tokens handed out since a login.
+type RefreshToken struct {
+	AccountID int64
+	Hash      string
+	Family    string
+	Expires   time.Time
+	Revoked   bool
+}
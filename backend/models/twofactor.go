@@ -0,0 +1,47 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package models
+
+import "time"
+
+// TwoFactor is the TOTP secret of an account. It is Confirmed once a code
+// from it has been entered, and LastStep is the time step of the last code
+// used, which cannot be used again.
+type TwoFactor struct {
+	AccountID int64
+	Secret    string
+	Confirmed bool
+	LastStep  int64
+}
+
+// TOTPEnrollment is a new TOTP secret for an authenticator app, as the
+// secret itself, an otpauth:// URI and a QR code of it as a PNG data URL.
+// The recovery codes are shown once and each logs in once in place of a
+// code.
+type TOTPEnrollment struct {
+	Secret        string   `json:"secret"`
+	URI           string   `json:"uri"`
+	QRCode        string   `json:"qrCode"`
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// TOTPCode is a code from an authenticator app, or a recovery code.
+type TOTPCode struct {
+	Code string `json:"code"`
+}
+
+// LoginChallenge answers a login that needs a second factor. The pre-auth
+// token is traded with a code at /auth/totp before it expires. Enroll is
+// set for accounts that must first enroll at /auth/totp/enroll.
+type LoginChallenge struct {
+	PreAuthToken string    `json:"preAuthToken"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+	Enroll       bool      `json:"enroll"`
+}
+
+// SecondFactorRequest completes a login with the pre-auth token of its
+// challenge and a code. Enrolling leaves the code empty.
+type SecondFactorRequest struct {
+	PreAuthToken string `json:"preAuthToken"`
+	Code         string `json:"code"`
+}
@@ -0,0 +1,300 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	UnitInch       = "in"
+	UnitCentimetre = "cm"
+	UnitMillimetre = "mm"
+)
+
+var LengthUnits = []string{UnitInch, UnitCentimetre, UnitMillimetre}
+
+const (
+	UnitPound    = "lb"
+	UnitKilogram = "kg"
+	UnitGram     = "g"
+)
+
+var WeightUnits = []string{UnitPound, UnitKilogram, UnitGram}
+
+// perInch and perPound hold how many of each unit make an inch or a pound.
+var perInch = map[string]float64{
+	UnitInch:       1,
+	UnitCentimetre: 2.54,
+	UnitMillimetre: 25.4,
+}
+
+var perPound = map[string]float64{
+	UnitPound:    1,
+	UnitKilogram: 0.45359237,
+	UnitGram:     453.59237,
+}
+
+const (
+	SystemMetric   = "metric"
+	SystemImperial = "imperial"
+)
+
+var UnitSystems = []string{SystemMetric, SystemImperial}
+
+// convert moves a value between units given as multiples of a common base
+// and drops the floating point noise the conversion leaves behind.
+func convert(v float64, from float64, to float64) float64 {
+	return math.Round(v/from*to*1e6) / 1e6
+}
+
+// Dimensions are the length, width and height of a box or carton in one
+// unit of length.
+type Dimensions struct {
+	Length float64 `json:"length" db:"length"`
+	Width  float64 `json:"width" db:"width"`
+	Height float64 `json:"height" db:"height"`
+	Unit   string  `json:"unit" db:"unit"`
+}
+
+// In converts dimensions to another length unit. Dimensions in an unknown
+// unit are returned unchanged.
+func (d Dimensions) In(unit string) Dimensions {
+	from, ok := perInch[d.Unit]
+	to, known := perInch[unit]
+	if !ok || !known || d.Unit == unit {
+		return d
+	}
+	return Dimensions{
+		Length: convert(d.Length, from, to),
+		Width:  convert(d.Width, from, to),
+		Height: convert(d.Height, from, to),
+		Unit:   unit,
+	}
+}
+
+// System converts dimensions to centimetres or inches. An empty system
+// keeps the unit they were given in.
+func (d Dimensions) System(system string) Dimensions {
+	switch system {
+	case SystemMetric:
+		return d.In(UnitCentimetre)
+	case SystemImperial:
+		return d.In(UnitInch)
+	}
+	return d
+}
+
+// ParseDimensions reads "LxWxH" dimensions such as "2x2x4" or
+// "30 x 20 x 10 cm", the free text boxes were once described with.
+// Dimensions without a unit are in inches.
+func ParseDimensions(s string) (Dimensions, error) {
+	text := strings.ToLower(strings.TrimSpace(s))
+	unit := UnitInch
+	for _, u := range LengthUnits {
+		if strings.HasSuffix(text, u) {
+			unit = u
+			text = strings.TrimSpace(strings.TrimSuffix(text, u))
+			break
+		}
+	}
+
+	parts := strings.Split(text, "x")
+	if len(parts) != 3 {
+		return Dimensions{}, fmt.Errorf("dimensions %q must look like LxWxH", s)
+	}
+	var sides [3]float64
+	for i, part := range parts {
+		side, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || side <= 0 || math.IsInf(side, 0) {
+			return Dimensions{}, fmt.Errorf("dimensions %q must be positive numbers", s)
+		}
+		sides[i] = side
+	}
+	return Dimensions{Length: sides[0], Width: sides[1], Height: sides[2], Unit: unit}, nil
+}
+
+// UnmarshalJSON also accepts dimensions written as a "LxWxH" string.
+func (d *Dimensions) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		parsed, err := ParseDimensions(text)
+		if err != nil {
+			return err
+		}
+		*d = parsed
+		return nil
+	}
+	type plain Dimensions
+	return json.Unmarshal(data, (*plain)(d))
+}
+
+// Weight is a mass in one unit of weight.
+type Weight struct {
+	Value float64 `json:"value" db:"weight"`
+	Unit  string  `json:"unit" db:"weight_unit"`
+}
+
+// In converts a weight to another unit. Weights in an unknown unit are
+// returned unchanged.
+func (w Weight) In(unit string) Weight {
+	from, ok := perPound[w.Unit]
+	to, known := perPound[unit]
+	if !ok || !known || w.Unit == unit {
+		return w
+	}
+	return Weight{Value: convert(w.Value, from, to), Unit: unit}
+}
+
+// System converts a weight to kilograms or pounds. An empty system keeps
+// the unit it was given in.
+func (w Weight) System(system string) Weight {
+	switch system {
+	case SystemMetric:
+		return w.In(UnitKilogram)
+	case SystemImperial:
+		return w.In(UnitPound)
+	}
+	return w
+}
+
+// ParseWeight reads a weight such as "2.5 kg". Weights without a unit are
+// in pounds.
+func ParseWeight(s string) (Weight, error) {
+	text := strings.ToLower(strings.TrimSpace(s))
+	unit := UnitPound
+	for _, u := range WeightUnits {
+		if strings.HasSuffix(text, u) {
+			unit = u
+			text = strings.TrimSpace(strings.TrimSuffix(text, u))
+			break
+		}
+	}
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil || value < 0 || math.IsInf(value, 0) {
+		return Weight{}, fmt.Errorf("weight %q must be a non-negative number", s)
+	}
+	return Weight{Value: value, Unit: unit}, nil
+}
+
+// UnmarshalJSON also accepts the plain numbers weights used to be, read as
+// pounds, and strings such as "2.5 kg".
+func (w *Weight) UnmarshalJSON(data []byte) error {
+	var value float64
+	if err := json.Unmarshal(data, &value); err == nil {
+		*w = Weight{Value: value, Unit: UnitPound}
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		parsed, err := ParseWeight(text)
+		if err != nil {
+			return err
+		}
+		*w = parsed
+		return nil
+	}
+	type plain Weight
+	return json.Unmarshal(data, (*plain)(w))
+}
+
+// The System methods below return a copy of a record with its
+// measurements converted to a measurement system.
+
+func (i Item) System(system string) Item {
+	i.Weight = i.Weight.System(system)
+	return i
+}
+
+func (g ItemGroup) System(system string) ItemGroup {
+	g.Item = g.Item.System(system)
+	return g
+}
+
+func groupsSystem(groups []ItemGroup, system string) []ItemGroup {
+	if groups == nil || system == "" {
+		return groups
+	}
+	converted := make([]ItemGroup, len(groups))
+	for i, group := range groups {
+		converted[i] = group.System(system)
+	}
+	return converted
+}
+
+func (b Box) System(system string) Box {
+	b.Item = b.Item.System(system)
+	b.Dimensions = b.Dimensions.System(system)
+	return b
+}
+
+func (v Inventory) System(system string) Inventory {
+	v.Item = v.Item.System(system)
+	return v
+}
+
+func (o Order) System(system string) Order {
+	o.Payload = groupsSystem(o.Payload, system)
+	return o
+}
+
+func (s Shipment) System(system string) Shipment {
+	s.Payload = groupsSystem(s.Payload, system)
+	return s
+}
+
+func (c Carton) System(system string) Carton {
+	c.Dimensions = c.Dimensions.System(system)
+	c.MaxWeight = c.MaxWeight.System(system)
+	c.TareWeight = c.TareWeight.System(system)
+	return c
+}
+
+func (c PackedCarton) System(system string) PackedCarton {
+	c.Dimensions = c.Dimensions.System(system)
+	c.Contents = groupsSystem(c.Contents, system)
+	c.Weight = c.Weight.System(system)
+	c.DimWeight = c.DimWeight.System(system)
+	return c
+}
+
+func (p Packing) System(system string) Packing {
+	if system != "" {
+		cartons := make([]PackedCarton, len(p.Cartons))
+		for i, carton := range p.Cartons {
+			cartons[i] = carton.System(system)
+		}
+		p.Cartons = cartons
+	}
+	p.Weight = p.Weight.System(system)
+	p.DimWeight = p.DimWeight.System(system)
+	p.BillableWeight = p.BillableWeight.System(system)
+	return p
+}
+
+func (r ScanResult) System(system string) ScanResult {
+	if system == "" {
+		return r
+	}
+	if r.Item != nil {
+		item := r.Item.System(system)
+		r.Item = &item
+	}
+	if r.Box != nil {
+		box := r.Box.System(system)
+		r.Box = &box
+	}
+	if r.Carton != nil {
+		carton := r.Carton.System(system)
+		r.Carton = &carton
+	}
+	if r.Order != nil {
+		order := r.Order.System(system)
+		r.Order = &order
+	}
+	return r
+}
@@ -6,10 +6,11 @@ import (
 	"net/http"
 
 	ctrl "github.com/WMS/controllers"
+	"github.com/WMS/services"
 	"github.com/labstack/echo/v5"
 )
 
-func InitRouter(e *echo.Echo, jwtConfig echo.MiddlewareFunc) {
+func InitRouter(e *echo.Echo, jwtConfig echo.MiddlewareFunc, ctl *ctrl.Controller) {
 	// UNPROTECTED ROUTES
 	e.GET("/", func(c *echo.Context) error {
 		return c.File("public/index.html")
@@ -23,39 +24,93 @@ func InitRouter(e *echo.Echo, jwtConfig echo.MiddlewareFunc) {
 	e.GET("/health", func(c *echo.Context) error {
 		return c.JSON(http.StatusOK, map[string]string{"status": "healthy"})
 	})
-	e.POST("/login", ctrl.AuthorizeLogin)
+	e.POST("/login", ctl.AuthorizeLogin)
+	e.POST("/auth/refresh", ctl.RefreshTokens)
+	e.POST("/auth/totp", ctl.VerifySecondFactor)
+	e.POST("/auth/totp/enroll", ctl.EnrollAtLogin)
+	e.POST("/auth/logout", ctl.Logout, jwtConfig)
 
 	// PROTECTED ROUTES
 	api := e.Group("/api")
-	api.Use(jwtConfig)
-
-	api.POST("/accounts", ctrl.AddAccount)
-	api.POST("/items", ctrl.AddItem)
-	api.POST("/orders", ctrl.AddOrder)
-	api.POST("/boxes", ctrl.AddBox)
-	api.POST("/inventory", ctrl.AddInventory)
-
-	api.GET("/accounts", ctrl.GetAccounts)
-	api.GET("/accounts/:id", ctrl.GetAccount)
-	api.GET("/items", ctrl.GetItems)
-	api.GET("/items/:id", ctrl.GetItem)
-	api.GET("/items/list", ctrl.GetItemsList)
-	api.GET("/orders", ctrl.GetOrders)
-	api.GET("/orders/:id", ctrl.GetOrder)
-	api.GET("/boxes", ctrl.GetBoxes)
-	api.GET("/boxes/:id", ctrl.GetBox)
-	api.GET("/inventory", ctrl.GetAllInventory)
-	api.GET("/inventory/:id", ctrl.GetInventory)
-
-	api.PUT("/accounts/:id", ctrl.UpdateAccount)
-	api.PUT("/items/:id", ctrl.UpdateItem)
-	api.PUT("/orders/:id", ctrl.UpdateOrder)
-	api.PUT("/boxes/:id", ctrl.UpdateBox)
-	api.PUT("/inventory/:id", ctrl.UpdateInventory)
-
-	api.DELETE("/accounts/:id", ctrl.DeleteAccount)
-	api.DELETE("/items/:id", ctrl.DeleteItem)
-	api.DELETE("/orders/:id", ctrl.DeleteOrder)
-	api.DELETE("/boxes/:id", ctrl.DeleteBox)
-	api.DELETE("/inventory/:id", ctrl.DeleteInventory)
+	api.Use(jwtConfig, services.AuthorizeRole)
+
+	api.POST("/accounts", ctl.AddAccount)
+	api.POST("/items", ctl.AddItem)
+	api.POST("/orders", ctl.AddOrder)
+	api.POST("/orders/:id/transition", ctl.TransitionOrder)
+	api.POST("/boxes", ctl.AddBox)
+	api.POST("/inventory", ctl.AddInventory)
+	api.POST("/shipments", ctl.AddShipment)
+	api.POST("/shipments/:id/arrive", ctl.ArriveShipment)
+	api.POST("/shipments/:id/receive", ctl.ReceiveShipment)
+	api.POST("/locations", ctl.AddLocation)
+	api.POST("/stock/moves", ctl.MoveStock)
+	api.POST("/stock/transfers", ctl.TransferStock)
+	api.POST("/stock/adjustments", ctl.AdjustStock)
+	api.POST("/waves", ctl.CreateWave)
+	api.POST("/picks/:id/claim", ctl.ClaimPickTask)
+	api.POST("/picks/:id/confirm", ctl.ConfirmPickTask)
+	api.POST("/picks/:id/short", ctl.ShortPickTask)
+	api.POST("/cartons", ctl.AddCarton)
+	api.POST("/packing/:id", ctl.PackOrder)
+	api.POST("/scan", ctl.ResolveScan)
+	api.POST("/query", ctl.Query)
+	api.POST("/apikeys", ctl.AddAPIKey)
+
+	api.GET("/accounts", ctl.GetAccounts)
+	api.GET("/accounts/:id", ctl.GetAccount)
+	api.GET("/items", ctl.GetItems)
+	api.GET("/items/:id", ctl.GetItem)
+	api.GET("/items/list", ctl.GetItemsList)
+	api.GET("/items/:id/uoms", ctl.GetItemUnits)
+	api.GET("/orders", ctl.GetOrders)
+	api.GET("/orders/:id", ctl.GetOrder)
+	api.GET("/boxes", ctl.GetBoxes)
+	api.GET("/boxes/:id", ctl.GetBox)
+	api.GET("/inventory", ctl.GetAllInventory)
+	api.GET("/inventory/:id", ctl.GetInventory)
+	api.GET("/inventory/:id/availability", ctl.GetAvailability)
+	api.GET("/shipments", ctl.GetShipments)
+	api.GET("/shipments/:id", ctl.GetShipment)
+	api.GET("/locations", ctl.GetLocations)
+	api.GET("/locations/:id", ctl.GetLocation)
+	api.GET("/locations/:id/inventory", ctl.GetLocationInventory)
+	api.GET("/stock/transactions", ctl.GetStockTransactions)
+	api.GET("/waves", ctl.GetWaves)
+	api.GET("/waves/:id", ctl.GetWave)
+	api.GET("/picks", ctl.GetPickTasks)
+	api.GET("/cartons", ctl.GetCartons)
+	api.GET("/cartons/:id", ctl.GetCarton)
+	api.GET("/packing/:id", ctl.GetPacking)
+	api.GET("/packing/:id/suggestion", ctl.SuggestCartons)
+	api.GET("/labels/:kind", ctl.GetLabels)
+	api.GET("/search", ctl.Search)
+	api.GET("/apikeys", ctl.GetAPIKeys)
+
+	api.PUT("/accounts/:id", ctl.UpdateAccount)
+	api.PUT("/accounts/:id/password", ctl.ChangePassword)
+	api.POST("/accounts/:id/totp", ctl.EnrollTOTP)
+	api.POST("/accounts/:id/totp/confirm", ctl.ConfirmTOTP)
+	api.PUT("/items/:id", ctl.UpdateItem)
+	api.PUT("/items/:id/uoms", ctl.UpdateItemUnits)
+	api.PUT("/orders/:id", ctl.UpdateOrder)
+	api.PUT("/boxes/:id", ctl.UpdateBox)
+	api.PUT("/inventory/:id", ctl.UpdateInventory)
+	api.PUT("/shipments/:id", ctl.UpdateShipment)
+	api.PUT("/locations/:id", ctl.UpdateLocation)
+	api.PUT("/cartons/:id", ctl.UpdateCarton)
+
+	api.DELETE("/accounts/:id", ctl.DeleteAccount)
+	api.DELETE("/accounts/:id/lockout", ctl.UnlockAccount)
+	api.DELETE("/accounts/:id/totp", ctl.DisableTOTP)
+	api.DELETE("/items/:id", ctl.DeleteItem)
+	api.DELETE("/orders/:id", ctl.DeleteOrder)
+	api.DELETE("/boxes/:id", ctl.DeleteBox)
+	api.DELETE("/inventory/:id", ctl.DeleteInventory)
+	api.DELETE("/shipments/:id", ctl.DeleteShipment)
+	api.DELETE("/locations/:id", ctl.DeleteLocation)
+	api.DELETE("/cartons/:id", ctl.DeleteCarton)
+	api.DELETE("/apikeys/:id", ctl.DeleteAPIKey)
+
+	api.GET("/system/pool", ctl.GetPoolStats)
 }
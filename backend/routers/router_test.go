@@ -0,0 +1,281 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package routers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	ctrl "github.com/WMS/controllers"
+	"github.com/WMS/models"
+	"github.com/WMS/services"
+	"github.com/golang-jwt/jwt/v5"
+	echojwt "github.com/labstack/echo-jwt/v5"
+	"github.com/labstack/echo/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+var testKey = []byte("router-test-key")
+
+func newTestServer(store *services.MemoryStore) *echo.Echo {
+	services.UseSigningKey(testKey, jwt.SigningMethodHS256)
+	e := echo.New()
+	jwtConfig := echojwt.WithConfig(echojwt.Config{
+		NewClaimsFunc: func(c *echo.Context) jwt.Claims {
+			return new(models.JwtCustomClaims)
+		},
+		SigningKey:     testKey,
+		SuccessHandler: services.RejectRevoked(store),
+	})
+	InitRouter(e, services.AcceptAPIKeys(store, jwtConfig), ctrl.NewController(store))
+	return e
+}
+
+func tokenFor(id int64, role string) string {
+	claims := &models.JwtCustomClaims{ID: id, Username: "test", Role: models.Role{Value: role}, RegisteredClaims: jwt.RegisteredClaims{
+		ID:        fmt.Sprintf("test-%d", id),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}}
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(testKey)
+	return token
+}
+
+func request(e *echo.Echo, method, target, token string, body any) *httptest.ResponseRecorder {
+	var payload []byte
+	if body != nil {
+		payload, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(method, target, bytes.NewReader(payload))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if strings.HasPrefix(token, "wms_") {
+		req.Header.Set(services.APIKeyHeader, token)
+	} else if token != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	}
+	res := httptest.NewRecorder()
+	e.ServeHTTP(res, req)
+	return res
+}
+
+func TestRouterWithMemoryStore(t *testing.T) {
+	e := newTestServer(services.NewMemoryStore())
+	admin := tokenFor(1, models.RoleAdmin)
+	customer := tokenFor(2, models.RoleCustomer)
+	item := models.Item{ID: 10, UPC: "036000291452", Name: "beans", Weight: models.Weight{Value: 1.5, Unit: models.UnitPound}, Image: models.ImageData{Valid: true}}
+
+	res := request(e, http.MethodGet, "/health", "", nil)
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	res = request(e, http.MethodGet, "/api/items", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+
+	res = request(e, http.MethodPost, "/api/items", admin, item)
+	assert.Equal(t, http.StatusCreated, res.Code)
+
+	res = request(e, http.MethodGet, "/api/items/10", customer, nil)
+	assert.Equal(t, http.StatusOK, res.Code)
+	var got models.Item
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &got))
+	assert.Equal(t, "beans", got.Name)
+
+	res = request(e, http.MethodDelete, "/api/items/10", customer, nil)
+	assert.Equal(t, http.StatusForbidden, res.Code)
+
+	res = request(e, http.MethodDelete, "/api/items/10", admin, nil)
+	assert.Equal(t, http.StatusAccepted, res.Code)
+
+	// Ledger entries record the account from the token
+	employee := tokenFor(3, models.RoleEmployee)
+	res = request(e, http.MethodPost, "/api/locations", admin, models.Location{Code: "A-1", Active: true})
+	assert.Equal(t, http.StatusCreated, res.Code)
+	res = request(e, http.MethodPost, "/api/items", admin, item)
+	assert.Equal(t, http.StatusCreated, res.Code)
+	res = request(e, http.MethodPost, "/api/stock/adjustments", employee, models.StockAdjustmentRequest{ItemID: 10, LocationID: 1, Quantity: 5, Reason: models.ReasonFound})
+	assert.Equal(t, http.StatusCreated, res.Code)
+	var posted []models.StockTransaction
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &posted))
+	assert.Equal(t, int64(3), posted[0].AccountID)
+
+	res = request(e, http.MethodGet, "/api/system/pool", admin, nil)
+	assert.Equal(t, http.StatusNotImplemented, res.Code)
+}
+
+func TestRouterTokens(t *testing.T) {
+	store := services.NewMemoryStore()
+	e := newTestServer(store)
+	ctx := context.Background()
+	account := models.Account{ID: 1, Username: "jo", Password: "secret", Role: models.Role{Value: models.RoleEmployee}, Active: true}
+	assert.Nil(t, store.AddAccount(ctx, account))
+
+	res := request(e, http.MethodGet, "/api/items", tokenFor(1, models.RoleEmployee)[:20], nil)
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+
+	unexpiring, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, &models.JwtCustomClaims{ID: 1, Role: models.Role{Value: models.RoleEmployee}}).SignedString(testKey)
+	res = request(e, http.MethodGet, "/api/items", unexpiring, nil)
+	assert.Equal(t, http.StatusUnauthorized, res.Code, "Tokens without an expiry are refused")
+
+	res = request(e, http.MethodPost, "/login", "", models.LoginDetails{Username: "jo", Password: "secret"})
+	assert.Equal(t, http.StatusAccepted, res.Code)
+	var login models.TokenPair
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &login))
+	assert.NotEmpty(t, login.RefreshToken)
+	assert.WithinDuration(t, time.Now().Add(services.AccessTokenTTL), login.ExpiresAt, time.Minute)
+
+	res = request(e, http.MethodGet, "/api/items", login.Token, nil)
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	res = request(e, http.MethodPost, "/auth/refresh", "", models.RefreshRequest{RefreshToken: login.RefreshToken})
+	assert.Equal(t, http.StatusAccepted, res.Code)
+	var refreshed models.TokenPair
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &refreshed))
+	assert.NotEqual(t, login.RefreshToken, refreshed.RefreshToken, "Refresh tokens rotate")
+
+	res = request(e, http.MethodPost, "/auth/logout", refreshed.Token, models.RefreshRequest{RefreshToken: refreshed.RefreshToken})
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	res = request(e, http.MethodGet, "/api/items", refreshed.Token, nil)
+	assert.Equal(t, http.StatusUnauthorized, res.Code, "Logged out tokens are revoked")
+	res = request(e, http.MethodPost, "/auth/refresh", "", models.RefreshRequest{RefreshToken: refreshed.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+
+	// Deactivating the account revokes the tokens it still holds
+	res = request(e, http.MethodPost, "/login", "", models.LoginDetails{Username: "jo", Password: "secret"})
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &login))
+	current, _ := store.GetAccount(ctx, 1)
+	current.Active = false
+	assert.Nil(t, store.UpdateAccount(ctx, 1, current))
+
+	res = request(e, http.MethodGet, "/api/items", login.Token, nil)
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+	res = request(e, http.MethodPost, "/auth/refresh", "", models.RefreshRequest{RefreshToken: login.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+
+	// So does deleting it, even when an account is created again with its ID
+	current.Active = true
+	assert.Nil(t, store.UpdateAccount(ctx, 1, current))
+	res = request(e, http.MethodPost, "/login", "", models.LoginDetails{Username: "jo", Password: "secret"})
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &login))
+	res = request(e, http.MethodDelete, "/api/accounts/1", tokenFor(9, models.RoleAdmin), nil)
+	assert.Equal(t, http.StatusAccepted, res.Code)
+	assert.Nil(t, store.AddAccount(ctx, account))
+
+	res = request(e, http.MethodGet, "/api/items", login.Token, nil)
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+	res = request(e, http.MethodDelete, "/api/accounts/1", tokenFor(9, models.RoleAdmin), nil)
+	assert.Equal(t, http.StatusAccepted, res.Code)
+	res = request(e, http.MethodDelete, "/api/accounts/1", tokenFor(9, models.RoleAdmin), nil)
+	assert.Equal(t, http.StatusNotFound, res.Code)
+}
+
+func TestRouterLoginLockout(t *testing.T) {
+	store := services.NewMemoryStore()
+	e := newTestServer(store)
+	ctx := context.Background()
+	assert.Nil(t, store.AddAccount(ctx, models.Account{ID: 1, Username: "admin", Password: "secret", Role: models.Role{Value: models.RoleAdmin}, Active: true}))
+	assert.Nil(t, store.AddAccount(ctx, models.Account{ID: 2, Username: "jo", Password: "secret", Active: true}))
+
+	unknown := request(e, http.MethodPost, "/login", "", models.LoginDetails{Username: "ghost", Password: "secret"})
+	assert.Equal(t, http.StatusUnauthorized, unknown.Code)
+	wrong := request(e, http.MethodPost, "/login", "", models.LoginDetails{Username: "jo", Password: "wrong"})
+	assert.Equal(t, http.StatusUnauthorized, wrong.Code)
+	assert.Equal(t, unknown.Body.String(), wrong.Body.String(), "Unknown usernames are refused like wrong passwords")
+
+	res := request(e, http.MethodPost, "/login", "", models.LoginDetails{Username: "jo", Password: "secret"})
+	assert.Equal(t, http.StatusTooManyRequests, res.Code)
+	assert.Equal(t, "1", res.Header().Get("Retry-After"))
+
+	for range services.UsernamePolicy.MaxFailures {
+		store.RecordLoginFailure(ctx, "user:jo", time.Now(), time.Now().Add(-time.Hour))
+	}
+	res = request(e, http.MethodDelete, "/api/accounts/2/lockout", tokenFor(2, models.RoleEmployee), nil)
+	assert.Equal(t, http.StatusForbidden, res.Code)
+	res = request(e, http.MethodDelete, "/api/accounts/2/lockout", tokenFor(1, models.RoleAdmin), nil)
+	assert.Equal(t, http.StatusAccepted, res.Code)
+
+	attempts, err := store.LoginAttempts(ctx, "user:jo")
+	assert.Nil(t, err)
+	assert.Zero(t, attempts.Failures)
+}
+
+func TestRouterSecondFactor(t *testing.T) {
+	store := services.NewMemoryStore()
+	e := newTestServer(store)
+	ctx := context.Background()
+	assert.Nil(t, store.AddAccount(ctx, models.Account{ID: 1, Username: "boss", Password: "secret", Role: models.Role{Value: models.RoleAdmin}, Active: true}))
+
+	res := request(e, http.MethodPost, "/login", "", models.LoginDetails{Username: "boss", Password: "secret"})
+	assert.Equal(t, http.StatusAccepted, res.Code)
+	var challenge models.LoginChallenge
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &challenge))
+	assert.NotEmpty(t, challenge.PreAuthToken)
+	assert.True(t, challenge.Enroll)
+
+	res = request(e, http.MethodGet, "/api/items", challenge.PreAuthToken, nil)
+	assert.Equal(t, http.StatusUnauthorized, res.Code, "Pre-auth tokens are not access tokens")
+
+	res = request(e, http.MethodPost, "/auth/totp/enroll", "", models.SecondFactorRequest{PreAuthToken: challenge.PreAuthToken})
+	assert.Equal(t, http.StatusCreated, res.Code)
+	var enrollment models.TOTPEnrollment
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &enrollment))
+	assert.NotEmpty(t, enrollment.QRCode)
+
+	res = request(e, http.MethodPost, "/auth/totp", "", models.SecondFactorRequest{PreAuthToken: challenge.PreAuthToken, Code: "000000"})
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+	assert.Nil(t, store.ClearLoginFailures(ctx, "user:boss"))
+	assert.Nil(t, store.ClearLoginFailures(ctx, "ip:192.0.2.1"))
+
+	res = request(e, http.MethodPost, "/auth/totp", "", models.SecondFactorRequest{PreAuthToken: challenge.PreAuthToken, Code: enrollment.RecoveryCodes[0]})
+	assert.Equal(t, http.StatusUnauthorized, res.Code, "Recovery codes wait for the secret to be confirmed")
+
+	res = request(e, http.MethodPost, "/api/accounts/2/totp", tokenFor(1, models.RoleAdmin), nil)
+	assert.Equal(t, http.StatusForbidden, res.Code, "Accounts enroll their own second factor")
+	res = request(e, http.MethodDelete, "/api/accounts/1/totp", tokenFor(1, models.RoleAdmin), nil)
+	assert.Equal(t, http.StatusAccepted, res.Code)
+}
+
+func TestRouterAPIKeys(t *testing.T) {
+	store := services.NewMemoryStore()
+	e := newTestServer(store)
+	admin := tokenFor(1, models.RoleAdmin)
+
+	res := request(e, http.MethodPost, "/api/apikeys", admin, models.NewAPIKey{Name: "sync", Scopes: []string{"accounts:read"}})
+	assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+	res = request(e, http.MethodPost, "/api/apikeys", admin, models.NewAPIKey{Name: "sync", Scopes: []string{"inventory:read", "orders:write"}})
+	assert.Equal(t, http.StatusCreated, res.Code)
+	var created models.CreatedAPIKey
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &created))
+	stored, err := store.GetAPIKeyByPrefix(context.Background(), created.Prefix)
+	assert.Nil(t, err)
+	assert.NotContains(t, res.Body.String(), stored.Hash, "Key hashes are not handed out")
+
+	res = request(e, http.MethodGet, "/api/inventory", created.Key, nil)
+	assert.Equal(t, http.StatusOK, res.Code)
+	res = request(e, http.MethodPost, "/api/inventory", created.Key, models.Inventory{})
+	assert.Equal(t, http.StatusForbidden, res.Code, "Reading does not grant writing")
+	res = request(e, http.MethodGet, "/api/orders", created.Key, nil)
+	assert.Equal(t, http.StatusForbidden, res.Code, "Writing does not grant reading")
+	res = request(e, http.MethodGet, "/api/apikeys", created.Key, nil)
+	assert.Equal(t, http.StatusForbidden, res.Code, "Keys cannot manage keys")
+	res = request(e, http.MethodGet, "/api/inventory", created.Key+"x", nil)
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+
+	res = request(e, http.MethodGet, "/api/apikeys", admin, nil)
+	assert.Equal(t, http.StatusOK, res.Code)
+	var keys models.Page[models.APIKey]
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &keys))
+	if assert.Len(t, keys.Data, 1) {
+		assert.NotNil(t, keys.Data[0].LastUsed)
+	}
+
+	res = request(e, http.MethodDelete, fmt.Sprintf("/api/apikeys/%d", created.ID), admin, nil)
+	assert.Equal(t, http.StatusAccepted, res.Code)
+	res = request(e, http.MethodGet, "/api/inventory", created.Key, nil)
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+}
@@ -0,0 +1,176 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/WMS/models"
+)
+
+// allocationPriority ranks location types for allocation. Pick faces are
+// drawn down first, stock still waiting at the dock last.
+var allocationPriority = []string{models.LocationPickFace, models.LocationReserve, models.LocationStaging, models.LocationDock}
+
+// reservingOrderStatuses are the statuses in which an order holds
+// reservations not yet handed to the floor as pick tasks.
+var reservingOrderStatuses = []string{models.OrderPlaced, models.OrderAllocated}
+
+// availability totals the per-location stock and reservations of an item.
+// Stock in an inactive location is on hand but cannot be promised.
+func availability(itemID int64, locations []models.LocationAvailability) models.Availability {
+	a := models.Availability{ItemID: itemID, Locations: locations}
+	if a.Locations == nil {
+		a.Locations = []models.LocationAvailability{}
+	}
+	for i := range a.Locations {
+		loc := &a.Locations[i]
+		loc.Available = 0
+		if loc.Active {
+			loc.Available = max(loc.OnHand-loc.Reserved, 0)
+		}
+		a.OnHand += loc.OnHand
+		a.Reserved += loc.Reserved
+		a.Available += loc.Available
+	}
+	return a
+}
+
+// allocateLines reserves the unreserved remainder of each order line from
+// the available stock of its item, taking locations in allocation priority.
+// stock is keyed by item ID and its Available quantities are used up as
+// lines are allocated.
+func allocateLines(order models.Order, reserved []models.Reservation, stock map[int64][]models.LocationAvailability) []models.Reservation {
+	have := map[int]int64{}
+	for _, r := range reserved {
+		have[r.Line] += r.Quantity
+	}
+	for _, locations := range stock {
+		slices.SortStableFunc(locations, func(a, b models.LocationAvailability) int {
+			return cmp.Or(
+				cmp.Compare(slices.Index(allocationPriority, a.Type), slices.Index(allocationPriority, b.Type)),
+				strings.Compare(a.LocationCode, b.LocationCode),
+			)
+		})
+	}
+
+	var added []models.Reservation
+	for line, group := range order.Payload {
+		need := group.Count - have[line]
+		locations := stock[group.Item.ID]
+		for i := 0; i < len(locations) && need > 0; i++ {
+			take := min(need, locations[i].Available)
+			if take <= 0 {
+				continue
+			}
+			locations[i].Available -= take
+			need -= take
+			added = append(added, models.Reservation{
+				OrderID:      order.ID,
+				Line:         line,
+				ItemID:       group.Item.ID,
+				LocationID:   locations[i].LocationID,
+				LocationCode: locations[i].LocationCode,
+				Quantity:     take,
+			})
+		}
+	}
+	return added
+}
+
+// lineAllocations summarises the reservations held against each line of an
+// order.
+func lineAllocations(order models.Order, reservations []models.Reservation) []models.LineAllocation {
+	lines := make([]models.LineAllocation, len(order.Payload))
+	for i, group := range order.Payload {
+		lines[i] = models.LineAllocation{Line: i, ItemID: group.Item.ID, Ordered: group.Count, Reservations: []models.Reservation{}}
+	}
+	for _, r := range reservations {
+		if r.Line < 0 || r.Line >= len(lines) {
+			continue
+		}
+		lines[r.Line].Allocated += r.Quantity
+		lines[r.Line].Reservations = append(lines[r.Line].Reservations, r)
+	}
+	for i := range lines {
+		switch line := &lines[i]; {
+		case line.Allocated >= line.Ordered:
+			line.Status = models.AllocationFull
+		case line.Allocated > 0:
+			line.Status = models.AllocationPartial
+		default:
+			line.Status = models.AllocationBackordered
+		}
+	}
+	return lines
+}
+
+func fullyAllocated(lines []models.LineAllocation) bool {
+	for _, line := range lines {
+		if line.Status != models.AllocationFull {
+			return false
+		}
+	}
+	return true
+}
+
+// orderItems lists the distinct items an order asks for.
+func orderItems(order models.Order) []int64 {
+	return ItemIDs(order.Payload)
+}
+
+// orderAllocator is the store side of the order workflow. Each store runs
+// it inside its own transaction or lock.
+type orderAllocator interface {
+	setOrderStatus(order *models.Order, status string) error
+	allocateOrder(order models.Order) ([]models.LineAllocation, error)
+	releaseOrder(order models.Order) error
+}
+
+// moveOrder moves an order to a new status along with the reservations
+// that go with it. Allocating requires every line to be covered, while
+// cancelling or returning to draft releases what the order holds and
+// cancels its open pick tasks.
+func moveOrder(a orderAllocator, order *models.Order, status string) error {
+	if err := checkTransition(order.ID, order.Status, status); err != nil {
+		return err
+	}
+	switch status {
+	case models.OrderDraft, models.OrderCancelled:
+		if err := a.releaseOrder(*order); err != nil {
+			return err
+		}
+	case models.OrderAllocated:
+		lines, err := a.allocateOrder(*order)
+		if err != nil {
+			return err
+		}
+		if !fullyAllocated(lines) {
+			return fmt.Errorf("order %d cannot be fully allocated: %w", order.ID, ErrConflict)
+		}
+	}
+	if err := a.setOrderStatus(order, status); err != nil {
+		return err
+	}
+	if status == models.OrderPlaced {
+		return placeOrder(a, order)
+	}
+	return nil
+}
+
+// placeOrder reserves stock for a placed order and moves it on to
+// allocated once every line is covered. Lines that cannot be covered stay
+// backordered until the order is allocated again.
+func placeOrder(a orderAllocator, order *models.Order) error {
+	lines, err := a.allocateOrder(*order)
+	if err != nil {
+		return err
+	}
+	if fullyAllocated(lines) {
+		return a.setOrderStatus(order, models.OrderAllocated)
+	}
+	return nil
+}
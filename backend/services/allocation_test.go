@@ -0,0 +1,57 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"testing"
+
+	"github.com/WMS/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAvailability(t *testing.T) {
+	a := availability(1, []models.LocationAvailability{
+		{LocationID: 1, Active: true, OnHand: 10, Reserved: 4},
+		{LocationID: 2, Active: true, OnHand: 2, Reserved: 3},
+		{LocationID: 3, OnHand: 5},
+	})
+	assert.Equal(t, int64(17), a.OnHand)
+	assert.Equal(t, int64(7), a.Reserved)
+	assert.Equal(t, int64(6), a.Available, "Over-reserved and inactive locations promise nothing")
+	assert.Equal(t, []int64{6, 0, 0}, []int64{a.Locations[0].Available, a.Locations[1].Available, a.Locations[2].Available})
+
+	assert.Equal(t, []models.LocationAvailability{}, availability(1, nil).Locations)
+}
+
+func TestAllocateLines(t *testing.T) {
+	order := models.Order{ID: 5, Payload: []models.ItemGroup{
+		{Item: models.Item{ID: 1}, Count: 8},
+		{Item: models.Item{ID: 1}, Count: 4},
+		{Item: models.Item{ID: 2}, Count: 3},
+	}}
+	stock := map[int64][]models.LocationAvailability{
+		1: {
+			{LocationID: 1, LocationCode: "B-01", Type: models.LocationReserve, Available: 6},
+			{LocationID: 2, LocationCode: "A-01", Type: models.LocationDock, Available: 9},
+			{LocationID: 3, LocationCode: "C-01", Type: models.LocationPickFace, Available: 5},
+		},
+	}
+	reserved := []models.Reservation{{Line: 0, ItemID: 1, LocationID: 3, Quantity: 2}}
+
+	// Line 0 already holds 2, pick faces are drawn down before reserve and dock
+	added := allocateLines(order, reserved, stock)
+	assert.Equal(t, []models.Reservation{
+		{OrderID: 5, Line: 0, ItemID: 1, LocationID: 3, LocationCode: "C-01", Quantity: 5},
+		{OrderID: 5, Line: 0, ItemID: 1, LocationID: 1, LocationCode: "B-01", Quantity: 1},
+		{OrderID: 5, Line: 1, ItemID: 1, LocationID: 1, LocationCode: "B-01", Quantity: 4},
+	}, added)
+
+	lines := lineAllocations(order, append(reserved, added...))
+	assert.Equal(t, []string{models.AllocationFull, models.AllocationFull, models.AllocationBackordered},
+		[]string{lines[0].Status, lines[1].Status, lines[2].Status})
+	assert.Equal(t, int64(8), lines[0].Allocated)
+	assert.False(t, fullyAllocated(lines))
+
+	lines = lineAllocations(order, []models.Reservation{{Line: 2, ItemID: 2, Quantity: 1}})
+	assert.Equal(t, models.AllocationPartial, lines[2].Status)
+}
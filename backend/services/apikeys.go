@@ -0,0 +1,186 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/WMS/models"
+	"github.com/labstack/echo/v5"
+)
+
+const (
+	// APIKeyHeader carries an API key in place of the Authorization header.
+	APIKeyHeader = "X-API-Key"
+	// APIKeyTTL is how long API keys created without an expiry last.
+	APIKeyTTL = 90 * 24 * time.Hour
+
+	apiKeyPrefix     = "wms_"
+	apiKeyContextKey = "apiKey"
+	// apiKeyTouchInterval spares a write per request to record last use.
+	apiKeyTouchInterval = time.Minute
+)
+
+// apiKeyUnscoped resources cannot be granted to API keys, as their handlers
+// act for the account making the request.
+var apiKeyUnscoped = []string{"accounts", "apikeys", "query", "search"}
+
+// checkScopes refuses scopes that are not a resource of the permission
+// matrix and "read" or "write".
+func checkScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return invalid("scopes", "an API key needs at least one scope")
+	}
+	for _, scope := range scopes {
+		resource, action, _ := strings.Cut(scope, ":")
+		if _, ok := Permissions[resource]; !ok || slices.Contains(apiKeyUnscoped, resource) {
+			return invalid("scopes", "%q is not a resource API keys can be granted", resource)
+		}
+		if action != models.ScopeRead && action != models.ScopeWrite {
+			return invalid("scopes", "%q is not a scope like %s:read or %s:write", scope, resource, resource)
+		}
+	}
+	return nil
+}
+
+// scopeAllows reports whether scopes grant a verb on a resource. Writing
+// does not grant reading, each is granted on its own.
+func scopeAllows(scopes []string, resource string, verb Verb) bool {
+	action := models.ScopeWrite
+	if verb == VerbRead {
+		action = models.ScopeRead
+	}
+	return slices.Contains(scopes, resource+":"+action)
+}
+
+// NewAPIKey creates an API key on behalf of an account. The key returned is
+// the only time its value is seen.
+func NewAPIKey(ctx context.Context, repo APIKeyRepository, request models.NewAPIKey, createdBy int64) (models.CreatedAPIKey, error) {
+	name := strings.TrimSpace(request.Name)
+	if name == "" || len(name) > 100 {
+		return models.CreatedAPIKey{}, invalid("name", "name must be 1 to 100 characters")
+	}
+	if err := checkScopes(request.Scopes); err != nil {
+		return models.CreatedAPIKey{}, err
+	}
+	now := time.Now()
+	expires := request.Expires
+	if expires.IsZero() {
+		expires = now.Add(APIKeyTTL)
+	}
+	if !expires.After(now) {
+		return models.CreatedAPIKey{}, invalid("expires", "expires must be in the future")
+	}
+
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return models.CreatedAPIKey{}, fmt.Errorf("API key generation failed: %w", err)
+	}
+	prefix := hex.EncodeToString(b)
+	secret, err := randomToken(32)
+	if err != nil {
+		return models.CreatedAPIKey{}, err
+	}
+	value := apiKeyPrefix + prefix + "_" + secret
+
+	key, err := repo.CreateAPIKey(ctx, models.APIKey{
+		Name:      name,
+		Prefix:    prefix,
+		Hash:      hashToken(value),
+		Scopes:    slices.Compact(slices.Sorted(slices.Values(request.Scopes))),
+		CreatedBy: createdBy,
+		Created:   now,
+		Expires:   expires,
+	})
+	if err != nil {
+		return models.CreatedAPIKey{}, err
+	}
+	return models.CreatedAPIKey{APIKey: key, Key: value}, nil
+}
+
+// AuthenticateAPIKey returns the API key a header value holds, recording
+// its use. Malformed, unknown and expired keys fail with ErrCredentials.
+func AuthenticateAPIKey(ctx context.Context, repo APIKeyRepository, value string) (models.APIKey, error) {
+	prefix, _, ok := strings.Cut(strings.TrimPrefix(value, apiKeyPrefix), "_")
+	if !ok || !strings.HasPrefix(value, apiKeyPrefix) {
+		return models.APIKey{}, ErrCredentials
+	}
+	key, err := repo.GetAPIKeyByPrefix(ctx, prefix)
+	if errors.Is(err, ErrNotFound) {
+		return models.APIKey{}, ErrCredentials
+	}
+	if err != nil {
+		return models.APIKey{}, err
+	}
+	now := time.Now()
+	if subtle.ConstantTimeCompare([]byte(hashToken(value)), []byte(key.Hash)) != 1 || !key.Expires.After(now) {
+		return models.APIKey{}, ErrCredentials
+	}
+
+	if key.LastUsed == nil || key.LastUsed.Before(now.Add(-apiKeyTouchInterval)) {
+		if err := repo.TouchAPIKey(ctx, key.ID, now); err != nil {
+			return models.APIKey{}, err
+		}
+		key.LastUsed = &now
+	}
+	return key, nil
+}
+
+// AcceptAPIKeys authenticates requests that send an API key by it, and
+// leaves the rest to the JWT middleware.
+func AcceptAPIKeys(repo APIKeyRepository, jwtAuth echo.MiddlewareFunc) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		withJWT := jwtAuth(next)
+		return func(c *echo.Context) error {
+			value := c.Request().Header.Get(APIKeyHeader)
+			if value == "" {
+				return withJWT(c)
+			}
+			key, err := AuthenticateAPIKey(c.Request().Context(), repo, value)
+			if errors.Is(err, ErrCredentials) {
+				return c.JSON(http.StatusUnauthorized, models.AuthError{
+					Error:   "unauthorized",
+					Message: "invalid or expired API key",
+				})
+			}
+			if err != nil {
+				return err
+			}
+			c.Set(apiKeyContextKey, key)
+			return next(c)
+		}
+	}
+}
+
+// APIKeyFrom returns the API key a request was authenticated by.
+func APIKeyFrom(c *echo.Context) (models.APIKey, bool) {
+	key, err := echo.ContextGet[models.APIKey](c, apiKeyContextKey)
+	return key, err == nil
+}
+
+// authorizeAPIKey checks the scopes of an API key for a request. Its stock
+// movements are recorded against the account that created it.
+func authorizeAPIKey(c *echo.Context, key models.APIKey, next echo.HandlerFunc) error {
+	path := c.Path()
+	resource := RequestResource(path)
+	verb := RequestVerb(c.Request().Method, path)
+	if !scopeAllows(key.Scopes, resource, verb) {
+		return c.JSON(http.StatusForbidden, models.AuthError{
+			Error:    "forbidden",
+			Message:  fmt.Sprintf("API key %s may not %s %s", key.Prefix, verb, resource),
+			Resource: resource,
+			Verb:     string(verb),
+		})
+	}
+	c.SetRequest(c.Request().WithContext(WithActor(c.Request().Context(), key.CreatedBy)))
+	return next(c)
+}
@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/WMS/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckScopes(t *testing.T) {
+	assert.Nil(t, checkScopes([]string{"inventory:read", "orders:write"}))
+	for _, scopes := range [][]string{nil, {"inventory"}, {"inventory:delete"}, {"warehouses:read"}, {"accounts:read"}, {"apikeys:write"}, {"query:read"}} {
+		var invalidErr *ValidationError
+		assert.ErrorAs(t, checkScopes(scopes), &invalidErr, scopes)
+	}
+}
+
+func TestScopeAllows(t *testing.T) {
+	scopes := []string{"inventory:read", "orders:write"}
+	assert.True(t, scopeAllows(scopes, "inventory", VerbRead))
+	assert.False(t, scopeAllows(scopes, "inventory", VerbUpdate))
+	assert.True(t, scopeAllows(scopes, "orders", VerbCreate))
+	assert.True(t, scopeAllows(scopes, "orders", VerbDelete))
+	assert.False(t, scopeAllows(scopes, "orders", VerbRead))
+	assert.False(t, scopeAllows(scopes, "items", VerbRead))
+}
+
+func TestAPIKeys(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	_, err := NewAPIKey(ctx, store, models.NewAPIKey{Name: "sync", Scopes: []string{"items:read"}, Expires: time.Now().Add(-time.Hour)}, 1)
+	var invalidErr *ValidationError
+	assert.ErrorAs(t, err, &invalidErr)
+
+	created, err := NewAPIKey(ctx, store, models.NewAPIKey{Name: " sync ", Scopes: []string{"items:read", "items:read"}}, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "sync", created.Name)
+	assert.Equal(t, []string{"items:read"}, created.Scopes)
+	assert.True(t, strings.HasPrefix(created.Key, apiKeyPrefix+created.Prefix+"_"))
+	assert.NotContains(t, created.Hash, created.Key, "Keys are stored hashed")
+	assert.WithinDuration(t, time.Now().Add(APIKeyTTL), created.Expires, time.Minute)
+	assert.Nil(t, created.LastUsed)
+
+	key, err := AuthenticateAPIKey(ctx, store, created.Key)
+	assert.Nil(t, err)
+	assert.Equal(t, created.ID, key.ID)
+	stored, err := store.GetAPIKeyByPrefix(ctx, created.Prefix)
+	assert.Nil(t, err)
+	assert.NotNil(t, stored.LastUsed, "Authenticating records the last use")
+
+	for _, value := range []string{"", "wms_", created.Prefix, created.Key + "x", "wms_000000000000_" + created.Key[len(created.Key)-10:]} {
+		_, err = AuthenticateAPIKey(ctx, store, value)
+		assert.ErrorIs(t, err, ErrCredentials, value)
+	}
+
+	stored.Expires = time.Now().Add(-time.Second)
+	store.apiKeys[stored.ID] = stored
+	_, err = AuthenticateAPIKey(ctx, store, created.Key)
+	assert.ErrorIs(t, err, ErrCredentials, "Expired keys are refused")
+
+	page, err := store.GetAPIKeys(ctx, models.ListQuery{})
+	assert.Nil(t, err)
+	assert.Len(t, page.Data, 1)
+	assert.Nil(t, store.DeleteAPIKey(ctx, int(created.ID)))
+	assert.ErrorIs(t, store.DeleteAPIKey(ctx, int(created.ID)), ErrNotFound)
+}
@@ -0,0 +1,133 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/WMS/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// itemAvailability returns the on-hand and reserved quantities of each item
+// per location, keyed by item ID.
+func itemAvailability(ctx context.Context, q pgxQuerier, itemIDs []int64) (map[int64][]models.LocationAvailability, error) {
+	rows, _ := q.Query(ctx, `with on_hand as (
+			select item_id, location_id, quantity from stock where item_id = any($1) and quantity > 0
+		), reserved as (
+			select item_id, location_id, sum(quantity) as quantity from reservation where item_id = any($1) group by item_id, location_id
+		)
+		select coalesce(s.item_id, r.item_id), l.id, l.code, l.type, l.active, coalesce(s.quantity, 0), coalesce(r.quantity, 0)
+		from on_hand s
+		full join reserved r on r.item_id = s.item_id and r.location_id = s.location_id
+		join location l on l.id = coalesce(s.location_id, r.location_id)
+		order by l.code`, itemIDs)
+
+	locations := map[int64][]models.LocationAvailability{}
+	var itemID int64
+	var loc models.LocationAvailability
+	_, err := pgx.ForEachRow(rows, []any{&itemID, &loc.LocationID, &loc.LocationCode, &loc.Type, &loc.Active, &loc.OnHand, &loc.Reserved}, func() error {
+		locations[itemID] = append(locations[itemID], loc)
+		return nil
+	})
+	return locations, err
+}
+
+func reservedStock(ctx context.Context, tx pgx.Tx, itemID int64, locationID int64) (int64, error) {
+	var reserved int64
+	err := tx.QueryRow(ctx, "select coalesce(sum(quantity), 0) from reservation where item_id=$1 and location_id=$2", itemID, locationID).Scan(&reserved)
+	return reserved, err
+}
+
+func orderReservations(ctx context.Context, q pgxQuerier, orderID int64) ([]models.Reservation, error) {
+	rows, _ := q.Query(ctx, `select r.id, r.order_id, r.line, r.item_id, r.location_id, l.code, r.quantity, r.created
+		from reservation r join location l on l.id = r.location_id
+		where r.order_id=$1 order by r.line, r.id`, orderID)
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Reservation, error) {
+		var n models.Reservation
+		err := row.Scan(&n.ID, &n.OrderID, &n.Line, &n.ItemID, &n.LocationID, &n.LocationCode, &n.Quantity, &n.Created)
+		return n, err
+	})
+}
+
+// pgOrderTx runs the order workflow inside a transaction holding the
+// order's row lock.
+type pgOrderTx struct {
+	ctx context.Context
+	tx  pgx.Tx
+}
+
+func (a pgOrderTx) setOrderStatus(order *models.Order, status string) error {
+	return setOrderStatus(a.ctx, a.tx, order, status)
+}
+
+func (a pgOrderTx) allocateOrder(order models.Order) ([]models.LineAllocation, error) {
+	itemIDs := orderItems(order)
+
+	// Locking the locations that hold the items serialises allocations and
+	// moves competing for the same stock
+	rows, _ := a.tx.Query(a.ctx, "select distinct location_id from stock where item_id = any($1) and quantity > 0", itemIDs)
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return nil, err
+	}
+	if _, err := lockLocations(a.ctx, a.tx, ids); err != nil {
+		return nil, err
+	}
+
+	stock, err := itemAvailability(a.ctx, a.tx, itemIDs)
+	if err != nil {
+		return nil, err
+	}
+	for itemID, locations := range stock {
+		stock[itemID] = availability(itemID, locations).Locations
+	}
+	reserved, err := orderReservations(a.ctx, a.tx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range allocateLines(order, reserved, stock) {
+		err := a.tx.QueryRow(a.ctx, "insert into reservation (order_id, line, item_id, location_id, quantity) values ($1, $2, $3, $4, $5) returning id, created",
+			r.OrderID,
+			r.Line,
+			r.ItemID,
+			r.LocationID,
+			r.Quantity,
+		).Scan(&r.ID, &r.Created)
+		if err != nil {
+			return nil, checkConstraint(err)
+		}
+		reserved = append(reserved, r)
+	}
+	return lineAllocations(order, reserved), nil
+}
+
+func (a pgOrderTx) releaseOrder(order models.Order) error {
+	if _, err := a.tx.Exec(a.ctx, "delete from reservation where order_id=$1", order.ID); err != nil {
+		return err
+	}
+	return cancelPicks(a.ctx, a.tx, order.ID)
+}
+
+func (s *PostgresStore) GetAvailability(ctx context.Context, id int) (models.Availability, error) {
+	fmt.Printf("Attempting to get availability of inventory: %v...\n", id)
+	var itemID int64
+	err := s.pool.QueryRow(ctx, "select item_id from inventory where id=$1", id).Scan(&itemID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Availability{}, fmt.Errorf("inventory %d: %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return models.Availability{}, err
+	}
+
+	stock, err := itemAvailability(ctx, s.pool, []int64{itemID})
+	if err != nil {
+		return models.Availability{}, err
+	}
+
+	fmt.Printf("Successfully retrieved availability of inventory: %v!\n", id)
+	return availability(itemID, stock[itemID]), nil
+}
@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/WMS/models"
+	"github.com/jackc/pgx/v5"
+)
+
+const apiKeyColumns = "id, name, prefix, key_hash, scopes, coalesce(created_by, 0), created, expires, last_used"
+
+func scanAPIKey(row pgx.CollectableRow) (models.APIKey, error) {
+	var key models.APIKey
+	err := row.Scan(
+		&key.ID,
+		&key.Name,
+		&key.Prefix,
+		&key.Hash,
+		&key.Scopes,
+		&key.CreatedBy,
+		&key.Created,
+		&key.Expires,
+		&key.LastUsed,
+	)
+	return key, err
+}
+
+func (s *PostgresStore) CreateAPIKey(ctx context.Context, key models.APIKey) (models.APIKey, error) {
+	fmt.Println("Attempting to add API key to database!")
+	rows, _ := s.pool.Query(ctx, `insert into api_key (name, prefix, key_hash, scopes, created_by, created, expires)
+		values ($1, $2, $3, $4, nullif($5, 0), $6, $7) returning `+apiKeyColumns,
+		key.Name, key.Prefix, key.Hash, key.Scopes, key.CreatedBy, key.Created, key.Expires,
+	)
+	created, err := pgx.CollectExactlyOneRow(rows, scanAPIKey)
+	if err != nil {
+		return models.APIKey{}, checkConstraint(err)
+	}
+
+	fmt.Printf("Successfully added API key: %v!\n", created.ID)
+	return created, nil
+}
+
+func (s *PostgresStore) GetAPIKeys(ctx context.Context, query models.ListQuery) (models.Page[models.APIKey], error) {
+	keys, err := listRows(ctx, s.pool, apiKeyList, query, apiKeyColumns, "api_key", scanAPIKey)
+	if err != nil {
+		return models.Page[models.APIKey]{}, err
+	}
+	return keys, nil
+}
+
+func (s *PostgresStore) GetAPIKeyByPrefix(ctx context.Context, prefix string) (models.APIKey, error) {
+	rows, _ := s.pool.Query(ctx, "select "+apiKeyColumns+" from api_key where prefix = $1", prefix)
+	key, err := pgx.CollectExactlyOneRow(rows, scanAPIKey)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.APIKey{}, ErrNotFound
+	}
+	return key, err
+}
+
+func (s *PostgresStore) TouchAPIKey(ctx context.Context, id int64, at time.Time) error {
+	command, err := s.pool.Exec(ctx, "update api_key set last_used = $2 where id = $1", id, at)
+	if err != nil {
+		return err
+	}
+	if command.RowsAffected() != 1 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *PostgresStore) DeleteAPIKey(ctx context.Context, id int) error {
+	fmt.Printf("Attempting to delete API key: %v...\n", id)
+	command, err := s.pool.Exec(ctx, "delete from api_key where id = $1", id)
+	if err != nil {
+		return err
+	}
+	if command.RowsAffected() != 1 {
+		return ErrNotFound
+	}
+
+	fmt.Printf("Successfully deleted API key: %v!\n", id)
+	return nil
+}
@@ -0,0 +1,201 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/WMS/models"
+	"github.com/jackc/pgx/v5"
+)
+
+const locationColumns = "id, code, zone, aisle, rack, shelf, bin, type, capacity, max_weight, active"
+
+func scanLocation(row pgx.CollectableRow) (models.Location, error) {
+	var n models.Location
+	err := row.Scan(
+		&n.ID,
+		&n.Code,
+		&n.Zone,
+		&n.Aisle,
+		&n.Rack,
+		&n.Shelf,
+		&n.Bin,
+		&n.Type,
+		&n.Capacity,
+		&n.MaxWeight,
+		&n.Active,
+	)
+	return n, err
+}
+
+func (s *PostgresStore) AddLocation(ctx context.Context, loc models.Location) error {
+	fmt.Println("Attempting to add location to database!")
+
+	commandstr := "insert into location (" + locationColumns + ") values (coalesce(nullif($1, 0), nextval(pg_get_serial_sequence('location', 'id'))), $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)"
+	command, err := s.pool.Exec(ctx, commandstr,
+		loc.ID,
+		loc.Code,
+		loc.Zone,
+		loc.Aisle,
+		loc.Rack,
+		loc.Shelf,
+		loc.Bin,
+		loc.Type,
+		loc.Capacity,
+		loc.MaxWeight,
+		loc.Active,
+	)
+	if err != nil {
+		return checkConstraint(err)
+	}
+	if command.RowsAffected() != 1 {
+		return errors.New("No new location created")
+	}
+
+	fmt.Println("Successfully added location!")
+	return nil
+}
+
+func (s *PostgresStore) GetLocations(ctx context.Context, query models.ListQuery) (models.Page[models.Location], error) {
+	fmt.Println("Attempting to get locations...")
+	locations, err := listRows(ctx, s.pool, locationList, query, locationColumns, "location", scanLocation)
+	if err != nil {
+		fmt.Printf("CollectRows error: %v", err)
+		return models.Page[models.Location]{}, err
+	}
+
+	fmt.Println("Successfully retrieved locations!")
+	return locations, nil
+}
+
+func (s *PostgresStore) GetLocation(ctx context.Context, id int) (models.Location, error) {
+	fmt.Printf("Attempting to get location: %v...\n", id)
+	rows, _ := s.pool.Query(ctx, "select "+locationColumns+" from location where id=$1", id)
+	location, err := pgx.CollectExactlyOneRow(rows, scanLocation)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Location{}, fmt.Errorf("location %d: %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return models.Location{}, err
+	}
+
+	fmt.Printf("Successfully retrieved location: %v!\n", id)
+	return location, nil
+}
+
+func (s *PostgresStore) GetLocationStock(ctx context.Context, id int) ([]models.StockLevel, error) {
+	if _, err := s.GetLocation(ctx, id); err != nil {
+		return nil, err
+	}
+	rows, _ := s.pool.Query(ctx, `select i.id, i.name, l.id, l.code, s.quantity
+		from stock s
+		join item i on i.id = s.item_id
+		join location l on l.id = s.location_id
+		where s.location_id=$1 and s.quantity > 0
+		order by i.name`, id)
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.StockLevel, error) {
+		var n models.StockLevel
+		err := row.Scan(&n.Item.ID, &n.Item.Name, &n.LocationID, &n.LocationCode, &n.Quantity)
+		return n, err
+	})
+}
+
+func (s *PostgresStore) UpdateLocation(ctx context.Context, id int, newData models.Location) error {
+	fmt.Printf("Attempting to update location: %v...\n", id)
+	commandstr := "update location set code=$1, zone=$2, aisle=$3, rack=$4, shelf=$5, bin=$6, type=$7, capacity=$8, max_weight=$9, active=$10 where id=$11"
+
+	command, err := s.pool.Exec(ctx, commandstr,
+		newData.Code,
+		newData.Zone,
+		newData.Aisle,
+		newData.Rack,
+		newData.Shelf,
+		newData.Bin,
+		newData.Type,
+		newData.Capacity,
+		newData.MaxWeight,
+		newData.Active,
+		id,
+	)
+	if err != nil {
+		return checkConstraint(err)
+	}
+	if command.RowsAffected() != 1 {
+		return fmt.Errorf("location %d: %w", id, ErrNotFound)
+	}
+
+	fmt.Printf("Successfully updated location: %v!\n", id)
+	return nil
+}
+
+func (s *PostgresStore) DeleteLocation(ctx context.Context, id int) error {
+	fmt.Printf("Attempting to delete location: %v...\n", id)
+	command, err := s.pool.Exec(ctx, "delete from location where id=$1", id)
+	if err != nil {
+		return checkConstraint(err)
+	}
+	if command.RowsAffected() != 1 {
+		return fmt.Errorf("location %d: %w", id, ErrNotFound)
+	}
+
+	fmt.Printf("Successfully deleted location: %v!\n", id)
+	return nil
+}
+
+// stockLocations returns the per-location quantities of each item, keyed by
+// item ID.
+func stockLocations(ctx context.Context, q pgxQuerier, itemIDs []int64) (map[int64][]models.LocationData, error) {
+	rows, _ := q.Query(ctx, `select s.item_id, l.id, l.code, s.quantity
+		from stock s join location l on l.id = s.location_id
+		where s.item_id = any($1) and s.quantity > 0
+		order by l.code`, itemIDs)
+
+	locations := map[int64][]models.LocationData{}
+	var itemID int64
+	var loc models.LocationData
+	_, err := pgx.ForEachRow(rows, []any{&itemID, &loc.LocationID, &loc.Area, &loc.Count}, func() error {
+		locations[itemID] = append(locations[itemID], loc)
+		return nil
+	})
+	return locations, err
+}
+
+func withStock(inv models.Inventory, locations []models.LocationData) models.Inventory {
+	inv.Locations = locations
+	if inv.Locations == nil {
+		inv.Locations = []models.LocationData{}
+	}
+	inv.TotalCount = 0
+	for _, loc := range inv.Locations {
+		inv.TotalCount += loc.Count
+	}
+	return inv
+}
+
+// mergeLocations validates requested quantities and folds repeated
+// locations together, keyed by location ID when given and code otherwise.
+func mergeLocations(locations []models.LocationData) ([]models.LocationData, error) {
+	var merged []models.LocationData
+	for _, loc := range locations {
+		if loc.Count < 0 {
+			return nil, invalid("locations", "count for %s cannot be negative", loc.Area)
+		}
+		if loc.LocationID == 0 && loc.Area == "" {
+			return nil, invalid("locations", "location id or area code is required")
+		}
+		i := slices.IndexFunc(merged, func(m models.LocationData) bool {
+			return (loc.LocationID != 0 && m.LocationID == loc.LocationID) ||
+				(loc.LocationID == 0 && m.LocationID == 0 && m.Area == loc.Area)
+		})
+		if i < 0 {
+			merged = append(merged, loc)
+		} else {
+			merged[i].Count += loc.Count
+		}
+	}
+	return merged, nil
+}
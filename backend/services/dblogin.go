@@ -0,0 +1,53 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/WMS/models"
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *PostgresStore) LoginAttempts(ctx context.Context, key string) (models.LoginAttempts, error) {
+	var attempts models.LoginAttempts
+	err := s.pool.QueryRow(ctx, "select failures, last_failure from login_attempt where key = $1", key).
+		Scan(&attempts.Failures, &attempts.LastFailure)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.LoginAttempts{}, nil
+	}
+	return attempts, err
+}
+
+func (s *PostgresStore) RecordLoginFailure(ctx context.Context, key string, at time.Time, forgetBefore time.Time) (models.LoginAttempts, error) {
+	var before models.LoginAttempts
+	err := s.inTransaction(ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "delete from login_attempt where last_failure < $1", forgetBefore); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, "insert into login_attempt (key, failures, last_failure) values ($1, 0, $2) on conflict (key) do nothing", key, at); err != nil {
+			return err
+		}
+		// The row lock has failures racing each other count one by one
+		err := tx.QueryRow(ctx, "select failures, last_failure from login_attempt where key = $1 for update", key).
+			Scan(&before.Failures, &before.LastFailure)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, "update login_attempt set failures = failures + 1, last_failure = $2 where key = $1", key, at)
+		return err
+	})
+	return before, err
+}
+
+func (s *PostgresStore) ForgiveLoginFailure(ctx context.Context, key string) error {
+	_, err := s.pool.Exec(ctx, "update login_attempt set failures = failures - 1 where key = $1 and failures > 0", key)
+	return err
+}
+
+func (s *PostgresStore) ClearLoginFailures(ctx context.Context, key string) error {
+	_, err := s.pool.Exec(ctx, "delete from login_attempt where key = $1", key)
+	return err
+}
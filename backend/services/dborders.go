@@ -0,0 +1,113 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/WMS/models"
+	"github.com/jackc/pgx/v5"
+)
+
+const orderColumns = "id, customer, address, timeOrdered, payload, status"
+
+func scanOrder(row pgx.CollectableRow) (models.Order, error) {
+	var n models.Order
+	err := row.Scan(
+		&n.ID,
+		&n.Customer,
+		&n.Address,
+		&n.TimeOrdered,
+		&n.Payload,
+		&n.Status,
+	)
+	return n, err
+}
+
+// lockOrder reads an order and locks it for the rest of the transaction.
+func lockOrder(ctx context.Context, tx pgx.Tx, id int) (models.Order, error) {
+	rows, _ := tx.Query(ctx, "select "+orderColumns+" from order_data where id=$1 for update", id)
+	order, err := pgx.CollectExactlyOneRow(rows, scanOrder)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Order{}, fmt.Errorf("order %d: %w", id, ErrNotFound)
+	}
+	return order, err
+}
+
+func orderHistory(ctx context.Context, q pgxQuerier, id int64) ([]models.OrderTransition, error) {
+	rows, _ := q.Query(ctx, "select from_status, to_status, coalesce(account_id, 0), created from order_transition where order_id=$1 order by id", id)
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.OrderTransition, error) {
+		var n models.OrderTransition
+		err := row.Scan(&n.From, &n.To, &n.AccountID, &n.Created)
+		return n, err
+	})
+}
+
+// setOrderStatus moves a locked order to a new status and records the
+// transition.
+func setOrderStatus(ctx context.Context, tx pgx.Tx, order *models.Order, status string) error {
+	if err := checkTransition(order.ID, order.Status, status); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, "update order_data set status=$1 where id=$2", status, order.ID); err != nil {
+		return err
+	}
+	_, err := tx.Exec(ctx, "insert into order_transition (order_id, from_status, to_status, account_id) values ($1, $2, $3, nullif($4, 0))",
+		order.ID,
+		order.Status,
+		status,
+		ActorFrom(ctx),
+	)
+	if err != nil {
+		return err
+	}
+	order.Status = status
+	return nil
+}
+
+// withAllocation fills in how the lines of an order holding reservations
+// are allocated.
+func withAllocation(ctx context.Context, q pgxQuerier, order models.Order) (models.Order, error) {
+	if !slices.Contains(reservingOrderStatuses, order.Status) {
+		return order, nil
+	}
+	reserved, err := orderReservations(ctx, q, order.ID)
+	if err != nil {
+		return order, err
+	}
+	order.Allocation = lineAllocations(order, reserved)
+	return order, nil
+}
+
+// orderUnchanged explains why an update guarded by status matched no row.
+func (s *PostgresStore) orderUnchanged(ctx context.Context, id int, message string) error {
+	var status string
+	err := s.pool.QueryRow(ctx, "select status from order_data where id=$1", id).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return errors.New(message)
+	}
+	if err != nil {
+		return err
+	}
+	return checkOrderEditable(int64(id), status)
+}
+
+func (s *PostgresStore) TransitionOrder(ctx context.Context, id int, status string) (models.Order, error) {
+	fmt.Printf("Attempting to move order %v to %v...\n", id, status)
+	err := s.inTransaction(ctx, func(tx pgx.Tx) error {
+		order, err := lockOrder(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		return moveOrder(pgOrderTx{ctx, tx}, &order, status)
+	})
+	if err != nil {
+		return models.Order{}, err
+	}
+
+	fmt.Printf("Successfully moved order %v to %v!\n", id, status)
+	return s.GetOrder(ctx, id)
+}
@@ -0,0 +1,302 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/WMS/models"
+	"github.com/jackc/pgx/v5"
+)
+
+const cartonColumns = "id, name, length, width, height, unit, max_weight, tare_weight, weight_unit, active"
+
+func scanCarton(row pgx.CollectableRow) (models.Carton, error) {
+	var n models.Carton
+	err := row.Scan(
+		&n.ID,
+		&n.Name,
+		&n.Dimensions.Length,
+		&n.Dimensions.Width,
+		&n.Dimensions.Height,
+		&n.Dimensions.Unit,
+		&n.MaxWeight.Value,
+		&n.TareWeight.Value,
+		&n.MaxWeight.Unit,
+		&n.Active,
+	)
+	n.TareWeight.Unit = n.MaxWeight.Unit
+	return n, err
+}
+
+const packedCartonColumns = "id, coalesce(sscc, ''), order_id, coalesce(carton_id, 0), carton, length, width, height, unit, contents, weight, dim_weight, weight_unit, coalesce(account_id, 0), created"
+
+func scanPackedCarton(row pgx.CollectableRow) (models.PackedCarton, error) {
+	var n models.PackedCarton
+	err := row.Scan(
+		&n.ID,
+		&n.SSCC,
+		&n.OrderID,
+		&n.CartonID,
+		&n.Carton,
+		&n.Dimensions.Length,
+		&n.Dimensions.Width,
+		&n.Dimensions.Height,
+		&n.Dimensions.Unit,
+		&n.Contents,
+		&n.Weight.Value,
+		&n.DimWeight.Value,
+		&n.Weight.Unit,
+		&n.AccountID,
+		&n.Created,
+	)
+	n.DimWeight.Unit = n.Weight.Unit
+	return n, err
+}
+
+func (s *PostgresStore) AddCarton(ctx context.Context, carton models.Carton) error {
+	fmt.Println("Attempting to add carton to database!")
+
+	commandstr := "insert into carton (" + cartonColumns + ") values (coalesce(nullif($1, 0), nextval(pg_get_serial_sequence('carton', 'id'))), $2, $3, $4, $5, $6, $7, $8, $9, $10)"
+	command, err := s.pool.Exec(ctx, commandstr,
+		carton.ID,
+		carton.Name,
+		carton.Dimensions.Length,
+		carton.Dimensions.Width,
+		carton.Dimensions.Height,
+		carton.Dimensions.Unit,
+		carton.MaxWeight.Value,
+		carton.TareWeight.Value,
+		carton.MaxWeight.Unit,
+		carton.Active,
+	)
+	if err != nil {
+		return checkConstraint(err)
+	}
+	if command.RowsAffected() != 1 {
+		return errors.New("No new carton created")
+	}
+
+	fmt.Println("Successfully added carton!")
+	return nil
+}
+
+func (s *PostgresStore) GetCartons(ctx context.Context, query models.ListQuery) (models.Page[models.Carton], error) {
+	fmt.Println("Attempting to get cartons...")
+	cartons, err := listRows(ctx, s.pool, cartonList, query, cartonColumns, "carton", scanCarton)
+	if err != nil {
+		fmt.Printf("CollectRows error: %v", err)
+		return models.Page[models.Carton]{}, err
+	}
+
+	fmt.Println("Successfully retrieved cartons!")
+	return cartons, nil
+}
+
+func (s *PostgresStore) GetCarton(ctx context.Context, id int) (models.Carton, error) {
+	fmt.Printf("Attempting to get carton: %v...\n", id)
+	rows, _ := s.pool.Query(ctx, "select "+cartonColumns+" from carton where id=$1", id)
+	carton, err := pgx.CollectExactlyOneRow(rows, scanCarton)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Carton{}, fmt.Errorf("carton %d: %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return models.Carton{}, err
+	}
+
+	fmt.Printf("Successfully retrieved carton: %v!\n", id)
+	return carton, nil
+}
+
+func (s *PostgresStore) UpdateCarton(ctx context.Context, id int, newData models.Carton) error {
+	fmt.Printf("Attempting to update carton: %v...\n", id)
+	commandstr := "update carton set name=$1, length=$2, width=$3, height=$4, unit=$5, max_weight=$6, tare_weight=$7, weight_unit=$8, active=$9 where id=$10"
+
+	command, err := s.pool.Exec(ctx, commandstr,
+		newData.Name,
+		newData.Dimensions.Length,
+		newData.Dimensions.Width,
+		newData.Dimensions.Height,
+		newData.Dimensions.Unit,
+		newData.MaxWeight.Value,
+		newData.TareWeight.Value,
+		newData.MaxWeight.Unit,
+		newData.Active,
+		id,
+	)
+	if err != nil {
+		return checkConstraint(err)
+	}
+	if command.RowsAffected() != 1 {
+		return fmt.Errorf("carton %d: %w", id, ErrNotFound)
+	}
+
+	fmt.Printf("Successfully updated carton: %v!\n", id)
+	return nil
+}
+
+func (s *PostgresStore) DeleteCarton(ctx context.Context, id int) error {
+	fmt.Printf("Attempting to delete carton: %v...\n", id)
+	command, err := s.pool.Exec(ctx, "delete from carton where id=$1", id)
+	if err != nil {
+		return checkConstraint(err)
+	}
+	if command.RowsAffected() != 1 {
+		return fmt.Errorf("carton %d: %w", id, ErrNotFound)
+	}
+
+	fmt.Printf("Successfully deleted carton: %v!\n", id)
+	return nil
+}
+
+// itemBoxes returns the box packs of the given items.
+func itemBoxes(ctx context.Context, q pgxQuerier, itemIDs []int64) ([]models.Box, error) {
+	rows, _ := q.Query(ctx, `select b.id, b.upc, b.length, b.width, b.height, b.unit, b.count, i.id, i.upc, i.name, coalesce(i.description, ''), i.weight, i.weight_unit
+		from box b join item i on i.id = b.item_id
+		where b.item_id = any($1) order by b.id`, itemIDs)
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Box, error) {
+		var n models.Box
+		err := row.Scan(
+			&n.ID,
+			&n.UPC,
+			&n.Dimensions.Length,
+			&n.Dimensions.Width,
+			&n.Dimensions.Height,
+			&n.Dimensions.Unit,
+			&n.Count,
+			&n.Item.ID,
+			&n.Item.UPC,
+			&n.Item.Name,
+			&n.Item.Description,
+			&n.Item.Weight.Value,
+			&n.Item.Weight.Unit,
+		)
+		return n, err
+	})
+}
+
+func (s *PostgresStore) SuggestCartons(ctx context.Context, orderID int) (models.Packing, error) {
+	fmt.Printf("Attempting to suggest cartons for order: %v...\n", orderID)
+	rows, _ := s.pool.Query(ctx, "select "+orderColumns+" from order_data where id=$1", orderID)
+	order, err := pgx.CollectExactlyOneRow(rows, scanOrder)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Packing{}, fmt.Errorf("order %d: %w", orderID, ErrNotFound)
+	}
+	if err != nil {
+		return models.Packing{}, err
+	}
+
+	boxes, err := itemBoxes(ctx, s.pool, orderItems(order))
+	if err != nil {
+		return models.Packing{}, err
+	}
+	units, err := packUnits(order.Payload, boxes)
+	if err != nil {
+		return models.Packing{}, err
+	}
+	rows, _ = s.pool.Query(ctx, "select "+cartonColumns+" from carton where active order by id")
+	cartons, err := pgx.CollectRows(rows, scanCarton)
+	if err != nil {
+		return models.Packing{}, err
+	}
+
+	fmt.Printf("Successfully suggested cartons for order: %v!\n", orderID)
+	return suggestCartons(order.ID, cartons, units)
+}
+
+func (s *PostgresStore) PackOrder(ctx context.Context, orderID int, req models.PackRequest) (models.Packing, error) {
+	fmt.Printf("Attempting to pack order: %v...\n", orderID)
+	err := s.inTransaction(ctx, func(tx pgx.Tx) error {
+		order, err := lockOrder(ctx, tx, orderID)
+		if err != nil {
+			return err
+		}
+		rows, _ := tx.Query(ctx, "select "+pickTaskColumns+pickTaskFrom+" where t.order_id=$1", order.ID)
+		tasks, err := pgx.CollectRows(rows, scanPickTask)
+		if err != nil {
+			return err
+		}
+		rows, _ = tx.Query(ctx, "select "+cartonColumns+" from carton")
+		list, err := pgx.CollectRows(rows, scanCarton)
+		if err != nil {
+			return err
+		}
+		cartons := map[int64]models.Carton{}
+		for _, carton := range list {
+			cartons[carton.ID] = carton
+		}
+		rows, _ = tx.Query(ctx, "select id, upc, name, coalesce(description, ''), weight, weight_unit from item where id = any($1)", orderItems(order))
+		found, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Item, error) {
+			var n models.Item
+			err := row.Scan(&n.ID, &n.UPC, &n.Name, &n.Description, &n.Weight.Value, &n.Weight.Unit)
+			return n, err
+		})
+		if err != nil {
+			return err
+		}
+		items := map[int64]models.Item{}
+		for _, item := range found {
+			items[item.ID] = item
+		}
+
+		packed, err := packedCartons(order, tasks, req, cartons, items)
+		if err != nil {
+			return err
+		}
+		for _, carton := range packed {
+			err := tx.QueryRow(ctx, `insert into packed_carton (order_id, carton_id, carton, length, width, height, unit, contents, weight, dim_weight, weight_unit, account_id)
+				values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, nullif($12, 0)) returning id`,
+				carton.OrderID,
+				carton.CartonID,
+				carton.Carton,
+				carton.Dimensions.Length,
+				carton.Dimensions.Width,
+				carton.Dimensions.Height,
+				carton.Dimensions.Unit,
+				carton.Contents,
+				carton.Weight.Value,
+				carton.DimWeight.In(carton.Weight.Unit).Value,
+				carton.Weight.Unit,
+				ActorFrom(ctx),
+			).Scan(&carton.ID)
+			if err != nil {
+				return checkConstraint(err)
+			}
+			sscc, err := licensePlate(carton.ID)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.Exec(ctx, "update packed_carton set sscc=$1 where id=$2", sscc, carton.ID); err != nil {
+				return checkConstraint(err)
+			}
+		}
+		return setOrderStatus(ctx, tx, &order, models.OrderPacked)
+	})
+	if err != nil {
+		return models.Packing{}, err
+	}
+
+	fmt.Printf("Successfully packed order: %v!\n", orderID)
+	return s.GetPacking(ctx, orderID)
+}
+
+func (s *PostgresStore) GetPacking(ctx context.Context, orderID int) (models.Packing, error) {
+	fmt.Printf("Attempting to get packing of order: %v...\n", orderID)
+	var exists bool
+	if err := s.pool.QueryRow(ctx, "select exists (select 1 from order_data where id=$1)", orderID).Scan(&exists); err != nil {
+		return models.Packing{}, err
+	}
+	if !exists {
+		return models.Packing{}, fmt.Errorf("order %d: %w", orderID, ErrNotFound)
+	}
+	rows, _ := s.pool.Query(ctx, "select "+packedCartonColumns+" from packed_carton where order_id=$1 order by id", orderID)
+	cartons, err := pgx.CollectRows(rows, scanPackedCarton)
+	if err != nil {
+		return models.Packing{}, err
+	}
+
+	fmt.Printf("Successfully retrieved packing of order: %v!\n", orderID)
+	return packingTotals(int64(orderID), cartons), nil
+}
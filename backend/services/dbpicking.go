@@ -0,0 +1,289 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/WMS/models"
+	"github.com/jackc/pgx/v5"
+)
+
+const waveColumns = "id, status, zone, cutoff, max_orders, coalesce(account_id, 0), created, completed"
+
+func scanWave(row pgx.CollectableRow) (models.Wave, error) {
+	var n models.Wave
+	err := row.Scan(
+		&n.ID,
+		&n.Status,
+		&n.Zone,
+		&n.Cutoff,
+		&n.MaxOrders,
+		&n.AccountID,
+		&n.Created,
+		&n.Completed,
+	)
+	return n, err
+}
+
+const pickTaskColumns = "t.id, t.wave_id, t.order_id, t.line, coalesce(t.reservation_id, 0), t.item_id, t.location_id, l.code, t.sequence, t.quantity, t.picked, t.status, coalesce(t.account_id, 0), t.updated"
+
+const (
+	pickTaskTables = "pick_task t join location l on l.id = t.location_id"
+	pickTaskFrom   = " from " + pickTaskTables
+)
+
+func scanPickTask(row pgx.CollectableRow) (models.PickTask, error) {
+	var n models.PickTask
+	err := row.Scan(
+		&n.ID,
+		&n.WaveID,
+		&n.OrderID,
+		&n.Line,
+		&n.ReservationID,
+		&n.ItemID,
+		&n.LocationID,
+		&n.LocationCode,
+		&n.Sequence,
+		&n.Quantity,
+		&n.Picked,
+		&n.Status,
+		&n.AccountID,
+		&n.Updated,
+	)
+	return n, err
+}
+
+// waveTasks fills in the tasks of a wave and the orders they belong to.
+func waveTasks(ctx context.Context, q pgxQuerier, wave models.Wave) (models.Wave, error) {
+	rows, _ := q.Query(ctx, "select "+pickTaskColumns+pickTaskFrom+" where t.wave_id=$1 order by t.sequence", wave.ID)
+	tasks, err := pgx.CollectRows(rows, scanPickTask)
+	if err != nil {
+		return wave, err
+	}
+	wave.Tasks = tasks
+	wave.Orders = []int64{}
+	for _, task := range tasks {
+		if !slices.Contains(wave.Orders, task.OrderID) {
+			wave.Orders = append(wave.Orders, task.OrderID)
+		}
+	}
+	return wave, nil
+}
+
+// lockPickTask reads a task and locks it for the rest of the transaction.
+func lockPickTask(ctx context.Context, tx pgx.Tx, id int) (models.PickTask, error) {
+	rows, _ := tx.Query(ctx, "select "+pickTaskColumns+pickTaskFrom+" where t.id=$1 for update of t", id)
+	task, err := pgx.CollectExactlyOneRow(rows, scanPickTask)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.PickTask{}, fmt.Errorf("pick task %d: %w", id, ErrNotFound)
+	}
+	return task, err
+}
+
+// completeWaves closes the open waves among ids that have no task left to
+// pick.
+func completeWaves(ctx context.Context, tx pgx.Tx, ids []int64) error {
+	_, err := tx.Exec(ctx, `update wave set status='COMPLETED', completed=now()
+		where id = any($1) and status='OPEN'
+		and not exists (select 1 from pick_task t where t.wave_id = wave.id and t.status in ('OPEN', 'CLAIMED'))`, ids)
+	return err
+}
+
+// cancelPicks cancels the tasks an order still had to pick.
+func cancelPicks(ctx context.Context, tx pgx.Tx, orderID int64) error {
+	rows, _ := tx.Query(ctx, "update pick_task set status='CANCELLED', updated=now() where order_id=$1 and status in ('OPEN', 'CLAIMED') returning wave_id", orderID)
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return err
+	}
+	return completeWaves(ctx, tx, ids)
+}
+
+func (s *PostgresStore) CreateWave(ctx context.Context, req models.WaveRequest) (models.Wave, error) {
+	fmt.Println("Attempting to create wave...")
+	if err := normalizeWaveRequest(&req); err != nil {
+		return models.Wave{}, err
+	}
+
+	var id int64
+	err := s.inTransaction(ctx, func(tx pgx.Tx) error {
+		// Orders being waved by a concurrent request are skipped
+		rows, _ := tx.Query(ctx, "select "+orderColumns+" from order_data where status='ALLOCATED' order by timeOrdered, id for update skip locked")
+		orders, err := pgx.CollectRows(rows, scanOrder)
+		if err != nil {
+			return err
+		}
+		reservations := map[int64][]models.Reservation{}
+		for _, order := range orders {
+			reservations[order.ID], err = orderReservations(ctx, tx, order.ID)
+			if err != nil {
+				return err
+			}
+		}
+		selected, err := waveOrders(req, orders, reservations)
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(ctx, "insert into wave (zone, cutoff, max_orders, account_id) values ($1, $2, $3, nullif($4, 0)) returning id",
+			req.Zone,
+			req.Cutoff,
+			req.MaxOrders,
+			ActorFrom(ctx),
+		).Scan(&id)
+		if err != nil {
+			return err
+		}
+		for i := range selected {
+			if err := setOrderStatus(ctx, tx, &selected[i], models.OrderPicking); err != nil {
+				return err
+			}
+		}
+		for _, task := range pickTasks(id, selected, reservations) {
+			_, err := tx.Exec(ctx, `insert into pick_task (wave_id, order_id, line, reservation_id, item_id, location_id, sequence, quantity)
+				values ($1, $2, $3, $4, $5, $6, $7, $8)`,
+				task.WaveID,
+				task.OrderID,
+				task.Line,
+				task.ReservationID,
+				task.ItemID,
+				task.LocationID,
+				task.Sequence,
+				task.Quantity,
+			)
+			if err != nil {
+				return checkConstraint(err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Wave{}, err
+	}
+
+	fmt.Printf("Successfully created wave: %v!\n", id)
+	return s.GetWave(ctx, int(id))
+}
+
+func (s *PostgresStore) GetWaves(ctx context.Context, query models.ListQuery) (models.Page[models.Wave], error) {
+	fmt.Println("Attempting to get waves...")
+	waves, err := listRows(ctx, s.pool, waveList, query, waveColumns, "wave", scanWave)
+	if err != nil {
+		fmt.Printf("CollectRows error: %v", err)
+		return models.Page[models.Wave]{}, err
+	}
+
+	fmt.Println("Successfully retrieved waves!")
+	return waves, nil
+}
+
+func (s *PostgresStore) GetWave(ctx context.Context, id int) (models.Wave, error) {
+	fmt.Printf("Attempting to get wave: %v...\n", id)
+	rows, _ := s.pool.Query(ctx, "select "+waveColumns+" from wave where id=$1", id)
+	wave, err := pgx.CollectExactlyOneRow(rows, scanWave)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Wave{}, fmt.Errorf("wave %d: %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return models.Wave{}, err
+	}
+	wave, err = waveTasks(ctx, s.pool, wave)
+	if err != nil {
+		return models.Wave{}, err
+	}
+
+	fmt.Printf("Successfully retrieved wave: %v!\n", id)
+	return wave, nil
+}
+
+func (s *PostgresStore) GetPickTasks(ctx context.Context, query models.ListQuery) (models.Page[models.PickTask], error) {
+	return listRows(ctx, s.pool, pickTaskList, query, pickTaskColumns, pickTaskTables, scanPickTask)
+}
+
+func (s *PostgresStore) ClaimPickTask(ctx context.Context, id int) (models.PickTask, error) {
+	fmt.Printf("Attempting to claim pick task: %v...\n", id)
+	var task models.PickTask
+	err := s.inTransaction(ctx, func(tx pgx.Tx) error {
+		var err error
+		task, err = lockPickTask(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if err := claimTask(&task, ActorFrom(ctx)); err != nil {
+			return err
+		}
+		return tx.QueryRow(ctx, "update pick_task set status=$1, account_id=nullif($2, 0), updated=now() where id=$3 returning updated",
+			task.Status,
+			task.AccountID,
+			task.ID,
+		).Scan(&task.Updated)
+	})
+	if err != nil {
+		return models.PickTask{}, err
+	}
+
+	fmt.Printf("Successfully claimed pick task: %v!\n", id)
+	return task, nil
+}
+
+// pick records the units picked for a claimed task, takes them out of
+// stock and consumes the reservation.
+func (s *PostgresStore) pick(ctx context.Context, id int, short *models.ShortPickRequest) (models.PickTask, error) {
+	var task models.PickTask
+	err := s.inTransaction(ctx, func(tx pgx.Tx) error {
+		var err error
+		task, err = lockPickTask(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if err := completeTask(&task, ActorFrom(ctx), short); err != nil {
+			return err
+		}
+		if _, err := postStock(ctx, tx, pickEntries(task)); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, "delete from reservation where id=$1", task.ReservationID); err != nil {
+			return err
+		}
+		err = tx.QueryRow(ctx, "update pick_task set status=$1, picked=$2, reservation_id=null, updated=now() where id=$3 returning updated",
+			task.Status,
+			task.Picked,
+			task.ID,
+		).Scan(&task.Updated)
+		if err != nil {
+			return err
+		}
+		task.ReservationID = 0
+		return completeWaves(ctx, tx, []int64{task.WaveID})
+	})
+	if err != nil {
+		return models.PickTask{}, err
+	}
+	return task, nil
+}
+
+func (s *PostgresStore) ConfirmPickTask(ctx context.Context, id int) (models.PickTask, error) {
+	fmt.Printf("Attempting to confirm pick task: %v...\n", id)
+	task, err := s.pick(ctx, id, nil)
+	if err != nil {
+		return models.PickTask{}, err
+	}
+
+	fmt.Printf("Successfully confirmed pick task: %v!\n", id)
+	return task, nil
+}
+
+func (s *PostgresStore) ShortPickTask(ctx context.Context, id int, req models.ShortPickRequest) (models.PickTask, error) {
+	fmt.Printf("Attempting to short pick task: %v...\n", id)
+	task, err := s.pick(ctx, id, &req)
+	if err != nil {
+		return models.PickTask{}, err
+	}
+
+	fmt.Printf("Successfully short picked task: %v!\n", id)
+	return task, nil
+}
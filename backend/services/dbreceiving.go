@@ -0,0 +1,137 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/WMS/models"
+	"github.com/jackc/pgx/v5"
+)
+
+const shipmentColumns = "id, supplier, distributor, eta, payload, status, arrived, received, coalesce(receiving_location_id, 0), receipt"
+
+func scanShipment(row pgx.CollectableRow) (models.Shipment, error) {
+	var n models.Shipment
+	err := row.Scan(
+		&n.ID,
+		&n.Supplier,
+		&n.Distributor,
+		&n.ETA,
+		&n.Payload,
+		&n.Status,
+		&n.Arrived,
+		&n.Received,
+		&n.ReceivingLocationID,
+		&n.Receipt,
+	)
+	return n, err
+}
+
+// lockShipment reads a shipment and locks it for the rest of the
+// transaction.
+func lockShipment(ctx context.Context, tx pgx.Tx, id int) (models.Shipment, error) {
+	rows, _ := tx.Query(ctx, "select "+shipmentColumns+" from shipment where id=$1 for update", id)
+	shipment, err := pgx.CollectExactlyOneRow(rows, scanShipment)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Shipment{}, fmt.Errorf("shipment %d: %w", id, ErrNotFound)
+	}
+	return shipment, err
+}
+
+// shipmentUnchanged explains why an update or delete guarded by status
+// matched no row.
+func (s *PostgresStore) shipmentUnchanged(ctx context.Context, id int) error {
+	var status string
+	err := s.pool.QueryRow(ctx, "select status from shipment where id=$1", id).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("shipment %d: %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return err
+	}
+	return checkShipmentStatus(models.Shipment{ID: int64(id), Status: status}, models.ShipmentExpected, models.ShipmentArrived)
+}
+
+func (s *PostgresStore) ArriveShipment(ctx context.Context, id int) (models.Shipment, error) {
+	fmt.Printf("Attempting to mark shipment %v arrived...\n", id)
+	var shipment models.Shipment
+	err := s.inTransaction(ctx, func(tx pgx.Tx) error {
+		var err error
+		shipment, err = lockShipment(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if err := checkShipmentStatus(shipment, models.ShipmentExpected); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		shipment.Status = models.ShipmentArrived
+		shipment.Arrived = &now
+		_, err = tx.Exec(ctx, "update shipment set status=$1, arrived=$2 where id=$3", shipment.Status, shipment.Arrived, id)
+		return err
+	})
+	if err != nil {
+		return models.Shipment{}, err
+	}
+
+	fmt.Printf("Successfully marked shipment %v arrived!\n", id)
+	return shipment, nil
+}
+
+func (s *PostgresStore) ReceiveShipment(ctx context.Context, id int, req models.ReceiveRequest) (models.Shipment, error) {
+	fmt.Printf("Attempting to receive shipment %v...\n", id)
+	var shipment models.Shipment
+	err := s.inTransaction(ctx, func(tx pgx.Tx) error {
+		var err error
+		shipment, err = lockShipment(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if err := checkShipmentStatus(shipment, models.ShipmentArrived); err != nil {
+			return err
+		}
+		lines, err := receiptLines(shipment, req)
+		if err != nil {
+			return err
+		}
+
+		// Received items get an inventory record if they have none yet
+		for _, line := range lines {
+			if line.Received == 0 {
+				continue
+			}
+			_, err := tx.Exec(ctx, "insert into inventory (item_id) values ($1) on conflict (item_id) do nothing", line.ItemID)
+			if err != nil {
+				return checkConstraint(err)
+			}
+		}
+		if _, err := postStock(ctx, tx, receiptEntries(shipment.ID, req.LocationID, lines)); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		shipment.Status = models.ShipmentReceived
+		shipment.Received = &now
+		shipment.ReceivingLocationID = req.LocationID
+		shipment.Receipt = lines
+		_, err = tx.Exec(ctx, "update shipment set status=$1, received=$2, receiving_location_id=$3, receipt=$4 where id=$5",
+			shipment.Status,
+			shipment.Received,
+			shipment.ReceivingLocationID,
+			shipment.Receipt,
+			id,
+		)
+		return err
+	})
+	if err != nil {
+		return models.Shipment{}, err
+	}
+
+	fmt.Printf("Successfully received shipment %v!\n", id)
+	return shipment, nil
+}
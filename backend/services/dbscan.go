@@ -0,0 +1,94 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/WMS/models"
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *PostgresStore) ResolveScan(ctx context.Context, scan models.Scan) (models.ScanResult, error) {
+	fmt.Printf("Attempting to resolve scan: %q...\n", scan.Data)
+	result := models.ScanResult{Scan: scan}
+	switch {
+	case scan.SSCC != "":
+		rows, _ := s.pool.Query(ctx, "select "+packedCartonColumns+" from packed_carton where sscc=$1", scan.SSCC)
+		carton, err := pgx.CollectExactlyOneRow(rows, scanPackedCarton)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return result, noMatch(scan)
+		}
+		if err != nil {
+			return result, err
+		}
+		rows, _ = s.pool.Query(ctx, "select "+orderColumns+" from order_data where id=$1", carton.OrderID)
+		order, err := pgx.CollectExactlyOneRow(rows, scanOrder)
+		if err != nil {
+			return result, err
+		}
+		result.Kind = models.ScanLicensePlate
+		result.Carton = &carton
+		result.Order = &order
+
+	case scan.GTIN != "":
+		var itemID int64
+		err := s.pool.QueryRow(ctx, `select id from (
+				select id, 1 as rank from item where upc=$1
+				union all select item_id, 2 from box where upc=$1
+				union all select item_id, 3 from item_uom where upc=$1
+			) product order by rank, id limit 1`, scan.GTIN).Scan(&itemID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return result, noMatch(scan)
+		}
+		if err != nil {
+			return result, err
+		}
+		var item models.Item
+		err = s.pool.QueryRow(ctx, "select id, upc, name, coalesce(description, ''), weight, weight_unit from item where id=$1", itemID).
+			Scan(&item.ID, &item.UPC, &item.Name, &item.Description, &item.Weight.Value, &item.Weight.Unit)
+		if err != nil {
+			return result, err
+		}
+		boxes, err := itemBoxes(ctx, s.pool, []int64{itemID})
+		if err != nil {
+			return result, err
+		}
+		units, err := s.GetUnitsOfMeasure(ctx, []int64{itemID})
+		if err != nil {
+			return result, err
+		}
+		productScan(&result, item, boxes, units[itemID])
+
+	default:
+		rows, _ := s.pool.Query(ctx, "select "+locationColumns+" from location where lower(code)=lower($1) order by id limit 1", scan.Code)
+		location, err := pgx.CollectExactlyOneRow(rows, scanLocation)
+		switch {
+		case err == nil:
+			result.Kind = models.ScanLocation
+			result.Location = &location
+		case !errors.Is(err, pgx.ErrNoRows):
+			return result, err
+		}
+		if orderID, ok := scanOrderID(scan.Code); ok && result.Location == nil {
+			rows, _ := s.pool.Query(ctx, "select "+orderColumns+" from order_data where id=$1", orderID)
+			order, err := pgx.CollectExactlyOneRow(rows, scanOrder)
+			switch {
+			case err == nil:
+				result.Kind = models.ScanOrder
+				result.Order = &order
+			case !errors.Is(err, pgx.ErrNoRows):
+				return result, err
+			}
+		}
+		if result.Kind == "" {
+			return result, noMatch(scan)
+		}
+	}
+	result.Actions = scanActions(result)
+
+	fmt.Printf("Successfully resolved scan to %s!\n", result.Kind)
+	return result, nil
+}
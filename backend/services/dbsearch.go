@@ -0,0 +1,108 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/WMS/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// searchSource is how a kind of record is searched. vector and document
+// are the expressions migration 0014 indexes, for full-text and trigram
+// matching, and must be kept the same as there for the indexes to be used.
+// owner is the customer of a record, when it has one.
+type searchSource struct {
+	table    string
+	vector   string
+	document string
+	title    string
+	detail   string
+	owner    string
+}
+
+var searchSources = map[string]searchSource{
+	models.SearchItems: {
+		table:    "item",
+		vector:   "setweight(to_tsvector('simple', name || ' ' || upc), 'A') || setweight(to_tsvector('simple', coalesce(description, '')), 'B')",
+		document: "name || ' ' || upc || ' ' || coalesce(description, '')",
+		title:    "name",
+		detail:   "upc",
+	},
+	models.SearchOrders: {
+		table:    "order_data",
+		vector:   "setweight(to_tsvector('simple', coalesce(customer->>'firstname', '') || ' ' || coalesce(customer->>'lastname', '')), 'A') || setweight(to_tsvector('simple', address), 'B')",
+		document: "coalesce(customer->>'firstname', '') || ' ' || coalesce(customer->>'lastname', '') || ' ' || address",
+		title:    "'Order ' || id",
+		detail:   "trim(coalesce(customer->>'firstname', '') || ' ' || coalesce(customer->>'lastname', '')) || ', ' || address",
+		owner:    "(customer->>'id')::bigint",
+	},
+	models.SearchAccounts: {
+		table:    "account",
+		vector:   "setweight(to_tsvector('simple', firstname || ' ' || lastname || ' ' || username), 'A') || setweight(to_tsvector('simple', coalesce(email, '')), 'B')",
+		document: "firstname || ' ' || lastname || ' ' || username || ' ' || coalesce(email, '')",
+		title:    "firstname || ' ' || lastname",
+		detail:   "username",
+	},
+	models.SearchLocations: {
+		table:    "location",
+		vector:   "to_tsvector('simple', code || ' ' || zone)",
+		document: "code || ' ' || zone",
+		title:    "code",
+		detail:   "type",
+	},
+}
+
+// Search ranks records by full-text matches of the words of the text as
+// prefixes, and by trigram similarity to catch misspellings.
+func (s *PostgresStore) Search(ctx context.Context, query models.SearchQuery) (models.SearchResults, error) {
+	fmt.Printf("Attempting to search for %q...\n", query.Text)
+	terms, err := checkSearch(&query)
+	if err != nil {
+		return models.SearchResults{}, err
+	}
+	prefixes := strings.Join(terms, ":* & ") + ":*"
+	text := strings.Join(terms, " ")
+
+	var hits []models.SearchHit
+	err = s.inTransaction(ctx, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, "select set_config('pg_trgm.word_similarity_threshold', $1, true)", fmt.Sprint(searchSimilarity))
+		if err != nil {
+			return err
+		}
+		for _, kind := range query.Kinds {
+			source := searchSources[kind]
+			args := []any{prefixes, text, query.Limit}
+			owned := ""
+			if source.owner != "" && query.CustomerID != 0 {
+				args = append(args, query.CustomerID)
+				owned = " and " + source.owner + " = $4"
+			}
+			rows, _ := tx.Query(ctx, fmt.Sprintf(`select id, %[3]s, %[4]s,
+				((%[1]s) @@ q)::int + ts_rank(%[1]s, q) + word_similarity($2, %[2]s)::float8 as rank
+				from %[5]s, to_tsquery('simple', $1) q
+				where ((%[1]s) @@ q or $2 <%% (%[2]s))%[6]s
+				order by rank desc, id limit $3`,
+				source.vector, source.document, source.title, source.detail, source.table, owned), args...)
+			found, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.SearchHit, error) {
+				hit := models.SearchHit{Kind: kind}
+				err := row.Scan(&hit.ID, &hit.Title, &hit.Detail, &hit.Rank)
+				return hit, err
+			})
+			if err != nil {
+				return err
+			}
+			hits = append(hits, found...)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.SearchResults{}, err
+	}
+
+	fmt.Printf("Successfully searched for %q!\n", query.Text)
+	return models.SearchResults{Query: text, Kinds: query.Kinds, Hits: bestHits(hits, query.Kinds, query.Limit)}, nil
+}
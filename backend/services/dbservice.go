@@ -10,66 +10,11 @@ import (
 	"image"
 	"image/png"
 	"log"
-	"os"
 
 	"github.com/WMS/models"
 	"github.com/jackc/pgx/v5"
 )
 
-func Connect() *pgx.Conn {
-	fmt.Println("Attempting to connect to database...")
-	url := fmt.Sprintf("postgres://%v:%v@%v:%v/%v",
-		os.Getenv("DBUSER"),
-		os.Getenv("DBPASS"),
-		os.Getenv("DBHOST"),
-		os.Getenv("DBPORT"),
-		os.Getenv("DBNAME"),
-	)
-
-	conn, err := pgx.Connect(context.Background(), url)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Unable to Connect to database: %v\n", err)
-		os.Exit(1)
-	}
-	/*
-		err = registerDataTypes(context.Background(), conn)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to register custom data types: %v\n", err)
-			os.Exit(1)
-		}
-	*/
-	fmt.Println("Successfully connected to database!")
-	return conn
-}
-
-/*
-	func registerDataTypes(c context.Context, conn *pgx.Conn) error {
-		dataTypeNames := []string{
-			//"role",
-			//"_role",
-			//"image",
-			//"_image",
-			//"customer",
-			//"_customer",
-			//"payload",
-			//"_payload",
-			//"item",
-			//"_item",
-		}
-
-		for _, typeName := range dataTypeNames {
-			dataType, err := conn.LoadType(c, typeName)
-			if err != nil {
-				return err
-			}
-			conn.TypeMap().RegisterType(dataType)
-		}
-
-		fmt.Println("Data Types Registered!")
-		return nil
-	}
-*/
-
 func convertByteToImage(imageData []byte) (image.Image, error) {
 	fmt.Println("Attempting to convert bytea to image...")
 
@@ -98,19 +43,16 @@ func ConvertImageToByte(image image.Image) ([]byte, error) {
 	return imageData, nil
 }
 
-func AddAccount(account models.Account) error {
+func (s *PostgresStore) AddAccount(ctx context.Context, account models.Account) error {
 	hashPass, err := hashPassword(account.Password)
 	if err != nil {
 		return errors.New("failed to hash password")
 	}
 
-	conn := Connect()
-	defer conn.Close(context.Background())
-
 	fmt.Println("Attempting to add account to database...")
 	commandstr := "insert into account (id, firstname, lastname, email, phone, username, password, role, active, created) values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)"
 
-	command, err := conn.Exec(context.Background(), commandstr,
+	command, err := s.pool.Exec(ctx, commandstr,
 		account.ID,
 		account.Firstname,
 		account.Lastname,
@@ -133,27 +75,25 @@ func AddAccount(account models.Account) error {
 	return nil
 }
 
-func AddItem(item models.Item) error {
-	conn := Connect()
-	defer conn.Close(context.Background())
-
+func (s *PostgresStore) AddItem(ctx context.Context, item models.Item) error {
 	fmt.Println("Attempting to add item to database...")
 	//imageBytes, err := models.ConvertImageToByte(item.Image.Img)
 	//if err != nil {
 	//	return err
 	//}
 
-	commandstr := "insert into item (id, upc, name, description, weight, image) values ($1, $2, $3, $4, $5, $6)"
-	command, err := conn.Exec(context.Background(), commandstr,
+	commandstr := "insert into item (id, upc, name, description, weight, weight_unit, image) values ($1, $2, $3, $4, $5, $6, $7)"
+	command, err := s.pool.Exec(ctx, commandstr,
 		item.ID,
 		item.UPC,
 		item.Name,
 		item.Description,
-		item.Weight,
+		item.Weight.Value,
+		item.Weight.Unit,
 		item.Image.Data,
 	)
 	if err != nil {
-		return err
+		return checkConstraint(err)
 	}
 	if command.RowsAffected() != 1 {
 		return errors.New("No new item created")
@@ -163,42 +103,56 @@ func AddItem(item models.Item) error {
 	return nil
 }
 
-func AddOrder(order models.Order) error {
-	conn := Connect()
-	defer conn.Close(context.Background())
-
+func (s *PostgresStore) AddOrder(ctx context.Context, order models.Order) error {
 	fmt.Println("Attempting to add order to database!")
-	commandstr := "insert into order_data (id, customer, address, timeOrdered, payload) values ($1, $2, $3, $4, $5)"
-	command, err := conn.Exec(context.Background(), commandstr,
-		order.ID,
-		order.Customer,
-		order.Address,
-		order.TimeOrdered,
-		order.Payload,
-	)
+	status, err := initialOrderStatus(order.Status)
 	if err != nil {
 		return err
 	}
-	if command.RowsAffected() != 1 {
-		return errors.New("No new order created")
+
+	commandstr := "insert into order_data (id, customer, address, timeOrdered, payload, status) values ($1, $2, $3, $4, $5, $6) returning " + orderColumns
+	err = s.inTransaction(ctx, func(tx pgx.Tx) error {
+		rows, _ := tx.Query(ctx, commandstr,
+			order.ID,
+			order.Customer,
+			order.Address,
+			order.TimeOrdered,
+			order.Payload,
+			status,
+		)
+		created, err := pgx.CollectExactlyOneRow(rows, scanOrder)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errors.New("No new order created")
+		}
+		if err != nil {
+			return err
+		}
+		// Orders created as placed reserve their stock straight away
+		if created.Status == models.OrderPlaced {
+			return placeOrder(pgOrderTx{ctx, tx}, &created)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	fmt.Println("Successfully added order!")
 	return nil
 }
 
-func AddBox(box models.Box) error {
-	conn := Connect()
-	defer conn.Close(context.Background())
-
+func (s *PostgresStore) AddBox(ctx context.Context, box models.Box) error {
 	fmt.Println("Attempting to add box to database!")
 
-	commandstr := "insert into box (id, upc, item, dimensions, count) values ($1, $2, $3, $4, $5)"
-	command, err := conn.Exec(context.Background(), commandstr,
+	commandstr := "insert into box (id, upc, item_id, length, width, height, unit, count) values ($1, $2, $3, $4, $5, $6, $7, $8)"
+	command, err := s.pool.Exec(ctx, commandstr,
 		box.ID,
 		box.UPC,
-		box.Item,
-		box.Dimensions,
+		box.Item.ID,
+		box.Dimensions.Length,
+		box.Dimensions.Width,
+		box.Dimensions.Height,
+		box.Dimensions.Unit,
 		box.Count,
 	)
 	if err != nil {
@@ -212,38 +166,66 @@ func AddBox(box models.Box) error {
 	return nil
 }
 
-func AddInventory(inv models.Inventory) error {
-	conn := Connect()
-	defer conn.Close(context.Background())
-
+func (s *PostgresStore) AddInventory(ctx context.Context, inv models.Inventory) error {
 	fmt.Println("Attempting to add inventory to database!")
 
-	commandstr := "insert into inventory (id, item, total, locations) values ($1, $2, $3, $4)"
-	command, err := conn.Exec(context.Background(), commandstr,
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	commandstr := "insert into inventory (id, item_id) values ($1, $2)"
+	command, err := tx.Exec(ctx, commandstr,
 		inv.ID,
-		inv.Item,
-		inv.TotalCount,
-		inv.Locations,
+		inv.Item.ID,
 	)
 	if err != nil {
-		return err
+		return checkConstraint(err)
 	}
 	if command.RowsAffected() != 1 {
 		return errors.New("No new row created")
 	}
+	entries, err := stockEntries(ctx, tx, inv.Item.ID, inv.Locations)
+	if err != nil {
+		return err
+	}
+	if _, err := postStock(ctx, tx, entries); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
 
 	fmt.Println("Successfully added inventory!")
 	return nil
 }
 
-func GetAccounts() ([]models.Account, error) {
-	conn := Connect()
+func (s *PostgresStore) AddShipment(ctx context.Context, shipment models.Shipment) error {
+	fmt.Println("Attempting to add shipment to database!")
 
-	defer conn.Close(context.Background())
+	commandstr := "insert into shipment (id, supplier, distributor, eta, payload) values ($1, $2, $3, $4, $5)"
+	command, err := s.pool.Exec(ctx, commandstr,
+		shipment.ID,
+		shipment.Supplier,
+		shipment.Distributor,
+		shipment.ETA,
+		shipment.Payload,
+	)
+	if err != nil {
+		return err
+	}
+	if command.RowsAffected() != 1 {
+		return errors.New("No new shipment created")
+	}
+
+	fmt.Println("Successfully added shipment!")
+	return nil
+}
 
+func (s *PostgresStore) GetAccounts(ctx context.Context, query models.ListQuery) (models.Page[models.Account], error) {
 	fmt.Println("Attempting to get accounts...")
-	rows, _ := conn.Query(context.Background(), "select * from account")
-	accounts, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Account, error) {
+	accounts, err := listRows(ctx, s.pool, accountList, query, "*", "account", func(row pgx.CollectableRow) (models.Account, error) {
 		var n models.Account
 		err := row.Scan(
 			&n.ID,
@@ -264,23 +246,16 @@ func GetAccounts() ([]models.Account, error) {
 	})
 	if err != nil {
 		fmt.Printf("CollectRows error: %v", err)
-		return []models.Account{}, err
-	}
-	if len(accounts) < 1 {
-		return []models.Account{}, errors.New("Accounts table is empty")
+		return models.Page[models.Account]{}, err
 	}
 
 	fmt.Println("Successfully retrieved accounts!")
 	return accounts, nil
 }
 
-func GetAccount(id int) (models.Account, error) {
-	conn := Connect()
-
-	defer conn.Close(context.Background())
-
+func (s *PostgresStore) GetAccount(ctx context.Context, id int) (models.Account, error) {
 	fmt.Printf("Attempting to get account: %v...\n", id)
-	rows, _ := conn.Query(context.Background(), "select * from account where id=$1", id)
+	rows, _ := s.pool.Query(ctx, "select * from account where id=$1", id)
 	col, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Account, error) {
 		var n models.Account
 		err := row.Scan(
@@ -304,7 +279,7 @@ func GetAccount(id int) (models.Account, error) {
 		return models.Account{}, err
 	}
 	if len(col) != 1 {
-		return models.Account{}, errors.New("Account does not exist or there are duplicate accounts")
+		return models.Account{}, fmt.Errorf("account %d: %w", id, ErrNotFound)
 	}
 
 	account := col[0]
@@ -313,21 +288,45 @@ func GetAccount(id int) (models.Account, error) {
 	return account, nil
 }
 
-func GetItems() ([]models.Item, error) {
-	conn := Connect()
-
-	defer conn.Close(context.Background())
+func (s *PostgresStore) GetAccountsByUsername(ctx context.Context, username string) ([]models.Account, error) {
+	rows, _ := s.pool.Query(ctx, "select * from account where username=$1", username)
+	accounts, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Account, error) {
+		var n models.Account
+		err := row.Scan(
+			&n.ID,
+			&n.Firstname,
+			&n.Lastname,
+			&n.Email,
+			&n.Phone,
+			&n.Username,
+			&n.Password,
+			&n.Role,
+			&n.Active,
+			&n.Created,
+		)
+		if err != nil {
+			return models.Account{}, err
+		}
+		return n, err
+	})
+	if err != nil {
+		fmt.Printf("CollectRows error: %v", err)
+		return []models.Account{}, err
+	}
+	return accounts, nil
+}
 
+func (s *PostgresStore) GetItems(ctx context.Context, query models.ListQuery) (models.Page[models.Item], error) {
 	fmt.Println("Attempting to get items...")
-	rows, _ := conn.Query(context.Background(), "select id,upc,name,description,weight from item")
-	items, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Item, error) {
+	items, err := listRows(ctx, s.pool, itemList, query, "id,upc,name,description,weight,weight_unit", "item", func(row pgx.CollectableRow) (models.Item, error) {
 		var n models.Item
 		err := row.Scan(
 			&n.ID,
 			&n.UPC,
 			&n.Name,
 			&n.Description,
-			&n.Weight,
+			&n.Weight.Value,
+			&n.Weight.Unit,
 		)
 		if err != nil {
 			return models.Item{}, err
@@ -336,24 +335,16 @@ func GetItems() ([]models.Item, error) {
 	})
 	if err != nil {
 		fmt.Printf("CollectRows error: %v", err)
-		return []models.Item{}, err
-	}
-	if len(items) < 1 {
-		return []models.Item{}, errors.New("Item table is empty")
+		return models.Page[models.Item]{}, err
 	}
 
 	fmt.Println("Successfully retrieved items!")
 	return items, nil
 }
 
-func GetItemsList() ([]models.ItemInfo, error) {
-	conn := Connect()
-
-	defer conn.Close(context.Background())
-
+func (s *PostgresStore) GetItemsList(ctx context.Context, query models.ListQuery) (models.Page[models.ItemInfo], error) {
 	fmt.Println("Attempting to get list of items...")
-	rows, _ := conn.Query(context.Background(), "select id, name from item")
-	items, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.ItemInfo, error) {
+	items, err := listRows(ctx, s.pool, itemInfoList, query, "id, name", "item", func(row pgx.CollectableRow) (models.ItemInfo, error) {
 		var n models.ItemInfo
 		err := row.Scan(
 			&n.ID,
@@ -366,21 +357,16 @@ func GetItemsList() ([]models.ItemInfo, error) {
 	})
 	if err != nil {
 		fmt.Printf("CollectRows error: %v", err)
-		return []models.ItemInfo{}, err
-	}
-	if len(items) < 1 {
-		return []models.ItemInfo{}, errors.New("Item table is empty")
+		return models.Page[models.ItemInfo]{}, err
 	}
 
 	fmt.Println("Successfully retrieved items!")
 	return items, nil
 }
 
-func GetItem(id int) (models.Item, error) {
-	conn := Connect()
-
+func (s *PostgresStore) GetItem(ctx context.Context, id int) (models.Item, error) {
 	fmt.Printf("Attempting to get item: %v...\n", id)
-	rows, _ := conn.Query(context.Background(), "select * from item where id=$1", id)
+	rows, _ := s.pool.Query(ctx, "select id, upc, name, description, weight, weight_unit, image from item where id=$1", id)
 	col, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Item, error) {
 		var n models.Item
 		err := row.Scan(
@@ -388,7 +374,8 @@ func GetItem(id int) (models.Item, error) {
 			&n.UPC,
 			&n.Name,
 			&n.Description,
-			&n.Weight,
+			&n.Weight.Value,
+			&n.Weight.Unit,
 			&n.Image,
 		)
 		if err != nil {
@@ -401,7 +388,7 @@ func GetItem(id int) (models.Item, error) {
 		return models.Item{}, err
 	}
 	if len(col) < 1 {
-		return models.Item{}, errors.New("Item table is empty")
+		return models.Item{}, fmt.Errorf("item %d: %w", id, ErrNotFound)
 	}
 
 	item := col[0]
@@ -410,87 +397,63 @@ func GetItem(id int) (models.Item, error) {
 	return item, nil
 }
 
-func GetOrders() ([]models.Order, error) {
-	conn := Connect()
-
-	defer conn.Close(context.Background())
-
+func (s *PostgresStore) GetOrders(ctx context.Context, query models.ListQuery) (models.Page[models.Order], error) {
 	fmt.Println("Attempting to get orders...")
-	rows, _ := conn.Query(context.Background(), "select * from order_data")
-	orders, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Order, error) {
-		var n models.Order
-		err := row.Scan(
-			&n.ID,
-			&n.Customer,
-			&n.Address,
-			&n.TimeOrdered,
-			&n.Payload,
-		)
-		if err != nil {
-			return models.Order{}, err
-		}
-		return n, nil
-	})
+	orders, err := listRows(ctx, s.pool, orderList, query, orderColumns, "order_data", scanOrder)
 	if err != nil {
 		fmt.Printf("CollectRows error: %v", err)
-		return []models.Order{}, err
-	}
-	if len(orders) < 1 {
-		return []models.Order{}, errors.New("Order table is empty")
+		return models.Page[models.Order]{}, err
 	}
 
 	fmt.Println("Successfully retrieved orders!")
 	return orders, nil
 }
 
-func GetOrder(id int) (models.Order, error) {
-	conn := Connect()
-
+func (s *PostgresStore) GetOrder(ctx context.Context, id int) (models.Order, error) {
 	fmt.Printf("Attempting to get order: %v...\n", id)
-	rows, _ := conn.Query(context.Background(), "select * from order_data where id=$1", id)
-	col, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Order, error) {
-		var n models.Order
-		err := row.Scan(
-			&n.ID,
-			&n.Customer,
-			&n.Address,
-			&n.TimeOrdered,
-			&n.Payload,
-		)
-		if err != nil {
-			return models.Order{}, err
-		}
-		return n, err
-	})
+	rows, _ := s.pool.Query(ctx, "select "+orderColumns+" from order_data where id=$1", id)
+	col, err := pgx.CollectRows(rows, scanOrder)
 	if err != nil {
 		fmt.Printf("CollectRows error: %v", err)
 		return models.Order{}, err
 	}
 	if len(col) < 1 {
-		return models.Order{}, errors.New("Order table is empty")
+		return models.Order{}, fmt.Errorf("order %d: %w", id, ErrNotFound)
 	}
 
 	order := col[0]
+	order.History, err = orderHistory(ctx, s.pool, order.ID)
+	if err != nil {
+		return models.Order{}, err
+	}
+	order, err = withAllocation(ctx, s.pool, order)
+	if err != nil {
+		return models.Order{}, err
+	}
 
 	fmt.Printf("Successfully retrieved order: %v!\n", id)
 	return order, nil
 }
 
-func GetBoxes() ([]models.Box, error) {
-	conn := Connect()
-
-	defer conn.Close(context.Background())
-
+func (s *PostgresStore) GetBoxes(ctx context.Context, query models.ListQuery) (models.Page[models.Box], error) {
 	fmt.Println("Attempting to get boxes...")
-	rows, _ := conn.Query(context.Background(), "select * from box")
-	boxes, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Box, error) {
+	columns := "b.id, b.upc, b.length, b.width, b.height, b.unit, b.count, i.id, i.upc, i.name, coalesce(i.description, ''), i.weight, i.weight_unit"
+	boxes, err := listRows(ctx, s.pool, boxList, query, columns, "box b join item i on i.id = b.item_id", func(row pgx.CollectableRow) (models.Box, error) {
 		var n models.Box
 		err := row.Scan(
 			&n.ID,
 			&n.UPC,
-			&n.Item,
-			&n.Dimensions,
+			&n.Dimensions.Length,
+			&n.Dimensions.Width,
+			&n.Dimensions.Height,
+			&n.Dimensions.Unit,
 			&n.Count,
+			&n.Item.ID,
+			&n.Item.UPC,
+			&n.Item.Name,
+			&n.Item.Description,
+			&n.Item.Weight.Value,
+			&n.Item.Weight.Unit,
 		)
 		if err != nil {
 			return models.Box{}, err
@@ -499,29 +462,33 @@ func GetBoxes() ([]models.Box, error) {
 	})
 	if err != nil {
 		fmt.Printf("CollectRows error: %v", err)
-		return []models.Box{}, err
-	}
-	if len(boxes) < 1 {
-		return []models.Box{}, errors.New("Box table is empty")
+		return models.Page[models.Box]{}, err
 	}
 
 	fmt.Println("Successfully retrieved boxes!")
 	return boxes, nil
 }
 
-func GetBox(id int) (models.Box, error) {
-	conn := Connect()
-
+func (s *PostgresStore) GetBox(ctx context.Context, id int) (models.Box, error) {
 	fmt.Printf("Attempting to get box: %v...\n", id)
-	rows, _ := conn.Query(context.Background(), "select * from box where id=$1", id)
+	rows, _ := s.pool.Query(ctx, "select b.id, b.upc, b.length, b.width, b.height, b.unit, b.count, i.id, i.upc, i.name, coalesce(i.description, ''), i.weight, i.weight_unit, i.image from box b join item i on i.id = b.item_id where b.id=$1", id)
 	boxes, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Box, error) {
 		var n models.Box
 		err := row.Scan(
 			&n.ID,
 			&n.UPC,
-			&n.Item,
-			&n.Dimensions,
+			&n.Dimensions.Length,
+			&n.Dimensions.Width,
+			&n.Dimensions.Height,
+			&n.Dimensions.Unit,
 			&n.Count,
+			&n.Item.ID,
+			&n.Item.UPC,
+			&n.Item.Name,
+			&n.Item.Description,
+			&n.Item.Weight.Value,
+			&n.Item.Weight.Unit,
+			&n.Item.Image,
 		)
 		if err != nil {
 			return models.Box{}, err
@@ -533,7 +500,7 @@ func GetBox(id int) (models.Box, error) {
 		return models.Box{}, err
 	}
 	if len(boxes) < 1 {
-		return models.Box{}, errors.New("Box table is empty")
+		return models.Box{}, fmt.Errorf("box %d: %w", id, ErrNotFound)
 	}
 
 	box := boxes[0]
@@ -542,20 +509,19 @@ func GetBox(id int) (models.Box, error) {
 	return box, nil
 }
 
-func GetAllInventory() ([]models.Inventory, error) {
-	conn := Connect()
-
-	defer conn.Close(context.Background())
-
+func (s *PostgresStore) GetAllInventory(ctx context.Context, query models.ListQuery) (models.Page[models.Inventory], error) {
 	fmt.Println("Attempting to get inventory...")
-	rows, _ := conn.Query(context.Background(), "select * from inventory")
-	inventory, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Inventory, error) {
+	columns := "v.id, i.id, i.upc, i.name, coalesce(i.description, ''), i.weight, i.weight_unit"
+	inventory, err := listRows(ctx, s.pool, inventoryList, query, columns, "inventory v join item i on i.id = v.item_id", func(row pgx.CollectableRow) (models.Inventory, error) {
 		var n models.Inventory
 		err := row.Scan(
 			&n.ID,
-			&n.Item,
-			&n.TotalCount,
-			&n.Locations,
+			&n.Item.ID,
+			&n.Item.UPC,
+			&n.Item.Name,
+			&n.Item.Description,
+			&n.Item.Weight.Value,
+			&n.Item.Weight.Unit,
 		)
 		if err != nil {
 			return models.Inventory{}, err
@@ -564,28 +530,39 @@ func GetAllInventory() ([]models.Inventory, error) {
 	})
 	if err != nil {
 		fmt.Printf("CollectRows error: %v", err)
-		return []models.Inventory{}, err
+		return models.Page[models.Inventory]{}, err
+	}
+
+	itemIDs := make([]int64, 0, len(inventory.Data))
+	for _, inv := range inventory.Data {
+		itemIDs = append(itemIDs, inv.Item.ID)
+	}
+	locations, err := stockLocations(ctx, s.pool, itemIDs)
+	if err != nil {
+		return models.Page[models.Inventory]{}, err
 	}
-	if len(inventory) < 1 {
-		return []models.Inventory{}, errors.New("Inventory table is empty")
+	for i, inv := range inventory.Data {
+		inventory.Data[i] = withStock(inv, locations[inv.Item.ID])
 	}
 
 	fmt.Println("Successfully retrieved inventory!")
 	return inventory, nil
 }
 
-func GetInventory(id int) (models.Inventory, error) {
-	conn := Connect()
-
+func (s *PostgresStore) GetInventory(ctx context.Context, id int) (models.Inventory, error) {
 	fmt.Printf("Attempting to get inventory: %v...\n", id)
-	rows, _ := conn.Query(context.Background(), "select * from inventory where id=$1", id)
+	rows, _ := s.pool.Query(ctx, "select v.id, i.id, i.upc, i.name, coalesce(i.description, ''), i.weight, i.weight_unit, i.image from inventory v join item i on i.id = v.item_id where v.id=$1", id)
 	allInventory, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Inventory, error) {
 		var n models.Inventory
 		err := row.Scan(
 			&n.ID,
-			&n.Item,
-			&n.TotalCount,
-			&n.Locations,
+			&n.Item.ID,
+			&n.Item.UPC,
+			&n.Item.Name,
+			&n.Item.Description,
+			&n.Item.Weight.Value,
+			&n.Item.Weight.Unit,
+			&n.Item.Image,
 		)
 		if err != nil {
 			return models.Inventory{}, err
@@ -597,109 +574,189 @@ func GetInventory(id int) (models.Inventory, error) {
 		return models.Inventory{}, err
 	}
 	if len(allInventory) < 1 {
-		return models.Inventory{}, errors.New("Inventory table is empty")
+		return models.Inventory{}, fmt.Errorf("inventory %d: %w", id, ErrNotFound)
 	}
 
 	inv := allInventory[0]
+	locations, err := stockLocations(ctx, s.pool, []int64{inv.Item.ID})
+	if err != nil {
+		return models.Inventory{}, err
+	}
+	inv = withStock(inv, locations[inv.Item.ID])
 
 	fmt.Printf("Successfully retrieved inventory: %v!\n", id)
 	return inv, nil
 }
 
-func UpdateAccount(id int, newData models.Account) error {
-	hashPass, err := hashPassword(newData.Password)
+func (s *PostgresStore) GetShipments(ctx context.Context, query models.ListQuery) (models.Page[models.Shipment], error) {
+	fmt.Println("Attempting to get shipments...")
+	shipments, err := listRows(ctx, s.pool, shipmentList, query, shipmentColumns, "shipment", scanShipment)
 	if err != nil {
-		return errors.New("failed to hash password")
+		fmt.Printf("CollectRows error: %v", err)
+		return models.Page[models.Shipment]{}, err
+	}
+
+	fmt.Println("Successfully retrieved shipments!")
+	return shipments, nil
+}
+
+func (s *PostgresStore) GetShipment(ctx context.Context, id int) (models.Shipment, error) {
+	fmt.Printf("Attempting to get shipment: %v...\n", id)
+	rows, _ := s.pool.Query(ctx, "select "+shipmentColumns+" from shipment where id=$1", id)
+	shipments, err := pgx.CollectRows(rows, scanShipment)
+	if err != nil {
+		fmt.Printf("CollectRows error: %v", err)
+		return models.Shipment{}, err
 	}
+	if len(shipments) < 1 {
+		return models.Shipment{}, fmt.Errorf("shipment %d: %w", id, ErrNotFound)
+	}
+
+	shipment := shipments[0]
 
-	conn := Connect()
-	defer conn.Close(context.Background())
+	fmt.Printf("Successfully retrieved shipment: %v!\n", id)
+	return shipment, nil
+}
 
+// UpdateAccount replaces the profile of an account, leaving its password
+// to SetPassword.
+func (s *PostgresStore) UpdateAccount(ctx context.Context, id int, newData models.Account) error {
 	fmt.Printf("Attempting to update account: %v...\n", id)
-	commandstr := "update account set firstname=$1, lastname=$2, email=$3, phone=$4, username=$5, password=$6, role=$7, active=$8 where id=$9"
-	command, err := conn.Exec(context.Background(), commandstr,
-		newData.Firstname,
-		newData.Lastname,
-		newData.Email,
-		newData.Phone,
-		newData.Username,
-		hashPass,
-		newData.Role.Value,
-		newData.Active,
-		id,
-	)
+	err := s.inTransaction(ctx, func(tx pgx.Tx) error {
+		var current models.Account
+		err := tx.QueryRow(ctx, "select role, active from account where id=$1 for update", id).
+			Scan(&current.Role.Value, &current.Active)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("account %d: %w", id, ErrNotFound)
+		}
+		if err != nil {
+			return err
+		}
+
+		commandstr := "update account set firstname=$1, lastname=$2, email=$3, phone=$4, username=$5, role=$6, active=$7 where id=$8"
+		_, err = tx.Exec(ctx, commandstr,
+			newData.Firstname,
+			newData.Lastname,
+			newData.Email,
+			newData.Phone,
+			newData.Username,
+			newData.Role.Value,
+			newData.Active,
+			id,
+		)
+		if err != nil {
+			return err
+		}
+		if tokensLapse(current, newData) {
+			return revokeAccountTokens(ctx, tx, int64(id))
+		}
+		return nil
+	})
 	if err != nil {
 		return err
 	}
-	if command.RowsAffected() != 1 {
-		return errors.New("No account updated")
-	}
 
 	fmt.Printf("Successfully updated account: %v!\n", id)
 	return nil
 }
 
-func UpdateItem(id int, newData models.Item) error {
-	conn := Connect()
-	defer conn.Close(context.Background())
+func (s *PostgresStore) SetPassword(ctx context.Context, id int, password string) error {
+	hashPass, err := hashPassword(password)
+	if err != nil {
+		return errors.New("failed to hash password")
+	}
+
+	fmt.Printf("Attempting to set password of account: %v...\n", id)
+	err = s.inTransaction(ctx, func(tx pgx.Tx) error {
+		command, err := tx.Exec(ctx, "update account set password=$1 where id=$2", hashPass, id)
+		if err != nil {
+			return err
+		}
+		if command.RowsAffected() != 1 {
+			return ErrNotFound
+		}
+		return revokeAccountTokens(ctx, tx, int64(id))
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Successfully set password of account: %v!\n", id)
+	return nil
+}
 
+func (s *PostgresStore) UpdateItem(ctx context.Context, id int, newData models.Item) error {
 	fmt.Printf("Attempting to update item: %v...\n", id)
-	commandstr := "update item set upc=$1, name=$2, description=$3, weight=$4, image=$5 where id=$6"
-	command, err := conn.Exec(context.Background(), commandstr,
+	commandstr := "update item set upc=$1, name=$2, description=$3, weight=$4, weight_unit=$5, image=$6 where id=$7"
+	command, err := s.pool.Exec(ctx, commandstr,
 		newData.UPC,
 		newData.Name,
 		newData.Description,
-		newData.Weight,
+		newData.Weight.Value,
+		newData.Weight.Unit,
 		newData.Image.Data,
 		id,
 	)
 	if err != nil {
-		return err
+		return checkConstraint(err)
 	}
 	if command.RowsAffected() != 1 {
-		return errors.New("No item updated")
+		return fmt.Errorf("item %d: %w", id, ErrNotFound)
 	}
 
 	fmt.Printf("Successfully updated item: %v!\n", id)
 	return nil
 }
 
-func UpdateOrder(id int, newData models.Order) error {
-	conn := Connect()
-	defer conn.Close(context.Background())
-
+func (s *PostgresStore) UpdateOrder(ctx context.Context, id int, newData models.Order) error {
 	fmt.Printf("Attempting to update order: %v...\n", id)
-	commandstr := "update order_data set customer=$1, address=$2, timeOrdered=$3, payload=$4 where id=$5"
-
-	command, err := conn.Exec(context.Background(), commandstr,
-		newData.Customer,
-		newData.Address,
-		newData.TimeOrdered,
-		newData.Payload,
-		id,
-	)
+	// Orders are only editable until they start moving through the warehouse
+	commandstr := "update order_data set customer=$1, address=$2, timeOrdered=$3, payload=$4 where id=$5 and status in ('DRAFT', 'PLACED') returning " + orderColumns
+
+	err := s.inTransaction(ctx, func(tx pgx.Tx) error {
+		rows, _ := tx.Query(ctx, commandstr,
+			newData.Customer,
+			newData.Address,
+			newData.TimeOrdered,
+			newData.Payload,
+			id,
+		)
+		order, err := pgx.CollectExactlyOneRow(rows, scanOrder)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return s.orderUnchanged(ctx, id, "No order updated")
+		}
+		if err != nil {
+			return err
+		}
+		// A placed order is allocated again against its new lines
+		if order.Status == models.OrderPlaced {
+			a := pgOrderTx{ctx, tx}
+			if err := a.releaseOrder(order); err != nil {
+				return err
+			}
+			return placeOrder(a, &order)
+		}
+		return nil
+	})
 	if err != nil {
 		return err
 	}
-	if command.RowsAffected() != 1 {
-		return errors.New("No order updated")
-	}
 
 	fmt.Printf("Successfully updated order: %v!\n", id)
 	return nil
 }
 
-func UpdateBox(id int, newData models.Box) error {
-	conn := Connect()
-	defer conn.Close(context.Background())
-
+func (s *PostgresStore) UpdateBox(ctx context.Context, id int, newData models.Box) error {
 	fmt.Printf("Attempting to update box: %v...\n", id)
-	commandstr := "update box set upc=$1, item=$2, dimensions=$3, count=$4 where id=$5"
+	commandstr := "update box set upc=$1, item_id=$2, length=$3, width=$4, height=$5, unit=$6, count=$7 where id=$8"
 
-	command, err := conn.Exec(context.Background(), commandstr,
+	command, err := s.pool.Exec(ctx, commandstr,
 		newData.UPC,
-		newData.Item,
-		newData.Dimensions,
+		newData.Item.ID,
+		newData.Dimensions.Length,
+		newData.Dimensions.Width,
+		newData.Dimensions.Height,
+		newData.Dimensions.Unit,
 		newData.Count,
 		id,
 	)
@@ -707,119 +764,195 @@ func UpdateBox(id int, newData models.Box) error {
 		return err
 	}
 	if command.RowsAffected() != 1 {
-		return errors.New("No box updated")
+		return fmt.Errorf("box %d: %w", id, ErrNotFound)
 	}
 
 	fmt.Printf("Successfully updated box: %v!\n", id)
 	return nil
 }
 
-func UpdateInventory(id int, newData models.Inventory) error {
-	conn := Connect()
-	defer conn.Close(context.Background())
-
+func (s *PostgresStore) UpdateInventory(ctx context.Context, id int, newData models.Inventory) error {
 	fmt.Printf("Attempting to update inventory: %v...\n", id)
-	commandstr := "update inventory set id=$1, item=$2, total=$3, locations=$4 where id=$5"
 
-	command, err := conn.Exec(context.Background(), commandstr,
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var oldItemID int64
+	err = tx.QueryRow(ctx, "select item_id from inventory where id=$1 for update", id).Scan(&oldItemID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("inventory %d: %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return err
+	}
+
+	commandstr := "update inventory set id=$1, item_id=$2 where id=$3"
+	command, err := tx.Exec(ctx, commandstr,
 		newData.ID,
-		newData.Item,
-		newData.TotalCount,
-		newData.Locations,
+		newData.Item.ID,
 		id,
 	)
 	if err != nil {
-		return err
+		return checkConstraint(err)
 	}
 	if command.RowsAffected() != 1 {
-		return errors.New("no inventory updated")
+		return fmt.Errorf("inventory %d: %w", id, ErrNotFound)
+	}
+	// Stock of a replaced item is counted out before the new item's is set
+	var entries []models.StockTransaction
+	if oldItemID != newData.Item.ID {
+		entries, err = stockEntries(ctx, tx, oldItemID, nil)
+		if err != nil {
+			return err
+		}
+	}
+	newEntries, err := stockEntries(ctx, tx, newData.Item.ID, newData.Locations)
+	if err != nil {
+		return err
+	}
+	if _, err := postStock(ctx, tx, append(entries, newEntries...)); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
 	}
 
 	fmt.Printf("Successfully updated inventory: %v!\n", id)
 	return nil
 }
 
-func DeleteAccount(id int) error {
-	conn := Connect()
-	defer conn.Close(context.Background())
+func (s *PostgresStore) UpdateShipment(ctx context.Context, id int, newData models.Shipment) error {
+	fmt.Printf("Attempting to update shipment: %v...\n", id)
+	// Received shipments are a record of what was posted to the ledger
+	commandstr := "update shipment set supplier=$1, distributor=$2, eta=$3, payload=$4 where id=$5 and status <> 'RECEIVED'"
 
-	fmt.Printf("Attempting to delete account: %v...\n", id)
-	command, err := conn.Exec(context.Background(), "delete from account where id=$1", id)
+	command, err := s.pool.Exec(ctx, commandstr,
+		newData.Supplier,
+		newData.Distributor,
+		newData.ETA,
+		newData.Payload,
+		id,
+	)
 	if err != nil {
 		return err
 	}
 	if command.RowsAffected() != 1 {
-		return errors.New("No account deleted!")
+		return s.shipmentUnchanged(ctx, id)
 	}
 
-	fmt.Printf("Successfully deleted account: %v!\n", id)
+	fmt.Printf("Successfully updated shipment: %v!\n", id)
 	return nil
 }
 
-func DeleteItem(id int) error {
-	conn := Connect()
-	defer conn.Close(context.Background())
+func (s *PostgresStore) DeleteAccount(ctx context.Context, id int) error {
+	fmt.Printf("Attempting to delete account: %v...\n", id)
+	// The generation outlives the account, so its access tokens stay
+	// revoked and do not pass for an account created again with its ID
+	err := s.inTransaction(ctx, func(tx pgx.Tx) error {
+		command, err := tx.Exec(ctx, "delete from account where id=$1", id)
+		if err != nil {
+			return err
+		}
+		if command.RowsAffected() != 1 {
+			return fmt.Errorf("account %d: %w", id, ErrNotFound)
+		}
+		return revokeAccountTokens(ctx, tx, int64(id))
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Successfully deleted account: %v!\n", id)
+	return nil
+}
 
+func (s *PostgresStore) DeleteItem(ctx context.Context, id int) error {
 	fmt.Printf("Attempting to delete item: %v...\n", id)
-	command, err := conn.Exec(context.Background(), "delete from item where id=$1", id)
+	command, err := s.pool.Exec(ctx, "delete from item where id=$1", id)
 	if err != nil {
-		return err
+		return checkConstraint(err)
 	}
 	if command.RowsAffected() != 1 {
-		return errors.New("No item deleted!")
+		return fmt.Errorf("item %d: %w", id, ErrNotFound)
 	}
 
 	fmt.Printf("Successfully deleted item: %v!\n", id)
 	return nil
 }
 
-func DeleteOrder(id int) error {
-	conn := Connect()
-	defer conn.Close(context.Background())
-
+func (s *PostgresStore) DeleteOrder(ctx context.Context, id int) error {
 	fmt.Printf("Attempting to delete order: %v...\n", id)
-	command, err := conn.Exec(context.Background(), "delete from order_data where id=$1", id)
+	command, err := s.pool.Exec(ctx, "delete from order_data where id=$1", id)
 	if err != nil {
 		return err
 	}
 	if command.RowsAffected() != 1 {
-		return errors.New("No order deleted!")
+		return fmt.Errorf("order %d: %w", id, ErrNotFound)
 	}
 
 	fmt.Printf("Successfully deleted order: %v!\n", id)
 	return nil
 }
 
-func DeleteBox(id int) error {
-	conn := Connect()
-	defer conn.Close(context.Background())
-
+func (s *PostgresStore) DeleteBox(ctx context.Context, id int) error {
 	fmt.Printf("Attempting to delete box: %v...\n", id)
-	command, err := conn.Exec(context.Background(), "delete from box where id=$1", id)
+	command, err := s.pool.Exec(ctx, "delete from box where id=$1", id)
 	if err != nil {
 		return err
 	}
 	if command.RowsAffected() != 1 {
-		return errors.New("No box deleted!")
+		return fmt.Errorf("box %d: %w", id, ErrNotFound)
 	}
 
 	fmt.Printf("Successfully deleted box: %v!\n", id)
 	return nil
 }
 
-func DeleteInventory(id int) error {
-	conn := Connect()
-	defer conn.Close(context.Background())
-
+func (s *PostgresStore) DeleteInventory(ctx context.Context, id int) error {
 	fmt.Printf("Attempting to delete inventory: %v...\n", id)
-	command, err := conn.Exec(context.Background(), "delete from inventory where id=$1", id)
+
+	tx, err := s.pool.Begin(ctx)
 	if err != nil {
 		return err
 	}
-	if command.RowsAffected() != 1 {
-		return errors.New("No inventory deleted!")
+	defer tx.Rollback(ctx)
+
+	var itemID int64
+	err = tx.QueryRow(ctx, "delete from inventory where id=$1 returning item_id", id).Scan(&itemID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("inventory %d: %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return err
+	}
+	entries, err := stockEntries(ctx, tx, itemID, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := postStock(ctx, tx, entries); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
 	}
 
 	fmt.Printf("Successfully deleted inventory: %v!\n", id)
 	return nil
 }
+
+func (s *PostgresStore) DeleteShipment(ctx context.Context, id int) error {
+	fmt.Printf("Attempting to delete shipment: %v...\n", id)
+	command, err := s.pool.Exec(ctx, "delete from shipment where id=$1 and status <> 'RECEIVED'", id)
+	if err != nil {
+		return err
+	}
+	if command.RowsAffected() != 1 {
+		return s.shipmentUnchanged(ctx, id)
+	}
+
+	fmt.Printf("Successfully deleted shipment: %v!\n", id)
+	return nil
+}
@@ -23,17 +23,33 @@ var (
 	testInventory models.Inventory
 )
 
-func TestConnection(t *testing.T) {
+func testStore(t *testing.T) *PostgresStore {
+	t.Helper()
 	err := godotenv.Load("../.env")
 	if err != nil {
 		fmt.Println("failed to load .env file")
 	}
-	db := Connect()
-	assert.NotNil(t, db, "Check Database Connection!")
-	assert.Nil(t, db.Ping(context.Background()), "Check Active Connection!")
+	if os.Getenv("DBHOST") == "" {
+		t.Skip("DBHOST is not set, skipping Postgres tests")
+	}
+	store, err := NewPostgresStore(context.Background(), DatabaseURL())
+	if err != nil {
+		t.Fatalf("Check Database Connection! %v", err)
+	}
+	t.Cleanup(store.Close)
+	return store
+}
+
+func TestConnection(t *testing.T) {
+	store := testStore(t)
+	assert.Nil(t, store.Ping(context.Background()), "Check Active Connection!")
+	assert.True(t, store.Stats().TotalConns > 0, "Check Pool Stats!")
 }
 
 func TestAccountService(t *testing.T) {
+	store := testStore(t)
+	ctx := context.Background()
+
 	// AddAccount
 	testAccount = models.Account{
 		ID:        66,
@@ -47,16 +63,16 @@ func TestAccountService(t *testing.T) {
 		Active:    true,
 		Created:   time.Now(),
 	}
-	stat := AddAccount(testAccount)
+	stat := store.AddAccount(ctx, testAccount)
 	assert.Nil(t, stat, "Account not empty")
 
 	// GetAccounts
-	accounts, err := GetAccounts()
+	accounts, err := store.GetAccounts(ctx, models.ListQuery{})
 	assert.Nil(t, err)
-	assert.True(t, len(accounts) > 0, "Accounts greater than zero")
+	assert.True(t, len(accounts.Data) > 0, "Accounts greater than zero")
 
 	// GetAccount
-	account, err := GetAccount(66)
+	account, err := store.GetAccount(ctx, 66)
 	assert.Nil(t, err)
 	assert.NotNil(t, account)
 
@@ -72,15 +88,18 @@ func TestAccountService(t *testing.T) {
 		Role:      models.Role{Value: "CUSTOMER"},
 		Active:    true,
 	}
-	stat = UpdateAccount(int(updateAccount.ID), updateAccount)
+	stat = store.UpdateAccount(ctx, int(updateAccount.ID), updateAccount)
 	assert.Nil(t, stat)
 
 	// DeleteAccount
-	stat = DeleteAccount(int(updateAccount.ID))
+	stat = store.DeleteAccount(ctx, int(updateAccount.ID))
 	assert.Nil(t, stat)
 }
 
 func TestItemService(t *testing.T) {
+	store := testStore(t)
+	ctx := context.Background()
+
 	testPng, err := os.Open("./sample.png")
 	if err != nil {
 		fmt.Println("Failed to open sample.png")
@@ -99,23 +118,23 @@ func TestItemService(t *testing.T) {
 		UPC:         "123456",
 		Name:        "demo item",
 		Description: "demo item demo item",
-		Weight:      1.0,
+		Weight:      models.Weight{Value: 1.0, Unit: models.UnitPound},
 		Image: models.ImageData{
 			Name:  "image.png",
 			Data:  imgBytes,
 			Valid: true,
 		},
 	}
-	stat := AddItem(testItem)
+	stat := store.AddItem(ctx, testItem)
 	assert.Nil(t, stat)
 
 	// GetItems
-	items, err := GetItems()
+	items, err := store.GetItems(ctx, models.ListQuery{})
 	assert.Nil(t, err)
-	assert.True(t, len(items) > 0)
+	assert.True(t, len(items.Data) > 0)
 
 	// GetItem
-	item, err := GetItem(int(testItem.ID))
+	item, err := store.GetItem(ctx, int(testItem.ID))
 	assert.Nil(t, err)
 	assert.NotNil(t, item)
 
@@ -125,42 +144,53 @@ func TestItemService(t *testing.T) {
 		UPC:         "234567",
 		Name:        "demo item1",
 		Description: "demo item demo item1",
-		Weight:      2.0,
+		Weight:      models.Weight{Value: 2.0, Unit: models.UnitPound},
 		Image: models.ImageData{
 			Name:  "sample.png",
 			Data:  imgBytes,
 			Valid: true,
 		},
 	}
-	stat = UpdateItem(int(updateItem.ID), updateItem)
+	stat = store.UpdateItem(ctx, int(updateItem.ID), updateItem)
 	assert.Nil(t, stat)
 
 	// DeleteItem
-	stat = DeleteItem(int(updateItem.ID))
+	stat = store.DeleteItem(ctx, int(updateItem.ID))
 	assert.Nil(t, stat)
 }
 
 func TestOrderService(t *testing.T) {
+	store := testStore(t)
+	ctx := context.Background()
+
 	// AddOrder
 	testOrder = models.Order{
 		ID:          66,
 		Customer:    testAccount,
 		Address:     "12345 N. test Ln.",
-		TimeOrdered: time.Now(),
+		TimeOrdered: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC),
 		Payload: []models.ItemGroup{
 			{Item: testItem, Count: 123},
 		},
 	}
-	stat := AddOrder(testOrder)
+	stat := store.AddOrder(ctx, testOrder)
 	assert.Nil(t, stat)
 
 	// GetOrders
-	orders, err := GetOrders()
+	orders, err := store.GetOrders(ctx, models.ListQuery{})
+	assert.Nil(t, err)
+	assert.True(t, len(orders.Data) > 0)
+
+	// Date filters and queries
+	orders, err = store.GetOrders(ctx, models.ListQuery{Filters: map[string][]string{"timeOrdered": {"2026-01-01", "2026-01-02"}}})
+	assert.Nil(t, err)
+	assert.True(t, len(orders.Data) > 0)
+	orders, err = store.GetOrders(ctx, models.ListQuery{Query: "orders where timeOrdered = 2026-01-01 and timeOrdered >= 2025-12-31"})
 	assert.Nil(t, err)
-	assert.True(t, len(orders) > 0)
+	assert.True(t, len(orders.Data) > 0)
 
 	// GetOrder
-	order, err := GetOrder(int(testOrder.ID))
+	order, err := store.GetOrder(ctx, int(testOrder.ID))
 	assert.Nil(t, err)
 	assert.NotNil(t, order)
 
@@ -185,34 +215,41 @@ func TestOrderService(t *testing.T) {
 		},
 	}
 
-	stat = UpdateOrder(int(updateOrder.ID), updateOrder)
+	stat = store.UpdateOrder(ctx, int(updateOrder.ID), updateOrder)
 	assert.Nil(t, stat)
 
 	// DeleteOrder
-	stat = DeleteOrder(int(updateOrder.ID))
+	stat = store.DeleteOrder(ctx, int(updateOrder.ID))
 	assert.Nil(t, stat)
 }
 
 func TestBoxService(t *testing.T) {
+	store := testStore(t)
+	ctx := context.Background()
+
+	// Box and inventory rows reference an existing item
+	assert.Nil(t, store.AddItem(ctx, testItem))
+	defer store.DeleteItem(ctx, int(testItem.ID))
+
 	// AddBox
 	testBox = models.Box{
 		ID:         66,
 		UPC:        "123456",
 		Item:       testItem,
-		Dimensions: "2x2x4",
+		Dimensions: models.Dimensions{Length: 2, Width: 2, Height: 4, Unit: models.UnitInch},
 		Count:      123,
 	}
 
-	stat := AddBox(testBox)
+	stat := store.AddBox(ctx, testBox)
 	assert.Nil(t, stat)
 
 	// GetBoxes
-	boxes, err := GetBoxes()
+	boxes, err := store.GetBoxes(ctx, models.ListQuery{})
 	assert.Nil(t, err)
 	assert.NotNil(t, boxes)
 
 	// GetBox
-	box, err := GetBox(int(testBox.ID))
+	box, err := store.GetBox(ctx, int(testBox.ID))
 	assert.Nil(t, err)
 	assert.NotNil(t, box)
 
@@ -221,19 +258,34 @@ func TestBoxService(t *testing.T) {
 		ID:         66,
 		UPC:        "23456",
 		Item:       testItem,
-		Dimensions: "4x4x8",
+		Dimensions: models.Dimensions{Length: 4, Width: 4, Height: 8, Unit: models.UnitInch},
 		Count:      234,
 	}
 
-	stat = UpdateBox(int(updatebox.ID), updatebox)
+	stat = store.UpdateBox(ctx, int(updatebox.ID), updatebox)
 	assert.Nil(t, stat)
 
 	// DeleteBox
-	stat = DeleteBox(int(updatebox.ID))
+	stat = store.DeleteBox(ctx, int(updatebox.ID))
 	assert.Nil(t, stat)
 }
 
 func TestInventoryService(t *testing.T) {
+	store := testStore(t)
+	ctx := context.Background()
+
+	// Box and inventory rows reference an existing item
+	assert.Nil(t, store.AddItem(ctx, testItem))
+	defer store.DeleteItem(ctx, int(testItem.ID))
+
+	// Stock is kept at existing locations
+	for i, code := range []string{"A12", "B23", "C3"} {
+		loc := models.Location{ID: int64(660 + i), Code: code, Active: true}
+		assert.Nil(t, NormalizeLocation(&loc))
+		assert.Nil(t, store.AddLocation(ctx, loc))
+		defer store.DeleteLocation(ctx, int(loc.ID))
+	}
+
 	// AddInventory
 	testInventory = models.Inventory{
 		ID:         66,
@@ -251,18 +303,19 @@ func TestInventoryService(t *testing.T) {
 		},
 	}
 
-	stat := AddInventory(testInventory)
+	stat := store.AddInventory(ctx, testInventory)
 	assert.Nil(t, stat)
 
 	// GetAllInventory
-	allInv, err := GetAllInventory()
+	allInv, err := store.GetAllInventory(ctx, models.ListQuery{})
 	assert.Nil(t, err)
 	assert.NotNil(t, allInv)
 
 	// GetInventory
-	inv, err := GetInventory(int(testInventory.ID))
+	inv, err := store.GetInventory(ctx, int(testInventory.ID))
 	assert.Nil(t, err)
-	assert.NotNil(t, inv)
+	assert.Equal(t, int64(2345), inv.TotalCount)
+	assert.Len(t, inv.Locations, 2)
 
 	// UpdateInventory
 	updateInventory := models.Inventory{
@@ -277,10 +330,79 @@ func TestInventoryService(t *testing.T) {
 		},
 	}
 
-	stat = UpdateInventory(int(updateInventory.ID), updateInventory)
+	stat = store.UpdateInventory(ctx, int(updateInventory.ID), updateInventory)
 	assert.Nil(t, stat)
 
 	// DeleteInventory
-	stat = DeleteInventory(int(updateInventory.ID))
+	stat = store.DeleteInventory(ctx, int(updateInventory.ID))
+	assert.Nil(t, stat)
+}
+
+func TestLocationService(t *testing.T) {
+	store := testStore(t)
+	ctx := context.Background()
+
+	// AddLocation
+	loc := models.Location{ID: 66, Code: "Z-01-02", Type: models.LocationPickFace, Capacity: 10, Active: true}
+	assert.Nil(t, NormalizeLocation(&loc))
+	stat := store.AddLocation(ctx, loc)
+	assert.Nil(t, stat)
+	assert.ErrorIs(t, store.AddLocation(ctx, models.Location{ID: 67, Code: loc.Code, Type: loc.Type}), ErrConflict)
+
+	// GetLocations
+	locations, err := store.GetLocations(ctx, models.ListQuery{Filters: map[string][]string{"code": {loc.Code}}})
+	assert.Nil(t, err)
+	assert.Len(t, locations.Data, 1)
+
+	// GetLocation
+	got, err := store.GetLocation(ctx, int(loc.ID))
+	assert.Nil(t, err)
+	assert.Equal(t, "01", got.Aisle)
+	_, err = store.GetLocation(ctx, 99999)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// GetLocationStock
+	stock, err := store.GetLocationStock(ctx, int(loc.ID))
+	assert.Nil(t, err)
+	assert.Empty(t, stock)
+
+	// UpdateLocation
+	loc.Capacity = 20
+	stat = store.UpdateLocation(ctx, int(loc.ID), loc)
+	assert.Nil(t, stat)
+
+	// DeleteLocation
+	stat = store.DeleteLocation(ctx, int(loc.ID))
+	assert.Nil(t, stat)
+}
+
+func TestShipmentService(t *testing.T) {
+	store := testStore(t)
+	ctx := context.Background()
+
+	// AddShipment
+	shipment := models.Shipment{ID: 66, Distributor: "beans united", ETA: time.Now(), Payload: []models.ItemGroup{}}
+	stat := store.AddShipment(ctx, shipment)
+	assert.Nil(t, stat)
+
+	// GetShipment
+	got, err := store.GetShipment(ctx, int(shipment.ID))
+	assert.Nil(t, err)
+	assert.Equal(t, models.ShipmentExpected, got.Status)
+
+	// ArriveShipment
+	got, err = store.ArriveShipment(ctx, int(shipment.ID))
+	assert.Nil(t, err)
+	assert.NotNil(t, got.Arrived)
+	_, err = store.ArriveShipment(ctx, int(shipment.ID))
+	assert.ErrorIs(t, err, ErrConflict)
+
+	// UpdateShipment
+	shipment.Distributor = "beans reunited"
+	stat = store.UpdateShipment(ctx, int(shipment.ID), shipment)
+	assert.Nil(t, stat)
+
+	// DeleteShipment
+	stat = store.DeleteShipment(ctx, int(shipment.ID))
 	assert.Nil(t, stat)
 }
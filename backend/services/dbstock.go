@@ -0,0 +1,273 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/WMS/models"
+	"github.com/jackc/pgx/v5"
+)
+
+const stockTransactionColumns = "id, type, item_id, coalesce(from_location_id, 0), coalesce(to_location_id, 0), quantity, reason, reference, coalesce(account_id, 0), created"
+
+func scanStockTransaction(row pgx.CollectableRow) (models.StockTransaction, error) {
+	var n models.StockTransaction
+	err := row.Scan(
+		&n.ID,
+		&n.Type,
+		&n.ItemID,
+		&n.FromLocationID,
+		&n.ToLocationID,
+		&n.Quantity,
+		&n.Reason,
+		&n.Reference,
+		&n.AccountID,
+		&n.Created,
+	)
+	return n, err
+}
+
+// lockLocations locks the given locations for the rest of the transaction,
+// in ID order so two movements never deadlock.
+func lockLocations(ctx context.Context, tx pgx.Tx, ids []int64) (map[int64]models.Location, error) {
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+	if len(ids) > 0 && ids[0] == 0 {
+		ids = ids[1:]
+	}
+
+	rows, _ := tx.Query(ctx, "select "+locationColumns+" from location where id = any($1) order by id for update", ids)
+	locations, err := pgx.CollectRows(rows, scanLocation)
+	if err != nil {
+		return nil, err
+	}
+	locked := make(map[int64]models.Location, len(locations))
+	for _, loc := range locations {
+		locked[loc.ID] = loc
+	}
+	for _, id := range ids {
+		if _, ok := locked[id]; !ok {
+			return nil, invalid("locationId", "location %d does not exist", id)
+		}
+	}
+	return locked, nil
+}
+
+func stockBalance(ctx context.Context, tx pgx.Tx, itemID int64, locationID int64) (int64, error) {
+	var balance int64
+	err := tx.QueryRow(ctx, "select coalesce(sum(quantity), 0) from stock where item_id=$1 and location_id=$2", itemID, locationID).Scan(&balance)
+	return balance, err
+}
+
+// postStock validates and appends entries to the ledger. The caller's
+// transaction makes the whole batch atomic.
+func postStock(ctx context.Context, tx pgx.Tx, entries []models.StockTransaction) ([]models.StockTransaction, error) {
+	var ids []int64
+	for _, entry := range entries {
+		if err := checkEntry(entry); err != nil {
+			return nil, err
+		}
+		ids = append(ids, entry.FromLocationID, entry.ToLocationID)
+	}
+	locations, err := lockLocations(ctx, tx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	posted := make([]models.StockTransaction, 0, len(entries))
+	for _, entry := range entries {
+		if entry.FromLocationID != 0 {
+			balance, err := stockBalance(ctx, tx, entry.ItemID, entry.FromLocationID)
+			if err != nil {
+				return nil, err
+			}
+			if err := checkWithdrawal(locations[entry.FromLocationID], entry.ItemID, balance, entry.Quantity); err != nil {
+				return nil, err
+			}
+			if entry.Type != models.StockPick {
+				reserved, err := reservedStock(ctx, tx, entry.ItemID, entry.FromLocationID)
+				if err != nil {
+					return nil, err
+				}
+				if err := checkUnreserved(locations[entry.FromLocationID], entry.ItemID, balance, reserved, entry.Quantity); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if entry.ToLocationID != 0 {
+			var stored int64
+			err := tx.QueryRow(ctx, "select coalesce(sum(quantity), 0) from stock where location_id=$1", entry.ToLocationID).Scan(&stored)
+			if err != nil {
+				return nil, err
+			}
+			if err := checkPlacement(locations[entry.ToLocationID], stored, entry.Quantity); err != nil {
+				return nil, err
+			}
+		}
+
+		entry.AccountID = ActorFrom(ctx)
+		err := tx.QueryRow(ctx, `insert into stock_transaction (type, item_id, from_location_id, to_location_id, quantity, reason, reference, account_id)
+			values ($1, $2, nullif($3, 0), nullif($4, 0), $5, $6, $7, nullif($8, 0)) returning id, created`,
+			entry.Type,
+			entry.ItemID,
+			entry.FromLocationID,
+			entry.ToLocationID,
+			entry.Quantity,
+			entry.Reason,
+			entry.Reference,
+			entry.AccountID,
+		).Scan(&entry.ID, &entry.Created)
+		if err != nil {
+			return nil, checkConstraint(err)
+		}
+		posted = append(posted, entry)
+	}
+	return posted, nil
+}
+
+// inTransaction runs fn in a transaction committed when it returns nil.
+func (s *PostgresStore) inTransaction(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *PostgresStore) MoveStock(ctx context.Context, move models.StockMoveRequest) ([]models.StockTransaction, error) {
+	fmt.Printf("Attempting to move %d of item %d...\n", move.Quantity, move.ItemID)
+	var posted []models.StockTransaction
+	err := s.inTransaction(ctx, func(tx pgx.Tx) error {
+		var err error
+		posted, err = postStock(ctx, tx, []models.StockTransaction{moveEntry(move)})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Println("Successfully moved stock!")
+	return posted, nil
+}
+
+func (s *PostgresStore) TransferStock(ctx context.Context, transfer models.StockTransferRequest) ([]models.StockTransaction, error) {
+	fmt.Printf("Attempting to transfer location %d to %d...\n", transfer.FromLocationID, transfer.ToLocationID)
+	var posted []models.StockTransaction
+	err := s.inTransaction(ctx, func(tx pgx.Tx) error {
+		locations, err := lockLocations(ctx, tx, []int64{transfer.FromLocationID, transfer.ToLocationID})
+		if err != nil {
+			return err
+		}
+		if transfer.FromLocationID == 0 {
+			return invalid("fromLocationId", "location is required")
+		}
+
+		rows, _ := tx.Query(ctx, "select item_id, quantity from stock where location_id=$1 and quantity > 0", transfer.FromLocationID)
+		balances := map[int64]int64{}
+		var itemID, quantity int64
+		_, err = pgx.ForEachRow(rows, []any{&itemID, &quantity}, func() error {
+			balances[itemID] = quantity
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		entries, err := transferEntries(transfer, locations[transfer.FromLocationID], balances)
+		if err != nil {
+			return err
+		}
+		posted, err = postStock(ctx, tx, entries)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Println("Successfully transferred stock!")
+	return posted, nil
+}
+
+func (s *PostgresStore) AdjustStock(ctx context.Context, adj models.StockAdjustmentRequest) ([]models.StockTransaction, error) {
+	fmt.Printf("Attempting to adjust item %d at location %d...\n", adj.ItemID, adj.LocationID)
+	var posted []models.StockTransaction
+	err := s.inTransaction(ctx, func(tx pgx.Tx) error {
+		// Lock before reading the balance so a count cannot race a move
+		if _, err := lockLocations(ctx, tx, []int64{adj.LocationID}); err != nil {
+			return err
+		}
+		balance, err := stockBalance(ctx, tx, adj.ItemID, adj.LocationID)
+		if err != nil {
+			return err
+		}
+		entries, err := adjustEntries(adj, balance)
+		if err != nil {
+			return err
+		}
+		posted, err = postStock(ctx, tx, entries)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Println("Successfully adjusted stock!")
+	return posted, nil
+}
+
+func (s *PostgresStore) GetStockTransactions(ctx context.Context, query models.ListQuery) (models.Page[models.StockTransaction], error) {
+	fmt.Println("Attempting to get stock transactions...")
+	entries, err := listRows(ctx, s.pool, stockTransactionList, query, stockTransactionColumns, "stock_transaction", scanStockTransaction)
+	if err != nil {
+		fmt.Printf("CollectRows error: %v", err)
+		return models.Page[models.StockTransaction]{}, err
+	}
+
+	fmt.Println("Successfully retrieved stock transactions!")
+	return entries, nil
+}
+
+// stockEntries resolves the requested locations of an item and returns the
+// count corrections that bring its stock to exactly those quantities.
+func stockEntries(ctx context.Context, tx pgx.Tx, itemID int64, locations []models.LocationData) ([]models.StockTransaction, error) {
+	merged, err := mergeLocations(locations)
+	if err != nil {
+		return nil, err
+	}
+
+	want := map[int64]int64{}
+	for _, loc := range merged {
+		id := loc.LocationID
+		if id == 0 {
+			err := tx.QueryRow(ctx, "select id from location where code=upper($1)", loc.Area).Scan(&id)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, invalid("locations", "location %q does not exist", locationLabel(loc))
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		want[id] += loc.Count
+	}
+
+	rows, _ := tx.Query(ctx, "select location_id, quantity from stock where item_id=$1 and quantity <> 0", itemID)
+	have := map[int64]int64{}
+	var locationID, quantity int64
+	_, err = pgx.ForEachRow(rows, []any{&locationID, &quantity}, func() error {
+		have[locationID] = quantity
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return countEntries(itemID, have, want), nil
+}
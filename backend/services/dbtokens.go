@@ -0,0 +1,114 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/WMS/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// revokeAccountTokens raises the token generation of an account and revokes
+// its refresh tokens.
+func revokeAccountTokens(ctx context.Context, tx pgx.Tx, accountID int64) error {
+	_, err := tx.Exec(ctx, `insert into token_generation (account_id, generation) values ($1, 1)
+		on conflict (account_id) do update set generation = token_generation.generation + 1`, accountID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, "update refresh_token set revoked = now() where account_id = $1 and revoked is null", accountID)
+	return err
+}
+
+func (s *PostgresStore) AddRefreshToken(ctx context.Context, token models.RefreshToken) error {
+	_, err := s.pool.Exec(ctx,
+		"insert into refresh_token (account_id, token_hash, family, expires) values ($1, $2, $3, $4)",
+		token.AccountID, token.Hash, token.Family, token.Expires,
+	)
+	if err != nil {
+		return checkConstraint(err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) RotateRefreshToken(ctx context.Context, hash string, next models.RefreshToken) (models.RefreshToken, error) {
+	reused := false
+	err := s.inTransaction(ctx, func(tx pgx.Tx) error {
+		var current models.RefreshToken
+		err := tx.QueryRow(ctx,
+			"select account_id, family, expires, revoked is not null from refresh_token where token_hash = $1 for update",
+			hash,
+		).Scan(&current.AccountID, &current.Family, &current.Expires, &current.Revoked)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrCredentials
+		}
+		if err != nil {
+			return err
+		}
+		if !current.Expires.After(time.Now()) {
+			return ErrCredentials
+		}
+		if current.Revoked {
+			// A revoked token used again was stolen or replayed, so the
+			// family goes. This is committed before refusing the token.
+			reused = true
+			_, err := tx.Exec(ctx, "update refresh_token set revoked = now() where family = $1 and revoked is null", current.Family)
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, "update refresh_token set revoked = now() where token_hash = $1", hash); err != nil {
+			return err
+		}
+		next.AccountID = current.AccountID
+		next.Family = current.Family
+		_, err = tx.Exec(ctx,
+			"insert into refresh_token (account_id, token_hash, family, expires) values ($1, $2, $3, $4)",
+			next.AccountID, next.Hash, next.Family, next.Expires,
+		)
+		return err
+	})
+	if err != nil {
+		return models.RefreshToken{}, err
+	}
+	if reused {
+		return models.RefreshToken{}, ErrCredentials
+	}
+	return next, nil
+}
+
+func (s *PostgresStore) RevokeRefreshToken(ctx context.Context, hash string) error {
+	_, err := s.pool.Exec(ctx, `update refresh_token set revoked = now()
+		where family = (select family from refresh_token where token_hash = $1) and revoked is null`, hash)
+	return err
+}
+
+func (s *PostgresStore) RevokeAccessToken(ctx context.Context, jti string, expires time.Time) error {
+	return s.inTransaction(ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "delete from revoked_token where expires < now()"); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, "insert into revoked_token (jti, expires) values ($1, $2) on conflict do nothing", jti, expires)
+		return err
+	})
+}
+
+func (s *PostgresStore) TokenGeneration(ctx context.Context, accountID int64) (int64, error) {
+	var generation int64
+	err := s.pool.QueryRow(ctx,
+		"select coalesce((select generation from token_generation where account_id = $1), 0)",
+		accountID,
+	).Scan(&generation)
+	return generation, err
+}
+
+func (s *PostgresStore) TokenRevoked(ctx context.Context, jti string, accountID int64, generation int64) (bool, error) {
+	var revoked bool
+	err := s.pool.QueryRow(ctx, `select exists (select 1 from revoked_token where jti = $1)
+		or coalesce((select generation from token_generation where account_id = $2), 0) > $3`,
+		jti, accountID, generation,
+	).Scan(&revoked)
+	return revoked, err
+}
@@ -0,0 +1,77 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/WMS/models"
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *PostgresStore) GetTwoFactor(ctx context.Context, accountID int64) (models.TwoFactor, error) {
+	secret := models.TwoFactor{AccountID: accountID}
+	err := s.pool.QueryRow(ctx, "select secret, confirmed, last_step from account_totp where account_id = $1", accountID).
+		Scan(&secret.Secret, &secret.Confirmed, &secret.LastStep)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.TwoFactor{}, ErrNotFound
+	}
+	return secret, err
+}
+
+func (s *PostgresStore) SetTwoFactor(ctx context.Context, secret models.TwoFactor, recoveryHashes []string) error {
+	return s.inTransaction(ctx, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `insert into account_totp (account_id, secret) values ($1, $2)
+			on conflict (account_id) do update set secret = $2, confirmed = false, last_step = 0, created = now()`,
+			secret.AccountID, secret.Secret,
+		)
+		if err != nil {
+			return checkConstraint(err)
+		}
+		if _, err := tx.Exec(ctx, "delete from recovery_code where account_id = $1", secret.AccountID); err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx,
+			"insert into recovery_code (account_id, code_hash) select $1, unnest($2::varchar[])",
+			secret.AccountID, recoveryHashes,
+		)
+		return err
+	})
+}
+
+func (s *PostgresStore) UseTOTPStep(ctx context.Context, accountID int64, step int64) (bool, error) {
+	command, err := s.pool.Exec(ctx,
+		"update account_totp set confirmed = true, last_step = $2 where account_id = $1 and last_step < $2",
+		accountID, step,
+	)
+	if err != nil {
+		return false, err
+	}
+	return command.RowsAffected() == 1, nil
+}
+
+func (s *PostgresStore) UseRecoveryCode(ctx context.Context, accountID int64, hash string) (bool, error) {
+	command, err := s.pool.Exec(ctx, `update recovery_code set used = now()
+		where id = (select id from recovery_code where account_id = $1 and code_hash = $2 and used is null limit 1)`,
+		accountID, hash,
+	)
+	if err != nil {
+		return false, err
+	}
+	return command.RowsAffected() == 1, nil
+}
+
+func (s *PostgresStore) DeleteTwoFactor(ctx context.Context, accountID int64) error {
+	return s.inTransaction(ctx, func(tx pgx.Tx) error {
+		command, err := tx.Exec(ctx, "delete from account_totp where account_id = $1", accountID)
+		if err != nil {
+			return err
+		}
+		if command.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+		_, err = tx.Exec(ctx, "delete from recovery_code where account_id = $1", accountID)
+		return err
+	})
+}
@@ -0,0 +1,94 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/WMS/models"
+	"github.com/jackc/pgx/v5"
+)
+
+const uomColumns = "item_id, uom, quantity, upc"
+
+func scanUnitOfMeasure(row pgx.CollectableRow) (models.UnitOfMeasure, error) {
+	var n models.UnitOfMeasure
+	err := row.Scan(
+		&n.ItemID,
+		&n.UOM,
+		&n.Quantity,
+		&n.UPC,
+	)
+	return n, err
+}
+
+func (s *PostgresStore) GetUnitsOfMeasure(ctx context.Context, itemIDs []int64) (map[int64][]models.UnitOfMeasure, error) {
+	fmt.Printf("Attempting to get units of measure of items: %v...\n", itemIDs)
+	rows, _ := s.pool.Query(ctx, "select id, upc from item where id = any($1)", itemIDs)
+	items, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Item, error) {
+		var n models.Item
+		err := row.Scan(&n.ID, &n.UPC)
+		return n, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	rows, _ = s.pool.Query(ctx, "select "+uomColumns+" from item_uom where item_id = any($1)", itemIDs)
+	defined, err := pgx.CollectRows(rows, scanUnitOfMeasure)
+	if err != nil {
+		return nil, err
+	}
+	boxes, err := itemBoxes(ctx, s.pool, itemIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	byItem := map[int64][]models.UnitOfMeasure{}
+	for _, unit := range defined {
+		byItem[unit.ItemID] = append(byItem[unit.ItemID], unit)
+	}
+	units := map[int64][]models.UnitOfMeasure{}
+	for _, item := range items {
+		units[item.ID] = itemUnits(item, byItem[item.ID], boxes)
+	}
+
+	fmt.Println("Successfully retrieved units of measure!")
+	return units, nil
+}
+
+func (s *PostgresStore) SetUnitsOfMeasure(ctx context.Context, itemID int, units []models.UnitOfMeasure) error {
+	fmt.Printf("Attempting to set units of measure of item: %v...\n", itemID)
+	err := s.inTransaction(ctx, func(tx pgx.Tx) error {
+		var id int64
+		err := tx.QueryRow(ctx, "select id from item where id=$1 for update", itemID).Scan(&id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("item %d: %w", itemID, ErrNotFound)
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, "delete from item_uom where item_id=$1", itemID); err != nil {
+			return err
+		}
+		for _, unit := range units {
+			_, err := tx.Exec(ctx, "insert into item_uom ("+uomColumns+") values ($1, $2, $3, $4)",
+				itemID,
+				unit.UOM,
+				unit.Quantity,
+				unit.UPC,
+			)
+			if err != nil {
+				return checkConstraint(err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Successfully set units of measure of item: %v!\n", itemID)
+	return nil
+}
@@ -0,0 +1,79 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrNotFound = errors.New("not found")
+	ErrConflict = errors.New("conflict")
+	// ErrCredentials is a password that does not match the account.
+	ErrCredentials = errors.New("invalid credentials")
+)
+
+// ValidationError reports a request the store refuses because of its
+// content rather than a failure of the store itself.
+type ValidationError struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e *ValidationError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+func invalid(field string, format string, args ...any) error {
+	return &ValidationError{Field: field, Message: fmt.Sprintf(format, args...)}
+}
+
+// QueryError reports where a query in the query language goes wrong,
+// Position counting characters from 1.
+type QueryError struct {
+	Position int    `json:"position"`
+	Message  string `json:"message"`
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("position %d: %s", e.Position, e.Message)
+}
+
+// LockoutError refuses a login tried before the backoff of earlier failed
+// logins has passed.
+type LockoutError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockoutError) Error() string {
+	return fmt.Sprintf("too many failed logins, retry in %s", e.RetryAfter.Round(time.Second))
+}
+
+// constraintError marks an integrity violation, such as a duplicate key or a
+// row that is still referenced elsewhere. It matches ErrConflict.
+type constraintError struct {
+	err error
+}
+
+func (e *constraintError) Error() string { return e.err.Error() }
+
+func (e *constraintError) Unwrap() error { return e.err }
+
+func (e *constraintError) Is(target error) bool { return target == ErrConflict }
+
+// checkConstraint marks unique (23505) and foreign key (23503) violations
+// reported by postgres so callers can tell them apart from failures of the store.
+func checkConstraint(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && (pgErr.Code == "23505" || pgErr.Code == "23503") {
+		return &constraintError{err: err}
+	}
+	return err
+}
@@ -0,0 +1,89 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/WMS/barcode"
+	"github.com/WMS/models"
+)
+
+// LabelKinds are the records labels are printed for, with the template
+// used when none is chosen. Packing labels are printed by order, one for
+// each carton it was packed into.
+var LabelKinds = map[string]string{
+	"items":     "standard",
+	"boxes":     "standard",
+	"locations": "standard",
+	"packing":   "shipping",
+}
+
+func dimensionsLine(d models.Dimensions) string {
+	return fmt.Sprintf("%g x %g x %g %s", d.Length, d.Width, d.Height, d.Unit)
+}
+
+func weightLine(w models.Weight) string {
+	return fmt.Sprintf("%g %s", w.Value, w.Unit)
+}
+
+// ItemLabel labels an item with its GTIN in GS1-128.
+func ItemLabel(item models.Item) barcode.Label {
+	var lines []string
+	if item.Description != "" {
+		lines = append(lines, item.Description)
+	}
+	lines = append(lines, "UPC "+item.UPC, "Weight "+weightLine(item.Weight))
+	return barcode.Label{Title: item.Name, Lines: lines, Symbology: barcode.GS1128, Data: "(01)" + item.UPC}
+}
+
+// BoxLabel labels a box with its own GTIN, or with the GTIN of the item it
+// holds and the count when it has none. Both scan as the item.
+func BoxLabel(box models.Box) barcode.Label {
+	lines := []string{fmt.Sprintf("Box of %d", box.Count), dimensionsLine(box.Dimensions)}
+	data := fmt.Sprintf("(02)%s(37)%d", box.Item.UPC, box.Count)
+	if box.UPC != "" {
+		lines = append(lines, "UPC "+box.UPC)
+		data = "(01)" + box.UPC
+	}
+	return barcode.Label{Title: box.Item.Name, Lines: lines, Symbology: barcode.GS1128, Data: data}
+}
+
+// LocationLabel labels a location with its code in Code 128.
+func LocationLabel(loc models.Location) barcode.Label {
+	var parts []string
+	for _, part := range []struct{ name, value string }{
+		{"Zone", loc.Zone}, {"Aisle", loc.Aisle}, {"Rack", loc.Rack}, {"Shelf", loc.Shelf}, {"Bin", loc.Bin},
+	} {
+		if part.value != "" {
+			parts = append(parts, part.name+" "+part.value)
+		}
+	}
+	lines := []string{strings.Join(parts, "  "), loc.Type}
+	return barcode.Label{Title: loc.Code, Lines: lines, Symbology: barcode.Code128, Data: loc.Code}
+}
+
+// CartonLabels labels each carton an order was packed into with its SSCC
+// in GS1-128 and where it ships to.
+func CartonLabels(order models.Order, packing models.Packing) ([]barcode.Label, error) {
+	if len(packing.Cartons) == 0 {
+		return nil, invalid("ids", "order %d has not been packed", order.ID)
+	}
+	labels := make([]barcode.Label, 0, len(packing.Cartons))
+	for i, carton := range packing.Cartons {
+		if carton.SSCC == "" {
+			return nil, invalid("ids", "carton %d of order %d has no license plate", carton.ID, order.ID)
+		}
+		lines := []string{"Ship to:", strings.TrimSpace(order.Customer.Firstname + " " + order.Customer.Lastname)}
+		lines = append(lines, strings.Split(order.Address, "\n")...)
+		lines = append(lines, fmt.Sprintf("%s carton, %s", carton.Carton, weightLine(carton.Weight)))
+		labels = append(labels, barcode.Label{
+			Title:     fmt.Sprintf("Order %d carton %d of %d", order.ID, i+1, len(packing.Cartons)),
+			Lines:     lines,
+			Symbology: barcode.GS1128,
+			Data:      "(00)" + carton.SSCC,
+		})
+	}
+	return labels, nil
+}
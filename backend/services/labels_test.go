@@ -0,0 +1,54 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"testing"
+
+	"github.com/WMS/barcode"
+	"github.com/WMS/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLabels(t *testing.T) {
+	item := models.Item{ID: 1, UPC: "00036000291452", Name: "beans", Weight: models.Weight{Value: 1, Unit: models.UnitPound}}
+	label := ItemLabel(item)
+	assert.Equal(t, "(01)00036000291452", label.Data)
+	assert.Equal(t, []string{"UPC 00036000291452", "Weight 1 lb"}, label.Lines)
+
+	box := models.Box{Item: item, Count: 12, Dimensions: models.Dimensions{Length: 2, Width: 2, Height: 4, Unit: models.UnitInch}}
+	assert.Equal(t, "(02)00036000291452(37)12", BoxLabel(box).Data, "Boxes without a UPC carry the item and count")
+	box.UPC = "10036000291459"
+	assert.Equal(t, "(01)10036000291459", BoxLabel(box).Data)
+	assert.Equal(t, "2 x 2 x 4 in", BoxLabel(box).Lines[1])
+
+	for _, label := range []barcode.Label{ItemLabel(item), BoxLabel(box)} {
+		scan, err := ParseScan(label.Data)
+		assert.Nil(t, err)
+		assert.NotEmpty(t, scan.GTIN, "Labels scan back as products")
+	}
+
+	loc := LocationLabel(models.Location{Code: "A-01-02", Zone: "A", Aisle: "01", Type: models.LocationPickFace})
+	assert.Equal(t, barcode.Code128, loc.Symbology)
+	assert.Equal(t, []string{"Zone A  Aisle 01", models.LocationPickFace}, loc.Lines)
+
+	order := models.Order{ID: 4, Customer: models.Account{Firstname: "J.", Lastname: "Doe"}, Address: "1 Main St.\nSpringfield"}
+	_, err := CartonLabels(order, models.Packing{OrderID: 4})
+	var validation *ValidationError
+	assert.ErrorAs(t, err, &validation, "Suggestions are not labelled")
+
+	packing := models.Packing{OrderID: 4, Cartons: []models.PackedCarton{
+		{ID: 1, SSCC: "000000000000000017", Carton: "S", Weight: models.Weight{Value: 2, Unit: models.UnitPound}},
+		{ID: 2, SSCC: "000000000000000024", Carton: "M", Weight: models.Weight{Value: 3, Unit: models.UnitPound}},
+	}}
+	labels, err := CartonLabels(order, packing)
+	assert.Nil(t, err)
+	assert.Len(t, labels, 2)
+	assert.Equal(t, "Order 4 carton 2 of 2", labels[1].Title)
+	assert.Equal(t, []string{"Ship to:", "J. Doe", "1 Main St.", "Springfield", "M carton, 3 lb"}, labels[1].Lines)
+	assert.Equal(t, "(00)000000000000000024", labels[1].Data)
+
+	packing.Cartons[1].SSCC = ""
+	_, err = CartonLabels(order, packing)
+	assert.ErrorAs(t, err, &validation, "Cartons packed before license plates cannot be labelled")
+}
@@ -0,0 +1,515 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/WMS/barcode"
+	"github.com/WMS/models"
+	"github.com/jackc/pgx/v5"
+)
+
+type fieldKind int
+
+const (
+	textField fieldKind = iota
+	intField
+	boolField
+	dateField
+)
+
+// listField is a field a collection is filtered and sorted by. column is
+// the SQL expression it is read from. Fields of related rows, that a row
+// has several of, put their condition inside within, %s standing for it,
+// and cannot be sorted by. value reads the field from a row of the memory
+// store and returns every value of fields a row has several of. gtin
+// fields hold barcodes stored as GTIN-14, values that are valid barcodes
+// are padded to match.
+type listField[T any] struct {
+	kind   fieldKind
+	column string
+	within string
+	enum   []string
+	gtin   bool
+	value  func(T) any
+}
+
+// listSpec lists the fields of a collection and the order its rows are
+// listed in when no sort is given. name is the collection in the query
+// language, the resource it is read from.
+type listSpec[T any] struct {
+	name   string
+	fields map[string]listField[T]
+	sort   []string
+}
+
+func (spec listSpec[T]) sortable(name string) bool {
+	field, ok := spec.fields[name]
+	return ok && field.within == ""
+}
+
+// check validates a query against the fields of a collection, fills in
+// the default limit and sort and returns the condition rows must meet. A
+// query in the query language takes the place of the limit, offset and
+// sort. Values of a filter may also be separated by commas. The ID breaks
+// ties between rows so that pages do not overlap.
+func (spec listSpec[T]) check(query *models.ListQuery) (condition[T], error) {
+	var conditions allOf[T]
+	if query.Query != "" {
+		parsed, err := parseQuery(query.Query)
+		if err != nil {
+			return nil, err
+		}
+		where, sort, err := bindQuery(spec, &parsed)
+		if err != nil {
+			return nil, err
+		}
+		if where != nil {
+			conditions = append(conditions, where)
+		}
+		query.Sort, query.Limit, query.Offset = sort, parsed.limit, parsed.offset
+	}
+
+	switch {
+	case query.Limit == 0:
+		query.Limit = models.DefaultPageLimit
+	case query.Limit < 0 || query.Limit > models.MaxPageLimit:
+		return nil, invalid("limit", "limit must be 1 to %d", models.MaxPageLimit)
+	}
+	if query.Offset < 0 {
+		return nil, invalid("offset", "offset cannot be negative")
+	}
+
+	if len(query.Sort) == 0 {
+		query.Sort = spec.sort
+	}
+	for _, sort := range query.Sort {
+		if !spec.sortable(strings.TrimPrefix(sort, "-")) {
+			return nil, invalid("sort", "cannot sort by %q", sort)
+		}
+	}
+	if !slices.Contains(query.Sort, "id") && !slices.Contains(query.Sort, "-id") {
+		query.Sort = append(slices.Clip(query.Sort), "id")
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(query.Filters)) {
+		field, ok := spec.fields[name]
+		if !ok {
+			return nil, invalid(name, "cannot filter by %q", name)
+		}
+		filter := comparison[T]{field: field, op: opIn}
+		for _, param := range query.Filters[name] {
+			for text := range strings.SplitSeq(param, ",") {
+				value, err := field.parse(strings.TrimSpace(text))
+				if err != nil {
+					return nil, invalid(name, "%s", err.Error())
+				}
+				filter.values = append(filter.values, value)
+			}
+		}
+		conditions = append(conditions, filter)
+	}
+	switch len(conditions) {
+	case 0:
+		return nil, nil
+	case 1:
+		return conditions[0], nil
+	}
+	return conditions, nil
+}
+
+func (field listField[T]) parse(text string) (any, error) {
+	switch field.kind {
+	case intField:
+		value, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a whole number", text)
+		}
+		return value, nil
+	case boolField:
+		value, err := strconv.ParseBool(text)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not true or false", text)
+		}
+		return value, nil
+	case dateField:
+		value, err := time.Parse(time.DateOnly, text)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a date like 2026-01-31", text)
+		}
+		return value, nil
+	}
+	if field.gtin {
+		if code, err := barcode.Normalize(text); err == nil {
+			return code, nil
+		}
+	}
+	if field.enum != nil {
+		text = strings.ToUpper(text)
+		if !slices.Contains(field.enum, text) {
+			return nil, fmt.Errorf("%q is not one of %s", text, strings.Join(field.enum, ", "))
+		}
+	}
+	return text, nil
+}
+
+// newPage wraps the rows of a page in its envelope.
+func newPage[T any](rows []T, total int64, query models.ListQuery) models.Page[T] {
+	if rows == nil {
+		rows = []T{}
+	}
+	info := models.PageInfo{Limit: query.Limit, Offset: query.Offset, Total: total, Sort: query.Sort}
+	if next := query.Offset + query.Limit; int64(next) < total {
+		info.Next = next
+	}
+	return models.Page[T]{Data: rows, Page: info}
+}
+
+// values returns every value a row has for a field.
+func values(value any) []any {
+	switch value := value.(type) {
+	case []string:
+		all := make([]any, len(value))
+		for i, v := range value {
+			all[i] = v
+		}
+		return all
+	case []int64:
+		all := make([]any, len(value))
+		for i, v := range value {
+			all[i] = v
+		}
+		return all
+	}
+	return []any{value}
+}
+
+// sameValue compares text without case and times by their date.
+func sameValue(value any, want any) bool {
+	return compareValues(normalized(value), normalized(want)) == 0
+}
+
+func normalized(value any) any {
+	switch value := value.(type) {
+	case string:
+		return strings.ToLower(value)
+	case time.Time:
+		year, month, day := value.UTC().Date()
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	return value
+}
+
+func compareValues(a any, b any) int {
+	switch a := a.(type) {
+	case string:
+		return strings.Compare(a, b.(string))
+	case int64:
+		return cmp.Compare(a, b.(int64))
+	case bool:
+		switch {
+		case a == b.(bool):
+			return 0
+		case a:
+			return 1
+		}
+		return -1
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	}
+	return 0
+}
+
+// listPage filters, sorts and pages the rows of a collection of the
+// memory store.
+func listPage[T any](rows []T, spec listSpec[T], query models.ListQuery) (models.Page[T], error) {
+	where, err := spec.check(&query)
+	if err != nil {
+		return models.Page[T]{}, err
+	}
+	if where != nil {
+		rows = slices.DeleteFunc(rows, func(row T) bool {
+			return !where.matches(row)
+		})
+	}
+	slices.SortStableFunc(rows, func(a, b T) int {
+		for _, sort := range query.Sort {
+			name := strings.TrimPrefix(sort, "-")
+			value := spec.fields[name].value
+			c := compareValues(value(a), value(b))
+			if name != sort {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+	start := min(query.Offset, len(rows))
+	end := min(start+query.Limit, len(rows))
+	return newPage(slices.Clone(rows[start:end]), int64(len(rows)), query), nil
+}
+
+// listRows pages through a collection in the database. from is the FROM
+// clause its rows are selected from, giving the aliases the columns of the
+// fields of the collection use.
+func listRows[T any](ctx context.Context, q pgxQuerier, spec listSpec[T], query models.ListQuery, columns string, from string, scan pgx.RowToFunc[T]) (models.Page[T], error) {
+	where, err := spec.check(&query)
+	if err != nil {
+		return models.Page[T]{}, err
+	}
+
+	var args []any
+	clause := ""
+	if where != nil {
+		clause = " where " + where.sql(&args)
+	}
+
+	rows, _ := q.Query(ctx, "select count(*) from "+from+clause, args...)
+	total, err := pgx.CollectExactlyOneRow(rows, pgx.RowTo[int64])
+	if err != nil {
+		return models.Page[T]{}, err
+	}
+
+	order := make([]string, 0, len(query.Sort))
+	for _, sort := range query.Sort {
+		name := strings.TrimPrefix(sort, "-")
+		column := spec.fields[name].column
+		if name != sort {
+			column += " desc"
+		}
+		order = append(order, column)
+	}
+	args = append(args, query.Limit, query.Offset)
+	rows, _ = q.Query(ctx, fmt.Sprintf("select %s from %s%s order by %s limit $%d offset $%d",
+		columns, from, clause, strings.Join(order, ", "), len(args)-1, len(args)), args...)
+	data, err := pgx.CollectRows(rows, scan)
+	if err != nil {
+		return models.Page[T]{}, err
+	}
+	return newPage(data, total, query), nil
+}
+
+var accountList = listSpec[models.Account]{
+	name: "accounts",
+	sort: []string{"id"},
+	fields: map[string]listField[models.Account]{
+		"id":        {kind: intField, column: "id", value: func(a models.Account) any { return a.ID }},
+		"username":  {column: "username", value: func(a models.Account) any { return a.Username }},
+		"firstname": {column: "firstname", value: func(a models.Account) any { return a.Firstname }},
+		"lastname":  {column: "lastname", value: func(a models.Account) any { return a.Lastname }},
+		"email":     {column: "coalesce(email, '')", value: func(a models.Account) any { return a.Email }},
+		"role":      {column: "role", enum: models.Roles, value: func(a models.Account) any { return a.Role.Value }},
+		"active":    {kind: boolField, column: "active", value: func(a models.Account) any { return a.Active }},
+		"created":   {kind: dateField, column: "created", value: func(a models.Account) any { return a.Created }},
+	},
+}
+
+var itemList = listSpec[models.Item]{
+	name: "items",
+	sort: []string{"id"},
+	fields: map[string]listField[models.Item]{
+		"id":   {kind: intField, column: "id", value: func(i models.Item) any { return i.ID }},
+		"upc":  {column: "upc", gtin: true, value: func(i models.Item) any { return i.UPC }},
+		"name": {column: "name", value: func(i models.Item) any { return i.Name }},
+	},
+}
+
+var itemInfoList = listSpec[models.ItemInfo]{
+	name: "items",
+	sort: []string{"name"},
+	fields: map[string]listField[models.ItemInfo]{
+		"id":   {kind: intField, column: "id", value: func(i models.ItemInfo) any { return i.ID }},
+		"name": {column: "name", value: func(i models.ItemInfo) any { return i.Name }},
+	},
+}
+
+var boxList = listSpec[models.Box]{
+	name: "boxes",
+	sort: []string{"id"},
+	fields: map[string]listField[models.Box]{
+		"id":     {kind: intField, column: "b.id", value: func(b models.Box) any { return b.ID }},
+		"upc":    {column: "b.upc", gtin: true, value: func(b models.Box) any { return b.UPC }},
+		"itemId": {kind: intField, column: "b.item_id", value: func(b models.Box) any { return b.Item.ID }},
+		"name":   {column: "i.name", value: func(b models.Box) any { return b.Item.Name }},
+		"count":  {kind: intField, column: "b.count", value: func(b models.Box) any { return b.Count }},
+	},
+}
+
+var inventoryArea = listField[models.Inventory]{
+	column: "l.code",
+	within: "exists (select 1 from stock s join location l on l.id = s.location_id where s.item_id = i.id and s.quantity > 0 and %s)",
+	value: func(v models.Inventory) any {
+		areas := make([]string, 0, len(v.Locations))
+		for _, loc := range v.Locations {
+			areas = append(areas, loc.Area)
+		}
+		return areas
+	},
+}
+
+// inventoryList filters inventory by the locations it is stocked at, by
+// their code or ID.
+var inventoryList = listSpec[models.Inventory]{
+	name: "inventory",
+	sort: []string{"id"},
+	fields: map[string]listField[models.Inventory]{
+		"id":     {kind: intField, column: "v.id", value: func(v models.Inventory) any { return v.ID }},
+		"itemId": {kind: intField, column: "i.id", value: func(v models.Inventory) any { return v.Item.ID }},
+		"upc":    {column: "i.upc", gtin: true, value: func(v models.Inventory) any { return v.Item.UPC }},
+		"name":   {column: "i.name", value: func(v models.Inventory) any { return v.Item.Name }},
+		"total": {
+			kind:   intField,
+			column: "(select coalesce(sum(s.quantity), 0) from stock s where s.item_id = i.id and s.quantity > 0)",
+			value:  func(v models.Inventory) any { return v.TotalCount },
+		},
+		"area":     inventoryArea,
+		"location": inventoryArea,
+		"locationId": {
+			kind:   intField,
+			column: "s.location_id",
+			within: "exists (select 1 from stock s where s.item_id = i.id and s.quantity > 0 and %s)",
+			value: func(v models.Inventory) any {
+				ids := make([]int64, 0, len(v.Locations))
+				for _, loc := range v.Locations {
+					ids = append(ids, loc.LocationID)
+				}
+				return ids
+			},
+		},
+	},
+}
+
+var orderList = listSpec[models.Order]{
+	name: "orders",
+	sort: []string{"id"},
+	fields: map[string]listField[models.Order]{
+		"id":          {kind: intField, column: "id", value: func(o models.Order) any { return o.ID }},
+		"status":      {column: "status", enum: models.OrderStatuses, value: func(o models.Order) any { return o.Status }},
+		"customerId":  {kind: intField, column: "(customer->>'id')::bigint", value: func(o models.Order) any { return o.Customer.ID }},
+		"timeOrdered": {kind: dateField, column: "timeOrdered", value: func(o models.Order) any { return o.TimeOrdered }},
+		"address":     {column: "address", value: func(o models.Order) any { return o.Address }},
+		"customer.username": {
+			column: "coalesce(customer->>'username', '')",
+			value:  func(o models.Order) any { return o.Customer.Username },
+		},
+		"customer.firstname": {
+			column: "coalesce(customer->>'firstname', '')",
+			value:  func(o models.Order) any { return o.Customer.Firstname },
+		},
+		"customer.lastname": {
+			column: "coalesce(customer->>'lastname', '')",
+			value:  func(o models.Order) any { return o.Customer.Lastname },
+		},
+	},
+}
+
+var shipmentList = listSpec[models.Shipment]{
+	name: "shipments",
+	sort: []string{"id"},
+	fields: map[string]listField[models.Shipment]{
+		"id": {kind: intField, column: "id", value: func(s models.Shipment) any { return s.ID }},
+		"status": {
+			column: "status",
+			enum:   []string{models.ShipmentExpected, models.ShipmentArrived, models.ShipmentReceived},
+			value:  func(s models.Shipment) any { return s.Status },
+		},
+		"distributor": {column: "distributor", value: func(s models.Shipment) any { return s.Distributor }},
+		"supplierId":  {kind: intField, column: "(supplier->>'id')::bigint", value: func(s models.Shipment) any { return s.Supplier.ID }},
+		"eta":         {kind: dateField, column: "eta", value: func(s models.Shipment) any { return s.ETA }},
+	},
+}
+
+var locationList = listSpec[models.Location]{
+	name: "locations",
+	sort: []string{"code"},
+	fields: map[string]listField[models.Location]{
+		"id":     {kind: intField, column: "id", value: func(l models.Location) any { return l.ID }},
+		"code":   {column: "code", value: func(l models.Location) any { return l.Code }},
+		"zone":   {column: "zone", value: func(l models.Location) any { return l.Zone }},
+		"aisle":  {column: "aisle", value: func(l models.Location) any { return l.Aisle }},
+		"rack":   {column: "rack", value: func(l models.Location) any { return l.Rack }},
+		"shelf":  {column: "shelf", value: func(l models.Location) any { return l.Shelf }},
+		"bin":    {column: "bin", value: func(l models.Location) any { return l.Bin }},
+		"type":   {column: "type", enum: models.LocationTypes, value: func(l models.Location) any { return l.Type }},
+		"active": {kind: boolField, column: "active", value: func(l models.Location) any { return l.Active }},
+	},
+}
+
+var apiKeyList = listSpec[models.APIKey]{
+	name: "apikeys",
+	sort: []string{"id"},
+	fields: map[string]listField[models.APIKey]{
+		"id":        {kind: intField, column: "id", value: func(k models.APIKey) any { return k.ID }},
+		"name":      {column: "name", value: func(k models.APIKey) any { return k.Name }},
+		"prefix":    {column: "prefix", value: func(k models.APIKey) any { return k.Prefix }},
+		"createdBy": {kind: intField, column: "coalesce(created_by, 0)", value: func(k models.APIKey) any { return k.CreatedBy }},
+		"created":   {kind: dateField, column: "created", value: func(k models.APIKey) any { return k.Created }},
+		"expires":   {kind: dateField, column: "expires", value: func(k models.APIKey) any { return k.Expires }},
+	},
+}
+
+var cartonList = listSpec[models.Carton]{
+	name: "cartons",
+	sort: []string{"id"},
+	fields: map[string]listField[models.Carton]{
+		"id":     {kind: intField, column: "id", value: func(c models.Carton) any { return c.ID }},
+		"name":   {column: "name", value: func(c models.Carton) any { return c.Name }},
+		"active": {kind: boolField, column: "active", value: func(c models.Carton) any { return c.Active }},
+	},
+}
+
+var waveList = listSpec[models.Wave]{
+	name: "waves",
+	sort: []string{"id"},
+	fields: map[string]listField[models.Wave]{
+		"id":      {kind: intField, column: "id", value: func(w models.Wave) any { return w.ID }},
+		"status":  {column: "status", enum: []string{models.WaveOpen, models.WaveCompleted}, value: func(w models.Wave) any { return w.Status }},
+		"zone":    {column: "zone", value: func(w models.Wave) any { return w.Zone }},
+		"created": {kind: dateField, column: "created", value: func(w models.Wave) any { return w.Created }},
+	},
+}
+
+var pickTaskList = listSpec[models.PickTask]{
+	name: "picks",
+	sort: []string{"waveId", "sequence"},
+	fields: map[string]listField[models.PickTask]{
+		"id":         {kind: intField, column: "t.id", value: func(t models.PickTask) any { return t.ID }},
+		"waveId":     {kind: intField, column: "t.wave_id", value: func(t models.PickTask) any { return t.WaveID }},
+		"orderId":    {kind: intField, column: "t.order_id", value: func(t models.PickTask) any { return t.OrderID }},
+		"itemId":     {kind: intField, column: "t.item_id", value: func(t models.PickTask) any { return t.ItemID }},
+		"locationId": {kind: intField, column: "t.location_id", value: func(t models.PickTask) any { return t.LocationID }},
+		"status":     {column: "t.status", enum: models.PickStatuses, value: func(t models.PickTask) any { return t.Status }},
+		"sequence":   {kind: intField, column: "t.sequence", value: func(t models.PickTask) any { return int64(t.Sequence) }},
+		"accountId":  {kind: intField, column: "coalesce(t.account_id, 0)", value: func(t models.PickTask) any { return t.AccountID }},
+	},
+}
+
+// stockTransactionList filters the ledger by location on either side of a
+// transaction.
+var stockTransactionList = listSpec[models.StockTransaction]{
+	name: "stock",
+	sort: []string{"id"},
+	fields: map[string]listField[models.StockTransaction]{
+		"id":     {kind: intField, column: "id", value: func(t models.StockTransaction) any { return t.ID }},
+		"type":   {column: "type", enum: models.StockTransactionTypes, value: func(t models.StockTransaction) any { return t.Type }},
+		"itemId": {kind: intField, column: "item_id", value: func(t models.StockTransaction) any { return t.ItemID }},
+		"locationId": {
+			kind:   intField,
+			column: "loc.id",
+			within: "exists (select 1 from unnest(array[from_location_id, to_location_id]) loc(id) where %s)",
+			value:  func(t models.StockTransaction) any { return []int64{t.FromLocationID, t.ToLocationID} },
+		},
+		"reference": {column: "reference", value: func(t models.StockTransaction) any { return t.Reference }},
+		"created":   {kind: dateField, column: "created", value: func(t models.StockTransaction) any { return t.Created }},
+	},
+}
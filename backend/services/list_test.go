@@ -0,0 +1,128 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/WMS/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListQueryCheck(t *testing.T) {
+	query := models.ListQuery{Sort: []string{"-zone"}, Filters: map[string][]string{
+		"type":   {"dock,pick_face"},
+		"active": {"true"},
+	}}
+	where, err := locationList.check(&query)
+	assert.Nil(t, err)
+	assert.Equal(t, models.DefaultPageLimit, query.Limit)
+	assert.Equal(t, []string{"-zone", "id"}, query.Sort, "ID breaks ties")
+	var args []any
+	assert.Equal(t, "(active = any($1) and lower(type) = any($2))", where.sql(&args))
+	assert.Equal(t, []any{[]bool{true}, []string{"dock", "pick_face"}}, args)
+
+	for field, query := range map[string]models.ListQuery{
+		"limit":  {Limit: models.MaxPageLimit + 1},
+		"offset": {Offset: -1},
+		"sort":   {Sort: []string{"capacity"}},
+		"id":     {Filters: map[string][]string{"id": {"one"}}},
+		"type":   {Filters: map[string][]string{"type": {"attic"}}},
+	} {
+		_, err := locationList.check(&query)
+		var validation *ValidationError
+		if assert.ErrorAs(t, err, &validation, field) {
+			assert.Equal(t, field, validation.Field)
+		}
+	}
+
+	_, err = inventoryList.check(&models.ListQuery{Sort: []string{"area"}})
+	assert.NotNil(t, err, "Fields of related rows cannot be sorted by")
+
+	query = models.ListQuery{Filters: map[string][]string{"upc": {"036000291452,0360"}}}
+	items, err := itemList.check(&query)
+	assert.Nil(t, err)
+	args = nil
+	assert.Equal(t, "lower(upc) = any($1)", items.sql(&args))
+	assert.Equal(t, []any{[]string{"00036000291452", "0360"}}, args, "Printed UPCs match their GTIN-14, other text is kept")
+
+	store := NewMemoryStore()
+	assert.Nil(t, store.AddItem(context.Background(), models.Item{ID: 1, UPC: "00036000291452", Name: "beans"}))
+	page, err := store.GetItems(context.Background(), models.ListQuery{Query: "items where upc = 036000291452"})
+	assert.Nil(t, err)
+	assert.Len(t, page.Data, 1)
+}
+
+func TestMemoryStoreLists(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	created := time.Date(2026, 3, 14, 23, 30, 0, 0, time.UTC)
+	for i, name := range []string{"Cy", "ada", "Bo", "Al", "bea"} {
+		role := models.RoleEmployee
+		if i%2 == 0 {
+			role = models.RoleCustomer
+		}
+		account := models.Account{ID: int64(i + 1), Username: name, Role: models.Role{Value: role}, Active: i != 4, Created: created.AddDate(0, 0, i)}
+		assert.Nil(t, store.AddAccount(ctx, account))
+	}
+	names := func(page models.Page[models.Account]) []string {
+		var usernames []string
+		for _, account := range page.Data {
+			usernames = append(usernames, account.Username)
+		}
+		return usernames
+	}
+
+	page, err := store.GetAccounts(ctx, models.ListQuery{Limit: 2, Offset: 1, Sort: []string{"username"}})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Bo", "Cy"}, names(page))
+	assert.Equal(t, models.PageInfo{Limit: 2, Offset: 1, Total: 5, Next: 3, Sort: []string{"username", "id"}}, page.Page)
+
+	page, err = store.GetAccounts(ctx, models.ListQuery{Offset: 4})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"bea"}, names(page))
+	assert.Zero(t, page.Page.Next, "The last page has no next page")
+
+	page, err = store.GetAccounts(ctx, models.ListQuery{Sort: []string{"-role", "-id"}, Filters: map[string][]string{
+		"role":   {"customer", "employee"},
+		"active": {"true"},
+	}})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Al", "ada", "Bo", "Cy"}, names(page))
+	assert.Equal(t, int64(4), page.Page.Total)
+
+	page, err = store.GetAccounts(ctx, models.ListQuery{Filters: map[string][]string{"username": {"BEA"}, "created": {"2026-03-18"}}})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"bea"}, names(page), "Text matches without case, times by date")
+
+	page, err = store.GetAccounts(ctx, models.ListQuery{Offset: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, []models.Account{}, page.Data)
+	assert.Equal(t, int64(5), page.Page.Total)
+
+	_, err = store.GetAccounts(ctx, models.ListQuery{Filters: map[string][]string{"password": {"demo"}}})
+	var validation *ValidationError
+	assert.ErrorAs(t, err, &validation)
+
+	// Inventory matches any of the locations it is stocked at
+	assert.Nil(t, store.AddItem(ctx, models.Item{ID: 1, Name: "beans"}))
+	assert.Nil(t, store.AddItem(ctx, models.Item{ID: 2, Name: "rice"}))
+	assert.Nil(t, store.AddLocation(ctx, models.Location{ID: 1, Code: "A-1", Active: true}))
+	assert.Nil(t, store.AddLocation(ctx, models.Location{ID: 2, Code: "B-1", Active: true}))
+	assert.Nil(t, store.AddInventory(ctx, models.Inventory{ID: 1, Item: models.Item{ID: 1}, Locations: []models.LocationData{{Area: "A-1", Count: 5}, {Area: "B-1", Count: 1}}}))
+	assert.Nil(t, store.AddInventory(ctx, models.Inventory{ID: 2, Item: models.Item{ID: 2}, Locations: []models.LocationData{{Area: "B-1", Count: 9}}}))
+
+	inventory, err := store.GetAllInventory(ctx, models.ListQuery{Filters: map[string][]string{"area": {"a-1"}}})
+	assert.Nil(t, err)
+	if assert.Len(t, inventory.Data, 1) {
+		assert.Equal(t, "beans", inventory.Data[0].Item.Name)
+	}
+	inventory, err = store.GetAllInventory(ctx, models.ListQuery{Sort: []string{"-total"}, Filters: map[string][]string{"locationId": {"2"}}})
+	assert.Nil(t, err)
+	if assert.Len(t, inventory.Data, 2) {
+		assert.Equal(t, []int64{9, 6}, []int64{inventory.Data[0].TotalCount, inventory.Data[1].TotalCount})
+	}
+}
@@ -0,0 +1,118 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/WMS/models"
+)
+
+const locationSeparator = "-"
+
+func validLocationPart(part string) bool {
+	if part == "" || len(part) > 16 {
+		return false
+	}
+	for _, r := range part {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseLocationCode splits a hierarchical code such as "A-21-03-B-02" into
+// its zone, aisle, rack, shelf and bin parts. Trailing levels may be left
+// out, so "A" names a whole zone and "A-21" an aisle.
+func ParseLocationCode(code string) ([5]string, error) {
+	var parts [5]string
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return parts, invalid("code", "location code is required")
+	}
+	split := strings.Split(code, locationSeparator)
+	if len(split) > len(parts) {
+		return parts, invalid("code", "location code %q has more than %d levels", code, len(parts))
+	}
+	for i, part := range split {
+		if !validLocationPart(part) {
+			return parts, invalid("code", "location code %q has an invalid level %q", code, part)
+		}
+		parts[i] = part
+	}
+	return parts, nil
+}
+
+func locationCode(parts [5]string) string {
+	end := len(parts)
+	for end > 0 && parts[end-1] == "" {
+		end--
+	}
+	return strings.Join(parts[:end], locationSeparator)
+}
+
+// NormalizeLocation fills in the code from the zone/aisle/rack/shelf/bin
+// parts, or the parts from the code, and validates the rest of the record.
+func NormalizeLocation(loc *models.Location) error {
+	parts := [5]string{loc.Zone, loc.Aisle, loc.Rack, loc.Shelf, loc.Bin}
+	for i := range parts {
+		parts[i] = strings.ToUpper(strings.TrimSpace(parts[i]))
+	}
+
+	if parts[0] != "" {
+		code := locationCode(parts)
+		if _, err := ParseLocationCode(code); err != nil {
+			return err
+		}
+		if loc.Code != "" && !strings.EqualFold(strings.TrimSpace(loc.Code), code) {
+			return invalid("code", "code %q does not match its levels %q", loc.Code, code)
+		}
+	} else {
+		parsed, err := ParseLocationCode(loc.Code)
+		if err != nil {
+			return err
+		}
+		parts = parsed
+	}
+
+	loc.Code = locationCode(parts)
+	loc.Zone, loc.Aisle, loc.Rack, loc.Shelf, loc.Bin = parts[0], parts[1], parts[2], parts[3], parts[4]
+
+	loc.Type = strings.ToUpper(strings.TrimSpace(loc.Type))
+	if loc.Type == "" {
+		loc.Type = models.LocationReserve
+	}
+	if !slices.Contains(models.LocationTypes, loc.Type) {
+		return invalid("type", "unknown location type %q", loc.Type)
+	}
+	if loc.Capacity < 0 {
+		return invalid("capacity", "capacity cannot be negative")
+	}
+	if loc.MaxWeight < 0 {
+		return invalid("maxWeight", "max weight cannot be negative")
+	}
+	return nil
+}
+
+// locationLabel names a requested location in error messages.
+func locationLabel(loc models.LocationData) string {
+	if loc.Area != "" {
+		return loc.Area
+	}
+	return strconv.FormatInt(loc.LocationID, 10)
+}
+
+// checkPlacement validates that quantity more units may be stored at loc,
+// which already holds stored units.
+func checkPlacement(loc models.Location, stored int64, quantity int64) error {
+	if !loc.Active {
+		return invalid("locations", "location %s is not active", loc.Code)
+	}
+	if loc.Capacity > 0 && stored+quantity > loc.Capacity {
+		return invalid("locations", "location %s would hold %d units, capacity is %d", loc.Code, stored+quantity, loc.Capacity)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"testing"
+
+	"github.com/WMS/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLocationCode(t *testing.T) {
+	parts, err := ParseLocationCode(" a-21-03-b-02 ")
+	assert.Nil(t, err)
+	assert.Equal(t, [5]string{"A", "21", "03", "B", "02"}, parts)
+
+	parts, err = ParseLocationCode("DOCK1")
+	assert.Nil(t, err)
+	assert.Equal(t, [5]string{"DOCK1"}, parts)
+
+	for _, code := range []string{"", "A--1", "A-1-2-3-4-5", "A 1", "A-ÄB"} {
+		_, err := ParseLocationCode(code)
+		var validation *ValidationError
+		assert.ErrorAs(t, err, &validation, code)
+	}
+}
+
+func TestNormalizeLocation(t *testing.T) {
+	loc := models.Location{Zone: "a", Aisle: "21", Rack: "3"}
+	assert.Nil(t, NormalizeLocation(&loc))
+	assert.Equal(t, "A-21-3", loc.Code)
+	assert.Equal(t, models.LocationReserve, loc.Type)
+
+	loc = models.Location{Code: "b-4", Type: "dock"}
+	assert.Nil(t, NormalizeLocation(&loc))
+	assert.Equal(t, "B", loc.Zone)
+	assert.Equal(t, "4", loc.Aisle)
+	assert.Equal(t, models.LocationDock, loc.Type)
+
+	assert.NotNil(t, NormalizeLocation(&models.Location{Code: "A-1", Zone: "B"}))
+	assert.NotNil(t, NormalizeLocation(&models.Location{Code: "A", Type: "FLOOR"}))
+	assert.NotNil(t, NormalizeLocation(&models.Location{Code: "A", Capacity: -1}))
+}
@@ -0,0 +1,144 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/WMS/models"
+)
+
+// LoginPolicy holds back failed logins of one kind of key. Each failure
+// doubles the wait before the next try, from BaseDelay up to MaxDelay, and
+// MaxFailures of them lock the key out for Lockout. Failures older than
+// Lockout are forgotten.
+type LoginPolicy struct {
+	MaxFailures int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Lockout     time.Duration
+}
+
+var (
+	UsernamePolicy = LoginPolicy{MaxFailures: 5, BaseDelay: time.Second, MaxDelay: time.Minute, Lockout: 15 * time.Minute}
+	// AddressPolicy is looser, as accounts behind one proxy share an address.
+	AddressPolicy = LoginPolicy{MaxFailures: 50, BaseDelay: 100 * time.Millisecond, MaxDelay: 10 * time.Second, Lockout: 15 * time.Minute}
+)
+
+// retryAt returns when a key may try to log in again.
+func (p LoginPolicy) retryAt(attempts models.LoginAttempts) time.Time {
+	if attempts.Failures == 0 {
+		return time.Time{}
+	}
+	if attempts.Failures >= p.MaxFailures {
+		return attempts.LastFailure.Add(p.Lockout)
+	}
+	delay := p.MaxDelay
+	if attempts.Failures < 32 {
+		delay = min(p.BaseDelay<<(attempts.Failures-1), p.MaxDelay)
+	}
+	return attempts.LastFailure.Add(delay)
+}
+
+func usernameKey(username string) string {
+	return "user:" + strings.ToLower(username)
+}
+
+func addressKey(address string) string {
+	return "ip:" + address
+}
+
+// loginKey is a username or address that failed logins count against.
+type loginKey struct {
+	key    string
+	policy LoginPolicy
+}
+
+func loginKeys(username string, address string) []loginKey {
+	return []loginKey{
+		{usernameKey(username), UsernamePolicy},
+		{addressKey(address), AddressPolicy},
+	}
+}
+
+// countAttempt counts an attempt against keys as a failure before its
+// credentials are checked, so attempts racing each other see those counted
+// before them. One made while any of keys is held back is taken back and
+// fails with a LockoutError.
+func countAttempt(ctx context.Context, repo LoginAttemptRepository, now time.Time, keys []loginKey) error {
+	for i, k := range keys {
+		before, err := repo.RecordLoginFailure(ctx, k.key, now, now.Add(-k.policy.Lockout))
+		if err != nil {
+			return err
+		}
+		if retry := k.policy.retryAt(before); retry.After(now) {
+			if err := forgiveAttempt(ctx, repo, keys[:i+1]); err != nil {
+				return err
+			}
+			return &LockoutError{RetryAfter: retry.Sub(now)}
+		}
+	}
+	return nil
+}
+
+// forgiveAttempt takes back an attempt counted against keys that did not
+// fail.
+func forgiveAttempt(ctx context.Context, repo LoginAttemptRepository, keys []loginKey) error {
+	for _, k := range keys {
+		if err := repo.ForgiveLoginFailure(ctx, k.key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AttemptLogin validates a login from a client address, counting failures
+// against both the username and the address. Either being held back fails
+// with a LockoutError before the password is checked.
+func AttemptLogin(ctx context.Context, repo Repository, username string, password string, address string) (*models.Account, error) {
+	keys := loginKeys(username, address)
+	if err := countAttempt(ctx, repo, time.Now(), keys); err != nil {
+		return nil, err
+	}
+
+	acc, err := ValidateLogin(ctx, repo, username, password)
+	if err != nil {
+		return nil, err
+	}
+	// Failures of the address stay, or one account could clear them
+	// between guesses at others. Those of the username stay until a second
+	// factor is entered too, so the password cannot reset guesses at codes.
+	required, err := secondFactorRequired(ctx, repo, *acc)
+	if err != nil {
+		return nil, err
+	}
+	forgiven := keys
+	if !required {
+		if err := repo.ClearLoginFailures(ctx, usernameKey(username)); err != nil {
+			return nil, err
+		}
+		forgiven = []loginKey{{addressKey(address), AddressPolicy}}
+	}
+	if err := forgiveAttempt(ctx, repo, forgiven); err != nil {
+		return nil, err
+	}
+	return acc, nil
+}
+
+// UnlockAccount forgets the failed logins of an account's username.
+func UnlockAccount(ctx context.Context, repo Repository, id int) error {
+	fmt.Printf("Attempting to unlock account: %v...\n", id)
+	acc, err := repo.GetAccount(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := repo.ClearLoginFailures(ctx, usernameKey(acc.Username)); err != nil {
+		return err
+	}
+
+	fmt.Printf("Successfully unlocked account: %v!\n", id)
+	return nil
+}
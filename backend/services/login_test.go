@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/WMS/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginPolicyRetryAt(t *testing.T) {
+	policy := LoginPolicy{MaxFailures: 5, BaseDelay: time.Second, MaxDelay: 5 * time.Second, Lockout: time.Hour}
+	last := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.True(t, policy.retryAt(models.LoginAttempts{}).IsZero())
+	for failures, delay := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second} {
+		assert.Equal(t, last.Add(delay), policy.retryAt(models.LoginAttempts{Failures: failures, LastFailure: last}), failures)
+	}
+	assert.Equal(t, last.Add(time.Hour), policy.retryAt(models.LoginAttempts{Failures: 5, LastFailure: last}))
+
+	policy.MaxFailures = 100
+	assert.Equal(t, last.Add(5*time.Second), policy.retryAt(models.LoginAttempts{Failures: 70, LastFailure: last}), "Large counts do not overflow the delay")
+}
+
+func TestAttemptLogin(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	assert.Nil(t, store.AddAccount(ctx, models.Account{ID: 1, Username: "jo", Password: "secret", Active: true}))
+
+	_, err := AttemptLogin(ctx, store, "ghost", "secret", "10.0.0.1")
+	assert.ErrorIs(t, err, ErrCredentials)
+	_, err = AttemptLogin(ctx, store, "ghost", "secret", "10.0.0.2")
+	var lockout *LockoutError
+	assert.ErrorAs(t, err, &lockout, "Unknown usernames are held back like known ones")
+
+	_, err = AttemptLogin(ctx, store, "jo", "wrong", "10.0.0.3")
+	assert.ErrorIs(t, err, ErrCredentials)
+	_, err = AttemptLogin(ctx, store, "JO", "secret", "10.0.0.4")
+	if assert.ErrorAs(t, err, &lockout, "The right password waits out the backoff") {
+		assert.LessOrEqual(t, lockout.RetryAfter, time.Second)
+	}
+
+	// Wind the last failures back past the backoff
+	store.loginAttempts[usernameKey("jo")] = models.LoginAttempts{Failures: 1, LastFailure: time.Now().Add(-time.Minute)}
+	store.loginAttempts[addressKey("10.0.0.3")] = models.LoginAttempts{Failures: 1, LastFailure: time.Now().Add(-time.Minute)}
+	acc, err := AttemptLogin(ctx, store, "jo", "secret", "10.0.0.3")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), acc.ID)
+	assert.NotContains(t, store.loginAttempts, usernameKey("jo"), "Logging in clears the failures of the username")
+	assert.Contains(t, store.loginAttempts, addressKey("10.0.0.3"), "but not those of the address")
+
+	store.loginAttempts[usernameKey("jo")] = models.LoginAttempts{Failures: UsernamePolicy.MaxFailures, LastFailure: time.Now()}
+	_, err = AttemptLogin(ctx, store, "jo", "secret", "10.0.0.5")
+	if assert.ErrorAs(t, err, &lockout) {
+		assert.Greater(t, lockout.RetryAfter, 14*time.Minute)
+	}
+	assert.Nil(t, UnlockAccount(ctx, store, 1))
+	_, err = AttemptLogin(ctx, store, "jo", "secret", "10.0.0.5")
+	assert.Nil(t, err)
+
+	assert.NotNil(t, UnlockAccount(ctx, store, 2))
+}
+
+func TestMemoryStoreLoginAttempts(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	now := time.Now()
+
+	before, err := store.RecordLoginFailure(ctx, "user:jo", now.Add(-time.Hour), now.Add(-2*time.Hour))
+	assert.Nil(t, err)
+	assert.Zero(t, before.Failures)
+	before, err = store.RecordLoginFailure(ctx, "user:jo", now, now.Add(-2*time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, models.LoginAttempts{Failures: 1, LastFailure: now.Add(-time.Hour)}, before, "The failures before are returned")
+	assert.Nil(t, store.ForgiveLoginFailure(ctx, "user:jo"))
+	attempts, err := store.LoginAttempts(ctx, "user:jo")
+	assert.Nil(t, err)
+	assert.Equal(t, models.LoginAttempts{Failures: 1, LastFailure: now}, attempts)
+
+	_, err = store.RecordLoginFailure(ctx, "ip:10.0.0.1", now.Add(time.Minute), now.Add(time.Second))
+	assert.Nil(t, err)
+	attempts, err = store.LoginAttempts(ctx, "user:jo")
+	assert.Nil(t, err)
+	assert.Zero(t, attempts.Failures, "Old failures of every key are forgotten")
+
+	assert.Nil(t, store.ClearLoginFailures(ctx, "ip:10.0.0.1"))
+	attempts, err = store.LoginAttempts(ctx, "ip:10.0.0.1")
+	assert.Nil(t, err)
+	assert.Zero(t, attempts.Failures)
+}
+
+func TestAttemptLoginConcurrent(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	assert.Nil(t, store.AddAccount(ctx, models.Account{ID: 1, Username: "jo", Password: "secret", Active: true}))
+
+	var wg sync.WaitGroup
+	var refused, locked atomic.Int64
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := AttemptLogin(ctx, store, "jo", "wrong", fmt.Sprint("10.0.0.", i))
+			var lockout *LockoutError
+			switch {
+			case errors.Is(err, ErrCredentials):
+				refused.Add(1)
+			case errors.As(err, &lockout):
+				locked.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(1), refused.Load(), "Racing guesses wait out the backoff of the first")
+	assert.Equal(t, int64(19), locked.Load())
+	attempts, err := store.LoginAttempts(ctx, usernameKey("jo"))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, attempts.Failures, "Guesses held back are not counted")
+}
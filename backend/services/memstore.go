@@ -0,0 +1,1870 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/WMS/models"
+)
+
+// MemoryStore is a thread-safe, non-persistent Repository used for tests,
+// demos and local development without a database. Errors mirror the ones
+// returned by PostgresStore so controllers behave the same on either store.
+type MemoryStore struct {
+	mu        sync.RWMutex
+	accounts  map[int64]models.Account
+	items     map[int64]models.Item
+	boxes     map[int64]models.Box
+	inventory map[int64]models.Inventory
+	locations map[int64]models.Location
+	ledger    []models.StockTransaction
+	stock     map[stockKey]int64 // balances derived from ledger
+	orders    map[int64]models.Order
+	shipments map[int64]models.Shipment
+
+	reservations  []models.Reservation
+	reservationID int64 // last reservation ID handed out
+	waves         map[int64]models.Wave
+	picks         map[int64]models.PickTask
+	cartons       map[int64]models.Carton
+	packed        map[int64]models.PackedCarton
+	uoms          map[int64][]models.UnitOfMeasure // defined pack levels by item
+
+	refreshTokens map[string]models.RefreshToken // by hash
+	revokedTokens map[string]time.Time           // expiry of revoked access tokens by jti
+	generations   map[int64]int64                // token generation by account
+	loginAttempts map[string]models.LoginAttempts
+	twoFactors    map[int64]models.TwoFactor
+	recoveryCodes map[int64][]string // unused recovery code hashes by account
+	apiKeys       map[int64]models.APIKey
+}
+
+type stockKey struct {
+	itemID     int64
+	locationID int64
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		accounts:  make(map[int64]models.Account),
+		items:     make(map[int64]models.Item),
+		boxes:     make(map[int64]models.Box),
+		inventory: make(map[int64]models.Inventory),
+		locations: make(map[int64]models.Location),
+		stock:     make(map[stockKey]int64),
+		orders:    make(map[int64]models.Order),
+		shipments: make(map[int64]models.Shipment),
+		waves:     make(map[int64]models.Wave),
+		picks:     make(map[int64]models.PickTask),
+		cartons:   make(map[int64]models.Carton),
+		packed:    make(map[int64]models.PackedCarton),
+		uoms:      make(map[int64][]models.UnitOfMeasure),
+
+		refreshTokens: make(map[string]models.RefreshToken),
+		revokedTokens: make(map[string]time.Time),
+		generations:   make(map[int64]int64),
+		loginAttempts: make(map[string]models.LoginAttempts),
+		twoFactors:    make(map[int64]models.TwoFactor),
+		recoveryCodes: make(map[int64][]string),
+		apiKeys:       make(map[int64]models.APIKey),
+	}
+}
+
+func (m *MemoryStore) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+func (m *MemoryStore) Close() {}
+
+// nextID returns id unchanged when it is set, otherwise the next free key
+// the way a SERIAL column would.
+func nextID[T any](table map[int64]T, id int64) int64 {
+	if id != 0 {
+		return id
+	}
+	var last int64
+	for k := range table {
+		last = max(last, k)
+	}
+	return last + 1
+}
+
+// reserveID picks the primary key for a new row and rejects duplicates.
+func reserveID[T any](table map[int64]T, id int64, name string) (int64, error) {
+	id = nextID(table, id)
+	if _, ok := table[id]; ok {
+		return 0, &constraintError{fmt.Errorf("duplicate key value violates unique constraint \"%s_pkey\"", name)}
+	}
+	return id, nil
+}
+
+func sortedRows[T any](table map[int64]T) []T {
+	keys := slices.Sorted(maps.Keys(table))
+	rows := make([]T, 0, len(keys))
+	for _, k := range keys {
+		rows = append(rows, table[k])
+	}
+	return rows
+}
+
+func cloneGroups(groups []models.ItemGroup) []models.ItemGroup {
+	return slices.Clone(groups)
+}
+
+func foreignKeyError(table string, column string) error {
+	return &constraintError{fmt.Errorf("insert or update on table \"%s\" violates foreign key constraint \"%s_%s_fkey\"", table, table, column)}
+}
+
+// itemRef checks the item a box or inventory row points at exists and
+// returns the reference to store. Callers must hold m.mu.
+func (m *MemoryStore) itemRef(item models.Item, table string) (models.Item, error) {
+	if _, ok := m.items[item.ID]; !ok {
+		return models.Item{}, foreignKeyError(table, "item_id")
+	}
+	return models.Item{ID: item.ID}, nil
+}
+
+// joinItem returns the live item row for a reference, without the image
+// when listing. Callers must hold m.mu.
+func (m *MemoryStore) joinItem(ref models.Item, withImage bool) models.Item {
+	item := m.items[ref.ID]
+	if !withImage {
+		item.Image = models.ImageData{}
+	}
+	return item
+}
+
+//  Accounts  //
+
+func (m *MemoryStore) AddAccount(ctx context.Context, account models.Account) error {
+	hashPass, err := hashPassword(account.Password)
+	if err != nil {
+		return errors.New("failed to hash password")
+	}
+	account.Password = hashPass
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id, err := reserveID(m.accounts, account.ID, "account")
+	if err != nil {
+		return err
+	}
+	account.ID = id
+	m.accounts[id] = account
+	return nil
+}
+
+func (m *MemoryStore) GetAccounts(ctx context.Context, query models.ListQuery) (models.Page[models.Account], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return listPage(sortedRows(m.accounts), accountList, query)
+}
+
+func (m *MemoryStore) GetAccount(ctx context.Context, id int) (models.Account, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	account, ok := m.accounts[int64(id)]
+	if !ok {
+		return models.Account{}, fmt.Errorf("account %d: %w", id, ErrNotFound)
+	}
+	return account, nil
+}
+
+func (m *MemoryStore) GetAccountsByUsername(ctx context.Context, username string) ([]models.Account, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var accounts []models.Account
+	for _, account := range sortedRows(m.accounts) {
+		if account.Username == username {
+			accounts = append(accounts, account)
+		}
+	}
+	return accounts, nil
+}
+
+func (m *MemoryStore) UpdateAccount(ctx context.Context, id int, newData models.Account) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	current, ok := m.accounts[int64(id)]
+	if !ok {
+		return fmt.Errorf("account %d: %w", id, ErrNotFound)
+	}
+	newData.ID = current.ID
+	newData.Created = current.Created
+	newData.Password = current.Password
+	m.accounts[int64(id)] = newData
+	if tokensLapse(current, newData) {
+		m.revokeAccountTokens(current.ID)
+	}
+	return nil
+}
+
+func (m *MemoryStore) SetPassword(ctx context.Context, id int, password string) error {
+	hashPass, err := hashPassword(password)
+	if err != nil {
+		return errors.New("failed to hash password")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	account, ok := m.accounts[int64(id)]
+	if !ok {
+		return ErrNotFound
+	}
+	account.Password = hashPass
+	m.accounts[int64(id)] = account
+	m.revokeAccountTokens(account.ID)
+	return nil
+}
+
+func (m *MemoryStore) DeleteAccount(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.accounts[int64(id)]; !ok {
+		return fmt.Errorf("account %d: %w", id, ErrNotFound)
+	}
+	m.revokeAccountTokens(int64(id))
+	delete(m.accounts, int64(id))
+	delete(m.twoFactors, int64(id))
+	delete(m.recoveryCodes, int64(id))
+	return nil
+}
+
+//  Tokens  //
+
+// revokeAccountTokens raises the token generation of an account and
+// revokes its refresh tokens. Callers must hold m.mu.
+func (m *MemoryStore) revokeAccountTokens(accountID int64) {
+	m.generations[accountID]++
+	for hash, token := range m.refreshTokens {
+		if token.AccountID == accountID {
+			token.Revoked = true
+			m.refreshTokens[hash] = token
+		}
+	}
+}
+
+// revokeFamily revokes every refresh token of a family. Callers must hold
+// m.mu.
+func (m *MemoryStore) revokeFamily(family string) {
+	for hash, token := range m.refreshTokens {
+		if token.Family == family {
+			token.Revoked = true
+			m.refreshTokens[hash] = token
+		}
+	}
+}
+
+func (m *MemoryStore) AddRefreshToken(ctx context.Context, token models.RefreshToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.accounts[token.AccountID]; !ok {
+		return ErrNotFound
+	}
+	m.refreshTokens[token.Hash] = token
+	return nil
+}
+
+func (m *MemoryStore) RotateRefreshToken(ctx context.Context, hash string, next models.RefreshToken) (models.RefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	current, ok := m.refreshTokens[hash]
+	if !ok || !current.Expires.After(time.Now()) {
+		return models.RefreshToken{}, ErrCredentials
+	}
+	if current.Revoked {
+		m.revokeFamily(current.Family)
+		return models.RefreshToken{}, ErrCredentials
+	}
+	current.Revoked = true
+	m.refreshTokens[hash] = current
+	next.AccountID = current.AccountID
+	next.Family = current.Family
+	m.refreshTokens[next.Hash] = next
+	return next, nil
+}
+
+func (m *MemoryStore) RevokeRefreshToken(ctx context.Context, hash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if token, ok := m.refreshTokens[hash]; ok {
+		m.revokeFamily(token.Family)
+	}
+	return nil
+}
+
+func (m *MemoryStore) RevokeAccessToken(ctx context.Context, jti string, expires time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	maps.DeleteFunc(m.revokedTokens, func(_ string, expires time.Time) bool {
+		return expires.Before(now)
+	})
+	m.revokedTokens[jti] = expires
+	return nil
+}
+
+func (m *MemoryStore) TokenGeneration(ctx context.Context, accountID int64) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.generations[accountID], nil
+}
+
+func (m *MemoryStore) TokenRevoked(ctx context.Context, jti string, accountID int64, generation int64) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, revoked := m.revokedTokens[jti]
+	return revoked || generation < m.generations[accountID], nil
+}
+
+//  Login Attempts  //
+
+func (m *MemoryStore) LoginAttempts(ctx context.Context, key string) (models.LoginAttempts, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.loginAttempts[key], nil
+}
+
+func (m *MemoryStore) RecordLoginFailure(ctx context.Context, key string, at time.Time, forgetBefore time.Time) (models.LoginAttempts, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	maps.DeleteFunc(m.loginAttempts, func(_ string, attempts models.LoginAttempts) bool {
+		return attempts.LastFailure.Before(forgetBefore)
+	})
+	before := m.loginAttempts[key]
+	m.loginAttempts[key] = models.LoginAttempts{Failures: before.Failures + 1, LastFailure: at}
+	return before, nil
+}
+
+func (m *MemoryStore) ForgiveLoginFailure(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	attempts, ok := m.loginAttempts[key]
+	switch {
+	case !ok:
+	case attempts.Failures <= 1:
+		delete(m.loginAttempts, key)
+	default:
+		attempts.Failures--
+		m.loginAttempts[key] = attempts
+	}
+	return nil
+}
+
+func (m *MemoryStore) ClearLoginFailures(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.loginAttempts, key)
+	return nil
+}
+
+//  Two-Factor  //
+
+func (m *MemoryStore) GetTwoFactor(ctx context.Context, accountID int64) (models.TwoFactor, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	secret, ok := m.twoFactors[accountID]
+	if !ok {
+		return models.TwoFactor{}, ErrNotFound
+	}
+	return secret, nil
+}
+
+func (m *MemoryStore) SetTwoFactor(ctx context.Context, secret models.TwoFactor, recoveryHashes []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.accounts[secret.AccountID]; !ok {
+		return ErrNotFound
+	}
+	m.twoFactors[secret.AccountID] = models.TwoFactor{AccountID: secret.AccountID, Secret: secret.Secret}
+	m.recoveryCodes[secret.AccountID] = slices.Clone(recoveryHashes)
+	return nil
+}
+
+func (m *MemoryStore) UseTOTPStep(ctx context.Context, accountID int64, step int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	secret, ok := m.twoFactors[accountID]
+	if !ok || secret.LastStep >= step {
+		return false, nil
+	}
+	secret.Confirmed = true
+	secret.LastStep = step
+	m.twoFactors[accountID] = secret
+	return true, nil
+}
+
+func (m *MemoryStore) UseRecoveryCode(ctx context.Context, accountID int64, hash string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	codes := m.recoveryCodes[accountID]
+	i := slices.Index(codes, hash)
+	if i < 0 {
+		return false, nil
+	}
+	m.recoveryCodes[accountID] = slices.Delete(codes, i, i+1)
+	return true, nil
+}
+
+func (m *MemoryStore) DeleteTwoFactor(ctx context.Context, accountID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.twoFactors[accountID]; !ok {
+		return ErrNotFound
+	}
+	delete(m.twoFactors, accountID)
+	delete(m.recoveryCodes, accountID)
+	return nil
+}
+
+//  API Keys  //
+
+func (m *MemoryStore) CreateAPIKey(ctx context.Context, key models.APIKey) (models.APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id, err := reserveID(m.apiKeys, key.ID, "api_key")
+	if err != nil {
+		return models.APIKey{}, err
+	}
+	for _, other := range m.apiKeys {
+		if other.Prefix == key.Prefix {
+			return models.APIKey{}, &constraintError{errors.New("duplicate key value violates unique constraint \"api_key_prefix_key\"")}
+		}
+	}
+	key.ID = id
+	key.Scopes = slices.Clone(key.Scopes)
+	m.apiKeys[id] = key
+	return key, nil
+}
+
+func (m *MemoryStore) GetAPIKeys(ctx context.Context, query models.ListQuery) (models.Page[models.APIKey], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	keys := sortedRows(m.apiKeys)
+	for i := range keys {
+		keys[i].Scopes = slices.Clone(keys[i].Scopes)
+	}
+	return listPage(keys, apiKeyList, query)
+}
+
+func (m *MemoryStore) GetAPIKeyByPrefix(ctx context.Context, prefix string) (models.APIKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, key := range m.apiKeys {
+		if key.Prefix == prefix {
+			key.Scopes = slices.Clone(key.Scopes)
+			return key, nil
+		}
+	}
+	return models.APIKey{}, ErrNotFound
+}
+
+func (m *MemoryStore) TouchAPIKey(ctx context.Context, id int64, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key, ok := m.apiKeys[id]
+	if !ok {
+		return ErrNotFound
+	}
+	key.LastUsed = &at
+	m.apiKeys[id] = key
+	return nil
+}
+
+func (m *MemoryStore) DeleteAPIKey(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.apiKeys[int64(id)]; !ok {
+		return ErrNotFound
+	}
+	delete(m.apiKeys, int64(id))
+	return nil
+}
+
+//  Items  //
+
+// upcTaken reports whether another item already has a UPC. Callers must
+// hold m.mu.
+func (m *MemoryStore) upcTaken(upc string, id int64) bool {
+	if upc == "" {
+		return false
+	}
+	for _, item := range m.items {
+		if item.UPC == upc && item.ID != id {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MemoryStore) AddItem(ctx context.Context, item models.Item) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id, err := reserveID(m.items, item.ID, "item")
+	if err != nil {
+		return err
+	}
+	if m.upcTaken(item.UPC, id) {
+		return &constraintError{errors.New("duplicate key value violates unique constraint \"item_upc_key\"")}
+	}
+	item.ID = id
+	m.items[id] = item
+	return nil
+}
+
+func (m *MemoryStore) GetItems(ctx context.Context, query models.ListQuery) (models.Page[models.Item], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	items := sortedRows(m.items)
+	// Listings omit the image, matching the postgres column list
+	for i := range items {
+		items[i].Image = models.ImageData{}
+	}
+	return listPage(items, itemList, query)
+}
+
+func (m *MemoryStore) GetItemsList(ctx context.Context, query models.ListQuery) (models.Page[models.ItemInfo], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	items := sortedRows(m.items)
+	list := make([]models.ItemInfo, 0, len(items))
+	for _, item := range items {
+		list = append(list, models.ItemInfo{ID: item.ID, Name: item.Name})
+	}
+	return listPage(list, itemInfoList, query)
+}
+
+func (m *MemoryStore) GetItem(ctx context.Context, id int) (models.Item, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	item, ok := m.items[int64(id)]
+	if !ok {
+		return models.Item{}, fmt.Errorf("item %d: %w", id, ErrNotFound)
+	}
+	return item, nil
+}
+
+func (m *MemoryStore) UpdateItem(ctx context.Context, id int, newData models.Item) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.items[int64(id)]; !ok {
+		return fmt.Errorf("item %d: %w", id, ErrNotFound)
+	}
+	if m.upcTaken(newData.UPC, int64(id)) {
+		return &constraintError{errors.New("duplicate key value violates unique constraint \"item_upc_key\"")}
+	}
+	newData.ID = int64(id)
+	m.items[int64(id)] = newData
+	return nil
+}
+
+func (m *MemoryStore) DeleteItem(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.items[int64(id)]; !ok {
+		return fmt.Errorf("item %d: %w", id, ErrNotFound)
+	}
+	for _, entry := range m.ledger {
+		if entry.ItemID == int64(id) {
+			return &constraintError{errors.New("update or delete on table \"item\" violates foreign key constraint \"stock_transaction_item_id_fkey\" on table \"stock_transaction\"")}
+		}
+	}
+	for _, inv := range m.inventory {
+		if inv.Item.ID == int64(id) {
+			return &constraintError{errors.New("update or delete on table \"item\" violates foreign key constraint \"inventory_item_id_fkey\" on table \"inventory\"")}
+		}
+	}
+	for boxID, box := range m.boxes {
+		if box.Item.ID == int64(id) {
+			delete(m.boxes, boxID)
+		}
+	}
+	delete(m.uoms, int64(id))
+	delete(m.items, int64(id))
+	return nil
+}
+
+func (m *MemoryStore) GetUnitsOfMeasure(ctx context.Context, itemIDs []int64) (map[int64][]models.UnitOfMeasure, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	boxes := sortedRows(m.boxes)
+	units := map[int64][]models.UnitOfMeasure{}
+	for _, id := range itemIDs {
+		if item, ok := m.items[id]; ok {
+			units[id] = itemUnits(item, m.uoms[id], boxes)
+		}
+	}
+	return units, nil
+}
+
+func (m *MemoryStore) SetUnitsOfMeasure(ctx context.Context, itemID int, units []models.UnitOfMeasure) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.items[int64(itemID)]; !ok {
+		return fmt.Errorf("item %d: %w", itemID, ErrNotFound)
+	}
+	m.uoms[int64(itemID)] = slices.Clone(units)
+	return nil
+}
+
+//  Boxes  //
+
+func (m *MemoryStore) AddBox(ctx context.Context, box models.Box) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id, err := reserveID(m.boxes, box.ID, "box")
+	if err != nil {
+		return err
+	}
+	box.Item, err = m.itemRef(box.Item, "box")
+	if err != nil {
+		return err
+	}
+	box.ID = id
+	m.boxes[id] = box
+	return nil
+}
+
+func (m *MemoryStore) GetBoxes(ctx context.Context, query models.ListQuery) (models.Page[models.Box], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	boxes := sortedRows(m.boxes)
+	for i := range boxes {
+		boxes[i].Item = m.joinItem(boxes[i].Item, false)
+	}
+	return listPage(boxes, boxList, query)
+}
+
+func (m *MemoryStore) GetBox(ctx context.Context, id int) (models.Box, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	box, ok := m.boxes[int64(id)]
+	if !ok {
+		return models.Box{}, fmt.Errorf("box %d: %w", id, ErrNotFound)
+	}
+	box.Item = m.joinItem(box.Item, true)
+	return box, nil
+}
+
+func (m *MemoryStore) UpdateBox(ctx context.Context, id int, newData models.Box) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.boxes[int64(id)]; !ok {
+		return fmt.Errorf("box %d: %w", id, ErrNotFound)
+	}
+	item, err := m.itemRef(newData.Item, "box")
+	if err != nil {
+		return err
+	}
+	newData.ID = int64(id)
+	newData.Item = item
+	m.boxes[int64(id)] = newData
+	return nil
+}
+
+func (m *MemoryStore) DeleteBox(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.boxes[int64(id)]; !ok {
+		return fmt.Errorf("box %d: %w", id, ErrNotFound)
+	}
+	delete(m.boxes, int64(id))
+	return nil
+}
+
+//  Locations  //
+
+// locationByCode returns the location with the given code. Callers must
+// hold m.mu.
+func (m *MemoryStore) locationByCode(code string) (models.Location, bool) {
+	for _, loc := range m.locations {
+		if loc.Code == strings.ToUpper(code) {
+			return loc, true
+		}
+	}
+	return models.Location{}, false
+}
+
+func (m *MemoryStore) AddLocation(ctx context.Context, loc models.Location) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id, err := reserveID(m.locations, loc.ID, "location")
+	if err != nil {
+		return err
+	}
+	if _, ok := m.locationByCode(loc.Code); ok {
+		return &constraintError{errors.New("duplicate key value violates unique constraint \"location_code_key\"")}
+	}
+	loc.ID = id
+	m.locations[id] = loc
+	return nil
+}
+
+func (m *MemoryStore) GetLocations(ctx context.Context, query models.ListQuery) (models.Page[models.Location], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return listPage(sortedRows(m.locations), locationList, query)
+}
+
+func (m *MemoryStore) GetLocation(ctx context.Context, id int) (models.Location, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	loc, ok := m.locations[int64(id)]
+	if !ok {
+		return models.Location{}, fmt.Errorf("location %d: %w", id, ErrNotFound)
+	}
+	return loc, nil
+}
+
+func (m *MemoryStore) GetLocationStock(ctx context.Context, id int) ([]models.StockLevel, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	loc, ok := m.locations[int64(id)]
+	if !ok {
+		return nil, fmt.Errorf("location %d: %w", id, ErrNotFound)
+	}
+	levels := []models.StockLevel{}
+	for key, quantity := range m.stock {
+		if key.locationID != loc.ID || quantity <= 0 {
+			continue
+		}
+		levels = append(levels, models.StockLevel{
+			Item:         models.ItemInfo{ID: key.itemID, Name: m.items[key.itemID].Name},
+			LocationID:   loc.ID,
+			LocationCode: loc.Code,
+			Quantity:     quantity,
+		})
+	}
+	slices.SortFunc(levels, func(a, b models.StockLevel) int {
+		return cmp.Or(strings.Compare(a.Item.Name, b.Item.Name), cmp.Compare(a.Item.ID, b.Item.ID))
+	})
+	return levels, nil
+}
+
+func (m *MemoryStore) UpdateLocation(ctx context.Context, id int, newData models.Location) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.locations[int64(id)]; !ok {
+		return fmt.Errorf("location %d: %w", id, ErrNotFound)
+	}
+	if other, ok := m.locationByCode(newData.Code); ok && other.ID != int64(id) {
+		return &constraintError{errors.New("duplicate key value violates unique constraint \"location_code_key\"")}
+	}
+	newData.ID = int64(id)
+	m.locations[int64(id)] = newData
+	return nil
+}
+
+func (m *MemoryStore) DeleteLocation(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.locations[int64(id)]; !ok {
+		return fmt.Errorf("location %d: %w", id, ErrNotFound)
+	}
+	for _, entry := range m.ledger {
+		if entry.FromLocationID == int64(id) || entry.ToLocationID == int64(id) {
+			return &constraintError{errors.New("update or delete on table \"location\" violates foreign key constraint on table \"stock_transaction\"")}
+		}
+	}
+	delete(m.locations, int64(id))
+	return nil
+}
+
+// stockOf fills in the per-location quantities of an inventory row. Callers
+// must hold m.mu.
+func (m *MemoryStore) stockOf(inv models.Inventory) models.Inventory {
+	var locations []models.LocationData
+	for key, quantity := range m.stock {
+		if key.itemID != inv.Item.ID || quantity <= 0 {
+			continue
+		}
+		loc := m.locations[key.locationID]
+		locations = append(locations, models.LocationData{LocationID: loc.ID, Area: loc.Code, Count: quantity})
+	}
+	slices.SortFunc(locations, func(a, b models.LocationData) int {
+		return strings.Compare(a.Area, b.Area)
+	})
+	return withStock(inv, locations)
+}
+
+// hasInventory reports whether an inventory row other than id already
+// tracks the item. Callers must hold m.mu.
+func (m *MemoryStore) hasInventory(itemID int64, id int64) bool {
+	for _, inv := range m.inventory {
+		if inv.Item.ID == itemID && inv.ID != id {
+			return true
+		}
+	}
+	return false
+}
+
+//  Inventory  //
+
+func (m *MemoryStore) AddInventory(ctx context.Context, inv models.Inventory) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id, err := reserveID(m.inventory, inv.ID, "inventory")
+	if err != nil {
+		return err
+	}
+	item, err := m.itemRef(inv.Item, "inventory")
+	if err != nil {
+		return err
+	}
+	if m.hasInventory(item.ID, 0) {
+		return &constraintError{errors.New("duplicate key value violates unique constraint \"inventory_item_id_key\"")}
+	}
+	entries, err := m.stockEntries(item.ID, inv.Locations)
+	if err != nil {
+		return err
+	}
+	if _, err := m.post(ctx, entries); err != nil {
+		return err
+	}
+	m.inventory[id] = models.Inventory{ID: id, Item: item}
+	return nil
+}
+
+func (m *MemoryStore) GetAllInventory(ctx context.Context, query models.ListQuery) (models.Page[models.Inventory], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	inventory := sortedRows(m.inventory)
+	for i := range inventory {
+		inventory[i] = m.stockOf(inventory[i])
+		inventory[i].Item = m.joinItem(inventory[i].Item, false)
+	}
+	return listPage(inventory, inventoryList, query)
+}
+
+func (m *MemoryStore) GetInventory(ctx context.Context, id int) (models.Inventory, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	inv, ok := m.inventory[int64(id)]
+	if !ok {
+		return models.Inventory{}, fmt.Errorf("inventory %d: %w", id, ErrNotFound)
+	}
+	inv = m.stockOf(inv)
+	inv.Item = m.joinItem(inv.Item, true)
+	return inv, nil
+}
+
+func (m *MemoryStore) GetAvailability(ctx context.Context, id int) (models.Availability, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	inv, ok := m.inventory[int64(id)]
+	if !ok {
+		return models.Availability{}, fmt.Errorf("inventory %d: %w", id, ErrNotFound)
+	}
+	return availability(inv.Item.ID, m.availabilityOf(inv.Item.ID)), nil
+}
+
+func (m *MemoryStore) UpdateInventory(ctx context.Context, id int, newData models.Inventory) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	current, ok := m.inventory[int64(id)]
+	if !ok {
+		return fmt.Errorf("inventory %d: %w", id, ErrNotFound)
+	}
+	item, err := m.itemRef(newData.Item, "inventory")
+	if err != nil {
+		return err
+	}
+	if m.hasInventory(item.ID, int64(id)) {
+		return &constraintError{errors.New("duplicate key value violates unique constraint \"inventory_item_id_key\"")}
+	}
+	// Stock of a replaced item is counted out before the new item's is set
+	var entries []models.StockTransaction
+	if current.Item.ID != item.ID {
+		entries, _ = m.stockEntries(current.Item.ID, nil)
+	}
+	newEntries, err := m.stockEntries(item.ID, newData.Locations)
+	if err != nil {
+		return err
+	}
+	if _, err := m.post(ctx, append(entries, newEntries...)); err != nil {
+		return err
+	}
+	// The postgres statement also rewrites the id column
+	delete(m.inventory, int64(id))
+	newID := cmp.Or(newData.ID, int64(id))
+	m.inventory[newID] = models.Inventory{ID: newID, Item: item}
+	return nil
+}
+
+func (m *MemoryStore) DeleteInventory(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	inv, ok := m.inventory[int64(id)]
+	if !ok {
+		return fmt.Errorf("inventory %d: %w", id, ErrNotFound)
+	}
+	entries, _ := m.stockEntries(inv.Item.ID, nil)
+	if _, err := m.post(ctx, entries); err != nil {
+		return err
+	}
+	delete(m.inventory, int64(id))
+	return nil
+}
+
+//  Stock  //
+
+// post validates entries and appends them to the ledger, updating the
+// balances. Either every entry is posted or none. Callers must hold m.mu.
+func (m *MemoryStore) post(ctx context.Context, entries []models.StockTransaction) ([]models.StockTransaction, error) {
+	balances := maps.Clone(m.stock)
+	posted := make([]models.StockTransaction, 0, len(entries))
+	for _, entry := range entries {
+		if err := checkEntry(entry); err != nil {
+			return nil, err
+		}
+		from, ok := m.locations[entry.FromLocationID]
+		if !ok && entry.FromLocationID != 0 {
+			return nil, invalid("locationId", "location %d does not exist", entry.FromLocationID)
+		}
+		to, ok := m.locations[entry.ToLocationID]
+		if !ok && entry.ToLocationID != 0 {
+			return nil, invalid("locationId", "location %d does not exist", entry.ToLocationID)
+		}
+
+		if entry.FromLocationID != 0 {
+			key := stockKey{itemID: entry.ItemID, locationID: from.ID}
+			if err := checkWithdrawal(from, entry.ItemID, balances[key], entry.Quantity); err != nil {
+				return nil, err
+			}
+			if entry.Type != models.StockPick {
+				if err := checkUnreserved(from, entry.ItemID, balances[key], m.reservedAt(key), entry.Quantity); err != nil {
+					return nil, err
+				}
+			}
+			balances[key] -= entry.Quantity
+			if balances[key] == 0 {
+				delete(balances, key)
+			}
+		}
+		if entry.ToLocationID != 0 {
+			if _, ok := m.items[entry.ItemID]; !ok {
+				return nil, foreignKeyError("stock_transaction", "item_id")
+			}
+			var stored int64
+			for key, quantity := range balances {
+				if key.locationID == to.ID {
+					stored += quantity
+				}
+			}
+			if err := checkPlacement(to, stored, entry.Quantity); err != nil {
+				return nil, err
+			}
+			balances[stockKey{itemID: entry.ItemID, locationID: to.ID}] += entry.Quantity
+		}
+
+		entry.AccountID = ActorFrom(ctx)
+		entry.Created = time.Now()
+		posted = append(posted, entry)
+	}
+
+	for i := range posted {
+		posted[i].ID = int64(len(m.ledger) + 1)
+		m.ledger = append(m.ledger, posted[i])
+	}
+	m.stock = balances
+	return posted, nil
+}
+
+// stockEntries resolves the requested locations of an item and returns the
+// count corrections that bring its stock to exactly those quantities.
+// Callers must hold m.mu.
+func (m *MemoryStore) stockEntries(itemID int64, locations []models.LocationData) ([]models.StockTransaction, error) {
+	merged, err := mergeLocations(locations)
+	if err != nil {
+		return nil, err
+	}
+
+	want := map[int64]int64{}
+	for _, loc := range merged {
+		id := loc.LocationID
+		if id == 0 {
+			found, ok := m.locationByCode(loc.Area)
+			if !ok {
+				return nil, invalid("locations", "location %q does not exist", locationLabel(loc))
+			}
+			id = found.ID
+		}
+		want[id] += loc.Count
+	}
+
+	have := map[int64]int64{}
+	for key, quantity := range m.stock {
+		if key.itemID == itemID {
+			have[key.locationID] = quantity
+		}
+	}
+	return countEntries(itemID, have, want), nil
+}
+
+func (m *MemoryStore) MoveStock(ctx context.Context, move models.StockMoveRequest) ([]models.StockTransaction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.post(ctx, []models.StockTransaction{moveEntry(move)})
+}
+
+func (m *MemoryStore) TransferStock(ctx context.Context, transfer models.StockTransferRequest) ([]models.StockTransaction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	from, ok := m.locations[transfer.FromLocationID]
+	if !ok {
+		return nil, invalid("locationId", "location %d does not exist", transfer.FromLocationID)
+	}
+	balances := map[int64]int64{}
+	for key, quantity := range m.stock {
+		if key.locationID == from.ID {
+			balances[key.itemID] = quantity
+		}
+	}
+	entries, err := transferEntries(transfer, from, balances)
+	if err != nil {
+		return nil, err
+	}
+	return m.post(ctx, entries)
+}
+
+func (m *MemoryStore) AdjustStock(ctx context.Context, adj models.StockAdjustmentRequest) ([]models.StockTransaction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.locations[adj.LocationID]; !ok && adj.LocationID != 0 {
+		return nil, invalid("locationId", "location %d does not exist", adj.LocationID)
+	}
+	balance := m.stock[stockKey{itemID: adj.ItemID, locationID: adj.LocationID}]
+	entries, err := adjustEntries(adj, balance)
+	if err != nil {
+		return nil, err
+	}
+	return m.post(ctx, entries)
+}
+
+func (m *MemoryStore) GetStockTransactions(ctx context.Context, query models.ListQuery) (models.Page[models.StockTransaction], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return listPage(slices.Clone(m.ledger), stockTransactionList, query)
+}
+
+//  Reservations  //
+
+// reservedAt returns the quantity of an item reserved at a location.
+// Callers must hold m.mu.
+func (m *MemoryStore) reservedAt(key stockKey) int64 {
+	var reserved int64
+	for _, r := range m.reservations {
+		if r.ItemID == key.itemID && r.LocationID == key.locationID {
+			reserved += r.Quantity
+		}
+	}
+	return reserved
+}
+
+// availabilityOf lists the on-hand and reserved quantities of an item per
+// location. Callers must hold m.mu.
+func (m *MemoryStore) availabilityOf(itemID int64) []models.LocationAvailability {
+	byLocation := map[int64]*models.LocationAvailability{}
+	entry := func(locationID int64) *models.LocationAvailability {
+		if _, ok := byLocation[locationID]; !ok {
+			loc := m.locations[locationID]
+			byLocation[locationID] = &models.LocationAvailability{LocationID: loc.ID, LocationCode: loc.Code, Type: loc.Type, Active: loc.Active}
+		}
+		return byLocation[locationID]
+	}
+	for key, quantity := range m.stock {
+		if key.itemID == itemID && quantity > 0 {
+			entry(key.locationID).OnHand = quantity
+		}
+	}
+	for _, r := range m.reservations {
+		if r.ItemID == itemID {
+			entry(r.LocationID).Reserved += r.Quantity
+		}
+	}
+
+	var locations []models.LocationAvailability
+	for _, loc := range byLocation {
+		locations = append(locations, *loc)
+	}
+	slices.SortFunc(locations, func(a, b models.LocationAvailability) int {
+		return strings.Compare(a.LocationCode, b.LocationCode)
+	})
+	return locations
+}
+
+// orderReservations returns the reservations held by an order. Callers
+// must hold m.mu.
+func (m *MemoryStore) orderReservations(orderID int64) []models.Reservation {
+	var reserved []models.Reservation
+	for _, r := range m.reservations {
+		if r.OrderID == orderID {
+			reserved = append(reserved, r)
+		}
+	}
+	return reserved
+}
+
+// withAllocation fills in how the lines of an order holding reservations
+// are allocated. Callers must hold m.mu.
+func (m *MemoryStore) withAllocation(order models.Order) models.Order {
+	if slices.Contains(reservingOrderStatuses, order.Status) {
+		order.Allocation = lineAllocations(order, m.orderReservations(order.ID))
+	}
+	return order
+}
+
+// memOrderTx runs the order workflow while holding m.mu.
+type memOrderTx struct {
+	ctx context.Context
+	m   *MemoryStore
+}
+
+func (a memOrderTx) setOrderStatus(order *models.Order, status string) error {
+	return a.m.setOrderStatus(a.ctx, order, status)
+}
+
+func (a memOrderTx) allocateOrder(order models.Order) ([]models.LineAllocation, error) {
+	stock := map[int64][]models.LocationAvailability{}
+	for _, itemID := range orderItems(order) {
+		stock[itemID] = availability(itemID, a.m.availabilityOf(itemID)).Locations
+	}
+	reserved := a.m.orderReservations(order.ID)
+	for _, r := range allocateLines(order, reserved, stock) {
+		a.m.reservationID++
+		r.ID = a.m.reservationID
+		r.Created = time.Now()
+		a.m.reservations = append(a.m.reservations, r)
+		reserved = append(reserved, r)
+	}
+	return lineAllocations(order, reserved), nil
+}
+
+func (a memOrderTx) releaseOrder(order models.Order) error {
+	a.m.reservations = slices.DeleteFunc(a.m.reservations, func(r models.Reservation) bool {
+		return r.OrderID == order.ID
+	})
+	var waves []int64
+	for id, task := range a.m.picks {
+		if task.OrderID == order.ID && taskOpen(task) {
+			task.Status = models.PickCancelled
+			task.Updated = time.Now()
+			a.m.picks[id] = task
+			waves = append(waves, task.WaveID)
+		}
+	}
+	a.m.completeWaves(waves...)
+	return nil
+}
+
+//  Orders  //
+
+// cloneOrder copies an order for a caller, listings leave out the history
+// like the postgres column list.
+func cloneOrder(order models.Order, withHistory bool) models.Order {
+	order.Payload = cloneGroups(order.Payload)
+	order.History = slices.Clone(order.History)
+	if !withHistory {
+		order.History = nil
+	}
+	return order
+}
+
+// setOrderStatus moves an order to a new status and records the
+// transition. Callers must hold m.mu.
+func (m *MemoryStore) setOrderStatus(ctx context.Context, order *models.Order, status string) error {
+	if err := checkTransition(order.ID, order.Status, status); err != nil {
+		return err
+	}
+	order.History = append(slices.Clone(order.History), models.OrderTransition{
+		From:      order.Status,
+		To:        status,
+		AccountID: ActorFrom(ctx),
+		Created:   time.Now(),
+	})
+	order.Status = status
+	m.orders[order.ID] = *order
+	return nil
+}
+
+func (m *MemoryStore) AddOrder(ctx context.Context, order models.Order) error {
+	status, err := initialOrderStatus(order.Status)
+	if err != nil {
+		return err
+	}
+	order.Payload = cloneGroups(order.Payload)
+	order.Status = status
+	order.History = nil
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id, err := reserveID(m.orders, order.ID, "order_data")
+	if err != nil {
+		return err
+	}
+	order.ID = id
+	m.orders[id] = order
+	if order.Status == models.OrderPlaced {
+		return placeOrder(memOrderTx{ctx, m}, &order)
+	}
+	return nil
+}
+
+func (m *MemoryStore) GetOrders(ctx context.Context, query models.ListQuery) (models.Page[models.Order], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	orders := sortedRows(m.orders)
+	for i := range orders {
+		orders[i] = cloneOrder(orders[i], false)
+	}
+	return listPage(orders, orderList, query)
+}
+
+func (m *MemoryStore) GetOrder(ctx context.Context, id int) (models.Order, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	order, ok := m.orders[int64(id)]
+	if !ok {
+		return models.Order{}, fmt.Errorf("order %d: %w", id, ErrNotFound)
+	}
+	return m.withAllocation(cloneOrder(order, true)), nil
+}
+
+func (m *MemoryStore) UpdateOrder(ctx context.Context, id int, newData models.Order) error {
+	newData.Payload = cloneGroups(newData.Payload)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	current, ok := m.orders[int64(id)]
+	if !ok {
+		return fmt.Errorf("order %d: %w", id, ErrNotFound)
+	}
+	if err := checkOrderEditable(current.ID, current.Status); err != nil {
+		return err
+	}
+	current.Customer = newData.Customer
+	current.Address = newData.Address
+	current.TimeOrdered = newData.TimeOrdered
+	current.Payload = newData.Payload
+	m.orders[int64(id)] = current
+	if current.Status == models.OrderPlaced {
+		a := memOrderTx{ctx, m}
+		a.releaseOrder(current)
+		return placeOrder(a, &current)
+	}
+	return nil
+}
+
+func (m *MemoryStore) DeleteOrder(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.orders[int64(id)]; !ok {
+		return fmt.Errorf("order %d: %w", id, ErrNotFound)
+	}
+	delete(m.orders, int64(id))
+	m.reservations = slices.DeleteFunc(m.reservations, func(r models.Reservation) bool {
+		return r.OrderID == int64(id)
+	})
+	maps.DeleteFunc(m.picks, func(_ int64, task models.PickTask) bool {
+		return task.OrderID == int64(id)
+	})
+	maps.DeleteFunc(m.packed, func(_ int64, carton models.PackedCarton) bool {
+		return carton.OrderID == int64(id)
+	})
+	return nil
+}
+
+func (m *MemoryStore) TransitionOrder(ctx context.Context, id int, status string) (models.Order, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	order, ok := m.orders[int64(id)]
+	if !ok {
+		return models.Order{}, fmt.Errorf("order %d: %w", id, ErrNotFound)
+	}
+	// Reservations made before a failed allocation are rolled back
+	reservations := slices.Clone(m.reservations)
+	if err := moveOrder(memOrderTx{ctx, m}, &order, status); err != nil {
+		m.reservations = reservations
+		return models.Order{}, err
+	}
+	return m.withAllocation(cloneOrder(order, true)), nil
+}
+
+//  Picking  //
+
+// completeWaves closes the open waves among ids that have no task left to
+// pick. Callers must hold m.mu.
+func (m *MemoryStore) completeWaves(ids ...int64) {
+	for _, id := range ids {
+		wave, ok := m.waves[id]
+		if !ok || wave.Status != models.WaveOpen {
+			continue
+		}
+		open := false
+		for _, task := range m.picks {
+			open = open || (task.WaveID == id && taskOpen(task))
+		}
+		if !open {
+			now := time.Now()
+			wave.Status = models.WaveCompleted
+			wave.Completed = &now
+			m.waves[id] = wave
+		}
+	}
+}
+
+// waveTasks fills in the tasks of a wave and the orders they belong to.
+// Callers must hold m.mu.
+func (m *MemoryStore) waveTasks(wave models.Wave) models.Wave {
+	wave.Tasks = []models.PickTask{}
+	wave.Orders = []int64{}
+	for _, task := range sortedRows(m.picks) {
+		if task.WaveID != wave.ID {
+			continue
+		}
+		wave.Tasks = append(wave.Tasks, task)
+		if !slices.Contains(wave.Orders, task.OrderID) {
+			wave.Orders = append(wave.Orders, task.OrderID)
+		}
+	}
+	slices.SortFunc(wave.Tasks, func(a, b models.PickTask) int {
+		return cmp.Compare(a.Sequence, b.Sequence)
+	})
+	return wave
+}
+
+func (m *MemoryStore) CreateWave(ctx context.Context, req models.WaveRequest) (models.Wave, error) {
+	if err := normalizeWaveRequest(&req); err != nil {
+		return models.Wave{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	orders := sortedRows(m.orders)
+	reservations := map[int64][]models.Reservation{}
+	for _, order := range orders {
+		reservations[order.ID] = m.orderReservations(order.ID)
+	}
+	selected, err := waveOrders(req, orders, reservations)
+	if err != nil {
+		return models.Wave{}, err
+	}
+
+	wave := models.Wave{
+		ID:        nextID(m.waves, 0),
+		Status:    models.WaveOpen,
+		Zone:      req.Zone,
+		Cutoff:    req.Cutoff,
+		MaxOrders: req.MaxOrders,
+		AccountID: ActorFrom(ctx),
+		Created:   time.Now(),
+	}
+	m.waves[wave.ID] = wave
+	for i := range selected {
+		if err := m.setOrderStatus(ctx, &selected[i], models.OrderPicking); err != nil {
+			return models.Wave{}, err
+		}
+	}
+	for _, task := range pickTasks(wave.ID, selected, reservations) {
+		task.ID = nextID(m.picks, 0)
+		task.Updated = wave.Created
+		m.picks[task.ID] = task
+	}
+	return m.waveTasks(wave), nil
+}
+
+func (m *MemoryStore) GetWaves(ctx context.Context, query models.ListQuery) (models.Page[models.Wave], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return listPage(sortedRows(m.waves), waveList, query)
+}
+
+func (m *MemoryStore) GetWave(ctx context.Context, id int) (models.Wave, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	wave, ok := m.waves[int64(id)]
+	if !ok {
+		return models.Wave{}, fmt.Errorf("wave %d: %w", id, ErrNotFound)
+	}
+	return m.waveTasks(wave), nil
+}
+
+func (m *MemoryStore) GetPickTasks(ctx context.Context, query models.ListQuery) (models.Page[models.PickTask], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return listPage(sortedRows(m.picks), pickTaskList, query)
+}
+
+func (m *MemoryStore) ClaimPickTask(ctx context.Context, id int) (models.PickTask, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	task, ok := m.picks[int64(id)]
+	if !ok {
+		return models.PickTask{}, fmt.Errorf("pick task %d: %w", id, ErrNotFound)
+	}
+	if err := claimTask(&task, ActorFrom(ctx)); err != nil {
+		return models.PickTask{}, err
+	}
+	task.Updated = time.Now()
+	m.picks[task.ID] = task
+	return task, nil
+}
+
+// pick records the units picked for a claimed task, takes them out of
+// stock and consumes the reservation.
+func (m *MemoryStore) pick(ctx context.Context, id int, short *models.ShortPickRequest) (models.PickTask, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	task, ok := m.picks[int64(id)]
+	if !ok {
+		return models.PickTask{}, fmt.Errorf("pick task %d: %w", id, ErrNotFound)
+	}
+	if err := completeTask(&task, ActorFrom(ctx), short); err != nil {
+		return models.PickTask{}, err
+	}
+	if _, err := m.post(ctx, pickEntries(task)); err != nil {
+		return models.PickTask{}, err
+	}
+	m.reservations = slices.DeleteFunc(m.reservations, func(r models.Reservation) bool {
+		return r.ID == task.ReservationID
+	})
+	task.ReservationID = 0
+	task.Updated = time.Now()
+	m.picks[task.ID] = task
+	m.completeWaves(task.WaveID)
+	return task, nil
+}
+
+func (m *MemoryStore) ConfirmPickTask(ctx context.Context, id int) (models.PickTask, error) {
+	return m.pick(ctx, id, nil)
+}
+
+func (m *MemoryStore) ShortPickTask(ctx context.Context, id int, req models.ShortPickRequest) (models.PickTask, error) {
+	return m.pick(ctx, id, &req)
+}
+
+//  Packing  //
+
+// cartonByName finds a carton by its unique name. Callers must hold m.mu.
+func (m *MemoryStore) cartonByName(name string) (models.Carton, bool) {
+	for _, carton := range m.cartons {
+		if carton.Name == name {
+			return carton, true
+		}
+	}
+	return models.Carton{}, false
+}
+
+func (m *MemoryStore) AddCarton(ctx context.Context, carton models.Carton) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id, err := reserveID(m.cartons, carton.ID, "carton")
+	if err != nil {
+		return err
+	}
+	if _, ok := m.cartonByName(carton.Name); ok {
+		return &constraintError{errors.New("duplicate key value violates unique constraint \"carton_name_key\"")}
+	}
+	carton.ID = id
+	m.cartons[id] = carton
+	return nil
+}
+
+func (m *MemoryStore) GetCartons(ctx context.Context, query models.ListQuery) (models.Page[models.Carton], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return listPage(sortedRows(m.cartons), cartonList, query)
+}
+
+func (m *MemoryStore) GetCarton(ctx context.Context, id int) (models.Carton, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	carton, ok := m.cartons[int64(id)]
+	if !ok {
+		return models.Carton{}, fmt.Errorf("carton %d: %w", id, ErrNotFound)
+	}
+	return carton, nil
+}
+
+func (m *MemoryStore) UpdateCarton(ctx context.Context, id int, newData models.Carton) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.cartons[int64(id)]; !ok {
+		return fmt.Errorf("carton %d: %w", id, ErrNotFound)
+	}
+	if other, ok := m.cartonByName(newData.Name); ok && other.ID != int64(id) {
+		return &constraintError{errors.New("duplicate key value violates unique constraint \"carton_name_key\"")}
+	}
+	newData.ID = int64(id)
+	m.cartons[int64(id)] = newData
+	return nil
+}
+
+func (m *MemoryStore) DeleteCarton(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.cartons[int64(id)]; !ok {
+		return fmt.Errorf("carton %d: %w", id, ErrNotFound)
+	}
+	delete(m.cartons, int64(id))
+	// Packed cartons keep their snapshot of the deleted carton
+	for key, carton := range m.packed {
+		if carton.CartonID == int64(id) {
+			carton.CartonID = 0
+			m.packed[key] = carton
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) SuggestCartons(ctx context.Context, orderID int) (models.Packing, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	order, ok := m.orders[int64(orderID)]
+	if !ok {
+		return models.Packing{}, fmt.Errorf("order %d: %w", orderID, ErrNotFound)
+	}
+	var boxes []models.Box
+	for _, box := range sortedRows(m.boxes) {
+		if slices.Contains(orderItems(order), box.Item.ID) {
+			box.Item = m.joinItem(box.Item, false)
+			boxes = append(boxes, box)
+		}
+	}
+	units, err := packUnits(order.Payload, boxes)
+	if err != nil {
+		return models.Packing{}, err
+	}
+	return suggestCartons(order.ID, sortedRows(m.cartons), units)
+}
+
+// packing totals the cartons an order was packed into. Callers must hold
+// m.mu.
+func (m *MemoryStore) packing(orderID int64) models.Packing {
+	var cartons []models.PackedCarton
+	for _, carton := range sortedRows(m.packed) {
+		if carton.OrderID == orderID {
+			carton.Contents = cloneGroups(carton.Contents)
+			cartons = append(cartons, carton)
+		}
+	}
+	return packingTotals(orderID, cartons)
+}
+
+func (m *MemoryStore) PackOrder(ctx context.Context, orderID int, req models.PackRequest) (models.Packing, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	order, ok := m.orders[int64(orderID)]
+	if !ok {
+		return models.Packing{}, fmt.Errorf("order %d: %w", orderID, ErrNotFound)
+	}
+	var tasks []models.PickTask
+	for _, task := range sortedRows(m.picks) {
+		if task.OrderID == order.ID {
+			tasks = append(tasks, task)
+		}
+	}
+	items := map[int64]models.Item{}
+	for _, id := range orderItems(order) {
+		if item, ok := m.items[id]; ok {
+			items[id] = m.joinItem(item, false)
+		}
+	}
+	cartons, err := packedCartons(order, tasks, req, m.cartons, items)
+	if err != nil {
+		return models.Packing{}, err
+	}
+	first := nextID(m.packed, 0)
+	for i := range cartons {
+		cartons[i].ID = first + int64(i)
+		if cartons[i].SSCC, err = licensePlate(cartons[i].ID); err != nil {
+			return models.Packing{}, err
+		}
+	}
+	if err := m.setOrderStatus(ctx, &order, models.OrderPacked); err != nil {
+		return models.Packing{}, err
+	}
+	now := time.Now()
+	for _, carton := range cartons {
+		carton.AccountID = ActorFrom(ctx)
+		carton.Created = now
+		m.packed[carton.ID] = carton
+	}
+	return m.packing(order.ID), nil
+}
+
+func (m *MemoryStore) GetPacking(ctx context.Context, orderID int) (models.Packing, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, ok := m.orders[int64(orderID)]; !ok {
+		return models.Packing{}, fmt.Errorf("order %d: %w", orderID, ErrNotFound)
+	}
+	return m.packing(int64(orderID)), nil
+}
+
+//  Scanning  //
+
+func (m *MemoryStore) ResolveScan(ctx context.Context, scan models.Scan) (models.ScanResult, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	result := models.ScanResult{Scan: scan}
+	switch {
+	case scan.SSCC != "":
+		var carton *models.PackedCarton
+		for _, packed := range sortedRows(m.packed) {
+			if packed.SSCC == scan.SSCC {
+				packed.Contents = cloneGroups(packed.Contents)
+				carton = &packed
+				break
+			}
+		}
+		if carton == nil {
+			return result, noMatch(scan)
+		}
+		order := cloneOrder(m.orders[carton.OrderID], false)
+		result.Kind = models.ScanLicensePlate
+		result.Carton = carton
+		result.Order = &order
+
+	case scan.GTIN != "":
+		itemID := m.productItem(scan.GTIN)
+		item, ok := m.items[itemID]
+		if !ok {
+			return result, noMatch(scan)
+		}
+		boxes := sortedRows(m.boxes)
+		productScan(&result, m.joinItem(item, false), boxes, itemUnits(item, m.uoms[itemID], boxes))
+
+	default:
+		for _, location := range sortedRows(m.locations) {
+			if strings.EqualFold(location.Code, scan.Code) {
+				result.Kind = models.ScanLocation
+				result.Location = &location
+				break
+			}
+		}
+		if orderID, ok := scanOrderID(scan.Code); ok && result.Location == nil {
+			if order, ok := m.orders[orderID]; ok {
+				order = cloneOrder(order, false)
+				result.Kind = models.ScanOrder
+				result.Order = &order
+			}
+		}
+		if result.Kind == "" {
+			return result, noMatch(scan)
+		}
+	}
+	result.Actions = scanActions(result)
+	return result, nil
+}
+
+// productItem finds the item a GTIN belongs to by the item, box and pack
+// level codes. Callers must hold m.mu.
+func (m *MemoryStore) productItem(gtin string) int64 {
+	for _, item := range sortedRows(m.items) {
+		if item.UPC == gtin {
+			return item.ID
+		}
+	}
+	for _, box := range sortedRows(m.boxes) {
+		if box.UPC == gtin {
+			return box.Item.ID
+		}
+	}
+	for _, itemID := range slices.Sorted(maps.Keys(m.uoms)) {
+		for _, unit := range m.uoms[itemID] {
+			if unit.UPC == gtin {
+				return itemID
+			}
+		}
+	}
+	return 0
+}
+
+//  Searching  //
+
+// Search matches records with the same rules as the database, without its
+// indexes or its full-text ranking.
+func (m *MemoryStore) Search(ctx context.Context, query models.SearchQuery) (models.SearchResults, error) {
+	terms, err := checkSearch(&query)
+	if err != nil {
+		return models.SearchResults{}, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var hits []models.SearchHit
+	add := func(kind string, id int64, title string, detail string, document string) {
+		if rank, ok := matchDocument(terms, document); ok {
+			hits = append(hits, models.SearchHit{Kind: kind, ID: id, Title: title, Detail: detail, Rank: rank})
+		}
+	}
+	for _, kind := range query.Kinds {
+		switch kind {
+		case models.SearchItems:
+			for _, item := range sortedRows(m.items) {
+				add(kind, item.ID, item.Name, item.UPC, item.Name+" "+item.UPC+" "+item.Description)
+			}
+		case models.SearchOrders:
+			for _, order := range sortedRows(m.orders) {
+				if query.CustomerID != 0 && order.Customer.ID != query.CustomerID {
+					continue
+				}
+				name := order.Customer.Firstname + " " + order.Customer.Lastname
+				add(kind, order.ID, fmt.Sprintf("Order %d", order.ID), strings.TrimSpace(name)+", "+order.Address, name+" "+order.Address)
+			}
+		case models.SearchAccounts:
+			for _, account := range sortedRows(m.accounts) {
+				name := account.Firstname + " " + account.Lastname
+				add(kind, account.ID, name, account.Username, name+" "+account.Username+" "+account.Email)
+			}
+		case models.SearchLocations:
+			for _, loc := range sortedRows(m.locations) {
+				add(kind, loc.ID, loc.Code, loc.Type, loc.Code+" "+loc.Zone)
+			}
+		}
+	}
+	text := strings.Join(terms, " ")
+	return models.SearchResults{Query: text, Kinds: query.Kinds, Hits: bestHits(hits, query.Kinds, query.Limit)}, nil
+}
+
+//  Shipments  //
+
+func cloneShipment(shipment models.Shipment) models.Shipment {
+	shipment.Payload = cloneGroups(shipment.Payload)
+	shipment.Receipt = slices.Clone(shipment.Receipt)
+	return shipment
+}
+
+func (m *MemoryStore) AddShipment(ctx context.Context, shipment models.Shipment) error {
+	shipment.Payload = cloneGroups(shipment.Payload)
+	shipment.Status = models.ShipmentExpected
+	shipment.Arrived, shipment.Received = nil, nil
+	shipment.ReceivingLocationID = 0
+	shipment.Receipt = []models.ReceiptLine{}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id, err := reserveID(m.shipments, shipment.ID, "shipment")
+	if err != nil {
+		return err
+	}
+	shipment.ID = id
+	m.shipments[id] = shipment
+	return nil
+}
+
+func (m *MemoryStore) GetShipments(ctx context.Context, query models.ListQuery) (models.Page[models.Shipment], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	shipments := sortedRows(m.shipments)
+	for i := range shipments {
+		shipments[i] = cloneShipment(shipments[i])
+	}
+	return listPage(shipments, shipmentList, query)
+}
+
+func (m *MemoryStore) GetShipment(ctx context.Context, id int) (models.Shipment, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	shipment, ok := m.shipments[int64(id)]
+	if !ok {
+		return models.Shipment{}, fmt.Errorf("shipment %d: %w", id, ErrNotFound)
+	}
+	return cloneShipment(shipment), nil
+}
+
+func (m *MemoryStore) UpdateShipment(ctx context.Context, id int, newData models.Shipment) error {
+	newData.Payload = cloneGroups(newData.Payload)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	current, ok := m.shipments[int64(id)]
+	if !ok {
+		return fmt.Errorf("shipment %d: %w", id, ErrNotFound)
+	}
+	if err := checkShipmentStatus(current, models.ShipmentExpected, models.ShipmentArrived); err != nil {
+		return err
+	}
+	current.Supplier = newData.Supplier
+	current.Distributor = newData.Distributor
+	current.ETA = newData.ETA
+	current.Payload = newData.Payload
+	m.shipments[int64(id)] = current
+	return nil
+}
+
+func (m *MemoryStore) DeleteShipment(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	current, ok := m.shipments[int64(id)]
+	if !ok {
+		return fmt.Errorf("shipment %d: %w", id, ErrNotFound)
+	}
+	if err := checkShipmentStatus(current, models.ShipmentExpected, models.ShipmentArrived); err != nil {
+		return err
+	}
+	delete(m.shipments, int64(id))
+	return nil
+}
+
+func (m *MemoryStore) ArriveShipment(ctx context.Context, id int) (models.Shipment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	shipment, ok := m.shipments[int64(id)]
+	if !ok {
+		return models.Shipment{}, fmt.Errorf("shipment %d: %w", id, ErrNotFound)
+	}
+	if err := checkShipmentStatus(shipment, models.ShipmentExpected); err != nil {
+		return models.Shipment{}, err
+	}
+	now := time.Now()
+	shipment.Status = models.ShipmentArrived
+	shipment.Arrived = &now
+	m.shipments[int64(id)] = shipment
+	return cloneShipment(shipment), nil
+}
+
+func (m *MemoryStore) ReceiveShipment(ctx context.Context, id int, req models.ReceiveRequest) (models.Shipment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	shipment, ok := m.shipments[int64(id)]
+	if !ok {
+		return models.Shipment{}, fmt.Errorf("shipment %d: %w", id, ErrNotFound)
+	}
+	if err := checkShipmentStatus(shipment, models.ShipmentArrived); err != nil {
+		return models.Shipment{}, err
+	}
+	lines, err := receiptLines(shipment, req)
+	if err != nil {
+		return models.Shipment{}, err
+	}
+	if _, err := m.post(ctx, receiptEntries(shipment.ID, req.LocationID, lines)); err != nil {
+		return models.Shipment{}, err
+	}
+
+	// Received items get an inventory record if they have none yet
+	for _, line := range lines {
+		if line.Received > 0 && !m.hasInventory(line.ItemID, 0) {
+			invID := nextID(m.inventory, 0)
+			m.inventory[invID] = models.Inventory{ID: invID, Item: models.Item{ID: line.ItemID}}
+		}
+	}
+
+	now := time.Now()
+	shipment.Status = models.ShipmentReceived
+	shipment.Received = &now
+	shipment.ReceivingLocationID = req.LocationID
+	shipment.Receipt = lines
+	m.shipments[int64(id)] = shipment
+	return cloneShipment(shipment), nil
+}
@@ -0,0 +1,529 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/WMS/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStoreAccounts(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	accounts, err := store.GetAccounts(ctx, models.ListQuery{})
+	assert.Nil(t, err)
+	assert.Equal(t, models.Page[models.Account]{Data: []models.Account{}, Page: models.PageInfo{Limit: models.DefaultPageLimit, Sort: []string{"id"}}}, accounts, "Empty tables list an empty page")
+
+	account := models.Account{ID: 7, Username: "demo", Password: "demo", Role: models.Role{Value: models.RoleCustomer}, Active: true}
+	assert.Nil(t, store.AddAccount(ctx, account))
+	assert.NotNil(t, store.AddAccount(ctx, account), "Duplicate id should be rejected")
+
+	stored, err := store.GetAccount(ctx, 7)
+	assert.Nil(t, err)
+	assert.NotEqual(t, "demo", stored.Password, "Password should be hashed")
+
+	acc, err := ValidateLogin(ctx, store, "demo", "demo")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(7), acc.ID)
+
+	_, err = ValidateLogin(ctx, store, "demo", "wrong")
+	assert.ErrorIs(t, err, ErrCredentials)
+	_, err = ValidateLogin(ctx, store, "nobody", "demo")
+	assert.ErrorIs(t, err, ErrCredentials, "Unknown usernames fail like wrong passwords")
+
+	stored.Firstname = "Demo"
+	stored.Password = ""
+	assert.Nil(t, store.UpdateAccount(ctx, 7, stored))
+	_, err = ValidateLogin(ctx, store, "demo", "demo")
+	assert.Nil(t, err, "Profile edits keep the password")
+
+	err = ChangePassword(ctx, store, 7, models.PasswordChange{CurrentPassword: "wrong", NewPassword: "next"})
+	assert.ErrorIs(t, err, ErrCredentials)
+	err = ChangePassword(ctx, store, 7, models.PasswordChange{CurrentPassword: "demo", NewPassword: "next"})
+	var lockout *LockoutError
+	assert.ErrorAs(t, err, &lockout, "Wrong current passwords count as failed logins")
+	assert.Nil(t, store.ClearLoginFailures(ctx, usernameKey("demo")))
+	err = ChangePassword(ctx, store, 7, models.PasswordChange{CurrentPassword: "demo", NewPassword: " "})
+	var validation *ValidationError
+	assert.ErrorAs(t, err, &validation)
+	generation, err := store.TokenGeneration(ctx, 7)
+	assert.Nil(t, err)
+	assert.Nil(t, ChangePassword(ctx, store, 7, models.PasswordChange{CurrentPassword: "demo", NewPassword: "next"}))
+	revoked, err := store.TokenRevoked(ctx, "before", 7, generation)
+	assert.Nil(t, err)
+	assert.True(t, revoked, "Changing the password revokes the tokens of the account")
+	_, err = ValidateLogin(ctx, store, "demo", "next")
+	assert.Nil(t, err)
+	assert.ErrorIs(t, store.SetPassword(ctx, 8, "next"), ErrNotFound)
+
+	assert.Nil(t, store.DeleteAccount(ctx, 7))
+	assert.NotNil(t, store.DeleteAccount(ctx, 7))
+}
+
+func TestMemoryStoreAssignsIDs(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	assert.Nil(t, store.AddItem(ctx, models.Item{ID: 4, Name: "first"}))
+	assert.Nil(t, store.AddItem(ctx, models.Item{Name: "second"}))
+
+	items, err := store.GetItems(ctx, models.ListQuery{})
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{4, 5}, []int64{items.Data[0].ID, items.Data[1].ID})
+}
+
+func TestMemoryStoreCopiesSlices(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	assert.Nil(t, store.AddItem(ctx, models.Item{ID: 1, Name: "beans"}))
+	assert.Nil(t, store.AddLocation(ctx, models.Location{ID: 1, Code: "A1", Zone: "A1", Active: true}))
+	inv := models.Inventory{ID: 1, Item: models.Item{ID: 1}, TotalCount: 5, Locations: []models.LocationData{{Area: "A1", Count: 5}}}
+	assert.Nil(t, store.AddInventory(ctx, inv))
+	inv.Locations[0].Count = 99
+
+	stored, err := store.GetInventory(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), stored.Locations[0].Count)
+}
+
+func TestMemoryStoreItemReferences(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	box := models.Box{ID: 1, UPC: "111", Item: models.Item{ID: 9, Name: "stale copy"}, Count: 12}
+	assert.NotNil(t, store.AddBox(ctx, box), "Box must reference an existing item")
+
+	assert.Nil(t, store.AddItem(ctx, models.Item{ID: 9, Name: "beans", Weight: models.Weight{Value: 1, Unit: models.UnitPound}}))
+	assert.Nil(t, store.AddBox(ctx, box))
+	assert.Nil(t, store.AddInventory(ctx, models.Inventory{ID: 1, Item: models.Item{ID: 9}, TotalCount: 3}))
+
+	// Renaming the item is reflected in joined rows
+	assert.Nil(t, store.UpdateItem(ctx, 9, models.Item{Name: "black beans", Weight: models.Weight{Value: 2, Unit: models.UnitPound}}))
+	gotBox, err := store.GetBox(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "black beans", gotBox.Item.Name)
+	gotInv, err := store.GetInventory(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 2.0, gotInv.Item.Weight.Value)
+
+	// Inventory restricts deleting the item, boxes cascade
+	assert.NotNil(t, store.DeleteItem(ctx, 9))
+	assert.Nil(t, store.DeleteInventory(ctx, 1))
+	assert.Nil(t, store.DeleteItem(ctx, 9))
+	_, err = store.GetBox(ctx, 1)
+	assert.NotNil(t, err)
+}
+
+func TestMemoryStoreStock(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	assert.Nil(t, store.AddItem(ctx, models.Item{ID: 1, Name: "beans"}))
+	assert.Nil(t, store.AddItem(ctx, models.Item{ID: 2, Name: "rice"}))
+	assert.Nil(t, store.AddLocation(ctx, models.Location{ID: 1, Code: "A-01", Capacity: 10, Active: true}))
+	assert.Nil(t, store.AddLocation(ctx, models.Location{ID: 2, Code: "B-01", Active: true}))
+	assert.Nil(t, store.AddLocation(ctx, models.Location{ID: 3, Code: "C-01"}))
+	assert.ErrorIs(t, store.AddLocation(ctx, models.Location{Code: "A-01"}), ErrConflict)
+
+	// Quantities are aggregated per item from the location rows
+	beans := models.Inventory{ID: 1, Item: models.Item{ID: 1}, Locations: []models.LocationData{
+		{Area: "b-01", Count: 4},
+		{LocationID: 1, Count: 3},
+		{Area: "A-01", Count: 2},
+	}}
+	assert.Nil(t, store.AddInventory(ctx, beans))
+	inv, err := store.GetInventory(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(9), inv.TotalCount)
+	assert.Equal(t, []models.LocationData{{LocationID: 1, Area: "A-01", Count: 5}, {LocationID: 2, Area: "B-01", Count: 4}}, inv.Locations)
+
+	// Unknown, inactive and full locations are refused without side effects
+	var validation *ValidationError
+	rice := models.Inventory{ID: 2, Item: models.Item{ID: 2}, Locations: []models.LocationData{{Area: "Z-99", Count: 1}}}
+	assert.ErrorAs(t, store.AddInventory(ctx, rice), &validation)
+	rice.Locations = []models.LocationData{{LocationID: 3, Count: 1}}
+	assert.ErrorAs(t, store.AddInventory(ctx, rice), &validation)
+	rice.Locations = []models.LocationData{{LocationID: 2, Count: 1}, {LocationID: 1, Count: 6}}
+	assert.ErrorAs(t, store.AddInventory(ctx, rice), &validation)
+	_, err = store.GetInventory(ctx, 2)
+	assert.NotNil(t, err)
+	rice.Locations[1].Count = 5
+	assert.Nil(t, store.AddInventory(ctx, rice))
+	assert.ErrorIs(t, store.AddInventory(ctx, models.Inventory{ID: 3, Item: models.Item{ID: 2}}), ErrConflict)
+
+	stock, err := store.GetLocationStock(ctx, 1)
+	assert.Nil(t, err)
+	assert.Len(t, stock, 2)
+	assert.Equal(t, "beans", stock[0].Item.Name)
+
+	// Replacing an item's stock frees its old space
+	beans.Locations = []models.LocationData{{LocationID: 1, Count: 5}}
+	assert.Nil(t, store.UpdateInventory(ctx, 1, beans))
+
+	// Locations with ledger history cannot be deleted
+	assert.ErrorIs(t, store.DeleteLocation(ctx, 2), ErrConflict)
+	assert.Nil(t, store.DeleteInventory(ctx, 2))
+	assert.ErrorIs(t, store.DeleteLocation(ctx, 2), ErrConflict)
+	assert.Nil(t, store.DeleteLocation(ctx, 3))
+	_, err = store.GetLocation(ctx, 3)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestMemoryStoreLedger(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := WithActor(context.Background(), 7)
+
+	assert.Nil(t, store.AddItem(ctx, models.Item{ID: 1, Name: "beans"}))
+	assert.Nil(t, store.AddItem(ctx, models.Item{ID: 2, Name: "rice"}))
+	assert.Nil(t, store.AddLocation(ctx, models.Location{ID: 1, Code: "A-01", Active: true}))
+	assert.Nil(t, store.AddLocation(ctx, models.Location{ID: 2, Code: "B-01", Capacity: 8, Active: true}))
+	assert.Nil(t, store.AddInventory(ctx, models.Inventory{ID: 1, Item: models.Item{ID: 1}, Locations: []models.LocationData{{LocationID: 1, Count: 10}}}))
+	assert.Nil(t, store.AddInventory(ctx, models.Inventory{ID: 2, Item: models.Item{ID: 2}, Locations: []models.LocationData{{LocationID: 1, Count: 2}}}))
+
+	var validation *ValidationError
+	posted, err := store.MoveStock(ctx, models.StockMoveRequest{ItemID: 1, FromLocationID: 1, ToLocationID: 2, Quantity: 4})
+	assert.Nil(t, err)
+	assert.Equal(t, models.ReasonRelocation, posted[0].Reason)
+	assert.Equal(t, int64(7), posted[0].AccountID)
+	_, err = store.MoveStock(ctx, models.StockMoveRequest{ItemID: 1, FromLocationID: 1, ToLocationID: 2, Quantity: 7})
+	assert.ErrorAs(t, err, &validation, "Only 6 left at A-01")
+	_, err = store.MoveStock(ctx, models.StockMoveRequest{ItemID: 1, FromLocationID: 1, ToLocationID: 1, Quantity: 1})
+	assert.ErrorAs(t, err, &validation)
+
+	// Adjustments need a reason, counts set the balance
+	_, err = store.AdjustStock(ctx, models.StockAdjustmentRequest{ItemID: 1, LocationID: 1, Quantity: -1})
+	assert.ErrorAs(t, err, &validation)
+	posted, err = store.AdjustStock(ctx, models.StockAdjustmentRequest{ItemID: 1, LocationID: 1, Quantity: -1, Reason: "damaged"})
+	assert.Nil(t, err)
+	assert.Equal(t, models.StockTransaction{ID: posted[0].ID, Type: models.StockAdjust, ItemID: 1, FromLocationID: 1, Quantity: 1, Reason: models.ReasonDamaged, AccountID: 7, Created: posted[0].Created}, posted[0])
+	counted := int64(3)
+	posted, err = store.AdjustStock(ctx, models.StockAdjustmentRequest{ItemID: 1, LocationID: 1, Counted: &counted})
+	assert.Nil(t, err)
+	assert.Equal(t, models.StockCount, posted[0].Type)
+	assert.Equal(t, int64(2), posted[0].Quantity)
+
+	// Transfers are all-or-nothing, B-01 only has room for 4 more
+	_, err = store.TransferStock(ctx, models.StockTransferRequest{FromLocationID: 1, ToLocationID: 2})
+	assert.ErrorAs(t, err, &validation)
+	inv, err := store.GetInventory(ctx, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []models.LocationData{{LocationID: 1, Area: "A-01", Count: 2}}, inv.Locations)
+	posted, err = store.TransferStock(ctx, models.StockTransferRequest{FromLocationID: 2, ToLocationID: 1})
+	assert.Nil(t, err)
+	assert.Len(t, posted, 1)
+
+	// Balances always equal the sum of the ledger
+	inv, err = store.GetInventory(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(7), inv.TotalCount)
+	ledger, err := store.GetStockTransactions(ctx, models.ListQuery{Filters: map[string][]string{"itemId": {"1"}}})
+	assert.Nil(t, err)
+	var total int64
+	for _, entry := range ledger.Data {
+		if entry.ToLocationID != 0 {
+			total += entry.Quantity
+		}
+		if entry.FromLocationID != 0 {
+			total -= entry.Quantity
+		}
+	}
+	assert.Equal(t, inv.TotalCount, total)
+	assert.ErrorIs(t, store.DeleteItem(ctx, 1), ErrConflict)
+}
+
+func TestMemoryStoreAllocation(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	assert.Nil(t, store.AddItem(ctx, models.Item{ID: 1, Name: "beans"}))
+	assert.Nil(t, store.AddLocation(ctx, models.Location{ID: 1, Code: "A-01", Type: models.LocationPickFace, Active: true}))
+	assert.Nil(t, store.AddLocation(ctx, models.Location{ID: 2, Code: "B-01", Type: models.LocationReserve, Active: true}))
+	assert.Nil(t, store.AddInventory(ctx, models.Inventory{ID: 1, Item: models.Item{ID: 1}, Locations: []models.LocationData{{LocationID: 1, Count: 4}, {LocationID: 2, Count: 6}}}))
+	beans := func(count int64) []models.ItemGroup {
+		return []models.ItemGroup{{Item: models.Item{ID: 1}, Count: count}}
+	}
+
+	// A placed order that can be covered is allocated straight away
+	assert.Nil(t, store.AddOrder(ctx, models.Order{ID: 1, Status: models.OrderPlaced, Payload: beans(7)}))
+	order, err := store.GetOrder(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, models.OrderAllocated, order.Status)
+	assert.Equal(t, models.AllocationFull, order.Allocation[0].Status)
+	assert.Len(t, order.Allocation[0].Reservations, 2)
+
+	available, err := store.GetAvailability(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, models.Availability{ItemID: 1, OnHand: 10, Reserved: 7, Available: 3, Locations: []models.LocationAvailability{
+		{LocationID: 1, LocationCode: "A-01", Type: models.LocationPickFace, Active: true, OnHand: 4, Reserved: 4},
+		{LocationID: 2, LocationCode: "B-01", Type: models.LocationReserve, Active: true, OnHand: 6, Reserved: 3, Available: 3},
+	}}, available)
+
+	// The second order cannot take the units promised to the first
+	assert.Nil(t, store.AddOrder(ctx, models.Order{ID: 2, Payload: beans(5)}))
+	order, err = store.TransitionOrder(ctx, 2, models.OrderPlaced)
+	assert.Nil(t, err)
+	assert.Equal(t, models.OrderPlaced, order.Status)
+	assert.Equal(t, models.AllocationPartial, order.Allocation[0].Status)
+	assert.Equal(t, int64(3), order.Allocation[0].Allocated)
+	_, err = store.TransitionOrder(ctx, 2, models.OrderAllocated)
+	assert.ErrorIs(t, err, ErrConflict)
+	order, err = store.GetOrder(ctx, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), order.Allocation[0].Allocated)
+
+	// Reserved stock stays where it is
+	var validation *ValidationError
+	_, err = store.MoveStock(ctx, models.StockMoveRequest{ItemID: 1, FromLocationID: 1, ToLocationID: 2, Quantity: 1})
+	assert.ErrorAs(t, err, &validation)
+	_, err = store.AdjustStock(ctx, models.StockAdjustmentRequest{ItemID: 1, LocationID: 1, Quantity: -1, Reason: models.ReasonDamaged})
+	assert.ErrorAs(t, err, &validation, "Adjustments cannot write off reserved units")
+	counted := int64(3)
+	_, err = store.AdjustStock(ctx, models.StockAdjustmentRequest{ItemID: 1, LocationID: 1, Counted: &counted})
+	assert.ErrorAs(t, err, &validation, "Counts cannot write off reserved units")
+
+	// Cancelling releases the reservations so the other order can be covered
+	_, err = store.TransitionOrder(ctx, 1, models.OrderCancelled)
+	assert.Nil(t, err)
+	assert.Nil(t, store.UpdateOrder(ctx, 2, models.Order{Payload: beans(10)}))
+	order, err = store.GetOrder(ctx, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, models.OrderAllocated, order.Status)
+	available, err = store.GetAvailability(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), available.Available)
+
+	assert.Nil(t, store.DeleteOrder(ctx, 2))
+	available, err = store.GetAvailability(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(10), available.Available)
+	_, err = store.GetAvailability(ctx, 9)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestMemoryStorePicking(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := WithActor(context.Background(), 3)
+
+	assert.Nil(t, store.AddItem(ctx, models.Item{ID: 1, Name: "beans"}))
+	assert.Nil(t, store.AddLocation(ctx, models.Location{ID: 1, Code: "A-01", Type: models.LocationPickFace, Active: true}))
+	assert.Nil(t, store.AddLocation(ctx, models.Location{ID: 2, Code: "B-01", Type: models.LocationPickFace, Active: true}))
+	assert.Nil(t, store.AddInventory(ctx, models.Inventory{ID: 1, Item: models.Item{ID: 1}, Locations: []models.LocationData{{LocationID: 1, Count: 4}, {LocationID: 2, Count: 6}}}))
+	beans := []models.ItemGroup{{Item: models.Item{ID: 1}, Count: 5}}
+	assert.Nil(t, store.AddOrder(ctx, models.Order{ID: 1, Status: models.OrderPlaced, Payload: beans}))
+	assert.Nil(t, store.AddOrder(ctx, models.Order{ID: 2, Status: models.OrderPlaced, Payload: beans}))
+
+	wave, err := store.CreateWave(ctx, models.WaveRequest{MaxOrders: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{1}, wave.Orders)
+	assert.Equal(t, []string{"A-01", "B-01"}, []string{wave.Tasks[0].LocationCode, wave.Tasks[1].LocationCode})
+	order, err := store.GetOrder(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, models.OrderPicking, order.Status)
+	_, err = store.CreateWave(ctx, models.WaveRequest{Zone: "A"})
+	var validation *ValidationError
+	assert.ErrorAs(t, err, &validation, "Order 2 also holds stock in zone B")
+
+	// Tasks are claimed by one picker and completed by them alone
+	first, second := int(wave.Tasks[0].ID), int(wave.Tasks[1].ID)
+	_, err = store.ConfirmPickTask(ctx, first)
+	assert.ErrorIs(t, err, ErrConflict)
+	_, err = store.ClaimPickTask(ctx, first)
+	assert.Nil(t, err)
+	_, err = store.ClaimPickTask(WithActor(ctx, 4), first)
+	assert.ErrorIs(t, err, ErrConflict)
+	task, err := store.ConfirmPickTask(ctx, first)
+	assert.Nil(t, err)
+	assert.Equal(t, models.PickPicked, task.Status)
+
+	_, err = store.ClaimPickTask(ctx, second)
+	assert.Nil(t, err)
+	task, err = store.ShortPickTask(ctx, second, models.ShortPickRequest{Quantity: 0})
+	assert.Nil(t, err)
+	assert.Equal(t, models.PickShort, task.Status)
+
+	wave, err = store.GetWave(ctx, int(wave.ID))
+	assert.Nil(t, err)
+	assert.Equal(t, models.WaveCompleted, wave.Status)
+
+	// Picks leave the ledger and release what was reserved
+	ledger, err := store.GetStockTransactions(ctx, models.ListQuery{Sort: []string{"-id"}, Filters: map[string][]string{"itemId": {"1"}}})
+	assert.Nil(t, err)
+	pick := ledger.Data[0]
+	assert.Equal(t, models.StockTransaction{ID: pick.ID, Type: models.StockPick, ItemID: 1, FromLocationID: 1, Quantity: 4, Reason: models.ReasonPicked, Reference: "order:1", AccountID: 3, Created: pick.Created}, pick)
+	available, err := store.GetAvailability(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, models.Availability{ItemID: 1, OnHand: 6, Reserved: 5, Available: 1, Locations: []models.LocationAvailability{
+		{LocationID: 2, LocationCode: "B-01", Type: models.LocationPickFace, Active: true, OnHand: 6, Reserved: 5, Available: 1},
+	}}, available)
+
+	// Cancelling a waved order cancels its open tasks
+	wave, err = store.CreateWave(ctx, models.WaveRequest{})
+	assert.Nil(t, err)
+	_, err = store.TransitionOrder(ctx, 2, models.OrderCancelled)
+	assert.Nil(t, err)
+	tasks, err := store.GetPickTasks(ctx, models.ListQuery{Filters: map[string][]string{
+		"waveId": {fmt.Sprint(wave.ID)},
+		"status": {models.PickCancelled},
+	}})
+	assert.Nil(t, err)
+	assert.Len(t, tasks.Data, len(wave.Tasks))
+	wave, err = store.GetWave(ctx, int(wave.ID))
+	assert.Nil(t, err)
+	assert.Equal(t, models.WaveCompleted, wave.Status)
+}
+
+func TestMemoryStorePacking(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := WithActor(context.Background(), 3)
+
+	assert.Nil(t, store.AddItem(ctx, models.Item{ID: 1, Name: "beans", Weight: models.Weight{Value: 1, Unit: models.UnitPound}}))
+	assert.Nil(t, store.AddBox(ctx, models.Box{ID: 1, Item: models.Item{ID: 1}, Dimensions: models.Dimensions{Length: 4, Width: 4, Height: 4, Unit: models.UnitInch}, Count: 2}))
+	assert.Nil(t, store.AddLocation(ctx, models.Location{ID: 1, Code: "A-01", Type: models.LocationPickFace, Active: true}))
+	assert.Nil(t, store.AddInventory(ctx, models.Inventory{ID: 1, Item: models.Item{ID: 1}, Locations: []models.LocationData{{LocationID: 1, Count: 10}}}))
+	carton := models.Carton{ID: 1, Name: "cube", Dimensions: models.Dimensions{Length: 8, Width: 8, Height: 8, Unit: models.UnitInch}, TareWeight: models.Weight{Value: 1, Unit: models.UnitPound}, Active: true}
+	assert.Nil(t, store.AddCarton(ctx, carton))
+	err := store.AddCarton(ctx, models.Carton{ID: 2, Name: "cube"})
+	var constraint *constraintError
+	assert.ErrorAs(t, err, &constraint)
+	assert.Nil(t, store.AddOrder(ctx, models.Order{ID: 1, Status: models.OrderPlaced, Payload: []models.ItemGroup{{Item: models.Item{ID: 1}, Count: 5}}}))
+
+	suggestion, err := store.SuggestCartons(ctx, 1)
+	assert.Nil(t, err)
+	assert.Len(t, suggestion.Cartons, 1)
+	assert.Equal(t, 6.0, suggestion.Weight.Value)
+
+	// Packing waits for the picks
+	req := models.PackRequest{Cartons: []models.PackRequestCarton{{CartonID: 1, Contents: []models.ItemGroup{{Item: models.Item{ID: 1}, Count: 5}}}}}
+	_, err = store.PackOrder(ctx, 1, req)
+	assert.ErrorIs(t, err, ErrConflict)
+	wave, err := store.CreateWave(ctx, models.WaveRequest{})
+	assert.Nil(t, err)
+	_, err = store.ClaimPickTask(ctx, int(wave.Tasks[0].ID))
+	assert.Nil(t, err)
+	_, err = store.ConfirmPickTask(ctx, int(wave.Tasks[0].ID))
+	assert.Nil(t, err)
+
+	packing, err := store.PackOrder(ctx, 1, req)
+	assert.Nil(t, err)
+	assert.Len(t, packing.Cartons, 1)
+	assert.Equal(t, int64(3), packing.Cartons[0].AccountID)
+	assert.Equal(t, models.Packing{OrderID: 1, Cartons: packing.Cartons, Weight: models.Weight{Value: 6, Unit: models.UnitPound}, DimWeight: models.Weight{Value: 3.68, Unit: models.UnitPound}, BillableWeight: models.Weight{Value: 6, Unit: models.UnitPound}}, packing)
+	order, err := store.GetOrder(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, models.OrderPacked, order.Status)
+
+	// The packed carton is scanned by its license plate
+	sscc, err := licensePlate(packing.Cartons[0].ID)
+	assert.Nil(t, err)
+	assert.Equal(t, sscc, packing.Cartons[0].SSCC)
+	scanned, err := store.ResolveScan(ctx, models.Scan{SSCC: sscc})
+	assert.Nil(t, err)
+	assert.Equal(t, models.ScanLicensePlate, scanned.Kind)
+	assert.Equal(t, int64(1), scanned.Order.ID)
+	assert.Equal(t, "SHIP", scanned.Actions[len(scanned.Actions)-1].Name)
+
+	// Deleting a carton keeps what was packed into it
+	assert.Nil(t, store.DeleteCarton(ctx, 1))
+	packing, err = store.GetPacking(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "cube", packing.Cartons[0].Carton)
+	assert.Zero(t, packing.Cartons[0].CartonID)
+	_, err = store.GetPacking(ctx, 2)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestMemoryStoreUnitsOfMeasure(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	assert.Nil(t, store.AddItem(ctx, models.Item{ID: 1, UPC: "100"}))
+	assert.Nil(t, store.AddBox(ctx, models.Box{ID: 1, UPC: "112", Item: models.Item{ID: 1}, Count: 12}))
+	assert.Nil(t, store.SetUnitsOfMeasure(ctx, 1, []models.UnitOfMeasure{{ItemID: 1, UOM: models.UOMPallet, Quantity: 480}}))
+	assert.ErrorIs(t, store.SetUnitsOfMeasure(ctx, 2, nil), ErrNotFound)
+
+	units, err := store.GetUnitsOfMeasure(ctx, []int64{1, 2})
+	assert.Nil(t, err)
+	assert.Equal(t, map[int64][]models.UnitOfMeasure{1: {
+		{ItemID: 1, UOM: models.UOMEach, Quantity: 1, UPC: "100"},
+		{ItemID: 1, UOM: models.UOMCase, Quantity: 12, UPC: "112"},
+		{ItemID: 1, UOM: models.UOMPallet, Quantity: 480},
+	}}, units, "Unknown items are left out")
+
+	assert.Nil(t, store.DeleteItem(ctx, 1))
+	assert.Nil(t, store.AddItem(ctx, models.Item{ID: 1}))
+	units, err = store.GetUnitsOfMeasure(ctx, []int64{1})
+	assert.Nil(t, err)
+	assert.Len(t, units[1], 1, "Deleting an item drops its units of measure")
+}
+
+func TestMemoryStoreResolveScan(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	assert.Nil(t, store.AddItem(ctx, models.Item{ID: 1, UPC: "00036000291452", Name: "beans"}))
+	assert.Nil(t, store.AddBox(ctx, models.Box{ID: 2, UPC: "10036000291459", Item: models.Item{ID: 1}, Count: 12}))
+	assert.Nil(t, store.SetUnitsOfMeasure(ctx, 1, []models.UnitOfMeasure{{ItemID: 1, UOM: models.UOMPallet, Quantity: 480, UPC: "20036000291456"}}))
+	assert.Nil(t, store.AddLocation(ctx, models.Location{ID: 3, Code: "A-01-02", Active: true}))
+	assert.Nil(t, store.AddOrder(ctx, models.Order{ID: 4, Status: models.OrderPlaced}))
+
+	result, err := store.ResolveScan(ctx, models.Scan{GTIN: "00036000291452"})
+	assert.Nil(t, err)
+	assert.Equal(t, models.ScanItem, result.Kind)
+	assert.Equal(t, "beans", result.Item.Name)
+	assert.Equal(t, models.UOMEach, result.Unit.UOM)
+
+	result, err = store.ResolveScan(ctx, models.Scan{GTIN: "10036000291459"})
+	assert.Nil(t, err)
+	assert.Equal(t, models.ScanBox, result.Kind)
+	assert.Equal(t, int64(2), result.Box.ID)
+	assert.Equal(t, "beans", result.Box.Item.Name)
+	assert.Equal(t, models.UnitOfMeasure{ItemID: 1, UOM: models.UOMCase, Quantity: 12, UPC: "10036000291459"}, *result.Unit, "The box is the case")
+
+	result, err = store.ResolveScan(ctx, models.Scan{GTIN: "20036000291456"})
+	assert.Nil(t, err)
+	assert.Equal(t, models.ScanItem, result.Kind)
+	assert.Equal(t, models.UOMPallet, result.Unit.UOM)
+
+	result, err = store.ResolveScan(ctx, models.Scan{Code: "a-01-02"})
+	assert.Nil(t, err)
+	assert.Equal(t, models.ScanLocation, result.Kind)
+	assert.Equal(t, int64(3), result.Location.ID)
+
+	result, err = store.ResolveScan(ctx, models.Scan{Code: "order:4"})
+	assert.Nil(t, err)
+	assert.Equal(t, models.ScanOrder, result.Kind)
+	assert.Equal(t, models.OrderAllocated, result.Order.Status, "Placing an empty order allocates it")
+	assert.Equal(t, "CREATE_WAVE", result.Actions[1].Name)
+
+	for _, scan := range []models.Scan{{GTIN: "04006381333931"}, {SSCC: "006141411234567890"}, {Code: "order:5"}, {Code: "B-01"}} {
+		_, err := store.ResolveScan(ctx, scan)
+		assert.ErrorIs(t, err, ErrNotFound, scan)
+	}
+}
+
+func TestMemoryStoreConcurrentWrites(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 50; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			store.AddShipment(ctx, models.Shipment{ID: int64(id), Distributor: fmt.Sprint("carrier-", id)})
+			store.GetShipments(ctx, models.ListQuery{})
+		}(i)
+	}
+	wg.Wait()
+
+	shipments, err := store.GetShipments(ctx, models.ListQuery{Limit: models.MaxPageLimit})
+	assert.Nil(t, err)
+	assert.Len(t, shipments.Data, 50)
+}
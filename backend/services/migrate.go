@@ -0,0 +1,248 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockKey is the pg_advisory_lock key held while migrating so two
+// replicas starting at once never apply the same migration twice.
+const migrationLockKey int64 = 0x574d53 // "WMS"
+
+type Migration struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Up        string     `json:"-"`
+	Down      string     `json:"-"`
+	AppliedAt *time.Time `json:"appliedAt"`
+}
+
+// LoadMigrations reads the embedded migrations/NNNN_name.{up,down}.sql files
+// and returns them ordered by version.
+func LoadMigrations() ([]Migration, error) {
+	return loadMigrations(migrationFiles, "migrations")
+}
+
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		name := entry.Name()
+		base, direction, ok := strings.Cut(strings.TrimSuffix(name, ".sql"), ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("invalid migration file name: %s", name)
+		}
+		versionStr, label, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("invalid migration file name: %s", name)
+		}
+		version, err := strconv.ParseInt(versionStr, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", name, err)
+		}
+
+		body, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: label}
+			byVersion[version] = m
+		}
+		if m.Name != label {
+			return nil, fmt.Errorf("migration %d has mismatched names: %s, %s", version, m.Name, label)
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both up and down files", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return int(a.Version - b.Version)
+	})
+	return migrations, nil
+}
+
+// withMigrationLock runs fn on a single connection holding the migration
+// advisory lock, creating the schema_migrations table if needed.
+func (s *PostgresStore) withMigrationLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	fmt.Println("Waiting for migration lock...")
+	if _, err := conn.Exec(ctx, "select pg_advisory_lock($1)", migrationLockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer conn.Exec(context.Background(), "select pg_advisory_unlock($1)", migrationLockKey)
+
+	_, err = conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version BIGINT PRIMARY KEY NOT NULL,
+    name VARCHAR(128) NOT NULL,
+    applied_at TIMESTAMP NOT NULL
+)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	return fn(conn)
+}
+
+func appliedVersions(ctx context.Context, conn *pgxpool.Conn) (map[int64]time.Time, error) {
+	rows, _ := conn.Query(ctx, "select version, applied_at from schema_migrations")
+	applied := map[int64]time.Time{}
+	var version int64
+	var appliedAt time.Time
+	_, err := pgx.ForEachRow(rows, []any{&version, &appliedAt}, func() error {
+		applied[version] = appliedAt
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+func runMigration(ctx context.Context, conn *pgxpool.Conn, m Migration, up bool) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if up {
+		if _, err := tx.Exec(ctx, m.Up); err != nil {
+			return fmt.Errorf("migration %d_%s up failed: %w", m.Version, m.Name, err)
+		}
+		_, err = tx.Exec(ctx, "insert into schema_migrations (version, name, applied_at) values ($1, $2, $3)", m.Version, m.Name, time.Now())
+	} else {
+		if _, err := tx.Exec(ctx, m.Down); err != nil {
+			return fmt.Errorf("migration %d_%s down failed: %w", m.Version, m.Name, err)
+		}
+		_, err = tx.Exec(ctx, "delete from schema_migrations where version=$1", m.Version)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// MigrateUp applies every pending migration in order and returns the ones
+// it applied.
+func (s *PostgresStore) MigrateUp(ctx context.Context) ([]Migration, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	err = s.withMigrationLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			if _, ok := applied[m.Version]; ok {
+				continue
+			}
+			fmt.Printf("Applying migration %d_%s...\n", m.Version, m.Name)
+			if err := runMigration(ctx, conn, m, true); err != nil {
+				return err
+			}
+			done = append(done, m)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// MigrateDown reverts the newest steps applied migrations and returns the
+// ones it reverted.
+func (s *PostgresStore) MigrateDown(ctx context.Context, steps int) ([]Migration, error) {
+	if steps < 1 {
+		return nil, errors.New("steps must be at least 1")
+	}
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	err = s.withMigrationLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range slices.Backward(migrations) {
+			if len(done) == steps {
+				break
+			}
+			if _, ok := applied[m.Version]; !ok {
+				continue
+			}
+			fmt.Printf("Reverting migration %d_%s...\n", m.Version, m.Name)
+			if err := runMigration(ctx, conn, m, false); err != nil {
+				return err
+			}
+			done = append(done, m)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// MigrationStatus lists every known migration with its applied time, nil
+// when still pending.
+func (s *PostgresStore) MigrationStatus(ctx context.Context) ([]Migration, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.withMigrationLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i, m := range migrations {
+			if at, ok := applied[m.Version]; ok {
+				migrations[i].AppliedAt = &at
+			}
+		}
+		return nil
+	})
+	return migrations, err
+}
@@ -0,0 +1,53 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := LoadMigrations()
+	assert.Nil(t, err)
+	assert.NotEmpty(t, migrations)
+
+	for i, m := range migrations {
+		assert.Equal(t, int64(i+1), m.Version, "Migration versions should be contiguous")
+		assert.NotEmpty(t, m.Up)
+		assert.NotEmpty(t, m.Down)
+	}
+}
+
+func TestLoadMigrationsOrdering(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/0002_second.up.sql":   {Data: []byte("select 2")},
+		"m/0002_second.down.sql": {Data: []byte("select -2")},
+		"m/0001_first.up.sql":    {Data: []byte("select 1")},
+		"m/0001_first.down.sql":  {Data: []byte("select -1")},
+	}
+	migrations, err := loadMigrations(fsys, "m")
+	assert.Nil(t, err)
+	assert.Equal(t, "first", migrations[0].Name)
+	assert.Equal(t, "select -2", migrations[1].Down)
+}
+
+func TestLoadMigrationsRejectsBadFiles(t *testing.T) {
+	_, err := loadMigrations(fstest.MapFS{
+		"m/0001_first.up.sql": {Data: []byte("select 1")},
+	}, "m")
+	assert.NotNil(t, err, "Missing down file")
+
+	_, err = loadMigrations(fstest.MapFS{
+		"m/first.up.sql":   {Data: []byte("select 1")},
+		"m/first.down.sql": {Data: []byte("select 1")},
+	}, "m")
+	assert.NotNil(t, err, "Missing version")
+
+	_, err = loadMigrations(fstest.MapFS{
+		"m/0001_first.sideways.sql": {Data: []byte("select 1")},
+	}, "m")
+	assert.NotNil(t, err, "Unknown direction")
+}
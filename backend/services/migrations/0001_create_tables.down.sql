@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS inventory;
+DROP TABLE IF EXISTS box;
+DROP TABLE IF EXISTS shipment;
+DROP TABLE IF EXISTS order_data;
+DROP TABLE IF EXISTS item;
+DROP TABLE IF EXISTS account;
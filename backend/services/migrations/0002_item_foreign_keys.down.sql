@@ -0,0 +1,15 @@
+ALTER TABLE box ADD COLUMN item JSON;
+UPDATE box SET item = (
+    SELECT json_build_object('id', i.id, 'upc', i.upc, 'name', i.name, 'description', i.description, 'weight', i.weight, 'image', NULL)
+    FROM item i WHERE i.id = box.item_id
+);
+ALTER TABLE box ALTER COLUMN item SET NOT NULL;
+ALTER TABLE box DROP COLUMN item_id;
+
+ALTER TABLE inventory ADD COLUMN item JSON;
+UPDATE inventory SET item = (
+    SELECT json_build_object('id', i.id, 'upc', i.upc, 'name', i.name, 'description', i.description, 'weight', i.weight, 'image', NULL)
+    FROM item i WHERE i.id = inventory.item_id
+);
+ALTER TABLE inventory ALTER COLUMN item SET NOT NULL;
+ALTER TABLE inventory DROP COLUMN item_id;
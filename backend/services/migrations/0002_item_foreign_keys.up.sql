@@ -0,0 +1,42 @@
+-- Recreate items that only exist as JSON snapshots so every row can be linked.
+INSERT INTO item (id, upc, name, description, weight)
+SELECT DISTINCT ON ((snapshot->>'id')::INT)
+    (snapshot->>'id')::INT,
+    COALESCE(snapshot->>'upc', ''),
+    COALESCE(snapshot->>'name', ''),
+    COALESCE(snapshot->>'description', ''),
+    COALESCE((snapshot->>'weight')::DOUBLE PRECISION, 0)
+FROM (
+    SELECT item AS snapshot FROM box
+    UNION ALL
+    SELECT item AS snapshot FROM inventory
+) snapshots
+WHERE snapshot->>'id' IS NOT NULL
+ON CONFLICT (id) DO NOTHING;
+
+SELECT setval(pg_get_serial_sequence('item', 'id'), GREATEST((SELECT MAX(id) FROM item), 1));
+
+ALTER TABLE box ADD COLUMN item_id INT;
+UPDATE box SET item_id = COALESCE(
+    (item->>'id')::INT,
+    (SELECT id FROM item WHERE upc = box.item->>'upc' ORDER BY id LIMIT 1)
+);
+ALTER TABLE box ALTER COLUMN item_id SET NOT NULL;
+ALTER TABLE box DROP COLUMN item;
+-- Packaging definitions are meaningless without their item.
+ALTER TABLE box ADD CONSTRAINT box_item_id_fkey
+    FOREIGN KEY (item_id) REFERENCES item (id) ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE inventory ADD COLUMN item_id INT;
+UPDATE inventory SET item_id = COALESCE(
+    (item->>'id')::INT,
+    (SELECT id FROM item WHERE upc = inventory.item->>'upc' ORDER BY id LIMIT 1)
+);
+ALTER TABLE inventory ALTER COLUMN item_id SET NOT NULL;
+ALTER TABLE inventory DROP COLUMN item;
+-- Stock records must be removed explicitly before their item can be deleted.
+ALTER TABLE inventory ADD CONSTRAINT inventory_item_id_fkey
+    FOREIGN KEY (item_id) REFERENCES item (id) ON UPDATE CASCADE ON DELETE RESTRICT;
+
+CREATE INDEX IF NOT EXISTS box_item_id_idx ON box (item_id);
+CREATE INDEX IF NOT EXISTS inventory_item_id_idx ON inventory (item_id);
@@ -0,0 +1,15 @@
+ALTER TABLE inventory DROP CONSTRAINT IF EXISTS inventory_item_id_key;
+ALTER TABLE inventory ADD COLUMN total INT NOT NULL DEFAULT 0;
+ALTER TABLE inventory ADD COLUMN locations JSON NOT NULL DEFAULT '[]';
+UPDATE inventory v SET
+    total = COALESCE((SELECT SUM(s.quantity) FROM stock s WHERE s.item_id = v.item_id), 0),
+    locations = COALESCE((
+        SELECT json_agg(json_build_object('area', l.code, 'count', s.quantity) ORDER BY l.code)
+        FROM stock s JOIN location l ON l.id = s.location_id
+        WHERE s.item_id = v.item_id
+    ), '[]'::JSON);
+ALTER TABLE inventory ALTER COLUMN total DROP DEFAULT;
+ALTER TABLE inventory ALTER COLUMN locations DROP DEFAULT;
+
+DROP TABLE IF EXISTS stock;
+DROP TABLE IF EXISTS location;
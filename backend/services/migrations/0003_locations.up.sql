@@ -0,0 +1,45 @@
+CREATE TABLE IF NOT EXISTS location (
+    id SERIAL PRIMARY KEY NOT NULL,
+    code VARCHAR(84) NOT NULL UNIQUE,
+    zone VARCHAR(16) NOT NULL,
+    aisle VARCHAR(16) NOT NULL DEFAULT '',
+    rack VARCHAR(16) NOT NULL DEFAULT '',
+    shelf VARCHAR(16) NOT NULL DEFAULT '',
+    bin VARCHAR(16) NOT NULL DEFAULT '',
+    type VARCHAR(16) NOT NULL CHECK (type IN ('PICK_FACE', 'RESERVE', 'DOCK', 'STAGING')),
+    capacity INT NOT NULL DEFAULT 0 CHECK (capacity >= 0),
+    max_weight DOUBLE PRECISION NOT NULL DEFAULT 0 CHECK (max_weight >= 0),
+    active BOOLEAN NOT NULL DEFAULT TRUE
+);
+CREATE TABLE IF NOT EXISTS stock (
+    item_id INT NOT NULL REFERENCES item (id) ON UPDATE CASCADE ON DELETE RESTRICT,
+    location_id INT NOT NULL REFERENCES location (id) ON UPDATE CASCADE ON DELETE RESTRICT,
+    quantity INT NOT NULL CHECK (quantity >= 0),
+    PRIMARY KEY (item_id, location_id)
+);
+CREATE INDEX IF NOT EXISTS stock_location_id_idx ON stock (location_id);
+
+-- Legacy locations are either [{"area": "A21", "count": 5}] or {"A21": 5}.
+CREATE TEMP TABLE legacy_stock ON COMMIT DROP AS
+SELECT v.item_id, upper(trim(e->>'area')) AS area, (e->>'count')::INT AS quantity
+FROM inventory v,
+    json_array_elements(CASE WHEN json_typeof(v.locations) = 'array' THEN v.locations ELSE '[]'::JSON END) e
+UNION ALL
+SELECT v.item_id, upper(trim(o.key)), (o.value #>> '{}')::INT
+FROM inventory v,
+    json_each(CASE WHEN json_typeof(v.locations) = 'object' THEN v.locations ELSE '{}'::JSON END) o;
+
+INSERT INTO location (code, zone, type)
+SELECT DISTINCT area, area, 'RESERVE' FROM legacy_stock WHERE area IS NOT NULL AND area <> ''
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO stock (item_id, location_id, quantity)
+SELECT l.item_id, loc.id, SUM(GREATEST(l.quantity, 0))
+FROM legacy_stock l JOIN location loc ON loc.code = l.area
+GROUP BY l.item_id, loc.id;
+
+-- One inventory record per item, its stock now lives in per-location rows.
+DELETE FROM inventory a USING inventory b WHERE a.item_id = b.item_id AND a.id > b.id;
+ALTER TABLE inventory DROP COLUMN total;
+ALTER TABLE inventory DROP COLUMN locations;
+ALTER TABLE inventory ADD CONSTRAINT inventory_item_id_key UNIQUE (item_id);
@@ -0,0 +1,16 @@
+CREATE TEMP TABLE ledger_balance ON COMMIT DROP AS
+SELECT item_id, location_id, quantity FROM stock WHERE quantity > 0;
+
+DROP VIEW IF EXISTS stock;
+CREATE TABLE IF NOT EXISTS stock (
+    item_id INT NOT NULL REFERENCES item (id) ON UPDATE CASCADE ON DELETE RESTRICT,
+    location_id INT NOT NULL REFERENCES location (id) ON UPDATE CASCADE ON DELETE RESTRICT,
+    quantity INT NOT NULL CHECK (quantity >= 0),
+    PRIMARY KEY (item_id, location_id)
+);
+CREATE INDEX IF NOT EXISTS stock_location_id_idx ON stock (location_id);
+INSERT INTO stock (item_id, location_id, quantity)
+SELECT item_id, location_id, quantity FROM ledger_balance;
+
+DROP TABLE IF EXISTS stock_transaction;
+DROP FUNCTION IF EXISTS stock_transaction_append_only();
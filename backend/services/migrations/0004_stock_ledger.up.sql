@@ -0,0 +1,45 @@
+CREATE TABLE IF NOT EXISTS stock_transaction (
+    id BIGSERIAL PRIMARY KEY NOT NULL,
+    type VARCHAR(16) NOT NULL CHECK (type IN ('RECEIPT', 'PICK', 'MOVE', 'ADJUST', 'COUNT')),
+    item_id INT NOT NULL REFERENCES item (id),
+    from_location_id INT REFERENCES location (id),
+    to_location_id INT REFERENCES location (id),
+    quantity INT NOT NULL CHECK (quantity > 0),
+    reason VARCHAR(32) NOT NULL,
+    reference VARCHAR(64) NOT NULL DEFAULT '',
+    account_id INT,
+    created TIMESTAMP NOT NULL DEFAULT now(),
+    CHECK (from_location_id IS NOT NULL OR to_location_id IS NOT NULL),
+    CHECK (from_location_id IS DISTINCT FROM to_location_id)
+);
+CREATE INDEX IF NOT EXISTS stock_transaction_item_id_idx ON stock_transaction (item_id);
+CREATE INDEX IF NOT EXISTS stock_transaction_from_location_id_idx ON stock_transaction (from_location_id);
+CREATE INDEX IF NOT EXISTS stock_transaction_to_location_id_idx ON stock_transaction (to_location_id);
+
+-- The ledger is append-only, corrections are new entries.
+CREATE OR REPLACE FUNCTION stock_transaction_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'stock_transaction is append-only';
+END;
+$$ LANGUAGE plpgsql;
+CREATE TRIGGER stock_transaction_append_only BEFORE UPDATE OR DELETE ON stock_transaction
+    FOR EACH ROW EXECUTE FUNCTION stock_transaction_append_only();
+
+-- Current stock becomes the opening balance of the ledger.
+INSERT INTO stock_transaction (type, item_id, to_location_id, quantity, reason)
+SELECT 'COUNT', item_id, location_id, quantity, 'OPENING_BALANCE'
+FROM stock WHERE quantity > 0
+ORDER BY item_id, location_id;
+
+-- Balances are derived from the ledger from now on.
+DROP TABLE stock;
+CREATE VIEW stock AS
+SELECT item_id, location_id, SUM(quantity)::INT AS quantity
+FROM (
+    SELECT item_id, to_location_id AS location_id, quantity
+    FROM stock_transaction WHERE to_location_id IS NOT NULL
+    UNION ALL
+    SELECT item_id, from_location_id, -quantity
+    FROM stock_transaction WHERE from_location_id IS NOT NULL
+) entries
+GROUP BY item_id, location_id;
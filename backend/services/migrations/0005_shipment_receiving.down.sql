@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS shipment_status_idx;
+ALTER TABLE shipment DROP COLUMN IF EXISTS receipt;
+ALTER TABLE shipment DROP COLUMN IF EXISTS receiving_location_id;
+ALTER TABLE shipment DROP COLUMN IF EXISTS received;
+ALTER TABLE shipment DROP COLUMN IF EXISTS arrived;
+ALTER TABLE shipment DROP COLUMN IF EXISTS status;
@@ -0,0 +1,7 @@
+ALTER TABLE shipment ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'EXPECTED'
+    CHECK (status IN ('EXPECTED', 'ARRIVED', 'RECEIVED'));
+ALTER TABLE shipment ADD COLUMN arrived TIMESTAMP;
+ALTER TABLE shipment ADD COLUMN received TIMESTAMP;
+ALTER TABLE shipment ADD COLUMN receiving_location_id INT REFERENCES location (id);
+ALTER TABLE shipment ADD COLUMN receipt JSON NOT NULL DEFAULT '[]';
+CREATE INDEX IF NOT EXISTS shipment_status_idx ON shipment (status);
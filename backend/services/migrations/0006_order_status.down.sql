@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS order_transition;
+DROP INDEX IF EXISTS order_data_status_idx;
+ALTER TABLE order_data DROP COLUMN IF EXISTS status;
@@ -0,0 +1,15 @@
+-- Orders placed before statuses existed are treated as placed.
+ALTER TABLE order_data ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'PLACED'
+    CHECK (status IN ('DRAFT', 'PLACED', 'ALLOCATED', 'PICKING', 'PACKED', 'SHIPPED', 'DELIVERED', 'CANCELLED'));
+ALTER TABLE order_data ALTER COLUMN status SET DEFAULT 'DRAFT';
+CREATE INDEX IF NOT EXISTS order_data_status_idx ON order_data (status);
+
+CREATE TABLE IF NOT EXISTS order_transition (
+    id BIGSERIAL PRIMARY KEY NOT NULL,
+    order_id INT NOT NULL REFERENCES order_data (id) ON DELETE CASCADE,
+    from_status VARCHAR(16) NOT NULL,
+    to_status VARCHAR(16) NOT NULL,
+    account_id INT,
+    created TIMESTAMP NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS order_transition_order_id_idx ON order_transition (order_id);
@@ -0,0 +1,13 @@
+-- Reservations hold stock for order lines between placement and picking.
+-- Available-to-promise is on-hand stock less the reserved quantity.
+CREATE TABLE IF NOT EXISTS reservation (
+    id BIGSERIAL PRIMARY KEY NOT NULL,
+    order_id INT NOT NULL REFERENCES order_data (id) ON DELETE CASCADE,
+    line INT NOT NULL CHECK (line >= 0),
+    item_id INT NOT NULL REFERENCES item (id),
+    location_id INT NOT NULL REFERENCES location (id),
+    quantity INT NOT NULL CHECK (quantity > 0),
+    created TIMESTAMP NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS reservation_order_id_idx ON reservation (order_id);
+CREATE INDEX IF NOT EXISTS reservation_item_location_idx ON reservation (item_id, location_id);
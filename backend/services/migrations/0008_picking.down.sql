@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS pick_task;
+DROP TABLE IF EXISTS wave;
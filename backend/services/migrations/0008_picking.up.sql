@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS wave (
+    id BIGSERIAL PRIMARY KEY NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'OPEN' CHECK (status IN ('OPEN', 'COMPLETED')),
+    zone VARCHAR(16) NOT NULL DEFAULT '',
+    cutoff TIMESTAMP,
+    max_orders INT NOT NULL DEFAULT 0 CHECK (max_orders >= 0),
+    account_id INT,
+    created TIMESTAMP NOT NULL DEFAULT now(),
+    completed TIMESTAMP
+);
+
+-- A task picks one reservation. The reservation is removed once picked, so
+-- the task keeps its own copy of the item, location and quantity.
+CREATE TABLE IF NOT EXISTS pick_task (
+    id BIGSERIAL PRIMARY KEY NOT NULL,
+    wave_id BIGINT NOT NULL REFERENCES wave (id) ON DELETE CASCADE,
+    order_id INT NOT NULL REFERENCES order_data (id) ON DELETE CASCADE,
+    line INT NOT NULL CHECK (line >= 0),
+    reservation_id BIGINT REFERENCES reservation (id) ON DELETE SET NULL,
+    item_id INT NOT NULL REFERENCES item (id),
+    location_id INT NOT NULL REFERENCES location (id),
+    sequence INT NOT NULL,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    picked INT NOT NULL DEFAULT 0 CHECK (picked >= 0 AND picked <= quantity),
+    status VARCHAR(16) NOT NULL DEFAULT 'OPEN' CHECK (status IN ('OPEN', 'CLAIMED', 'PICKED', 'SHORT', 'CANCELLED')),
+    account_id INT,
+    updated TIMESTAMP NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS pick_task_wave_id_idx ON pick_task (wave_id);
+CREATE INDEX IF NOT EXISTS pick_task_order_id_idx ON pick_task (order_id);
+CREATE INDEX IF NOT EXISTS pick_task_status_idx ON pick_task (status);
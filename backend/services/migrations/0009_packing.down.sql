@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS packed_carton;
+DROP TABLE IF EXISTS carton;
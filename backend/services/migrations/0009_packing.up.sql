@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS carton (
+    id SERIAL PRIMARY KEY NOT NULL,
+    name VARCHAR(64) NOT NULL UNIQUE,
+    length DOUBLE PRECISION NOT NULL CHECK (length > 0),
+    width DOUBLE PRECISION NOT NULL CHECK (width > 0),
+    height DOUBLE PRECISION NOT NULL CHECK (height > 0),
+    unit VARCHAR(8) NOT NULL DEFAULT 'in' CHECK (unit IN ('in', 'cm', 'mm')),
+    max_weight DOUBLE PRECISION NOT NULL DEFAULT 0 CHECK (max_weight >= 0),
+    tare_weight DOUBLE PRECISION NOT NULL DEFAULT 0 CHECK (tare_weight >= 0),
+    active BOOLEAN NOT NULL DEFAULT TRUE
+);
+
+-- Packed cartons keep a copy of the carton so later catalog changes do not
+-- rewrite what was shipped.
+CREATE TABLE IF NOT EXISTS packed_carton (
+    id BIGSERIAL PRIMARY KEY NOT NULL,
+    order_id INT NOT NULL REFERENCES order_data (id) ON DELETE CASCADE,
+    carton_id INT REFERENCES carton (id) ON DELETE SET NULL,
+    carton VARCHAR(64) NOT NULL,
+    length DOUBLE PRECISION NOT NULL,
+    width DOUBLE PRECISION NOT NULL,
+    height DOUBLE PRECISION NOT NULL,
+    unit VARCHAR(8) NOT NULL,
+    contents JSON NOT NULL,
+    weight DOUBLE PRECISION NOT NULL,
+    dim_weight DOUBLE PRECISION NOT NULL,
+    account_id INT,
+    created TIMESTAMP NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS packed_carton_order_id_idx ON packed_carton (order_id);
@@ -0,0 +1,18 @@
+-- Weights go back to the pounds unitless weights were read as.
+UPDATE item SET weight = weight / CASE weight_unit WHEN 'kg' THEN 0.45359237 WHEN 'g' THEN 453.59237 ELSE 1 END;
+UPDATE carton SET
+    max_weight = max_weight / CASE weight_unit WHEN 'kg' THEN 0.45359237 WHEN 'g' THEN 453.59237 ELSE 1 END,
+    tare_weight = tare_weight / CASE weight_unit WHEN 'kg' THEN 0.45359237 WHEN 'g' THEN 453.59237 ELSE 1 END;
+ALTER TABLE packed_carton DROP COLUMN IF EXISTS weight_unit;
+ALTER TABLE carton DROP COLUMN IF EXISTS weight_unit;
+ALTER TABLE item DROP COLUMN IF EXISTS weight_unit;
+
+ALTER TABLE box ADD COLUMN dimensions VARCHAR(128);
+UPDATE box SET dimensions = length || 'x' || width || 'x' || height || CASE WHEN unit = 'in' THEN '' ELSE ' ' || unit END;
+ALTER TABLE box
+    ALTER COLUMN dimensions SET NOT NULL,
+    DROP CONSTRAINT IF EXISTS box_dimensions_check,
+    DROP COLUMN length,
+    DROP COLUMN width,
+    DROP COLUMN height,
+    DROP COLUMN unit;
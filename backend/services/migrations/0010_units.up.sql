@@ -0,0 +1,43 @@
+-- Box dimensions were free text such as "2x2x4" or "30 x 20 x 10 cm".
+-- Boxes that cannot be read stop the migration so they can be fixed first.
+DO $$
+DECLARE
+    unreadable TEXT;
+BEGIN
+    SELECT string_agg(id || ' (' || dimensions || ')', ', ' ORDER BY id) INTO unreadable
+    FROM box
+    WHERE lower(trim(dimensions)) !~ '^\d*\.?\d+\s*x\s*\d*\.?\d+\s*x\s*\d*\.?\d+\s*(in|cm|mm)?$';
+    IF unreadable IS NOT NULL THEN
+        RAISE EXCEPTION 'box dimensions must look like LxWxH: %', unreadable;
+    END IF;
+END $$;
+
+ALTER TABLE box
+    ADD COLUMN length DOUBLE PRECISION,
+    ADD COLUMN width DOUBLE PRECISION,
+    ADD COLUMN height DOUBLE PRECISION,
+    ADD COLUMN unit VARCHAR(8) NOT NULL DEFAULT 'in' CHECK (unit IN ('in', 'cm', 'mm'));
+-- Dimensions written without a unit were entered in inches.
+UPDATE box SET
+    length = split_part(sides, 'x', 1)::DOUBLE PRECISION,
+    width = split_part(sides, 'x', 2)::DOUBLE PRECISION,
+    height = split_part(sides, 'x', 3)::DOUBLE PRECISION,
+    unit = COALESCE(NULLIF(suffix, ''), 'in')
+FROM (
+    SELECT id,
+        regexp_replace(lower(dimensions), '[^0-9.x]', '', 'g') AS sides,
+        substring(lower(trim(dimensions)) FROM '(in|cm|mm)$') AS suffix
+    FROM box
+) parsed
+WHERE parsed.id = box.id;
+ALTER TABLE box
+    ALTER COLUMN length SET NOT NULL,
+    ALTER COLUMN width SET NOT NULL,
+    ALTER COLUMN height SET NOT NULL,
+    ADD CONSTRAINT box_dimensions_check CHECK (length > 0 AND width > 0 AND height > 0),
+    DROP COLUMN dimensions;
+
+-- Item weights were entered without a unit and are taken to be pounds.
+ALTER TABLE item ADD COLUMN weight_unit VARCHAR(4) NOT NULL DEFAULT 'lb' CHECK (weight_unit IN ('lb', 'kg', 'g'));
+ALTER TABLE carton ADD COLUMN weight_unit VARCHAR(4) NOT NULL DEFAULT 'lb' CHECK (weight_unit IN ('lb', 'kg', 'g'));
+ALTER TABLE packed_carton ADD COLUMN weight_unit VARCHAR(4) NOT NULL DEFAULT 'lb' CHECK (weight_unit IN ('lb', 'kg', 'g'));
@@ -0,0 +1,9 @@
+-- Pack levels of an item above the each, which every quantity is stored
+-- in. Items without a case level use their smallest box as the case.
+CREATE TABLE IF NOT EXISTS item_uom (
+    item_id INT NOT NULL REFERENCES item (id) ON DELETE CASCADE,
+    uom VARCHAR(16) NOT NULL CHECK (uom IN ('INNER', 'CASE', 'PALLET')),
+    quantity BIGINT NOT NULL CHECK (quantity > 1),
+    upc VARCHAR(128) NOT NULL DEFAULT '',
+    PRIMARY KEY (item_id, uom)
+);
@@ -0,0 +1,3 @@
+-- Codes stay padded to GTIN-14, which older versions read as any other
+-- string.
+DROP INDEX IF EXISTS item_upc_key;
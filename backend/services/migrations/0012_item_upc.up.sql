@@ -0,0 +1,43 @@
+-- Codes are stored as GTIN-14: EAN-8, UPC-A and EAN-13 codes are padded
+-- with zeros so the same product reads the same however it was entered.
+UPDATE item SET upc = lpad(trim(upc), 14, '0') WHERE trim(upc) ~ '^(\d{8}|\d{12,14})$';
+UPDATE box SET upc = lpad(trim(upc), 14, '0') WHERE trim(upc) ~ '^(\d{8}|\d{12,14})$';
+UPDATE item_uom SET upc = lpad(trim(upc), 14, '0') WHERE trim(upc) ~ '^(\d{8}|\d{12,14})$';
+
+-- Codes with a wrong check digit are left as they are and listed so they
+-- can be corrected; the API refuses them from now on.
+DO $$
+DECLARE
+    bad TEXT;
+BEGIN
+    SELECT string_agg(id || ' (' || upc || ')', ', ' ORDER BY id) INTO bad
+    FROM item
+    WHERE CASE WHEN upc ~ '^\d{14}$'
+        THEN (10 - (SELECT sum(substr(upc, i, 1)::int * CASE WHEN i % 2 = 1 THEN 3 ELSE 1 END) FROM generate_series(1, 13) i) % 10) % 10 <> substr(upc, 14, 1)::int
+        ELSE TRUE
+    END;
+    IF bad IS NOT NULL THEN
+        RAISE NOTICE 'items with an invalid UPC: %', bad;
+    END IF;
+END $$;
+
+-- Two items cannot share a UPC. Duplicates stop the migration so they can
+-- be merged first.
+DO $$
+DECLARE
+    duplicates TEXT;
+BEGIN
+    SELECT string_agg(upc || ' (items ' || ids || ')', ', ' ORDER BY upc) INTO duplicates
+    FROM (
+        SELECT upc, string_agg(id::text, ', ' ORDER BY id) AS ids
+        FROM item
+        WHERE upc <> ''
+        GROUP BY upc
+        HAVING count(*) > 1
+    ) d;
+    IF duplicates IS NOT NULL THEN
+        RAISE EXCEPTION 'item UPCs must be unique: %', duplicates;
+    END IF;
+END $$;
+
+CREATE UNIQUE INDEX IF NOT EXISTS item_upc_key ON item (upc) WHERE upc <> '';
@@ -0,0 +1 @@
+ALTER TABLE packed_carton DROP COLUMN IF EXISTS sscc;
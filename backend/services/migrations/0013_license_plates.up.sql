@@ -0,0 +1,3 @@
+-- Packed cartons are labelled with an SSCC, the license plate they are
+-- scanned by. Cartons packed before have none.
+ALTER TABLE packed_carton ADD COLUMN IF NOT EXISTS sscc VARCHAR(18) UNIQUE;
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS location_trgm_idx;
+DROP INDEX IF EXISTS location_search_idx;
+DROP INDEX IF EXISTS account_trgm_idx;
+DROP INDEX IF EXISTS account_search_idx;
+DROP INDEX IF EXISTS order_data_trgm_idx;
+DROP INDEX IF EXISTS order_data_search_idx;
+DROP INDEX IF EXISTS item_trgm_idx;
+DROP INDEX IF EXISTS item_search_idx;
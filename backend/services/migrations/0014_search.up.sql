@@ -0,0 +1,16 @@
+-- Search matches words by prefix with full-text search and misspelled
+-- words by trigram similarity. The indexed expressions are the ones the
+-- search queries use, see searchSources.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS item_search_idx ON item USING GIN ((setweight(to_tsvector('simple', name || ' ' || upc), 'A') || setweight(to_tsvector('simple', coalesce(description, '')), 'B')));
+CREATE INDEX IF NOT EXISTS item_trgm_idx ON item USING GIN ((name || ' ' || upc || ' ' || coalesce(description, '')) gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS order_data_search_idx ON order_data USING GIN ((setweight(to_tsvector('simple', coalesce(customer->>'firstname', '') || ' ' || coalesce(customer->>'lastname', '')), 'A') || setweight(to_tsvector('simple', address), 'B')));
+CREATE INDEX IF NOT EXISTS order_data_trgm_idx ON order_data USING GIN ((coalesce(customer->>'firstname', '') || ' ' || coalesce(customer->>'lastname', '') || ' ' || address) gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS account_search_idx ON account USING GIN ((setweight(to_tsvector('simple', firstname || ' ' || lastname || ' ' || username), 'A') || setweight(to_tsvector('simple', coalesce(email, '')), 'B')));
+CREATE INDEX IF NOT EXISTS account_trgm_idx ON account USING GIN ((firstname || ' ' || lastname || ' ' || username || ' ' || coalesce(email, '')) gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS location_search_idx ON location USING GIN ((to_tsvector('simple', code || ' ' || zone)));
+CREATE INDEX IF NOT EXISTS location_trgm_idx ON location USING GIN ((code || ' ' || zone) gin_trgm_ops);
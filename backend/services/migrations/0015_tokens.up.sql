@@ -0,0 +1,26 @@
+-- Refresh tokens are stored by the SHA-256 of their value. Each refresh
+-- revokes the token used and adds the next one of its family.
+CREATE TABLE IF NOT EXISTS refresh_token (
+    id BIGSERIAL PRIMARY KEY NOT NULL,
+    account_id INT NOT NULL REFERENCES account (id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    family VARCHAR(32) NOT NULL,
+    expires TIMESTAMPTZ NOT NULL,
+    revoked TIMESTAMPTZ,
+    created TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS refresh_token_family_idx ON refresh_token (family);
+CREATE INDEX IF NOT EXISTS refresh_token_account_id_idx ON refresh_token (account_id);
+
+-- Access tokens logged out before they expire, by their jti.
+CREATE TABLE IF NOT EXISTS revoked_token (
+    jti VARCHAR(64) PRIMARY KEY NOT NULL,
+    expires TIMESTAMPTZ NOT NULL
+);
+
+-- Raising the generation of an account revokes every access token signed
+-- before, when it is deactivated or its role changes.
+CREATE TABLE IF NOT EXISTS token_generation (
+    account_id INT PRIMARY KEY NOT NULL,
+    generation BIGINT NOT NULL DEFAULT 0
+);
@@ -0,0 +1,8 @@
+-- Failed logins by key: "user:<username>" or "ip:<address>". Keys are kept
+-- for unknown usernames too, so lockouts do not reveal which exist.
+CREATE TABLE IF NOT EXISTS login_attempt (
+    key TEXT PRIMARY KEY NOT NULL,
+    failures INT NOT NULL DEFAULT 0,
+    last_failure TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS login_attempt_last_failure_idx ON login_attempt (last_failure);
@@ -0,0 +1,18 @@
+-- TOTP secrets of accounts. last_step is the time step of the last code
+-- used, so a code cannot be replayed.
+CREATE TABLE IF NOT EXISTS account_totp (
+    account_id INT PRIMARY KEY NOT NULL REFERENCES account (id) ON DELETE CASCADE,
+    secret VARCHAR(64) NOT NULL,
+    confirmed BOOLEAN NOT NULL DEFAULT FALSE,
+    last_step BIGINT NOT NULL DEFAULT 0,
+    created TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- One-time recovery codes, stored by the SHA-256 of their value.
+CREATE TABLE IF NOT EXISTS recovery_code (
+    id BIGSERIAL PRIMARY KEY NOT NULL,
+    account_id INT NOT NULL REFERENCES account (id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS recovery_code_account_id_idx ON recovery_code (account_id);
@@ -0,0 +1,13 @@
+-- API keys for machine integrations, stored by the SHA-256 of the key and
+-- found by its prefix.
+CREATE TABLE IF NOT EXISTS api_key (
+    id BIGSERIAL PRIMARY KEY NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL UNIQUE,
+    key_hash VARCHAR(64) NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    created_by INT REFERENCES account (id) ON DELETE SET NULL,
+    created TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires TIMESTAMPTZ NOT NULL,
+    last_used TIMESTAMPTZ
+);
@@ -0,0 +1,66 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/WMS/models"
+)
+
+// OrderTransitions lists the statuses an order may move to from each
+// status. Delivered and cancelled orders are final.
+var OrderTransitions = map[string][]string{
+	models.OrderDraft:     {models.OrderPlaced, models.OrderCancelled},
+	models.OrderPlaced:    {models.OrderDraft, models.OrderAllocated, models.OrderCancelled},
+	models.OrderAllocated: {models.OrderPicking, models.OrderCancelled},
+	models.OrderPicking:   {models.OrderPacked, models.OrderCancelled},
+	models.OrderPacked:    {models.OrderShipped},
+	models.OrderShipped:   {models.OrderDelivered},
+}
+
+// OrderOwner returns the customer whose orders a role is limited to,
+// or 0 when the role may see and place orders for every customer.
+func OrderOwner(role string, accountID int64) int64 {
+	if role == models.RoleCustomer {
+		return accountID
+	}
+	return 0
+}
+
+// editableOrderStatuses are the statuses in which UpdateOrder may still
+// change an order.
+var editableOrderStatuses = []string{models.OrderDraft, models.OrderPlaced}
+
+// initialOrderStatus validates the status a new order is created with,
+// draft when left empty.
+func initialOrderStatus(status string) (string, error) {
+	status = strings.ToUpper(strings.TrimSpace(status))
+	if status == "" {
+		return models.OrderDraft, nil
+	}
+	if !slices.Contains(editableOrderStatuses, status) {
+		return "", invalid("status", "new orders must be %s or %s", models.OrderDraft, models.OrderPlaced)
+	}
+	return status, nil
+}
+
+// checkTransition validates moving an order from one status to another.
+func checkTransition(id int64, from string, to string) error {
+	if !slices.Contains(models.OrderStatuses, to) {
+		return invalid("status", "unknown order status %q", to)
+	}
+	if !slices.Contains(OrderTransitions[from], to) {
+		return fmt.Errorf("order %d cannot move from %s to %s: %w", id, from, to, ErrConflict)
+	}
+	return nil
+}
+
+func checkOrderEditable(id int64, status string) error {
+	if !slices.Contains(editableOrderStatuses, status) {
+		return fmt.Errorf("order %d is %s and can no longer be edited: %w", id, status, ErrConflict)
+	}
+	return nil
+}
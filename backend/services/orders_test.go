@@ -0,0 +1,42 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"testing"
+
+	"github.com/WMS/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderTransitions(t *testing.T) {
+	// Every status can be reached from draft
+	reached := map[string]bool{models.OrderDraft: true}
+	queue := []string{models.OrderDraft}
+	for len(queue) > 0 {
+		from := queue[0]
+		queue = queue[1:]
+		for _, to := range OrderTransitions[from] {
+			assert.Nil(t, checkTransition(1, from, to))
+			if !reached[to] {
+				reached[to] = true
+				queue = append(queue, to)
+			}
+		}
+	}
+	assert.Len(t, reached, len(models.OrderStatuses))
+
+	assert.ErrorIs(t, checkTransition(1, models.OrderShipped, models.OrderCancelled), ErrConflict)
+	assert.ErrorIs(t, checkTransition(1, models.OrderCancelled, models.OrderPlaced), ErrConflict)
+	var validation *ValidationError
+	assert.ErrorAs(t, checkTransition(1, models.OrderDraft, "LOST"), &validation)
+
+	status, err := initialOrderStatus("")
+	assert.Nil(t, err)
+	assert.Equal(t, models.OrderDraft, status)
+	_, err = initialOrderStatus(models.OrderShipped)
+	assert.ErrorAs(t, err, &validation)
+
+	assert.Nil(t, checkOrderEditable(1, models.OrderPlaced))
+	assert.ErrorIs(t, checkOrderEditable(1, models.OrderPicking), ErrConflict)
+}
@@ -0,0 +1,316 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/WMS/models"
+)
+
+// dimWeightDivisor turns cubic inches into dimensional weight, the divisor
+// most parcel carriers bill by.
+const dimWeightDivisor = 139.0
+
+// NormalizeCarton validates a carton before it is stored. Both weights
+// are kept in the unit of the max weight.
+func NormalizeCarton(carton *models.Carton) error {
+	carton.Name = strings.TrimSpace(carton.Name)
+	if carton.Name == "" {
+		return invalid("name", "carton name is required")
+	}
+	if err := checkDimensions(&carton.Dimensions); err != nil {
+		return err
+	}
+	carton.MaxWeight.Unit = cmp.Or(carton.MaxWeight.Unit, carton.TareWeight.Unit)
+	if err := checkWeight("maxWeight", &carton.MaxWeight); err != nil {
+		return err
+	}
+	if err := checkWeight("tareWeight", &carton.TareWeight); err != nil {
+		return err
+	}
+	carton.TareWeight = carton.TareWeight.In(carton.MaxWeight.Unit)
+	return nil
+}
+
+// inches returns dimensions in inches, longest side first.
+func inches(d models.Dimensions) [3]float64 {
+	d = d.In(models.UnitInch)
+	sides := []float64{d.Length, d.Width, d.Height}
+	slices.SortFunc(sides, func(a, b float64) int {
+		return cmp.Compare(b, a)
+	})
+	return [3]float64{sides[0], sides[1], sides[2]}
+}
+
+func volume(size [3]float64) float64 {
+	return size[0] * size[1] * size[2]
+}
+
+func round2(x float64) float64 {
+	return math.Round(x*100) / 100
+}
+
+// pounds returns a weight in pounds, the unit packing works in.
+func pounds(w models.Weight) float64 {
+	return w.In(models.UnitPound).Value
+}
+
+func dimWeight(d models.Dimensions) models.Weight {
+	return models.Weight{Value: round2(volume(inches(d)) / dimWeightDivisor), Unit: models.UnitPound}
+}
+
+// packUnit is one box of an item, holding up to the box count of units.
+type packUnit struct {
+	item  models.Item
+	count int64
+	size  [3]float64
+}
+
+func (u packUnit) weight() float64 {
+	return pounds(u.item.Weight) * float64(u.count)
+}
+
+// packUnits splits order lines into the boxes their items ship in, using
+// the smallest box pack of each item. The largest boxes come first.
+func packUnits(lines []models.ItemGroup, boxes []models.Box) ([]packUnit, error) {
+	packs := map[int64]models.Box{}
+	for _, box := range boxes {
+		if checkDimensions(&box.Dimensions) != nil || box.Count <= 0 {
+			continue
+		}
+		if current, ok := packs[box.Item.ID]; !ok || box.Count < current.Count {
+			packs[box.Item.ID] = box
+		}
+	}
+
+	var units []packUnit
+	for _, line := range lines {
+		if line.Count <= 0 {
+			continue
+		}
+		box, ok := packs[line.Item.ID]
+		if !ok {
+			return nil, invalid("payload", "item %d has no box dimensions to pack by", line.Item.ID)
+		}
+		for left := line.Count; left > 0; left -= box.Count {
+			units = append(units, packUnit{item: box.Item, count: min(left, box.Count), size: inches(box.Dimensions)})
+		}
+	}
+	slices.SortStableFunc(units, func(a, b packUnit) int {
+		return cmp.Or(cmp.Compare(volume(b.size), volume(a.size)), cmp.Compare(a.item.ID, b.item.ID))
+	})
+	return units, nil
+}
+
+var rotations = [6][3]int{{0, 1, 2}, {0, 2, 1}, {1, 0, 2}, {1, 2, 0}, {2, 0, 1}, {2, 1, 0}}
+
+// fit returns an orientation of size that fits into space.
+func fit(size [3]float64, space [3]float64) ([3]float64, bool) {
+	for _, r := range rotations {
+		o := [3]float64{size[r[0]], size[r[1]], size[r[2]]}
+		if o[0] <= space[0] && o[1] <= space[1] && o[2] <= space[2] {
+			return o, true
+		}
+	}
+	return size, false
+}
+
+// fillCarton packs units into a single carton in order, placing each in
+// the smallest free space it fits and splitting what is left beside, in
+// front of and above it. It reports which units went in.
+func fillCarton(carton models.Carton, units []packUnit) []bool {
+	spaces := [][3]float64{inches(carton.Dimensions)}
+	weight, limit := pounds(carton.TareWeight), pounds(carton.MaxWeight)
+	packed := make([]bool, len(units))
+	for i, unit := range units {
+		if limit > 0 && weight+unit.weight() > limit {
+			continue
+		}
+		for j, space := range spaces {
+			o, ok := fit(unit.size, space)
+			if !ok {
+				continue
+			}
+			spaces = slices.Delete(spaces, j, j+1)
+			for _, rest := range [][3]float64{
+				{space[0] - o[0], space[1], space[2]},
+				{o[0], space[1] - o[1], space[2]},
+				{o[0], o[1], space[2] - o[2]},
+			} {
+				if volume(rest) > 0 {
+					spaces = append(spaces, rest)
+				}
+			}
+			slices.SortFunc(spaces, func(a, b [3]float64) int {
+				return cmp.Compare(volume(a), volume(b))
+			})
+			packed[i] = true
+			weight += unit.weight()
+			break
+		}
+	}
+	return packed
+}
+
+// contentsOf folds packed boxes back into item lines.
+func contentsOf(units []packUnit) []models.ItemGroup {
+	var contents []models.ItemGroup
+	for _, unit := range units {
+		i := slices.IndexFunc(contents, func(g models.ItemGroup) bool {
+			return g.Item.ID == unit.item.ID
+		})
+		if i < 0 {
+			contents = append(contents, models.ItemGroup{Item: unit.item, Count: unit.count})
+		} else {
+			contents[i].Count += unit.count
+		}
+	}
+	return contents
+}
+
+func packedCarton(orderID int64, carton models.Carton, contents []models.ItemGroup) models.PackedCarton {
+	weight := pounds(carton.TareWeight)
+	for _, group := range contents {
+		weight += pounds(group.Item.Weight) * float64(group.Count)
+	}
+	return models.PackedCarton{
+		OrderID:    orderID,
+		CartonID:   carton.ID,
+		Carton:     carton.Name,
+		Dimensions: carton.Dimensions,
+		Contents:   contents,
+		Weight:     models.Weight{Value: round2(weight), Unit: models.UnitPound},
+		DimWeight:  dimWeight(carton.Dimensions),
+	}
+}
+
+// packingTotals adds up the weights of the cartons of a shipment in
+// pounds.
+func packingTotals(orderID int64, cartons []models.PackedCarton) models.Packing {
+	packing := models.Packing{OrderID: orderID, Cartons: cartons}
+	if packing.Cartons == nil {
+		packing.Cartons = []models.PackedCarton{}
+	}
+	var weight, dim, billable float64
+	for _, carton := range packing.Cartons {
+		weight += pounds(carton.Weight)
+		dim += pounds(carton.DimWeight)
+		billable += max(pounds(carton.Weight), pounds(carton.DimWeight))
+	}
+	packing.Weight = models.Weight{Value: round2(weight), Unit: models.UnitPound}
+	packing.DimWeight = models.Weight{Value: round2(dim), Unit: models.UnitPound}
+	packing.BillableWeight = models.Weight{Value: round2(billable), Unit: models.UnitPound}
+	return packing
+}
+
+// suggestCartons chooses cartons greedily: the smallest carton that takes
+// every box left, otherwise the one that takes the most volume, until all
+// boxes are packed.
+func suggestCartons(orderID int64, cartons []models.Carton, units []packUnit) (models.Packing, error) {
+	var active []models.Carton
+	for _, carton := range cartons {
+		if carton.Active {
+			active = append(active, carton)
+		}
+	}
+	slices.SortStableFunc(active, func(a, b models.Carton) int {
+		return cmp.Compare(volume(inches(a.Dimensions)), volume(inches(b.Dimensions)))
+	})
+
+	var suggested []models.PackedCarton
+	for len(units) > 0 {
+		var best models.Carton
+		var bestPacked []bool
+		var bestVolume float64
+		for _, carton := range active {
+			packed := fillCarton(carton, units)
+			var packedVolume float64
+			for i, ok := range packed {
+				if ok {
+					packedVolume += volume(units[i].size)
+				}
+			}
+			if packedVolume > bestVolume {
+				best, bestPacked, bestVolume = carton, packed, packedVolume
+			}
+			if !slices.Contains(packed, false) {
+				break
+			}
+		}
+		if bestPacked == nil {
+			return models.Packing{}, invalid("payload", "a box of item %d does not fit any carton", units[0].item.ID)
+		}
+
+		var contents, rest []packUnit
+		for i, unit := range units {
+			if bestPacked[i] {
+				contents = append(contents, unit)
+			} else {
+				rest = append(rest, unit)
+			}
+		}
+		suggested = append(suggested, packedCarton(orderID, best, contentsOf(contents)))
+		units = rest
+	}
+	return packingTotals(orderID, suggested), nil
+}
+
+// packedCartons checks the cartons an order was packed into against what
+// was picked for it, or its payload when it was never waved, and weighs
+// them. Every unit has to be packed.
+func packedCartons(order models.Order, tasks []models.PickTask, req models.PackRequest, cartons map[int64]models.Carton, items map[int64]models.Item) ([]models.PackedCarton, error) {
+	if order.Status != models.OrderPicking {
+		return nil, fmt.Errorf("order %d is %s and cannot be packed: %w", order.ID, order.Status, ErrConflict)
+	}
+	expected := map[int64]int64{}
+	for _, task := range tasks {
+		if taskOpen(task) {
+			return nil, fmt.Errorf("order %d still has pick tasks open: %w", order.ID, ErrConflict)
+		}
+		expected[task.ItemID] += task.Picked
+	}
+	if len(tasks) == 0 {
+		for _, group := range order.Payload {
+			expected[group.Item.ID] += group.Count
+		}
+	}
+
+	packed := map[int64]int64{}
+	var result []models.PackedCarton
+	for i, requested := range req.Cartons {
+		carton, ok := cartons[requested.CartonID]
+		if !ok || !carton.Active {
+			return nil, invalid("cartons", "carton %d does not exist", requested.CartonID)
+		}
+		var contents []models.ItemGroup
+		for _, group := range requested.Contents {
+			item, ok := items[group.Item.ID]
+			if !ok || expected[group.Item.ID] == 0 {
+				return nil, invalid("cartons", "item %d is not part of order %d", group.Item.ID, order.ID)
+			}
+			if group.Count <= 0 {
+				return nil, invalid("cartons", "count of item %d in carton %d must be positive", group.Item.ID, i)
+			}
+			packed[item.ID] += group.Count
+			contents = append(contents, models.ItemGroup{Item: item, Count: group.Count})
+		}
+		if len(contents) == 0 {
+			return nil, invalid("cartons", "carton %d is empty", i)
+		}
+		result = append(result, packedCarton(order.ID, carton, contents))
+		if limit := pounds(carton.MaxWeight); limit > 0 && result[i].Weight.Value > limit {
+			return nil, invalid("cartons", "carton %d weighs %.2f lb, %s holds at most %.2f lb", i, result[i].Weight.Value, carton.Name, limit)
+		}
+	}
+	for itemID, count := range expected {
+		if packed[itemID] != count {
+			return nil, invalid("cartons", "%d of item %d packed, %d picked", packed[itemID], itemID, count)
+		}
+	}
+	return result, nil
+}
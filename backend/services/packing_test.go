@@ -0,0 +1,102 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"testing"
+
+	"github.com/WMS/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuggestCartons(t *testing.T) {
+	item := models.Item{ID: 1, Weight: models.Weight{Value: 1, Unit: models.UnitPound}}
+	boxes := []models.Box{
+		{ID: 1, Item: item, Dimensions: models.Dimensions{Length: 4, Width: 4, Height: 4, Unit: models.UnitInch}, Count: 1},
+		{ID: 2, Item: item, Dimensions: models.Dimensions{Length: 8, Width: 8, Height: 8, Unit: models.UnitInch}, Count: 8},
+	}
+	small := models.Carton{ID: 1, Name: "small", Dimensions: models.Dimensions{Length: 8, Width: 8, Height: 4, Unit: models.UnitInch}, TareWeight: models.Weight{Value: 0.5, Unit: models.UnitPound}, Active: true}
+	large := models.Carton{ID: 2, Name: "large", Dimensions: models.Dimensions{Length: 8, Width: 8, Height: 8, Unit: models.UnitInch}, TareWeight: models.Weight{Value: 0.5, Unit: models.UnitPound}, Active: true}
+	closed := models.Carton{ID: 3, Name: "closed", Dimensions: models.Dimensions{Length: 40, Width: 40, Height: 40, Unit: models.UnitInch}}
+	cartons := []models.Carton{large, small, closed}
+
+	units, err := packUnits([]models.ItemGroup{{Item: item, Count: 10}}, boxes)
+	assert.Nil(t, err)
+	assert.Len(t, units, 10, "Items are packed by their smallest box")
+
+	packing, err := suggestCartons(7, cartons, units)
+	assert.Nil(t, err)
+	assert.Equal(t, models.Packing{
+		OrderID: 7,
+		Cartons: []models.PackedCarton{
+			{OrderID: 7, CartonID: 2, Carton: "large", Dimensions: large.Dimensions, Contents: []models.ItemGroup{{Item: item, Count: 8}}, Weight: models.Weight{Value: 8.5, Unit: models.UnitPound}, DimWeight: models.Weight{Value: 3.68, Unit: models.UnitPound}},
+			{OrderID: 7, CartonID: 1, Carton: "small", Dimensions: small.Dimensions, Contents: []models.ItemGroup{{Item: item, Count: 2}}, Weight: models.Weight{Value: 2.5, Unit: models.UnitPound}, DimWeight: models.Weight{Value: 1.84, Unit: models.UnitPound}},
+		},
+		Weight:         models.Weight{Value: 11, Unit: models.UnitPound},
+		DimWeight:      models.Weight{Value: 5.52, Unit: models.UnitPound},
+		BillableWeight: models.Weight{Value: 11, Unit: models.UnitPound},
+	}, packing)
+
+	// The weight limit stops a carton short of its volume
+	large.MaxWeight = models.Weight{Value: 6.5, Unit: models.UnitPound}
+	packing, err = suggestCartons(7, []models.Carton{small, large}, units)
+	assert.Nil(t, err)
+	assert.Len(t, packing.Cartons, 2)
+	assert.Equal(t, int64(6), packing.Cartons[0].Contents[0].Count)
+	assert.Equal(t, int64(4), packing.Cartons[1].Contents[0].Count)
+
+	units, err = packUnits([]models.ItemGroup{{Item: models.Item{ID: 1, Weight: models.Weight{Value: 1, Unit: models.UnitPound}}, Count: 1}}, []models.Box{{Item: item, Dimensions: models.Dimensions{Length: 9, Width: 1, Height: 1, Unit: models.UnitInch}, Count: 1}})
+	assert.Nil(t, err)
+	_, err = suggestCartons(7, cartons, units)
+	var validation *ValidationError
+	assert.ErrorAs(t, err, &validation, "A box longer than every active carton")
+
+	_, err = packUnits([]models.ItemGroup{{Item: models.Item{ID: 2}, Count: 1}}, boxes)
+	assert.ErrorAs(t, err, &validation, "Item without a box")
+}
+
+func TestPackedCartons(t *testing.T) {
+	item := models.Item{ID: 1, Weight: models.Weight{Value: 2, Unit: models.UnitPound}}
+	order := models.Order{ID: 7, Status: models.OrderPicking, Payload: []models.ItemGroup{{Item: item, Count: 3}}}
+	tasks := []models.PickTask{
+		{ItemID: 1, Quantity: 2, Picked: 2, Status: models.PickPicked},
+		{ItemID: 1, Quantity: 1, Picked: 0, Status: models.PickShort},
+	}
+	cartons := map[int64]models.Carton{
+		1: {ID: 1, Name: "small", Dimensions: models.Dimensions{Length: 8, Width: 8, Height: 4, Unit: models.UnitInch}, MaxWeight: models.Weight{Value: 3, Unit: models.UnitPound}, TareWeight: models.Weight{Value: 0.5, Unit: models.UnitPound}, Active: true},
+		2: {ID: 2, Name: "large", Dimensions: models.Dimensions{Length: 8, Width: 8, Height: 8, Unit: models.UnitInch}, TareWeight: models.Weight{Value: 1, Unit: models.UnitPound}, Active: true},
+		3: {ID: 3, Name: "closed", Dimensions: models.Dimensions{Length: 8, Width: 8, Height: 8, Unit: models.UnitInch}},
+	}
+	items := map[int64]models.Item{1: item}
+	pack := func(cartonID int64, count int64) models.PackRequest {
+		return models.PackRequest{Cartons: []models.PackRequestCarton{{CartonID: cartonID, Contents: []models.ItemGroup{{Item: models.Item{ID: 1}, Count: count}}}}}
+	}
+
+	packed, err := packedCartons(order, tasks, pack(2, 2), cartons, items)
+	assert.Nil(t, err)
+	assert.Equal(t, []models.PackedCarton{
+		{OrderID: 7, CartonID: 2, Carton: "large", Dimensions: cartons[2].Dimensions, Contents: []models.ItemGroup{{Item: item, Count: 2}}, Weight: models.Weight{Value: 5, Unit: models.UnitPound}, DimWeight: models.Weight{Value: 3.68, Unit: models.UnitPound}},
+	}, packed, "Only what was picked is packed")
+
+	var validation *ValidationError
+	_, err = packedCartons(order, tasks, pack(2, 3), cartons, items)
+	assert.ErrorAs(t, err, &validation)
+	_, err = packedCartons(order, tasks, pack(1, 2), cartons, items)
+	assert.ErrorAs(t, err, &validation, "Over the carton weight limit")
+	_, err = packedCartons(order, tasks, pack(3, 2), cartons, items)
+	assert.ErrorAs(t, err, &validation, "Inactive carton")
+	_, err = packedCartons(order, tasks, models.PackRequest{}, cartons, items)
+	assert.ErrorAs(t, err, &validation)
+
+	// Orders without pick tasks pack their payload
+	packed, err = packedCartons(order, nil, pack(2, 3), cartons, items)
+	assert.Nil(t, err)
+	assert.Equal(t, models.Weight{Value: 7, Unit: models.UnitPound}, packed[0].Weight)
+
+	tasks[1].Status = models.PickClaimed
+	_, err = packedCartons(order, tasks, pack(2, 2), cartons, items)
+	assert.ErrorIs(t, err, ErrConflict, "Open pick tasks")
+	order.Status = models.OrderAllocated
+	_, err = packedCartons(order, nil, pack(2, 3), cartons, items)
+	assert.ErrorIs(t, err, ErrConflict)
+}
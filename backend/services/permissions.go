@@ -0,0 +1,214 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/WMS/models"
+	"github.com/labstack/echo/v5"
+)
+
+type Verb string
+
+const (
+	VerbRead   Verb = "read"
+	VerbCreate Verb = "create"
+	VerbUpdate Verb = "update"
+	VerbDelete Verb = "delete"
+)
+
+var (
+	allRoles   = models.Roles
+	staffRoles = []string{models.RoleAdmin, models.RoleManager, models.RoleEmployee}
+	leadRoles  = []string{models.RoleAdmin, models.RoleManager}
+	adminRoles = []string{models.RoleAdmin}
+)
+
+// Permissions is the resource x verb x role matrix enforced on every /api
+// route. A resource missing from the matrix, or a verb missing from a
+// resource, is denied to every role.
+var Permissions = map[string]map[Verb][]string{
+	"accounts": {
+		VerbRead:   leadRoles,
+		VerbCreate: adminRoles,
+		VerbUpdate: adminRoles,
+		VerbDelete: adminRoles,
+	},
+	"items": {
+		VerbRead:   allRoles,
+		VerbCreate: leadRoles,
+		VerbUpdate: leadRoles,
+		VerbDelete: adminRoles,
+	},
+	"boxes": {
+		VerbRead:   []string{models.RoleAdmin, models.RoleManager, models.RoleEmployee, models.RoleSupplier},
+		VerbCreate: leadRoles,
+		VerbUpdate: leadRoles,
+		VerbDelete: leadRoles,
+	},
+	"inventory": {
+		VerbRead:   staffRoles,
+		VerbCreate: leadRoles,
+		VerbUpdate: leadRoles,
+		VerbDelete: leadRoles,
+	},
+	"shipments": {
+		VerbRead:   []string{models.RoleAdmin, models.RoleManager, models.RoleEmployee, models.RoleSupplier},
+		VerbCreate: []string{models.RoleAdmin, models.RoleManager, models.RoleSupplier},
+		VerbUpdate: staffRoles,
+		VerbDelete: leadRoles,
+	},
+	"locations": {
+		VerbRead:   staffRoles,
+		VerbCreate: leadRoles,
+		VerbUpdate: leadRoles,
+		VerbDelete: leadRoles,
+	},
+	"stock": {
+		VerbRead:   staffRoles,
+		VerbCreate: staffRoles,
+	},
+	"waves": {
+		VerbRead:   staffRoles,
+		VerbCreate: leadRoles,
+	},
+	"picks": {
+		VerbRead:   staffRoles,
+		VerbUpdate: staffRoles,
+	},
+	"cartons": {
+		VerbRead:   staffRoles,
+		VerbCreate: leadRoles,
+		VerbUpdate: leadRoles,
+		VerbDelete: leadRoles,
+	},
+	"packing": {
+		VerbRead:   staffRoles,
+		VerbUpdate: staffRoles,
+	},
+	"scan": {
+		VerbCreate: staffRoles,
+	},
+	"labels": {
+		VerbRead: staffRoles,
+	},
+	"search": {
+		VerbRead: allRoles,
+	},
+	"query": {
+		VerbCreate: leadRoles,
+	},
+	"orders": {
+		VerbRead:   []string{models.RoleAdmin, models.RoleManager, models.RoleEmployee, models.RoleCustomer},
+		VerbCreate: []string{models.RoleAdmin, models.RoleManager, models.RoleCustomer},
+		VerbUpdate: leadRoles,
+		VerbDelete: adminRoles,
+	},
+	"system": {
+		VerbRead: adminRoles,
+	},
+	"apikeys": {
+		VerbRead:   adminRoles,
+		VerbCreate: adminRoles,
+		VerbDelete: adminRoles,
+	},
+}
+
+// SelfService lists the verbs any role may perform on a resource when the
+// :id path parameter is the caller's own account ID.
+var SelfService = map[string][]Verb{
+	"accounts": {VerbRead, VerbUpdate},
+}
+
+func IsAllowed(role string, resource string, verb Verb) bool {
+	verbs, ok := Permissions[resource]
+	if !ok {
+		return false
+	}
+	return slices.Contains(verbs[verb], role)
+}
+
+// RequestResource returns the resource a registered route path belongs to,
+// e.g. "/api/accounts/:id" -> "accounts".
+func RequestResource(path string) string {
+	path = strings.TrimPrefix(path, "/api")
+	path = strings.TrimPrefix(path, "/")
+	resource, _, _ := strings.Cut(path, "/")
+	return resource
+}
+
+// RequestVerb maps an HTTP method onto a permission verb. A POST to a route
+// below a single record (e.g. "/api/orders/:id/transition") acts on that
+// record and is treated as an update.
+func RequestVerb(method string, path string) Verb {
+	switch method {
+	case http.MethodGet, http.MethodHead:
+		return VerbRead
+	case http.MethodPost:
+		if strings.Contains(path, "/:") {
+			return VerbUpdate
+		}
+		return VerbCreate
+	case http.MethodPut, http.MethodPatch:
+		return VerbUpdate
+	case http.MethodDelete:
+		return VerbDelete
+	default:
+		return ""
+	}
+}
+
+func isSelf(c *echo.Context, claims *models.JwtCustomClaims, resource string, verb Verb) bool {
+	if !slices.Contains(SelfService[resource], verb) {
+		return false
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return false
+	}
+	return id == claims.ID
+}
+
+func forbidden(c *echo.Context, resource string, verb Verb, role string) error {
+	return c.JSON(http.StatusForbidden, models.AuthError{
+		Error:    "forbidden",
+		Message:  fmt.Sprintf("role %q may not %s %s", role, verb, resource),
+		Resource: resource,
+		Verb:     string(verb),
+		Role:     role,
+	})
+}
+
+// AuthorizeRole checks the JWT claims of the caller against the Permissions
+// matrix, or the scopes of its API key. It must run after the JWT
+// middleware has placed the token on the context.
+func AuthorizeRole(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		if key, ok := APIKeyFrom(c); ok {
+			return authorizeAPIKey(c, key, next)
+		}
+		claims, err := GetClaims(c)
+		if err != nil {
+			return c.JSON(http.StatusUnauthorized, models.AuthError{
+				Error:   "unauthorized",
+				Message: "missing or invalid token claims",
+			})
+		}
+
+		path := c.Path()
+		resource := RequestResource(path)
+		verb := RequestVerb(c.Request().Method, path)
+		role := claims.Role.Value
+
+		if IsAllowed(role, resource, verb) || isSelf(c, claims, resource, verb) {
+			c.SetRequest(c.Request().WithContext(WithActor(c.Request().Context(), claims.ID)))
+			return next(c)
+		}
+		return forbidden(c, resource, verb, role)
+	}
+}
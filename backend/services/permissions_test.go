@@ -0,0 +1,152 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/WMS/models"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+type permissionCase struct {
+	method string
+	path   string
+	target string
+	status map[string]int
+}
+
+// Expected status per role: 200 when the handler runs, 403 when refused.
+var permissionCases = []permissionCase{
+	{http.MethodGet, "/api/accounts", "/api/accounts", statuses(200, 200, 403, 403, 403)},
+	{http.MethodPost, "/api/accounts", "/api/accounts", statuses(200, 403, 403, 403, 403)},
+	{http.MethodGet, "/api/accounts/:id", "/api/accounts/2", statuses(200, 200, 403, 403, 403)},
+	{http.MethodGet, "/api/accounts/:id", "/api/accounts/1", statuses(200, 200, 200, 200, 200)},
+	{http.MethodPut, "/api/accounts/:id", "/api/accounts/1", statuses(200, 200, 200, 200, 200)},
+	{http.MethodPut, "/api/accounts/:id", "/api/accounts/2", statuses(200, 403, 403, 403, 403)},
+	{http.MethodPut, "/api/accounts/:id/password", "/api/accounts/1/password", statuses(200, 200, 200, 200, 200)},
+	{http.MethodPut, "/api/accounts/:id/password", "/api/accounts/2/password", statuses(200, 403, 403, 403, 403)},
+	{http.MethodDelete, "/api/accounts/:id", "/api/accounts/1", statuses(200, 403, 403, 403, 403)},
+	{http.MethodDelete, "/api/accounts/:id/lockout", "/api/accounts/1/lockout", statuses(200, 403, 403, 403, 403)},
+	{http.MethodPost, "/api/accounts/:id/totp", "/api/accounts/1/totp", statuses(200, 200, 200, 200, 200)},
+	{http.MethodPost, "/api/accounts/:id/totp/confirm", "/api/accounts/2/totp/confirm", statuses(200, 403, 403, 403, 403)},
+	{http.MethodDelete, "/api/accounts/:id/totp", "/api/accounts/1/totp", statuses(200, 403, 403, 403, 403)},
+	{http.MethodGet, "/api/items", "/api/items", statuses(200, 200, 200, 200, 200)},
+	{http.MethodGet, "/api/items/list", "/api/items/list", statuses(200, 200, 200, 200, 200)},
+	{http.MethodPost, "/api/items", "/api/items", statuses(200, 200, 403, 403, 403)},
+	{http.MethodPut, "/api/items/:id", "/api/items/3", statuses(200, 200, 403, 403, 403)},
+	{http.MethodGet, "/api/items/:id/uoms", "/api/items/3/uoms", statuses(200, 200, 200, 200, 200)},
+	{http.MethodPut, "/api/items/:id/uoms", "/api/items/3/uoms", statuses(200, 200, 403, 403, 403)},
+	{http.MethodDelete, "/api/items/:id", "/api/items/3", statuses(200, 403, 403, 403, 403)},
+	{http.MethodGet, "/api/boxes", "/api/boxes", statuses(200, 200, 200, 200, 403)},
+	{http.MethodPost, "/api/boxes", "/api/boxes", statuses(200, 200, 403, 403, 403)},
+	{http.MethodDelete, "/api/boxes/:id", "/api/boxes/3", statuses(200, 200, 403, 403, 403)},
+	{http.MethodGet, "/api/inventory/:id", "/api/inventory/3", statuses(200, 200, 200, 403, 403)},
+	{http.MethodPut, "/api/inventory/:id", "/api/inventory/3", statuses(200, 200, 403, 403, 403)},
+	{http.MethodGet, "/api/shipments", "/api/shipments", statuses(200, 200, 200, 200, 403)},
+	{http.MethodPost, "/api/shipments", "/api/shipments", statuses(200, 200, 403, 200, 403)},
+	{http.MethodPost, "/api/shipments/:id/receive", "/api/shipments/3/receive", statuses(200, 200, 200, 403, 403)},
+	{http.MethodDelete, "/api/shipments/:id", "/api/shipments/3", statuses(200, 200, 403, 403, 403)},
+	{http.MethodGet, "/api/locations/:id/inventory", "/api/locations/3/inventory", statuses(200, 200, 200, 403, 403)},
+	{http.MethodPost, "/api/locations", "/api/locations", statuses(200, 200, 403, 403, 403)},
+	{http.MethodDelete, "/api/locations/:id", "/api/locations/3", statuses(200, 200, 403, 403, 403)},
+	{http.MethodPost, "/api/stock/moves", "/api/stock/moves", statuses(200, 200, 200, 403, 403)},
+	{http.MethodGet, "/api/stock/transactions", "/api/stock/transactions", statuses(200, 200, 200, 403, 403)},
+	{http.MethodPost, "/api/waves", "/api/waves", statuses(200, 200, 403, 403, 403)},
+	{http.MethodGet, "/api/picks", "/api/picks", statuses(200, 200, 200, 403, 403)},
+	{http.MethodPost, "/api/picks/:id/claim", "/api/picks/3/claim", statuses(200, 200, 200, 403, 403)},
+	{http.MethodPost, "/api/cartons", "/api/cartons", statuses(200, 200, 403, 403, 403)},
+	{http.MethodGet, "/api/cartons/:id", "/api/cartons/1", statuses(200, 200, 200, 403, 403)},
+	{http.MethodPost, "/api/packing/:id", "/api/packing/4", statuses(200, 200, 200, 403, 403)},
+	{http.MethodPost, "/api/scan", "/api/scan", statuses(200, 200, 200, 403, 403)},
+	{http.MethodGet, "/api/labels/:kind", "/api/labels/items", statuses(200, 200, 200, 403, 403)},
+	{http.MethodGet, "/api/search", "/api/search", statuses(200, 200, 200, 200, 200)},
+	{http.MethodPost, "/api/query", "/api/query", statuses(200, 200, 403, 403, 403)},
+	{http.MethodGet, "/api/apikeys", "/api/apikeys", statuses(200, 403, 403, 403, 403)},
+	{http.MethodPost, "/api/apikeys", "/api/apikeys", statuses(200, 403, 403, 403, 403)},
+	{http.MethodDelete, "/api/apikeys/:id", "/api/apikeys/3", statuses(200, 403, 403, 403, 403)},
+	{http.MethodGet, "/api/packing/:id/suggestion", "/api/packing/4/suggestion", statuses(200, 200, 200, 403, 403)},
+	{http.MethodGet, "/api/orders", "/api/orders", statuses(200, 200, 200, 403, 200)},
+	{http.MethodPost, "/api/orders", "/api/orders", statuses(200, 200, 403, 403, 200)},
+	{http.MethodPut, "/api/orders/:id", "/api/orders/3", statuses(200, 200, 403, 403, 403)},
+	{http.MethodPost, "/api/orders/:id/transition", "/api/orders/3/transition", statuses(200, 200, 403, 403, 403)},
+	{http.MethodDelete, "/api/orders/:id", "/api/orders/3", statuses(200, 403, 403, 403, 403)},
+	{http.MethodGet, "/api/unknown", "/api/unknown", statuses(403, 403, 403, 403, 403)},
+}
+
+// statuses lists expected codes in models.Roles order:
+// ADMIN, MANAGER, EMPLOYEE, SUPPLIER, CUSTOMER.
+func statuses(codes ...int) map[string]int {
+	m := make(map[string]int, len(codes))
+	for i, code := range codes {
+		m[models.Roles[i]] = code
+	}
+	return m
+}
+
+func withClaims(claims *models.JwtCustomClaims) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c *echo.Context) error {
+			if claims != nil {
+				c.Set("user", jwt.NewWithClaims(jwt.SigningMethodRS256, claims))
+			}
+			return next(c)
+		}
+	}
+}
+
+func serveAs(claims *models.JwtCustomClaims, method, path, target string) *httptest.ResponseRecorder {
+	e := echo.New()
+	e.Add(method, path, func(c *echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}, withClaims(claims), AuthorizeRole)
+
+	req := httptest.NewRequest(method, target, nil)
+	res := httptest.NewRecorder()
+	e.ServeHTTP(res, req)
+	return res
+}
+
+func TestAuthorizeRolePerRole(t *testing.T) {
+	for _, role := range models.Roles {
+		t.Run(role, func(t *testing.T) {
+			claims := &models.JwtCustomClaims{ID: 1, Username: "test", Role: models.Role{Value: role}}
+			for _, tc := range permissionCases {
+				res := serveAs(claims, tc.method, tc.path, tc.target)
+				assert.Equal(t, tc.status[role], res.Code, "%s %s", tc.method, tc.target)
+			}
+		})
+	}
+}
+
+func TestAuthorizeRoleForbiddenBody(t *testing.T) {
+	claims := &models.JwtCustomClaims{ID: 1, Role: models.Role{Value: models.RoleCustomer}}
+	res := serveAs(claims, http.MethodDelete, "/api/items/:id", "/api/items/3")
+
+	assert.Equal(t, http.StatusForbidden, res.Code)
+	var body models.AuthError
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &body))
+	assert.Equal(t, "forbidden", body.Error)
+	assert.Equal(t, "items", body.Resource)
+	assert.Equal(t, "delete", body.Verb)
+	assert.Equal(t, models.RoleCustomer, body.Role)
+}
+
+func TestAuthorizeRoleMissingClaims(t *testing.T) {
+	res := serveAs(nil, http.MethodGet, "/api/items", "/api/items")
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+}
+
+func TestRequestVerb(t *testing.T) {
+	assert.Equal(t, VerbRead, RequestVerb(http.MethodGet, "/api/orders"))
+	assert.Equal(t, VerbCreate, RequestVerb(http.MethodPost, "/api/orders"))
+	assert.Equal(t, VerbUpdate, RequestVerb(http.MethodPost, "/api/orders/:id/transition"))
+	assert.Equal(t, VerbUpdate, RequestVerb(http.MethodPut, "/api/orders/:id"))
+	assert.Equal(t, VerbDelete, RequestVerb(http.MethodDelete, "/api/orders/:id"))
+	assert.Equal(t, "orders", RequestResource("/api/orders/:id/transition"))
+}
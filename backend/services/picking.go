@@ -0,0 +1,205 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/WMS/models"
+)
+
+func orderReference(id int64) string {
+	return fmt.Sprintf("order:%d", id)
+}
+
+// normalizeWaveRequest validates the filters of a new wave.
+func normalizeWaveRequest(req *models.WaveRequest) error {
+	req.Zone = strings.ToUpper(strings.TrimSpace(req.Zone))
+	if req.Zone != "" && !validLocationPart(req.Zone) {
+		return invalid("zone", "invalid zone %q", req.Zone)
+	}
+	if req.MaxOrders < 0 {
+		return invalid("maxOrders", "max orders cannot be negative")
+	}
+	return nil
+}
+
+func locationZone(code string) string {
+	parts, err := ParseLocationCode(code)
+	if err != nil {
+		return ""
+	}
+	return parts[0]
+}
+
+// waveOrders selects the allocated orders a wave takes, oldest first.
+// Orders without reservations have nothing to pick and are left out.
+func waveOrders(req models.WaveRequest, orders []models.Order, reservations map[int64][]models.Reservation) ([]models.Order, error) {
+	orders = slices.Clone(orders)
+	slices.SortStableFunc(orders, func(a, b models.Order) int {
+		return cmp.Or(a.TimeOrdered.Compare(b.TimeOrdered), cmp.Compare(a.ID, b.ID))
+	})
+
+	var selected []models.Order
+	for _, order := range orders {
+		if req.MaxOrders > 0 && len(selected) == req.MaxOrders {
+			break
+		}
+		if order.Status != models.OrderAllocated || len(reservations[order.ID]) == 0 {
+			continue
+		}
+		if req.Cutoff != nil && order.TimeOrdered.After(*req.Cutoff) {
+			continue
+		}
+		if req.Zone != "" && slices.ContainsFunc(reservations[order.ID], func(r models.Reservation) bool {
+			return locationZone(r.LocationCode) != req.Zone
+		}) {
+			continue
+		}
+		selected = append(selected, order)
+	}
+	if len(selected) == 0 {
+		return nil, invalid("orders", "no allocated orders match the wave")
+	}
+	return selected, nil
+}
+
+// pickTasks turns the reservations of the selected orders into tasks
+// sequenced along the walking path.
+func pickTasks(waveID int64, orders []models.Order, reservations map[int64][]models.Reservation) []models.PickTask {
+	var tasks []models.PickTask
+	for _, order := range orders {
+		for _, r := range reservations[order.ID] {
+			tasks = append(tasks, models.PickTask{
+				WaveID:        waveID,
+				OrderID:       order.ID,
+				Line:          r.Line,
+				ReservationID: r.ID,
+				ItemID:        r.ItemID,
+				LocationID:    r.LocationID,
+				LocationCode:  r.LocationCode,
+				Quantity:      r.Quantity,
+				Status:        models.PickOpen,
+			})
+		}
+	}
+	walkingPath(tasks)
+	return tasks
+}
+
+// compareLevel orders two levels of a location code, numerically when both
+// are numbers so aisle 9 comes before aisle 10.
+func compareLevel(a string, b string) int {
+	x, errX := strconv.Atoi(a)
+	y, errY := strconv.Atoi(b)
+	if errX == nil && errY == nil {
+		return cmp.Compare(x, y)
+	}
+	return strings.Compare(a, b)
+}
+
+// walkingPath sorts tasks into a serpentine route and numbers them: zone by
+// zone and aisle by aisle, walking every other aisle back from its far end
+// so the picker never doubles back.
+func walkingPath(tasks []models.PickTask) {
+	levels := func(task models.PickTask) [5]string {
+		parts, err := ParseLocationCode(task.LocationCode)
+		if err != nil {
+			return [5]string{task.LocationCode}
+		}
+		return parts
+	}
+	slices.SortStableFunc(tasks, func(a, b models.PickTask) int {
+		x, y := levels(a), levels(b)
+		for i := range x {
+			if c := compareLevel(x[i], y[i]); c != 0 {
+				return c
+			}
+		}
+		return cmp.Or(cmp.Compare(a.OrderID, b.OrderID), cmp.Compare(a.Line, b.Line))
+	})
+
+	// Reverse the racks of every second aisle within a zone
+	aisle := 0
+	for start := 0; start < len(tasks); {
+		zone, name := levels(tasks[start])[0], levels(tasks[start])[1]
+		end := start + 1
+		for end < len(tasks) && levels(tasks[end])[0] == zone && levels(tasks[end])[1] == name {
+			end++
+		}
+		if start > 0 && levels(tasks[start-1])[0] != zone {
+			aisle = 0
+		}
+		if aisle%2 == 1 {
+			slices.SortStableFunc(tasks[start:end], func(a, b models.PickTask) int {
+				return compareLevel(levels(b)[2], levels(a)[2])
+			})
+		}
+		aisle++
+		start = end
+	}
+
+	for i := range tasks {
+		tasks[i].Sequence = i + 1
+	}
+}
+
+// claimTask hands an open task to the account picking it. Claiming a task
+// twice is harmless, a task held by someone else is refused.
+func claimTask(task *models.PickTask, accountID int64) error {
+	switch {
+	case task.Status == models.PickClaimed && task.AccountID == accountID:
+		return nil
+	case task.Status != models.PickOpen:
+		return fmt.Errorf("pick task %d is %s: %w", task.ID, task.Status, ErrConflict)
+	}
+	task.Status = models.PickClaimed
+	task.AccountID = accountID
+	return nil
+}
+
+// completeTask records the units picked by the account holding the task,
+// all of them unless the pick is reported short.
+func completeTask(task *models.PickTask, accountID int64, short *models.ShortPickRequest) error {
+	if task.Status != models.PickClaimed {
+		return fmt.Errorf("pick task %d is %s and must be claimed first: %w", task.ID, task.Status, ErrConflict)
+	}
+	if task.AccountID != accountID {
+		return fmt.Errorf("pick task %d is claimed by account %d: %w", task.ID, task.AccountID, ErrConflict)
+	}
+	if short == nil {
+		task.Picked = task.Quantity
+		task.Status = models.PickPicked
+		return nil
+	}
+	if short.Quantity < 0 || short.Quantity >= task.Quantity {
+		return invalid("quantity", "a short pick must find between 0 and %d units", task.Quantity-1)
+	}
+	task.Picked = short.Quantity
+	task.Status = models.PickShort
+	return nil
+}
+
+// pickEntries takes the picked units of a task out of its location.
+func pickEntries(task models.PickTask) []models.StockTransaction {
+	if task.Picked == 0 {
+		return nil
+	}
+	return []models.StockTransaction{{
+		Type:           models.StockPick,
+		ItemID:         task.ItemID,
+		FromLocationID: task.LocationID,
+		Quantity:       task.Picked,
+		Reason:         models.ReasonPicked,
+		Reference:      orderReference(task.OrderID),
+	}}
+}
+
+// taskOpen reports whether a task still has to be picked.
+func taskOpen(task models.PickTask) bool {
+	return task.Status == models.PickOpen || task.Status == models.PickClaimed
+}
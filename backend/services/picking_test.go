@@ -0,0 +1,83 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WMS/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWalkingPath(t *testing.T) {
+	var tasks []models.PickTask
+	for _, code := range []string{"B-01-02", "A-10-01", "A-2-01", "A-2-03", "A-10-05", "A-9-04", "A-9-01", "B-01-01"} {
+		tasks = append(tasks, models.PickTask{LocationCode: code})
+	}
+	walkingPath(tasks)
+
+	var codes []string
+	for i, task := range tasks {
+		assert.Equal(t, i+1, task.Sequence)
+		codes = append(codes, task.LocationCode)
+	}
+	// Aisles in numeric order, every second one walked back down
+	assert.Equal(t, []string{"A-2-01", "A-2-03", "A-9-04", "A-9-01", "A-10-01", "A-10-05", "B-01-01", "B-01-02"}, codes)
+}
+
+func TestWaveOrders(t *testing.T) {
+	now := time.Now()
+	orders := []models.Order{
+		{ID: 1, Status: models.OrderAllocated, TimeOrdered: now},
+		{ID: 2, Status: models.OrderAllocated, TimeOrdered: now.Add(-time.Hour)},
+		{ID: 3, Status: models.OrderPlaced, TimeOrdered: now.Add(-2 * time.Hour)},
+		{ID: 4, Status: models.OrderAllocated, TimeOrdered: now.Add(-3 * time.Hour)},
+	}
+	reservations := map[int64][]models.Reservation{
+		1: {{LocationCode: "A-01"}},
+		2: {{LocationCode: "A-02"}, {LocationCode: "B-01"}},
+		3: {{LocationCode: "A-01"}},
+		4: {{LocationCode: "A-03"}},
+	}
+	ids := func(req models.WaveRequest) []int64 {
+		selected, err := waveOrders(req, orders, reservations)
+		if err != nil {
+			return nil
+		}
+		var ids []int64
+		for _, order := range selected {
+			ids = append(ids, order.ID)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []int64{4, 2, 1}, ids(models.WaveRequest{}), "Oldest allocated orders first")
+	assert.Equal(t, []int64{4, 2}, ids(models.WaveRequest{MaxOrders: 2}))
+	cutoff := now.Add(-30 * time.Minute)
+	assert.Equal(t, []int64{4, 2}, ids(models.WaveRequest{Cutoff: &cutoff}))
+	assert.Equal(t, []int64{4, 1}, ids(models.WaveRequest{Zone: "A"}))
+
+	var validation *ValidationError
+	_, err := waveOrders(models.WaveRequest{Zone: "C"}, orders, reservations)
+	assert.ErrorAs(t, err, &validation)
+	req := models.WaveRequest{Zone: " a ", MaxOrders: 1}
+	assert.Nil(t, normalizeWaveRequest(&req))
+	assert.Equal(t, "A", req.Zone)
+}
+
+func TestPickTaskStates(t *testing.T) {
+	task := models.PickTask{ID: 1, Quantity: 5, Status: models.PickOpen}
+	assert.ErrorIs(t, completeTask(&task, 7, nil), ErrConflict, "Tasks are claimed before picking")
+	assert.Nil(t, claimTask(&task, 7))
+	assert.Nil(t, claimTask(&task, 7))
+	assert.ErrorIs(t, claimTask(&task, 8), ErrConflict)
+	assert.ErrorIs(t, completeTask(&task, 8, nil), ErrConflict)
+
+	var validation *ValidationError
+	assert.ErrorAs(t, completeTask(&task, 7, &models.ShortPickRequest{Quantity: 5}), &validation)
+	assert.Nil(t, completeTask(&task, 7, &models.ShortPickRequest{Quantity: 3}))
+	assert.Equal(t, models.PickShort, task.Status)
+	assert.Equal(t, int64(3), pickEntries(task)[0].Quantity)
+	assert.ErrorIs(t, claimTask(&task, 7), ErrConflict)
+}
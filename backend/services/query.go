@@ -0,0 +1,637 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// The query language filters, sorts and pages a collection:
+//
+//	inventory where total < 50 and location startswith "A" order by total desc limit 20
+//	orders where timeOrdered > 2026-01-01 and customer.username = "acme"
+//
+// Conditions compare a field with =, !=, <, <=, >, >= or a list of values
+// with in (...), and text fields with contains, startswith and endswith.
+// They combine with and, or, not and parentheses. Keywords and field names
+// are not case sensitive, text compares without case.
+
+// queryEntities are the collections that can be queried, named after the
+// resources they are read from.
+var queryEntities = []string{
+	accountList.name, itemList.name, boxList.name, inventoryList.name, orderList.name,
+	shipmentList.name, locationList.name, cartonList.name, waveList.name, pickTaskList.name,
+}
+
+const (
+	opIn         = "in"
+	opContains   = "contains"
+	opStartsWith = "startswith"
+	opEndsWith   = "endswith"
+)
+
+var queryOperators = []string{"=", "!=", "<", "<=", ">", ">=", opIn, opContains, opStartsWith, opEndsWith}
+
+type tokenKind int
+
+const (
+	endToken tokenKind = iota
+	wordToken
+	stringToken
+	operatorToken
+	punctuationToken
+)
+
+// token is a word, quoted string, operator or one of ( ) and , at a
+// position of the query.
+type token struct {
+	kind     tokenKind
+	text     string
+	position int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case endToken:
+		return "the end of the query"
+	case stringToken:
+		return strconv.Quote(t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+func wordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_.-:", r)
+}
+
+// lexQuery splits a query into tokens. Words run over letters, digits and
+// _ . - : so that dates, codes and field paths are single words.
+func lexQuery(text string) ([]token, error) {
+	runes := []rune(text)
+	var tokens []token
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		start := i
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case r == '"':
+			var value strings.Builder
+			for i++; ; i++ {
+				if i == len(runes) {
+					return nil, &QueryError{Position: start + 1, Message: "string is never closed"}
+				}
+				if runes[i] == '"' {
+					break
+				}
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				value.WriteRune(runes[i])
+			}
+			i++
+			tokens = append(tokens, token{kind: stringToken, text: value.String(), position: start + 1})
+		case strings.ContainsRune("(),", r):
+			i++
+			tokens = append(tokens, token{kind: punctuationToken, text: string(r), position: start + 1})
+		case strings.ContainsRune("=!<>", r):
+			i++
+			if r != '=' && i < len(runes) && runes[i] == '=' {
+				i++
+			}
+			op := string(runes[start:i])
+			if op == "!" {
+				return nil, &QueryError{Position: start + 1, Message: `expected "!="`}
+			}
+			tokens = append(tokens, token{kind: operatorToken, text: op, position: start + 1})
+		case wordRune(r):
+			for i < len(runes) && wordRune(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: wordToken, text: string(runes[start:i]), position: start + 1})
+		default:
+			return nil, &QueryError{Position: start + 1, Message: fmt.Sprintf("unexpected %q", r)}
+		}
+	}
+	return append(tokens, token{kind: endToken, position: len(runes) + 1}), nil
+}
+
+// queryNode is a parsed condition: and, or and not of its nodes, or the
+// comparison of a field with values.
+type queryNode struct {
+	op     string
+	nodes  []queryNode
+	field  token
+	values []token
+}
+
+// parsedQuery is a query as written, before it is checked against the
+// fields of its collection.
+type parsedQuery struct {
+	entity token
+	where  *queryNode
+	sort   []token
+	desc   []bool
+	limit  int
+	offset int
+}
+
+type queryParser struct {
+	tokens []token
+	next   int
+}
+
+func (p *queryParser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *queryParser) take() token {
+	t := p.tokens[p.next]
+	if t.kind != endToken {
+		p.next++
+	}
+	return t
+}
+
+// keyword takes the next token when it is the word given.
+func (p *queryParser) keyword(word string) bool {
+	t := p.peek()
+	if t.kind == wordToken && strings.EqualFold(t.text, word) {
+		p.next++
+		return true
+	}
+	return false
+}
+
+// punctuation takes the next token when it is the punctuation given.
+func (p *queryParser) punctuation(text string) bool {
+	t := p.peek()
+	if t.kind == punctuationToken && t.text == text {
+		p.next++
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) expected(what string) error {
+	t := p.peek()
+	return &QueryError{Position: t.position, Message: fmt.Sprintf("expected %s, found %s", what, t)}
+}
+
+func (p *queryParser) number() (int, error) {
+	t := p.peek()
+	n, err := strconv.Atoi(t.text)
+	if t.kind != wordToken || err != nil || n < 0 {
+		return 0, p.expected("a number")
+	}
+	p.next++
+	return n, nil
+}
+
+// parseQuery parses a query of the form
+//
+//	collection [where condition] [order by field [asc|desc], ...] [limit n] [offset n]
+func parseQuery(text string) (parsedQuery, error) {
+	tokens, err := lexQuery(text)
+	if err != nil {
+		return parsedQuery{}, err
+	}
+	p := &queryParser{tokens: tokens}
+
+	var query parsedQuery
+	if p.peek().kind != wordToken {
+		return parsedQuery{}, p.expected("a collection")
+	}
+	query.entity = p.take()
+	follows := "where, order by, limit or offset"
+
+	if p.keyword("where") {
+		where, err := p.or()
+		if err != nil {
+			return parsedQuery{}, err
+		}
+		query.where = &where
+		follows = "and, or, order by, limit or offset"
+	}
+	if p.keyword("order") {
+		if !p.keyword("by") {
+			return parsedQuery{}, p.expected(`"by"`)
+		}
+		for {
+			if p.peek().kind != wordToken {
+				return parsedQuery{}, p.expected("a field")
+			}
+			query.sort = append(query.sort, p.take())
+			desc := p.keyword("desc")
+			if !desc {
+				p.keyword("asc")
+			}
+			query.desc = append(query.desc, desc)
+			if !p.punctuation(",") {
+				break
+			}
+		}
+		follows = "asc, desc, a comma, limit or offset"
+	}
+	if p.keyword("limit") {
+		if query.limit, err = p.number(); err != nil {
+			return parsedQuery{}, err
+		}
+		follows = "offset"
+	}
+	if p.keyword("offset") {
+		if query.offset, err = p.number(); err != nil {
+			return parsedQuery{}, err
+		}
+		follows = "nothing more"
+	}
+	if p.peek().kind != endToken {
+		return parsedQuery{}, p.expected(follows)
+	}
+	return query, nil
+}
+
+func (p *queryParser) or() (queryNode, error) {
+	return p.combine("or", p.and)
+}
+
+func (p *queryParser) and() (queryNode, error) {
+	return p.combine("and", p.unary)
+}
+
+// combine parses one or more operands joined by a keyword.
+func (p *queryParser) combine(keyword string, operand func() (queryNode, error)) (queryNode, error) {
+	node, err := operand()
+	if err != nil {
+		return queryNode{}, err
+	}
+	nodes := []queryNode{node}
+	for p.keyword(keyword) {
+		node, err := operand()
+		if err != nil {
+			return queryNode{}, err
+		}
+		nodes = append(nodes, node)
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return queryNode{op: keyword, nodes: nodes}, nil
+}
+
+func (p *queryParser) unary() (queryNode, error) {
+	if p.keyword("not") {
+		node, err := p.unary()
+		if err != nil {
+			return queryNode{}, err
+		}
+		return queryNode{op: "not", nodes: []queryNode{node}}, nil
+	}
+	if p.punctuation("(") {
+		node, err := p.or()
+		if err != nil {
+			return queryNode{}, err
+		}
+		if !p.punctuation(")") {
+			return queryNode{}, p.expected(`")"`)
+		}
+		return node, nil
+	}
+
+	if p.peek().kind != wordToken {
+		return queryNode{}, p.expected("a field")
+	}
+	node := queryNode{field: p.take()}
+	op := p.peek()
+	node.op = strings.ToLower(op.text)
+	if (op.kind != operatorToken && op.kind != wordToken) || !slices.Contains(queryOperators, node.op) {
+		return queryNode{}, p.expected("an operator: " + strings.Join(queryOperators, " "))
+	}
+	p.next++
+
+	if node.op != opIn {
+		value, err := p.value()
+		if err != nil {
+			return queryNode{}, err
+		}
+		node.values = []token{value}
+		return node, nil
+	}
+	if !p.punctuation("(") {
+		return queryNode{}, p.expected(`"("`)
+	}
+	for {
+		value, err := p.value()
+		if err != nil {
+			return queryNode{}, err
+		}
+		node.values = append(node.values, value)
+		if p.punctuation(")") {
+			return node, nil
+		}
+		if !p.punctuation(",") {
+			return queryNode{}, p.expected(`a comma or ")"`)
+		}
+	}
+}
+
+func (p *queryParser) value() (token, error) {
+	if t := p.peek(); t.kind != wordToken && t.kind != stringToken {
+		return token{}, p.expected("a value")
+	}
+	return p.take(), nil
+}
+
+// QueryEntity parses a query and returns the collection it queries.
+func QueryEntity(text string) (string, error) {
+	query, err := parseQuery(text)
+	if err != nil {
+		return "", err
+	}
+	entity := strings.ToLower(query.entity.text)
+	if !slices.Contains(queryEntities, entity) {
+		return "", &QueryError{
+			Position: query.entity.position,
+			Message:  fmt.Sprintf("cannot query %q, only %s", query.entity.text, strings.Join(queryEntities, ", ")),
+		}
+	}
+	return entity, nil
+}
+
+// field looks up a field of a collection by its name in a query and
+// returns the name the collection gives it.
+func (spec listSpec[T]) field(name token) (string, listField[T], error) {
+	for key, field := range spec.fields {
+		if strings.EqualFold(key, name.text) {
+			return key, field, nil
+		}
+	}
+	return "", listField[T]{}, &QueryError{
+		Position: name.position,
+		Message:  fmt.Sprintf("no field %q in %s, only %s", name.text, spec.name, strings.Join(slices.Sorted(maps.Keys(spec.fields)), ", ")),
+	}
+}
+
+// bindQuery checks a parsed query against the fields of its collection and
+// returns the condition rows must meet, nil when it has none. Sorted
+// fields are named the way the collection names them.
+func bindQuery[T any](spec listSpec[T], query *parsedQuery) (condition[T], []string, error) {
+	if !strings.EqualFold(query.entity.text, spec.name) {
+		return nil, nil, &QueryError{Position: query.entity.position, Message: fmt.Sprintf("expected %s, found %s", spec.name, query.entity)}
+	}
+
+	sort := make([]string, 0, len(query.sort))
+	for i, name := range query.sort {
+		key, _, err := spec.field(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !spec.sortable(key) {
+			return nil, nil, &QueryError{Position: name.position, Message: fmt.Sprintf("cannot sort by %s", key)}
+		}
+		if query.desc[i] {
+			key = "-" + key
+		}
+		sort = append(sort, key)
+	}
+
+	if query.where == nil {
+		return nil, sort, nil
+	}
+	where, err := bindNode(spec, *query.where)
+	return where, sort, err
+}
+
+func bindNode[T any](spec listSpec[T], node queryNode) (condition[T], error) {
+	switch node.op {
+	case "and", "or", "not":
+		conditions := make([]condition[T], 0, len(node.nodes))
+		for _, n := range node.nodes {
+			c, err := bindNode(spec, n)
+			if err != nil {
+				return nil, err
+			}
+			conditions = append(conditions, c)
+		}
+		switch node.op {
+		case "and":
+			return allOf[T](conditions), nil
+		case "or":
+			return anyOf[T](conditions), nil
+		}
+		return negation[T]{conditions[0]}, nil
+	}
+
+	_, field, err := spec.field(node.field)
+	if err != nil {
+		return nil, err
+	}
+	op := node.op
+	switch {
+	case (op == opContains || op == opStartsWith || op == opEndsWith) && field.kind != textField:
+		return nil, &QueryError{Position: node.field.position, Message: fmt.Sprintf("%s only applies to text, not %s", op, node.field.text)}
+	case strings.ContainsAny(op, "<>") && field.kind == boolField:
+		return nil, &QueryError{Position: node.field.position, Message: fmt.Sprintf("%s is true or false and cannot be ordered", node.field.text)}
+	}
+
+	c := comparison[T]{field: field, op: op}
+	if op == "=" || op == "!=" {
+		c.op = opIn
+	}
+	for _, value := range node.values {
+		if c.op == opContains || c.op == opStartsWith || c.op == opEndsWith {
+			c.values = append(c.values, strings.ToLower(value.text))
+			continue
+		}
+		parsed, err := field.parse(value.text)
+		if err != nil {
+			return nil, &QueryError{Position: value.position, Message: err.Error()}
+		}
+		c.values = append(c.values, parsed)
+	}
+	if op == "!=" {
+		return negation[T]{c}, nil
+	}
+	return c, nil
+}
+
+// condition is a condition on the rows of a collection, met by rows of
+// the memory store or compiled to SQL with its values as arguments.
+type condition[T any] interface {
+	matches(row T) bool
+	sql(args *[]any) string
+}
+
+type allOf[T any] []condition[T]
+
+func (all allOf[T]) matches(row T) bool {
+	for _, c := range all {
+		if !c.matches(row) {
+			return false
+		}
+	}
+	return true
+}
+
+func (all allOf[T]) sql(args *[]any) string {
+	return joinSQL(all, " and ", args)
+}
+
+type anyOf[T any] []condition[T]
+
+func (some anyOf[T]) matches(row T) bool {
+	for _, c := range some {
+		if c.matches(row) {
+			return true
+		}
+	}
+	return false
+}
+
+func (some anyOf[T]) sql(args *[]any) string {
+	return joinSQL(some, " or ", args)
+}
+
+func joinSQL[T any](conditions []condition[T], op string, args *[]any) string {
+	parts := make([]string, len(conditions))
+	for i, c := range conditions {
+		parts[i] = c.sql(args)
+	}
+	return "(" + strings.Join(parts, op) + ")"
+}
+
+type negation[T any] struct {
+	inner condition[T]
+}
+
+func (n negation[T]) matches(row T) bool {
+	return !n.inner.matches(row)
+}
+
+func (n negation[T]) sql(args *[]any) string {
+	return "not " + n.inner.sql(args)
+}
+
+// comparison compares a field with values. A field a row has several
+// values of meets it when any of them does.
+type comparison[T any] struct {
+	field  listField[T]
+	op     string
+	values []any
+}
+
+func (c comparison[T]) matches(row T) bool {
+	return slices.ContainsFunc(values(c.field.value(row)), func(value any) bool {
+		switch c.op {
+		case opIn:
+			return slices.ContainsFunc(c.values, func(want any) bool {
+				return sameValue(value, want)
+			})
+		case opContains:
+			return strings.Contains(strings.ToLower(value.(string)), c.values[0].(string))
+		case opStartsWith:
+			return strings.HasPrefix(strings.ToLower(value.(string)), c.values[0].(string))
+		case opEndsWith:
+			return strings.HasSuffix(strings.ToLower(value.(string)), c.values[0].(string))
+		}
+		order := compareValues(normalized(value), normalized(c.values[0]))
+		switch c.op {
+		case "<":
+			return order < 0
+		case "<=":
+			return order <= 0
+		case ">":
+			return order > 0
+		}
+		return order >= 0
+	})
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// sql compares the column of the field, text without case and timestamps
+// by their date, with values passed as arguments.
+func (c comparison[T]) sql(args *[]any) string {
+	column := c.field.column
+	switch c.field.kind {
+	case textField:
+		column = "lower(" + column + ")"
+	case dateField:
+		column += "::date"
+	}
+
+	var match string
+	switch c.op {
+	case opIn:
+		*args = append(*args, sqlValues(c.field.kind, c.values))
+		if c.field.kind == dateField {
+			match = fmt.Sprintf("%s = any($%d::date[])", column, len(*args))
+		} else {
+			match = fmt.Sprintf("%s = any($%d)", column, len(*args))
+		}
+	case opContains, opStartsWith, opEndsWith:
+		pattern := likeEscaper.Replace(c.values[0].(string))
+		switch c.op {
+		case opContains:
+			pattern = "%" + pattern + "%"
+		case opStartsWith:
+			pattern += "%"
+		case opEndsWith:
+			pattern = "%" + pattern
+		}
+		*args = append(*args, pattern)
+		match = fmt.Sprintf("%s like $%d", column, len(*args))
+	default:
+		*args = append(*args, sqlValue(c.values[0]))
+		match = fmt.Sprintf("%s %s $%d", column, c.op, len(*args))
+		if c.field.kind == dateField {
+			match += "::date"
+		}
+	}
+
+	if c.field.within != "" {
+		return fmt.Sprintf(c.field.within, match)
+	}
+	return match
+}
+
+// sqlValues converts the values of a comparison to the arguments they are
+// compared with: text in lower case and dates as text.
+func sqlValues(kind fieldKind, values []any) any {
+	switch kind {
+	case intField:
+		ints := make([]int64, len(values))
+		for i, v := range values {
+			ints[i] = v.(int64)
+		}
+		return ints
+	case boolField:
+		bools := make([]bool, len(values))
+		for i, v := range values {
+			bools[i] = v.(bool)
+		}
+		return bools
+	}
+	texts := make([]string, len(values))
+	for i, v := range values {
+		texts[i] = sqlValue(v).(string)
+	}
+	return texts
+}
+
+func sqlValue(value any) any {
+	switch value := value.(type) {
+	case string:
+		return strings.ToLower(value)
+	case time.Time:
+		return value.Format(time.DateOnly)
+	}
+	return value
+}
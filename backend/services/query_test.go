@@ -0,0 +1,145 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/WMS/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuerySQL(t *testing.T) {
+	query := models.ListQuery{Query: `inventory where total < 50 and location startswith "A_" order by total desc limit 20`}
+	where, err := inventoryList.check(&query)
+	assert.Nil(t, err)
+	var args []any
+	assert.Equal(t, "((select coalesce(sum(s.quantity), 0) from stock s where s.item_id = i.id and s.quantity > 0) < $1"+
+		" and exists (select 1 from stock s join location l on l.id = s.location_id where s.item_id = i.id and s.quantity > 0 and lower(l.code) like $2))",
+		where.sql(&args))
+	assert.Equal(t, []any{int64(50), `a\_%`}, args, "Wildcards in values are escaped")
+	assert.Equal(t, []string{"-total", "id"}, query.Sort)
+	assert.Equal(t, 20, query.Limit)
+
+	query = models.ListQuery{Query: `ORDERS WHERE timeordered > 2026-01-01 and not (customer.username = "Acme" or id in (1, 2))`}
+	orders, err := orderList.check(&query)
+	assert.Nil(t, err)
+	args = nil
+	assert.Equal(t, "(timeOrdered::date > $1::date and not (lower(coalesce(customer->>'username', '')) = any($2) or id = any($3)))", orders.sql(&args))
+	assert.Equal(t, []any{"2026-01-01", []string{"acme"}, []int64{1, 2}}, args)
+
+	query = models.ListQuery{Query: "orders where timeOrdered in (2026-01-01, 2026-01-02)"}
+	orders, err = orderList.check(&query)
+	assert.Nil(t, err)
+	args = nil
+	assert.Equal(t, "timeOrdered::date = any($1::date[])", orders.sql(&args), "Dates are cast inside any")
+	assert.Equal(t, models.DefaultPageLimit, query.Limit)
+
+	query = models.ListQuery{Query: "locations where active != true", Filters: map[string][]string{"zone": {"A"}}}
+	locations, err := locationList.check(&query)
+	assert.Nil(t, err)
+	args = nil
+	assert.Equal(t, "(not active = any($1) and lower(zone) = any($2))", locations.sql(&args), "Filters are met along with the query")
+}
+
+func TestQueryErrors(t *testing.T) {
+	for text, want := range map[string]QueryError{
+		`inventory where total <`:          {Position: 24, Message: "expected a value, found the end of the query"},
+		`inventory where totl < 5`:         {Position: 17},
+		`inventory where (total < 5`:       {Position: 27, Message: `expected ")", found the end of the query`},
+		`inventory where total ~ 5`:        {Position: 23, Message: `unexpected '~'`},
+		`inventory where name = "beans`:    {Position: 24, Message: "string is never closed"},
+		`inventory where total = many`:     {Position: 25, Message: `"many" is not a whole number`},
+		`inventory where total contains 5`: {Position: 17, Message: "contains only applies to text, not total"},
+		`inventory where id = 1 and`:       {Position: 27, Message: "expected a field, found the end of the query"},
+		`inventory where total is 5`:       {Position: 23},
+		`inventory order total`:            {Position: 17, Message: `expected "by", found "total"`},
+		`inventory order by area`:          {Position: 20, Message: "cannot sort by area"},
+		`inventory limit ten`:              {Position: 17, Message: "expected a number, found \"ten\""},
+		`inventory limit 5 sideways`:       {Position: 19, Message: `expected offset, found "sideways"`},
+		`items where id = 1`:               {Position: 1, Message: `expected inventory, found "items"`},
+	} {
+		_, err := inventoryList.check(&models.ListQuery{Query: text})
+		var queryErr *QueryError
+		if assert.ErrorAs(t, err, &queryErr, text) {
+			assert.Equal(t, want.Position, queryErr.Position, text)
+			if want.Message != "" {
+				assert.Equal(t, want.Message, queryErr.Message, text)
+			}
+		}
+	}
+
+	_, err := orderList.check(&models.ListQuery{Query: "orders where status = lost"})
+	assert.EqualError(t, err, `position 23: "LOST" is not one of `+strings.Join(models.OrderStatuses, ", "))
+
+	_, err = cartonList.check(&models.ListQuery{Query: "cartons where active > false"})
+	assert.EqualError(t, err, "position 15: active is true or false and cannot be ordered")
+}
+
+func TestQueryEntity(t *testing.T) {
+	entity, err := QueryEntity(`Inventory where total < 50`)
+	assert.Nil(t, err)
+	assert.Equal(t, "inventory", entity)
+
+	_, err = QueryEntity(`pallets where total < 50`)
+	var queryErr *QueryError
+	if assert.ErrorAs(t, err, &queryErr) {
+		assert.Equal(t, 1, queryErr.Position)
+	}
+
+	_, err = QueryEntity(`"inventory"`)
+	assert.EqualError(t, err, `position 1: expected a collection, found "inventory"`)
+}
+
+func TestMemoryStoreQuery(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	ordered := time.Date(2026, 1, 1, 18, 0, 0, 0, time.UTC)
+	for i, username := range []string{"acme", "bolt", "acme", "cogs"} {
+		customer := models.Account{ID: int64(10 + i), Username: username}
+		order := models.Order{ID: int64(i + 1), Customer: customer, TimeOrdered: ordered.AddDate(0, 0, i)}
+		assert.Nil(t, store.AddOrder(ctx, order))
+	}
+	ids := func(page models.Page[models.Order]) []int64 {
+		var found []int64
+		for _, order := range page.Data {
+			found = append(found, order.ID)
+		}
+		return found
+	}
+
+	page, err := store.GetOrders(ctx, models.ListQuery{Query: `orders where timeOrdered > 2026-01-01 and customer.username = "ACME"`})
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{3}, ids(page), "Times compare by date")
+
+	page, err = store.GetOrders(ctx, models.ListQuery{Query: `orders where not customer.username in (acme, bolt) or id <= 1 order by id desc`})
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{4, 1}, ids(page))
+
+	page, err = store.GetOrders(ctx, models.ListQuery{Query: `orders where customer.username endswith "S" or customer.username contains "ol" limit 1 offset 1`})
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{4}, ids(page))
+	assert.Equal(t, models.PageInfo{Limit: 1, Offset: 1, Total: 2, Sort: []string{"id"}}, page.Page)
+
+	// Inventory matches when any of its locations does
+	assert.Nil(t, store.AddItem(ctx, models.Item{ID: 1, Name: "beans"}))
+	assert.Nil(t, store.AddItem(ctx, models.Item{ID: 2, Name: "rice"}))
+	for i, code := range []string{"A-1", "B-1", "B-2"} {
+		assert.Nil(t, store.AddLocation(ctx, models.Location{ID: int64(i + 1), Code: code, Active: true}))
+	}
+	assert.Nil(t, store.AddInventory(ctx, models.Inventory{ID: 1, Item: models.Item{ID: 1}, Locations: []models.LocationData{{Area: "A-1", Count: 5}, {Area: "B-1", Count: 60}}}))
+	assert.Nil(t, store.AddInventory(ctx, models.Inventory{ID: 2, Item: models.Item{ID: 2}, Locations: []models.LocationData{{Area: "B-2", Count: 9}}}))
+
+	inventory, err := store.GetAllInventory(ctx, models.ListQuery{Query: `inventory where total < 50 and location startswith "b"`})
+	assert.Nil(t, err)
+	if assert.Len(t, inventory.Data, 1) {
+		assert.Equal(t, int64(2), inventory.Data[0].ID)
+	}
+	inventory, err = store.GetAllInventory(ctx, models.ListQuery{Query: `inventory where not location startswith "b"`})
+	assert.Nil(t, err)
+	assert.Empty(t, inventory.Data, "Inventory at any matching location does not meet the negation")
+}
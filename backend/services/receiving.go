@@ -0,0 +1,87 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"fmt"
+
+	"github.com/WMS/models"
+)
+
+func shipmentReference(id int64) string {
+	return fmt.Sprintf("shipment:%d", id)
+}
+
+// checkShipmentStatus returns ErrConflict unless the shipment is in one of
+// the given statuses.
+func checkShipmentStatus(shipment models.Shipment, statuses ...string) error {
+	for _, status := range statuses {
+		if shipment.Status == status {
+			return nil
+		}
+	}
+	return fmt.Errorf("shipment %d is %s: %w", shipment.ID, shipment.Status, ErrConflict)
+}
+
+// receiptLines matches the counted lines of a request against the shipment
+// payload. Lines left out of the request were not received at all.
+func receiptLines(shipment models.Shipment, req models.ReceiveRequest) ([]models.ReceiptLine, error) {
+	if req.LocationID == 0 {
+		return nil, invalid("locationId", "receiving location is required")
+	}
+
+	lines := make([]models.ReceiptLine, len(shipment.Payload))
+	for i, group := range shipment.Payload {
+		lines[i] = models.ReceiptLine{Line: i, ItemID: group.Item.ID, Expected: group.Count}
+	}
+
+	counted := make([]bool, len(lines))
+	for _, line := range req.Lines {
+		if line.Line < 0 || line.Line >= len(lines) {
+			return nil, invalid("lines", "shipment %d has no line %d", shipment.ID, line.Line)
+		}
+		if counted[line.Line] {
+			return nil, invalid("lines", "line %d is counted twice", line.Line)
+		}
+		if line.Received < 0 || line.Damaged < 0 {
+			return nil, invalid("lines", "counts on line %d cannot be negative", line.Line)
+		}
+		counted[line.Line] = true
+		lines[line.Line].Received = line.Received
+		lines[line.Line].Damaged = line.Damaged
+	}
+
+	for i := range lines {
+		line := &lines[i]
+		switch total := line.Received + line.Damaged; {
+		case total > line.Expected:
+			line.Discrepancies = append(line.Discrepancies, models.DiscrepancyOver)
+		case total < line.Expected:
+			line.Discrepancies = append(line.Discrepancies, models.DiscrepancyShort)
+		}
+		if line.Damaged > 0 {
+			line.Discrepancies = append(line.Discrepancies, models.DiscrepancyDamaged)
+		}
+	}
+	return lines, nil
+}
+
+// receiptEntries posts the good units of every line into the receiving
+// location.
+func receiptEntries(shipmentID int64, locationID int64, lines []models.ReceiptLine) []models.StockTransaction {
+	var entries []models.StockTransaction
+	for _, line := range lines {
+		if line.Received == 0 {
+			continue
+		}
+		entries = append(entries, models.StockTransaction{
+			Type:         models.StockReceipt,
+			ItemID:       line.ItemID,
+			ToLocationID: locationID,
+			Quantity:     line.Received,
+			Reason:       models.ReasonReceived,
+			Reference:    shipmentReference(shipmentID),
+		})
+	}
+	return entries
+}
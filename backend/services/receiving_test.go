@@ -0,0 +1,41 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"testing"
+
+	"github.com/WMS/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReceiptLines(t *testing.T) {
+	shipment := models.Shipment{ID: 4, Payload: []models.ItemGroup{
+		{Item: models.Item{ID: 1}, Count: 10},
+		{Item: models.Item{ID: 2}, Count: 5},
+		{Item: models.Item{ID: 3}, Count: 2},
+	}}
+
+	lines, err := receiptLines(shipment, models.ReceiveRequest{LocationID: 9, Lines: []models.ReceiptLine{
+		{Line: 0, Received: 10},
+		{Line: 1, Received: 5, Damaged: 2},
+	}})
+	assert.Nil(t, err)
+	assert.Nil(t, lines[0].Discrepancies)
+	assert.Equal(t, []string{models.DiscrepancyOver, models.DiscrepancyDamaged}, lines[1].Discrepancies)
+	assert.Equal(t, []string{models.DiscrepancyShort}, lines[2].Discrepancies, "Lines left out were not received")
+
+	entries := receiptEntries(shipment.ID, 9, lines)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, models.StockTransaction{Type: models.StockReceipt, ItemID: 2, ToLocationID: 9, Quantity: 5, Reason: models.ReasonReceived, Reference: "shipment:4"}, entries[1])
+
+	var validation *ValidationError
+	_, err = receiptLines(shipment, models.ReceiveRequest{Lines: []models.ReceiptLine{{Line: 0}}})
+	assert.ErrorAs(t, err, &validation, "Location is required")
+	_, err = receiptLines(shipment, models.ReceiveRequest{LocationID: 9, Lines: []models.ReceiptLine{{Line: 3}}})
+	assert.ErrorAs(t, err, &validation)
+	_, err = receiptLines(shipment, models.ReceiveRequest{LocationID: 9, Lines: []models.ReceiptLine{{Line: 1}, {Line: 1}}})
+	assert.ErrorAs(t, err, &validation)
+	_, err = receiptLines(shipment, models.ReceiveRequest{LocationID: 9, Lines: []models.ReceiptLine{{Line: 1, Damaged: -1}}})
+	assert.ErrorAs(t, err, &validation)
+}
@@ -0,0 +1,229 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/WMS/models"
+)
+
+type AccountRepository interface {
+	AddAccount(ctx context.Context, account models.Account) error
+	GetAccounts(ctx context.Context, query models.ListQuery) (models.Page[models.Account], error)
+	GetAccount(ctx context.Context, id int) (models.Account, error)
+	GetAccountsByUsername(ctx context.Context, username string) ([]models.Account, error)
+	UpdateAccount(ctx context.Context, id int, newData models.Account) error
+	// SetPassword hashes and stores a new password and revokes the tokens
+	// of the account.
+	SetPassword(ctx context.Context, id int, password string) error
+	DeleteAccount(ctx context.Context, id int) error
+}
+
+// TokenRepository keeps the refresh tokens of accounts and the access
+// tokens revoked before they expire.
+type TokenRepository interface {
+	AddRefreshToken(ctx context.Context, token models.RefreshToken) error
+	// RotateRefreshToken revokes the live refresh token with a hash and
+	// stores next in its family in its place. A token revoked before has
+	// been used twice and revokes its whole family.
+	RotateRefreshToken(ctx context.Context, hash string, next models.RefreshToken) (models.RefreshToken, error)
+	// RevokeRefreshToken revokes the family of the refresh token with a
+	// hash.
+	RevokeRefreshToken(ctx context.Context, hash string) error
+	RevokeAccessToken(ctx context.Context, jti string, expires time.Time) error
+	// TokenGeneration returns the token generation of an account, raised
+	// by UpdateAccount when it is deactivated or its role changes.
+	TokenGeneration(ctx context.Context, accountID int64) (int64, error)
+	TokenRevoked(ctx context.Context, jti string, accountID int64, generation int64) (bool, error)
+}
+
+// LoginAttemptRepository counts failed logins by key, a username or a
+// client address.
+type LoginAttemptRepository interface {
+	LoginAttempts(ctx context.Context, key string) (models.LoginAttempts, error)
+	// RecordLoginFailure counts a failed login at a time, returning the
+	// failures counted before it. Failures of any key last seen before
+	// forgetBefore are forgotten first.
+	RecordLoginFailure(ctx context.Context, key string, at time.Time, forgetBefore time.Time) (models.LoginAttempts, error)
+	// ForgiveLoginFailure takes back one failure, counted for an attempt
+	// that did not fail.
+	ForgiveLoginFailure(ctx context.Context, key string) error
+	ClearLoginFailures(ctx context.Context, key string) error
+}
+
+// TwoFactorRepository keeps the TOTP secrets and recovery codes of
+// accounts.
+type TwoFactorRepository interface {
+	GetTwoFactor(ctx context.Context, accountID int64) (models.TwoFactor, error)
+	// SetTwoFactor replaces the secret of an account with an unconfirmed
+	// one, and its recovery codes with those hashed.
+	SetTwoFactor(ctx context.Context, secret models.TwoFactor, recoveryHashes []string) error
+	// UseTOTPStep records a code of an account's secret as used, which
+	// confirms the secret. It reports false if a code of the step or a
+	// later one was used already.
+	UseTOTPStep(ctx context.Context, accountID int64, step int64) (bool, error)
+	// UseRecoveryCode spends the unused recovery code with a hash, and
+	// reports whether there was one.
+	UseRecoveryCode(ctx context.Context, accountID int64, hash string) (bool, error)
+	DeleteTwoFactor(ctx context.Context, accountID int64) error
+}
+
+// APIKeyRepository keeps the API keys of machine integrations.
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key models.APIKey) (models.APIKey, error)
+	GetAPIKeys(ctx context.Context, query models.ListQuery) (models.Page[models.APIKey], error)
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (models.APIKey, error)
+	// TouchAPIKey records when an API key was last used.
+	TouchAPIKey(ctx context.Context, id int64, at time.Time) error
+	DeleteAPIKey(ctx context.Context, id int) error
+}
+
+type ItemRepository interface {
+	AddItem(ctx context.Context, item models.Item) error
+	GetItems(ctx context.Context, query models.ListQuery) (models.Page[models.Item], error)
+	GetItemsList(ctx context.Context, query models.ListQuery) (models.Page[models.ItemInfo], error)
+	GetItem(ctx context.Context, id int) (models.Item, error)
+	UpdateItem(ctx context.Context, id int, newData models.Item) error
+	DeleteItem(ctx context.Context, id int) error
+	// GetUnitsOfMeasure returns the complete unit-of-measure hierarchy of
+	// each item that exists, keyed by item ID.
+	GetUnitsOfMeasure(ctx context.Context, itemIDs []int64) (map[int64][]models.UnitOfMeasure, error)
+	SetUnitsOfMeasure(ctx context.Context, itemID int, units []models.UnitOfMeasure) error
+}
+
+type BoxRepository interface {
+	AddBox(ctx context.Context, box models.Box) error
+	GetBoxes(ctx context.Context, query models.ListQuery) (models.Page[models.Box], error)
+	GetBox(ctx context.Context, id int) (models.Box, error)
+	UpdateBox(ctx context.Context, id int, newData models.Box) error
+	DeleteBox(ctx context.Context, id int) error
+}
+
+type InventoryRepository interface {
+	AddInventory(ctx context.Context, inv models.Inventory) error
+	GetAllInventory(ctx context.Context, query models.ListQuery) (models.Page[models.Inventory], error)
+	GetInventory(ctx context.Context, id int) (models.Inventory, error)
+	GetAvailability(ctx context.Context, id int) (models.Availability, error)
+	UpdateInventory(ctx context.Context, id int, newData models.Inventory) error
+	DeleteInventory(ctx context.Context, id int) error
+}
+
+type LocationRepository interface {
+	AddLocation(ctx context.Context, loc models.Location) error
+	GetLocations(ctx context.Context, query models.ListQuery) (models.Page[models.Location], error)
+	GetLocation(ctx context.Context, id int) (models.Location, error)
+	GetLocationStock(ctx context.Context, id int) ([]models.StockLevel, error)
+	UpdateLocation(ctx context.Context, id int, newData models.Location) error
+	DeleteLocation(ctx context.Context, id int) error
+}
+
+// StockRepository changes stock only by appending to the ledger, each call
+// in a single transaction.
+type StockRepository interface {
+	MoveStock(ctx context.Context, move models.StockMoveRequest) ([]models.StockTransaction, error)
+	TransferStock(ctx context.Context, transfer models.StockTransferRequest) ([]models.StockTransaction, error)
+	AdjustStock(ctx context.Context, adj models.StockAdjustmentRequest) ([]models.StockTransaction, error)
+	GetStockTransactions(ctx context.Context, query models.ListQuery) (models.Page[models.StockTransaction], error)
+}
+
+type OrderRepository interface {
+	AddOrder(ctx context.Context, order models.Order) error
+	GetOrders(ctx context.Context, query models.ListQuery) (models.Page[models.Order], error)
+	GetOrder(ctx context.Context, id int) (models.Order, error)
+	UpdateOrder(ctx context.Context, id int, newData models.Order) error
+	DeleteOrder(ctx context.Context, id int) error
+	TransitionOrder(ctx context.Context, id int, status string) (models.Order, error)
+}
+
+// PickingRepository turns allocated orders into waves of pick tasks worked
+// from the floor. Picking posts to the stock ledger.
+type PickingRepository interface {
+	CreateWave(ctx context.Context, req models.WaveRequest) (models.Wave, error)
+	GetWaves(ctx context.Context, query models.ListQuery) (models.Page[models.Wave], error)
+	GetWave(ctx context.Context, id int) (models.Wave, error)
+	GetPickTasks(ctx context.Context, query models.ListQuery) (models.Page[models.PickTask], error)
+	ClaimPickTask(ctx context.Context, id int) (models.PickTask, error)
+	ConfirmPickTask(ctx context.Context, id int) (models.PickTask, error)
+	ShortPickTask(ctx context.Context, id int, req models.ShortPickRequest) (models.PickTask, error)
+}
+
+// PackingRepository keeps the cartons orders ship in, suggests how to pack
+// an order and records the cartons it was packed into.
+type PackingRepository interface {
+	AddCarton(ctx context.Context, carton models.Carton) error
+	GetCartons(ctx context.Context, query models.ListQuery) (models.Page[models.Carton], error)
+	GetCarton(ctx context.Context, id int) (models.Carton, error)
+	UpdateCarton(ctx context.Context, id int, newData models.Carton) error
+	DeleteCarton(ctx context.Context, id int) error
+	SuggestCartons(ctx context.Context, orderID int) (models.Packing, error)
+	PackOrder(ctx context.Context, orderID int, req models.PackRequest) (models.Packing, error)
+	GetPacking(ctx context.Context, orderID int) (models.Packing, error)
+}
+
+// ScanRepository resolves what a handheld scanned to the record it
+// identifies.
+type ScanRepository interface {
+	ResolveScan(ctx context.Context, scan models.Scan) (models.ScanResult, error)
+}
+
+// SearchRepository finds records of several kinds by the words they
+// contain.
+type SearchRepository interface {
+	Search(ctx context.Context, query models.SearchQuery) (models.SearchResults, error)
+}
+
+type ShipmentRepository interface {
+	AddShipment(ctx context.Context, shipment models.Shipment) error
+	GetShipments(ctx context.Context, query models.ListQuery) (models.Page[models.Shipment], error)
+	GetShipment(ctx context.Context, id int) (models.Shipment, error)
+	UpdateShipment(ctx context.Context, id int, newData models.Shipment) error
+	DeleteShipment(ctx context.Context, id int) error
+	ArriveShipment(ctx context.Context, id int) (models.Shipment, error)
+	ReceiveShipment(ctx context.Context, id int, req models.ReceiveRequest) (models.Shipment, error)
+}
+
+// Repository is the full data layer used by the controllers. PostgresStore
+// and MemoryStore both implement it.
+type Repository interface {
+	AccountRepository
+	TokenRepository
+	LoginAttemptRepository
+	TwoFactorRepository
+	APIKeyRepository
+	ItemRepository
+	BoxRepository
+	InventoryRepository
+	LocationRepository
+	StockRepository
+	OrderRepository
+	PickingRepository
+	PackingRepository
+	ScanRepository
+	SearchRepository
+	ShipmentRepository
+	Ping(ctx context.Context) error
+	Close()
+}
+
+// StatsReporter is implemented by repositories backed by a connection pool.
+type StatsReporter interface {
+	Stats() models.PoolStats
+}
+
+// NewRepository opens the repository selected by the DBDRIVER environment
+// variable: "postgres" (default) or "memory".
+func NewRepository(ctx context.Context) (Repository, error) {
+	switch driver := os.Getenv("DBDRIVER"); driver {
+	case "", "postgres":
+		return NewPostgresStore(ctx, DatabaseURL())
+	case "memory":
+		fmt.Println("Using in-memory store, data will not persist!")
+		return NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown DBDRIVER: %q", driver)
+	}
+}
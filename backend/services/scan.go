@@ -0,0 +1,179 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/WMS/barcode"
+	"github.com/WMS/models"
+)
+
+// defaultCompanyPrefix numbers license plates when GS1PREFIX does not
+// give the GS1 company prefix of the warehouse.
+const defaultCompanyPrefix = "0000000"
+
+// licensePlate returns the SSCC a packed carton is labelled and scanned
+// by.
+func licensePlate(cartonID int64) (string, error) {
+	return barcode.SSCC(cmp.Or(os.Getenv("GS1PREFIX"), defaultCompanyPrefix), cartonID)
+}
+
+// ParseScan reads raw scanner data. GS1-128 data is split into its
+// application identifiers, bare GTINs and SSCCs are recognized by their
+// check digits and anything else is kept as a text code such as a
+// location code or an order reference.
+func ParseScan(data string) (models.Scan, error) {
+	scan := models.Scan{Data: data}
+	text := strings.TrimSpace(data)
+	if text == "" {
+		return scan, invalid("data", "scan is empty")
+	}
+
+	if barcode.IsGS1(text) {
+		elements, err := barcode.ParseGS1(text)
+		if err != nil {
+			return scan, invalid("data", "%s", err.Error())
+		}
+		for _, element := range elements {
+			switch element.AI {
+			case barcode.AISSCC:
+				scan.SSCC = element.Value
+			case barcode.AIGTIN:
+				scan.GTIN = element.Value
+			case barcode.AIContent:
+				scan.GTIN = cmp.Or(scan.GTIN, element.Value)
+			case barcode.AILot:
+				scan.Lot = element.Value
+			case barcode.AIExpiry:
+				expiry, _ := barcode.ParseDate(element.Value)
+				scan.Expiry = &expiry
+			case barcode.AISerial:
+				scan.Serial = element.Value
+			case barcode.AICount:
+				scan.Count, _ = strconv.ParseInt(element.Value, 10, 64)
+			}
+		}
+		if scan.GTIN == "" && scan.SSCC == "" {
+			return scan, invalid("data", "GS1 scan has neither a GTIN nor an SSCC")
+		}
+		return scan, nil
+	}
+
+	if gtin, err := barcode.Normalize(text); err == nil {
+		scan.GTIN = gtin
+	} else if barcode.ValidateSSCC(text) == nil {
+		scan.SSCC = text
+	} else {
+		scan.Code = text
+	}
+	return scan, nil
+}
+
+// scanOrderID reads an order reference such as "order:42".
+func scanOrderID(code string) (int64, bool) {
+	prefix, id, ok := strings.Cut(strings.ToLower(code), ":")
+	if !ok || prefix != "order" {
+		return 0, false
+	}
+	orderID, err := strconv.ParseInt(id, 10, 64)
+	return orderID, err == nil && orderID > 0
+}
+
+// productScan fills in a scanned product code from the item it belongs
+// to: the item itself, one of its boxes or one of its pack levels.
+func productScan(result *models.ScanResult, item models.Item, boxes []models.Box, units []models.UnitOfMeasure) {
+	result.Kind = models.ScanItem
+	result.Item = &item
+	for _, unit := range units {
+		if unit.UPC == result.Scan.GTIN {
+			result.Unit = &unit
+			break
+		}
+	}
+	for _, box := range boxes {
+		if box.UPC == result.Scan.GTIN {
+			box.Item = item
+			result.Kind = models.ScanBox
+			result.Box = &box
+			break
+		}
+	}
+}
+
+func action(name string, method string, path string, args ...any) models.ScanAction {
+	return models.ScanAction{Name: name, Method: method, Path: fmt.Sprintf(path, args...)}
+}
+
+// scanActions lists what the device may do next with what it scanned.
+// Orders, by themselves or through a license plate, offer the next step
+// of their workflow.
+func scanActions(result models.ScanResult) []models.ScanAction {
+	actions := []models.ScanAction{}
+	switch result.Kind {
+	case models.ScanItem, models.ScanBox:
+		id := result.Item.ID
+		actions = append(actions,
+			action("VIEW_ITEM", http.MethodGet, "/api/items/%d", id),
+			action("VIEW_UNITS", http.MethodGet, "/api/items/%d/uoms", id),
+			action("VIEW_STOCK", http.MethodGet, "/api/stock/transactions?itemId=%d", id),
+			action("MOVE_STOCK", http.MethodPost, "/api/stock/moves"),
+			action("ADJUST_STOCK", http.MethodPost, "/api/stock/adjustments"),
+		)
+		if result.Box != nil {
+			actions = append(actions, action("VIEW_BOX", http.MethodGet, "/api/boxes/%d", result.Box.ID))
+		}
+
+	case models.ScanLocation:
+		location := result.Location
+		actions = append(actions,
+			action("VIEW_LOCATION", http.MethodGet, "/api/locations/%d", location.ID),
+			action("VIEW_INVENTORY", http.MethodGet, "/api/locations/%d/inventory", location.ID),
+		)
+		if location.Active {
+			actions = append(actions,
+				action("MOVE_STOCK", http.MethodPost, "/api/stock/moves"),
+				action("ADJUST_STOCK", http.MethodPost, "/api/stock/adjustments"),
+			)
+			if location.Type == models.LocationDock {
+				actions = append(actions, action("RECEIVE_SHIPMENT", http.MethodPost, "/api/shipments/:id/receive"))
+			}
+		}
+
+	case models.ScanLicensePlate, models.ScanOrder:
+		order := result.Order
+		actions = append(actions, action("VIEW_ORDER", http.MethodGet, "/api/orders/%d", order.ID))
+		switch order.Status {
+		case models.OrderPlaced:
+			actions = append(actions, action("ALLOCATE", http.MethodPost, "/api/orders/%d/transition", order.ID))
+		case models.OrderAllocated:
+			actions = append(actions, action("CREATE_WAVE", http.MethodPost, "/api/waves"))
+		case models.OrderPicking:
+			actions = append(actions,
+				action("SUGGEST_CARTONS", http.MethodGet, "/api/packing/%d/suggestion", order.ID),
+				action("PACK", http.MethodPost, "/api/packing/%d", order.ID),
+			)
+		case models.OrderPacked:
+			actions = append(actions,
+				action("VIEW_PACKING", http.MethodGet, "/api/packing/%d", order.ID),
+				action("SHIP", http.MethodPost, "/api/orders/%d/transition", order.ID),
+			)
+		case models.OrderShipped:
+			actions = append(actions,
+				action("VIEW_PACKING", http.MethodGet, "/api/packing/%d", order.ID),
+				action("DELIVER", http.MethodPost, "/api/orders/%d/transition", order.ID),
+			)
+		}
+	}
+	return actions
+}
+
+// noMatch reports a scan nothing was found for.
+func noMatch(scan models.Scan) error {
+	return fmt.Errorf("nothing matches scan %q: %w", cmp.Or(scan.SSCC, scan.GTIN, scan.Code), ErrNotFound)
+}
@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WMS/barcode"
+	"github.com/WMS/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseScan(t *testing.T) {
+	expiry := time.Date(2026, time.December, 31, 0, 0, 0, 0, time.UTC)
+	scan, err := ParseScan("]C101100360002914591726123110L42" + barcode.GS + "21SN7")
+	assert.Nil(t, err)
+	assert.Equal(t, models.Scan{
+		Data:   "]C101100360002914591726123110L42" + barcode.GS + "21SN7",
+		GTIN:   "10036000291459",
+		Lot:    "L42",
+		Expiry: &expiry,
+		Serial: "SN7",
+	}, scan)
+
+	scan, err = ParseScan("(00)006141411234567890(02)00036000291452(37)12")
+	assert.Nil(t, err)
+	assert.Equal(t, "006141411234567890", scan.SSCC)
+	assert.Equal(t, "00036000291452", scan.GTIN, "Contents of a logistic unit")
+	assert.Equal(t, int64(12), scan.Count)
+
+	scan, err = ParseScan(" 036000291452\n")
+	assert.Nil(t, err)
+	assert.Equal(t, "00036000291452", scan.GTIN, "Bare codes are normalized")
+
+	scan, err = ParseScan("006141411234567890")
+	assert.Nil(t, err)
+	assert.Equal(t, "006141411234567890", scan.SSCC)
+
+	scan, err = ParseScan("036000291453")
+	assert.Nil(t, err)
+	assert.Equal(t, "036000291453", scan.Code, "A bad check digit is no GTIN")
+
+	var validation *ValidationError
+	for _, data := range []string{"", " ", "]C1011003600029145817261231", "(10)L42"} {
+		_, err := ParseScan(data)
+		assert.ErrorAs(t, err, &validation, data)
+	}
+}
+
+func TestScanActions(t *testing.T) {
+	names := func(result models.ScanResult) []string {
+		var names []string
+		for _, action := range scanActions(result) {
+			names = append(names, action.Name)
+		}
+		return names
+	}
+
+	item := models.Item{ID: 3}
+	assert.Equal(t, []string{"VIEW_ITEM", "VIEW_UNITS", "VIEW_STOCK", "MOVE_STOCK", "ADJUST_STOCK", "VIEW_BOX"},
+		names(models.ScanResult{Kind: models.ScanBox, Item: &item, Box: &models.Box{ID: 4}}))
+	assert.Equal(t, models.ScanAction{Name: "VIEW_STOCK", Method: "GET", Path: "/api/stock/transactions?itemId=3"},
+		scanActions(models.ScanResult{Kind: models.ScanItem, Item: &item})[2])
+
+	dock := models.Location{ID: 1, Type: models.LocationDock, Active: true}
+	assert.Contains(t, names(models.ScanResult{Kind: models.ScanLocation, Location: &dock}), "RECEIVE_SHIPMENT")
+	dock.Active = false
+	assert.Equal(t, []string{"VIEW_LOCATION", "VIEW_INVENTORY"}, names(models.ScanResult{Kind: models.ScanLocation, Location: &dock}))
+
+	order := models.Order{ID: 7, Status: models.OrderPacked}
+	assert.Equal(t, []string{"VIEW_ORDER", "VIEW_PACKING", "SHIP"}, names(models.ScanResult{Kind: models.ScanLicensePlate, Order: &order}))
+	order.Status = models.OrderDelivered
+	assert.Equal(t, []string{"VIEW_ORDER"}, names(models.ScanResult{Kind: models.ScanOrder, Order: &order}))
+}
@@ -0,0 +1,134 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/WMS/models"
+)
+
+// searchSimilarity is how close a misspelled word must be to a word of a
+// record, the share of its trigrams the closest word has.
+const searchSimilarity = 0.4
+
+// searchTerms splits search text into lower case words of letters and
+// digits.
+func searchTerms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// checkSearch validates a search, fills in the default limit and kinds
+// and returns its words.
+func checkSearch(query *models.SearchQuery) ([]string, error) {
+	terms := searchTerms(query.Text)
+	if len(terms) == 0 {
+		return nil, invalid("q", "search text needs a letter or digit")
+	}
+	switch {
+	case query.Limit == 0:
+		query.Limit = models.DefaultSearchLimit
+	case query.Limit < 0 || query.Limit > models.MaxSearchLimit:
+		return nil, invalid("limit", "limit must be 1 to %d", models.MaxSearchLimit)
+	}
+	if len(query.Kinds) == 0 {
+		query.Kinds = models.SearchKinds
+	}
+	for _, kind := range query.Kinds {
+		if !slices.Contains(models.SearchKinds, kind) {
+			return nil, invalid("kinds", "cannot search %q", kind)
+		}
+	}
+	return terms, nil
+}
+
+// ScopeSearch limits a search to the kinds a role may read, and customers
+// to their own orders. It returns false when the role may read none of
+// them.
+func ScopeSearch(query models.SearchQuery, role string, accountID int64) (models.SearchQuery, bool) {
+	kinds := query.Kinds
+	if len(kinds) == 0 {
+		kinds = models.SearchKinds
+	}
+	// Unknown kinds are kept for the search to reject
+	query.Kinds = slices.DeleteFunc(slices.Clone(kinds), func(kind string) bool {
+		return slices.Contains(models.SearchKinds, kind) && !IsAllowed(role, kind, VerbRead)
+	})
+	if role == models.RoleCustomer {
+		query.CustomerID = accountID
+	}
+	return query, len(query.Kinds) > 0
+}
+
+// trigrams returns the trigrams of a word the way pg_trgm takes them, with
+// two spaces before it and one after.
+func trigrams(word string) map[string]bool {
+	runes := []rune("  " + word + " ")
+	set := make(map[string]bool, len(runes))
+	for i := 0; i+3 <= len(runes); i++ {
+		set[string(runes[i:i+3])] = true
+	}
+	return set
+}
+
+// wordSimilarity is the share of the trigrams of a term found in the word
+// of a document that shares the most of them.
+func wordSimilarity(term string, words []string) float64 {
+	want := trigrams(term)
+	var best float64
+	for _, word := range words {
+		found := 0
+		for trigram := range trigrams(word) {
+			if want[trigram] {
+				found++
+			}
+		}
+		best = max(best, float64(found)/float64(len(want)))
+	}
+	return best
+}
+
+// matchDocument matches search terms against the text of a record the way
+// the database does. A record matches when every term starts one of its
+// words or, failing that, when the terms are on average close enough to
+// its words. The rank adds how close they are to the prefix match.
+func matchDocument(terms []string, document string) (float64, bool) {
+	words := searchTerms(document)
+	prefixes := true
+	var similarity float64
+	for _, term := range terms {
+		if !slices.ContainsFunc(words, func(word string) bool { return strings.HasPrefix(word, term) }) {
+			prefixes = false
+		}
+		similarity += wordSimilarity(term, words)
+	}
+	similarity /= float64(len(terms))
+	switch {
+	case prefixes:
+		return 1 + similarity, true
+	case similarity >= searchSimilarity:
+		return similarity, true
+	}
+	return 0, false
+}
+
+// bestHits orders hits from the best match down and keeps the first
+// limit of them.
+func bestHits(hits []models.SearchHit, kinds []string, limit int) []models.SearchHit {
+	slices.SortStableFunc(hits, func(a, b models.SearchHit) int {
+		return cmp.Or(
+			cmp.Compare(b.Rank, a.Rank),
+			cmp.Compare(slices.Index(kinds, a.Kind), slices.Index(kinds, b.Kind)),
+			cmp.Compare(a.ID, b.ID),
+		)
+	})
+	if hits == nil {
+		hits = []models.SearchHit{}
+	}
+	return hits[:min(limit, len(hits))]
+}
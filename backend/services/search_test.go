@@ -0,0 +1,87 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/WMS/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchDocument(t *testing.T) {
+	rank, ok := matchDocument(searchTerms("Bak BEA"), "Baked beans 00036000291452")
+	assert.True(t, ok, "Words match as prefixes")
+	assert.Greater(t, rank, 1.0)
+
+	_, ok = matchDocument(searchTerms("0003600"), "Baked beans 00036000291452")
+	assert.True(t, ok, "Codes match as prefixes")
+
+	rank, ok = matchDocument(searchTerms("beens"), "Baked beans")
+	assert.True(t, ok, "Misspelled words match")
+	assert.Less(t, rank, 1.0, "Misspellings rank below prefix matches")
+
+	_, ok = matchDocument(searchTerms("rice"), "Baked beans")
+	assert.False(t, ok)
+}
+
+func TestScopeSearch(t *testing.T) {
+	query, ok := ScopeSearch(models.SearchQuery{Text: "x"}, models.RoleAdmin, 1)
+	assert.True(t, ok)
+	assert.Equal(t, models.SearchKinds, query.Kinds)
+	assert.Zero(t, query.CustomerID)
+
+	query, ok = ScopeSearch(models.SearchQuery{Text: "x"}, models.RoleCustomer, 66)
+	assert.True(t, ok)
+	assert.Equal(t, []string{models.SearchItems, models.SearchOrders}, query.Kinds)
+	assert.Equal(t, int64(66), query.CustomerID, "Customers only find their own orders")
+
+	query, ok = ScopeSearch(models.SearchQuery{Kinds: []string{models.SearchLocations, "boxes"}}, models.RoleSupplier, 5)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"boxes"}, query.Kinds, "Unknown kinds are left to be rejected")
+
+	_, ok = ScopeSearch(models.SearchQuery{Kinds: []string{models.SearchAccounts}}, models.RoleEmployee, 5)
+	assert.False(t, ok)
+}
+
+func TestSearchIndexes(t *testing.T) {
+	migration, err := migrationFiles.ReadFile("migrations/0014_search.up.sql")
+	assert.Nil(t, err)
+	for kind, source := range searchSources {
+		assert.Contains(t, string(migration), "ON "+source.table+" USING GIN (("+source.vector+"));", kind)
+		assert.Contains(t, string(migration), "ON "+source.table+" USING GIN (("+source.document+") gin_trgm_ops);", kind)
+	}
+	assert.Len(t, searchSources, len(models.SearchKinds))
+}
+
+func TestMemoryStoreSearch(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	assert.Nil(t, store.AddLocation(ctx, models.Location{ID: 1, Code: "A-21-03", Zone: "A", Type: models.LocationPickFace}))
+	assert.Nil(t, store.AddLocation(ctx, models.Location{ID: 2, Code: "DOCK1", Zone: "DOCK1", Type: models.LocationDock}))
+	for i := range 3 {
+		assert.Nil(t, store.AddItem(ctx, models.Item{Name: "Baked beans", UPC: string(rune('1' + i))}))
+	}
+
+	results, err := store.Search(ctx, models.SearchQuery{Text: "a-21", Kinds: []string{models.SearchLocations}})
+	assert.Nil(t, err)
+	assert.Equal(t, []models.SearchHit{{Kind: models.SearchLocations, ID: 1, Title: "A-21-03", Detail: models.LocationPickFace, Rank: results.Hits[0].Rank}}, results.Hits)
+	assert.Equal(t, "a 21", results.Query)
+
+	results, err = store.Search(ctx, models.SearchQuery{Text: "beans", Limit: 2})
+	assert.Nil(t, err)
+	assert.Len(t, results.Hits, 2)
+	assert.Equal(t, models.SearchKinds, results.Kinds)
+
+	results, err = store.Search(ctx, models.SearchQuery{Text: "zzz"})
+	assert.Nil(t, err)
+	assert.Equal(t, []models.SearchHit{}, results.Hits)
+
+	for _, query := range []models.SearchQuery{{Text: "-"}, {Text: "a", Limit: -1}, {Text: "a", Kinds: []string{"boxes"}}} {
+		_, err := store.Search(ctx, query)
+		var validation *ValidationError
+		assert.ErrorAs(t, err, &validation, query.Text)
+	}
+}
@@ -12,15 +12,15 @@ import (
 	"encoding/pem"
 	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"os"
-	"strconv"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/WMS/models"
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/jackc/pgx/v5"
 	"github.com/labstack/echo/v5"
 	"golang.org/x/crypto/argon2"
 )
@@ -134,28 +134,20 @@ func verifyPassword(storedHash, providedPassword string) (bool, error) {
 	return true, nil
 }
 
-func ValidateLogin(username string, password string) (*models.Account, error) {
-	conn := Connect()
-	defer conn.Close(context.Background())
-
+// unknownAccountHash is verified against when no account has the username,
+// so unknown usernames take as long to refuse as wrong passwords.
+var unknownAccountHash = sync.OnceValue(func() string {
+	hash, _ := hashPassword("unknown account")
+	return hash
+})
+
+// ValidateLogin returns the account a username and password log in to. Unknown
+// usernames, wrong passwords and inactive accounts all fail with
+// ErrCredentials.
+func ValidateLogin(ctx context.Context, repo AccountRepository, username string, password string) (*models.Account, error) {
 	fmt.Println("Attempting To [Authorize] Account:", username)
-	rows, _ := conn.Query(context.Background(), "select id, username, password, role, active from account where username=$1", username)
-	accounts, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Account, error) {
-		var n models.Account
-		err := row.Scan(
-			&n.ID,
-			&n.Username,
-			&n.Password,
-			&n.Role,
-			&n.Active,
-		)
-		if err != nil {
-			return models.Account{}, err
-		}
-		return n, nil
-	})
+	accounts, err := repo.GetAccountsByUsername(ctx, username)
 	if err != nil {
-		fmt.Printf("CollectRows error: %v", err)
 		return nil, err
 	}
 	if len(accounts) > 1 {
@@ -169,49 +161,66 @@ func ValidateLogin(username string, password string) (*models.Account, error) {
 			n,
 		)
 	}
+	if len(accounts) == 0 {
+		verifyPassword(unknownAccountHash(), password)
+		return nil, ErrCredentials
+	}
 
 	acc := accounts[0]
-	if acc.Active != true {
-		return nil, errors.New("account is not active")
-	}
-	isValid, err := verifyPassword(acc.Password, password)
-	if err != nil {
-		return nil, fmt.Errorf("authentication process failed: %w\n", err)
-	}
-	if !isValid {
-		return nil, fmt.Errorf("authentication credentials invalid\n")
+	isValid, _ := verifyPassword(acc.Password, password)
+	if !isValid || !acc.Active {
+		return nil, ErrCredentials
 	}
 
 	fmt.Println("Successfully [Authenticated] Account:", username)
 	return &acc, nil
 }
 
-func InitJWT(acc *models.Account) (map[string]string, error) {
-	claims := &models.JwtCustomClaims{
-		ID:       acc.ID,
-		Username: acc.Username,
-		Role:     acc.Role,
+// CheckPassword refuses a new password that could not be logged in with.
+func CheckPassword(field string, password string) error {
+	if strings.TrimSpace(password) == "" {
+		return invalid(field, "password cannot be empty")
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-
-	var jwtKey *rsa.PrivateKey
+	return nil
+}
 
-	jwtKey, err := LoadRSAPrivateKey(os.Getenv("JWTKEY"))
-	if err != nil {
-		jwtKey, err = LoadRSAPrivateKey("../wms-jwt.pem")
-		if err != nil {
-			return nil, fmt.Errorf("Failed to open JWT Key: %w", err)
-		}
+// CheckRole refuses a role that is not one of models.Roles.
+func CheckRole(field string, role models.Role) error {
+	if !slices.Contains(models.Roles, role.Value) {
+		return invalid(field, "unknown role %q, must be one of %s", role.Value, strings.Join(models.Roles, ", "))
 	}
+	return nil
+}
 
-	t, err := token.SignedString(jwtKey)
+// ChangePassword replaces the password of an account once its current
+// password is proven, and revokes the tokens of the account. Wrong
+// passwords count against its username like failed logins, so the check
+// cannot be used to guess around the lockout.
+func ChangePassword(ctx context.Context, repo Repository, id int, change models.PasswordChange) error {
+	fmt.Printf("Attempting to change password of account: %v...\n", id)
+	if err := CheckPassword("newPassword", change.NewPassword); err != nil {
+		return err
+	}
+	acc, err := repo.GetAccount(ctx, id)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	keys := []loginKey{{usernameKey(acc.Username), UsernamePolicy}}
+	if err := countAttempt(ctx, repo, time.Now(), keys); err != nil {
+		return err
+	}
+	if ok, _ := verifyPassword(acc.Password, change.CurrentPassword); !ok {
+		return ErrCredentials
+	}
+	if err := forgiveAttempt(ctx, repo, keys); err != nil {
+		return err
+	}
+	if err := repo.SetPassword(ctx, id, change.NewPassword); err != nil {
+		return err
 	}
 
-	return map[string]string{
-		"token": t,
-	}, nil
+	fmt.Printf("Successfully changed password of account: %v!\n", id)
+	return nil
 }
 
 func DecodeJWT(c *echo.Context) (jwt.MapClaims, error) {
@@ -226,29 +235,30 @@ func DecodeJWT(c *echo.Context) (jwt.MapClaims, error) {
 	return claims, nil
 }
 
-func AuthorizeRequest(c *echo.Context) error {
-	fmt.Println("Authorizing HTTP request...")
+func GetClaims(c *echo.Context) (*models.JwtCustomClaims, error) {
 	user, err := echo.ContextGet[*jwt.Token](c, "user")
 	if err != nil {
-		return echo.ErrUnauthorized.Wrap(err)
+		return nil, echo.ErrUnauthorized.Wrap(err)
 	}
 	claims, ok := user.Claims.(*models.JwtCustomClaims)
 	if !ok {
-		return errors.New("failed to cast claims as jwt.MapClaims")
+		return nil, errors.New("failed to cast claims as models.JwtCustomClaims")
 	}
-	log.Println(claims)
+	return claims, nil
+}
 
-	// TODO Add role based JWT authorization
-	idParam := c.Param("id")
-	id, _ := strconv.Atoi(idParam)
-	path := c.Request().URL.Path
+type actorKey struct{}
 
-	if (strings.Contains(path, "accounts") && claims.ID != int64(id)) && claims.Role.Value != "ADMIN" {
-		return echo.ErrUnauthorized
-	}
+// WithActor returns a context carrying the ID of the account making the
+// request, recorded on the stock ledger.
+func WithActor(ctx context.Context, accountID int64) context.Context {
+	return context.WithValue(ctx, actorKey{}, accountID)
+}
 
-	fmt.Println("User Authorized!")
-	return nil
+// ActorFrom returns the account ID set by WithActor, or 0.
+func ActorFrom(ctx context.Context) int64 {
+	id, _ := ctx.Value(actorKey{}).(int64)
+	return id
 }
 
 func Accessible(c *echo.Context) error {
@@ -0,0 +1,163 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/WMS/models"
+)
+
+// checkEntry validates a ledger entry before it is posted.
+func checkEntry(entry models.StockTransaction) error {
+	if !slices.Contains(models.StockTransactionTypes, entry.Type) {
+		return invalid("type", "unknown stock transaction type %q", entry.Type)
+	}
+	if entry.ItemID == 0 {
+		return invalid("itemId", "item is required")
+	}
+	if entry.Quantity <= 0 {
+		return invalid("quantity", "quantity must be positive")
+	}
+	if entry.FromLocationID == 0 && entry.ToLocationID == 0 {
+		return invalid("locationId", "location is required")
+	}
+	if entry.FromLocationID == entry.ToLocationID {
+		return invalid("toLocationId", "source and destination are the same location")
+	}
+	if !slices.Contains(models.ReasonCodes, entry.Reason) {
+		return invalid("reason", "unknown reason code %q", entry.Reason)
+	}
+	return nil
+}
+
+// checkWithdrawal validates that quantity units of an item may be taken
+// from loc, which holds balance of them.
+func checkWithdrawal(loc models.Location, itemID int64, balance int64, quantity int64) error {
+	if balance < quantity {
+		return invalid("quantity", "location %s holds %d of item %d, cannot remove %d", loc.Code, balance, itemID, quantity)
+	}
+	return nil
+}
+
+// checkUnreserved validates that taking quantity units of an item out of
+// loc leaves the reserved units in place. Picks are exempt, as they take
+// the reserved units themselves.
+func checkUnreserved(loc models.Location, itemID int64, balance int64, reserved int64, quantity int64) error {
+	if balance-reserved < quantity {
+		return invalid("quantity", "location %s holds %d of item %d with %d reserved, cannot remove %d", loc.Code, balance, itemID, reserved, quantity)
+	}
+	return nil
+}
+
+func reasonCode(reason string, fallback string) string {
+	return cmp.Or(strings.ToUpper(strings.TrimSpace(reason)), fallback)
+}
+
+func moveEntry(move models.StockMoveRequest) models.StockTransaction {
+	return models.StockTransaction{
+		Type:           models.StockMove,
+		ItemID:         move.ItemID,
+		FromLocationID: move.FromLocationID,
+		ToLocationID:   move.ToLocationID,
+		Quantity:       move.Quantity,
+		Reason:         reasonCode(move.Reason, models.ReasonRelocation),
+		Reference:      move.Reference,
+	}
+}
+
+// adjustEntries turns an adjustment into ledger entries given the current
+// balance at the location. A count matching the balance posts nothing.
+func adjustEntries(adj models.StockAdjustmentRequest, balance int64) ([]models.StockTransaction, error) {
+	entry := models.StockTransaction{
+		Type:      models.StockAdjust,
+		ItemID:    adj.ItemID,
+		Reason:    reasonCode(adj.Reason, ""),
+		Reference: adj.Reference,
+	}
+	delta := adj.Quantity
+	if adj.Counted != nil {
+		if *adj.Counted < 0 {
+			return nil, invalid("counted", "counted quantity cannot be negative")
+		}
+		entry.Type = models.StockCount
+		entry.Reason = reasonCode(adj.Reason, models.ReasonCycleCount)
+		delta = *adj.Counted - balance
+	} else if delta == 0 {
+		return nil, invalid("quantity", "quantity must not be zero")
+	}
+	if entry.Reason == "" {
+		return nil, invalid("reason", "reason is required")
+	}
+	if adj.LocationID == 0 {
+		return nil, invalid("locationId", "location is required")
+	}
+
+	switch {
+	case delta > 0:
+		entry.ToLocationID = adj.LocationID
+		entry.Quantity = delta
+	case delta < 0:
+		entry.FromLocationID = adj.LocationID
+		entry.Quantity = -delta
+	default:
+		return []models.StockTransaction{}, nil
+	}
+	return []models.StockTransaction{entry}, nil
+}
+
+// transferEntries moves every item in balances, keyed by item ID, from
+// one location to another.
+func transferEntries(transfer models.StockTransferRequest, from models.Location, balances map[int64]int64) ([]models.StockTransaction, error) {
+	var entries []models.StockTransaction
+	for _, itemID := range slices.Sorted(maps.Keys(balances)) {
+		if balances[itemID] <= 0 {
+			continue
+		}
+		entries = append(entries, moveEntry(models.StockMoveRequest{
+			ItemID:         itemID,
+			FromLocationID: transfer.FromLocationID,
+			ToLocationID:   transfer.ToLocationID,
+			Quantity:       balances[itemID],
+			Reason:         transfer.Reason,
+			Reference:      transfer.Reference,
+		}))
+	}
+	if len(entries) == 0 {
+		return nil, invalid("fromLocationId", "location %s holds no stock", from.Code)
+	}
+	return entries, nil
+}
+
+// countEntries corrects the stock of an item from have to want, both keyed
+// by location ID, the way a wholesale inventory update does.
+func countEntries(itemID int64, have map[int64]int64, want map[int64]int64) []models.StockTransaction {
+	locationIDs := slices.Sorted(maps.Keys(have))
+	for id := range want {
+		if _, ok := have[id]; !ok {
+			locationIDs = append(locationIDs, id)
+		}
+	}
+	slices.Sort(locationIDs)
+
+	var entries []models.StockTransaction
+	for _, id := range locationIDs {
+		delta := want[id] - have[id]
+		entry := models.StockTransaction{Type: models.StockCount, ItemID: itemID, Reason: models.ReasonCorrection}
+		switch {
+		case delta > 0:
+			entry.ToLocationID = id
+			entry.Quantity = delta
+		case delta < 0:
+			entry.FromLocationID = id
+			entry.Quantity = -delta
+		default:
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
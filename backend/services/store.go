@@ -0,0 +1,82 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/WMS/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// pgxQuerier is satisfied by both the pool and a transaction.
+type pgxQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// PostgresStore holds the connection pool shared by every request. It is
+// created once at startup and closed on shutdown.
+type PostgresStore struct {
+	pool *pgxpool.Pool
+}
+
+func DatabaseURL() string {
+	return fmt.Sprintf("postgres://%v:%v@%v:%v/%v",
+		os.Getenv("DBUSER"),
+		os.Getenv("DBPASS"),
+		os.Getenv("DBHOST"),
+		os.Getenv("DBPORT"),
+		os.Getenv("DBNAME"),
+	)
+}
+
+func NewPostgresStore(ctx context.Context, url string) (*PostgresStore, error) {
+	fmt.Println("Attempting to connect to database...")
+	config, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid database url: %w", err)
+	}
+	if maxConns, err := strconv.Atoi(os.Getenv("DBMAXCONNS")); err == nil && maxConns > 0 {
+		config.MaxConns = int32(maxConns)
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create connection pool: %w", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("unable to connect to database: %w", err)
+	}
+
+	fmt.Println("Successfully connected to database!")
+	return &PostgresStore{pool: pool}, nil
+}
+
+func (s *PostgresStore) Close() {
+	s.pool.Close()
+}
+
+func (s *PostgresStore) Ping(ctx context.Context) error {
+	return s.pool.Ping(ctx)
+}
+
+func (s *PostgresStore) Stats() models.PoolStats {
+	stat := s.pool.Stat()
+	return models.PoolStats{
+		TotalConns:           stat.TotalConns(),
+		AcquiredConns:        stat.AcquiredConns(),
+		IdleConns:            stat.IdleConns(),
+		ConstructingConns:    stat.ConstructingConns(),
+		MaxConns:             stat.MaxConns(),
+		AcquireCount:         stat.AcquireCount(),
+		AcquireDuration:      stat.AcquireDuration(),
+		CanceledAcquireCount: stat.CanceledAcquireCount(),
+		EmptyAcquireCount:    stat.EmptyAcquireCount(),
+		NewConnsCount:        stat.NewConnsCount(),
+	}
+}
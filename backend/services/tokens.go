@@ -0,0 +1,207 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/WMS/models"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v5"
+)
+
+const (
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+var (
+	signingKey    any
+	signingMethod jwt.SigningMethod = jwt.SigningMethodRS256
+)
+
+// UseSigningKey signs access tokens with key instead of the RSA key named
+// by JWTKEY.
+func UseSigningKey(key any, method jwt.SigningMethod) {
+	signingKey = key
+	signingMethod = method
+}
+
+func loadSigningKey() (any, error) {
+	if signingKey != nil {
+		return signingKey, nil
+	}
+	jwtKey, err := LoadRSAPrivateKey(os.Getenv("JWTKEY"))
+	if err != nil {
+		jwtKey, err = LoadRSAPrivateKey("../wms-jwt.pem")
+		if err != nil {
+			return nil, fmt.Errorf("Failed to open JWT Key: %w", err)
+		}
+	}
+	return jwtKey, nil
+}
+
+// tokensLapse reports whether an update of an account revokes its tokens:
+// deactivating it or changing its role.
+func tokensLapse(before models.Account, after models.Account) bool {
+	return (before.Active && !after.Active) || before.Role.Value != after.Role.Value
+}
+
+func randomToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("token generation failed: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// signAccessToken signs an access token for an account in its current
+// token generation, expiring after AccessTokenTTL.
+func signAccessToken(acc models.Account, generation int64) (string, time.Time, error) {
+	jti, err := randomToken(16)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	now := time.Now()
+	expires := now.Add(AccessTokenTTL)
+	claims := &models.JwtCustomClaims{
+		ID:         acc.ID,
+		Username:   acc.Username,
+		Role:       acc.Role,
+		Generation: generation,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   strconv.FormatInt(acc.ID, 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expires),
+		},
+	}
+
+	key, err := loadSigningKey()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	t, err := jwt.NewWithClaims(signingMethod, claims).SignedString(key)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return t, expires, nil
+}
+
+// tokenPair signs an access token to hand out along with a refresh token.
+func tokenPair(ctx context.Context, repo TokenRepository, acc models.Account, refresh string) (models.TokenPair, error) {
+	generation, err := repo.TokenGeneration(ctx, acc.ID)
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+	token, expires, err := signAccessToken(acc, generation)
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+	return models.TokenPair{Token: token, RefreshToken: refresh, ExpiresAt: expires}, nil
+}
+
+// IssueTokens logs an account in, starting a new family of refresh tokens.
+func IssueTokens(ctx context.Context, repo TokenRepository, acc models.Account) (models.TokenPair, error) {
+	refresh, err := randomToken(32)
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+	family, err := randomToken(16)
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+	err = repo.AddRefreshToken(ctx, models.RefreshToken{
+		AccountID: acc.ID,
+		Hash:      hashToken(refresh),
+		Family:    family,
+		Expires:   time.Now().Add(RefreshTokenTTL),
+	})
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+	return tokenPair(ctx, repo, acc, refresh)
+}
+
+// RefreshTokens trades a refresh token for a new pair. The token used is
+// revoked, and using it again revokes its whole family. Tokens that are
+// unknown, expired or revoked, or whose account is no longer active, fail
+// with ErrCredentials.
+func RefreshTokens(ctx context.Context, repo Repository, refresh string) (models.TokenPair, error) {
+	fmt.Println("Attempting To [Refresh] Tokens")
+	next, err := randomToken(32)
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+	token, err := repo.RotateRefreshToken(ctx, hashToken(refresh), models.RefreshToken{
+		Hash:    hashToken(next),
+		Expires: time.Now().Add(RefreshTokenTTL),
+	})
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+	acc, err := repo.GetAccount(ctx, int(token.AccountID))
+	if err != nil || !acc.Active {
+		if err := repo.RevokeRefreshToken(ctx, token.Hash); err != nil {
+			return models.TokenPair{}, err
+		}
+		return models.TokenPair{}, ErrCredentials
+	}
+
+	fmt.Println("Successfully [Refreshed] Tokens For Account:", acc.Username)
+	return tokenPair(ctx, repo, acc, next)
+}
+
+// Logout revokes the access token of the claims until it expires, and the
+// family of the refresh token when one is given.
+func Logout(ctx context.Context, repo TokenRepository, claims *models.JwtCustomClaims, refresh string) error {
+	if claims.RegisteredClaims.ID != "" && claims.ExpiresAt != nil {
+		if err := repo.RevokeAccessToken(ctx, claims.RegisteredClaims.ID, claims.ExpiresAt.Time); err != nil {
+			return err
+		}
+	}
+	if refresh != "" {
+		return repo.RevokeRefreshToken(ctx, hashToken(refresh))
+	}
+	return nil
+}
+
+// RejectRevoked is the success handler of the JWT middleware. It turns
+// away tokens that were logged out or predate a revocation of their
+// account, tokens signed without an expiry or ID, and pre-auth tokens.
+func RejectRevoked(repo TokenRepository) func(c *echo.Context) error {
+	return func(c *echo.Context) error {
+		claims, err := GetClaims(c)
+		if err != nil {
+			return err
+		}
+		if claims.ExpiresAt == nil || claims.RegisteredClaims.ID == "" {
+			return echo.NewHTTPError(http.StatusUnauthorized, "token has no expiry")
+		}
+		if len(claims.Audience) > 0 {
+			return echo.NewHTTPError(http.StatusUnauthorized, "token is not an access token")
+		}
+		revoked, err := repo.TokenRevoked(c.Request().Context(), claims.RegisteredClaims.ID, claims.ID, claims.Generation)
+		if err != nil {
+			return err
+		}
+		if revoked {
+			return echo.NewHTTPError(http.StatusUnauthorized, "token has been revoked")
+		}
+		return nil
+	}
+}
@@ -0,0 +1,89 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/WMS/models"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokensLapse(t *testing.T) {
+	employee := models.Account{Role: models.Role{Value: models.RoleEmployee}, Active: true}
+
+	manager := employee
+	manager.Role.Value = models.RoleManager
+	assert.True(t, tokensLapse(employee, manager))
+
+	inactive := employee
+	inactive.Active = false
+	assert.True(t, tokensLapse(employee, inactive))
+	assert.False(t, tokensLapse(inactive, employee), "Reactivating leaves no tokens to revoke")
+
+	renamed := employee
+	renamed.Firstname = "Jo"
+	assert.False(t, tokensLapse(employee, renamed))
+}
+
+func TestMemoryStoreTokens(t *testing.T) {
+	UseSigningKey([]byte("tokens-test-key"), jwt.SigningMethodHS256)
+	store := NewMemoryStore()
+	ctx := context.Background()
+	account := models.Account{ID: 1, Username: "jo", Password: "secret", Role: models.Role{Value: models.RoleEmployee}, Active: true}
+	assert.Nil(t, store.AddAccount(ctx, account))
+
+	login, err := IssueTokens(ctx, store, account)
+	assert.Nil(t, err)
+	claims := new(models.JwtCustomClaims)
+	_, err = jwt.ParseWithClaims(login.Token, claims, func(*jwt.Token) (any, error) { return []byte("tokens-test-key"), nil })
+	assert.Nil(t, err)
+	assert.NotEmpty(t, claims.RegisteredClaims.ID)
+	assert.Equal(t, login.ExpiresAt.Unix(), claims.ExpiresAt.Unix())
+	assert.NotContains(t, store.refreshTokens, login.RefreshToken, "Refresh tokens are stored hashed")
+
+	refreshed, err := RefreshTokens(ctx, store, login.RefreshToken)
+	assert.Nil(t, err)
+	_, err = RefreshTokens(ctx, store, login.RefreshToken)
+	assert.ErrorIs(t, err, ErrCredentials)
+	_, err = RefreshTokens(ctx, store, refreshed.RefreshToken)
+	assert.ErrorIs(t, err, ErrCredentials, "Reusing a rotated token revokes its family")
+
+	_, err = RefreshTokens(ctx, store, "unknown")
+	assert.ErrorIs(t, err, ErrCredentials)
+
+	expired := models.RefreshToken{AccountID: 1, Hash: hashToken("expired"), Family: "old", Expires: time.Now().Add(-time.Minute)}
+	assert.Nil(t, store.AddRefreshToken(ctx, expired))
+	_, err = RefreshTokens(ctx, store, "expired")
+	assert.ErrorIs(t, err, ErrCredentials)
+	assert.ErrorIs(t, store.AddRefreshToken(ctx, models.RefreshToken{AccountID: 9, Hash: "x"}), ErrNotFound)
+
+	// Changing the role revokes tokens signed before
+	login, err = IssueTokens(ctx, store, account)
+	assert.Nil(t, err)
+	revoked, err := store.TokenRevoked(ctx, "jti", 1, 0)
+	assert.Nil(t, err)
+	assert.False(t, revoked)
+	account.Role.Value = models.RoleManager
+	assert.Nil(t, store.UpdateAccount(ctx, 1, account))
+	revoked, err = store.TokenRevoked(ctx, "jti", 1, 0)
+	assert.Nil(t, err)
+	assert.True(t, revoked)
+	_, err = RefreshTokens(ctx, store, login.RefreshToken)
+	assert.ErrorIs(t, err, ErrCredentials)
+
+	generation, err := store.TokenGeneration(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), generation)
+	revoked, err = store.TokenRevoked(ctx, "jti", 1, generation)
+	assert.Nil(t, err)
+	assert.False(t, revoked, "Tokens signed since are valid")
+
+	assert.Nil(t, store.RevokeAccessToken(ctx, "jti", time.Now().Add(time.Minute)))
+	revoked, err = store.TokenRevoked(ctx, "jti", 1, generation)
+	assert.Nil(t, err)
+	assert.True(t, revoked)
+}
@@ -0,0 +1,340 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/WMS/barcode"
+	"github.com/WMS/models"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// TOTPIssuer names the service in authenticator apps.
+	TOTPIssuer = "WMS"
+	// PreAuthTokenTTL is how long a login has to enter its second factor.
+	PreAuthTokenTTL = 5 * time.Minute
+	// PreAuthAudience marks pre-auth tokens, which are not access tokens.
+	PreAuthAudience = "totp"
+
+	totpPeriod        = 30
+	totpDigits        = 6
+	totpSkew          = 1 // steps of clock drift allowed either way
+	recoveryCodeCount = 10
+)
+
+// TwoFactorRoles must log in with a second factor, enrolling at their next
+// login if they have not yet.
+var TwoFactorRoles = []string{models.RoleAdmin, models.RoleManager}
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// totpCode is the RFC 6238 code of a secret for a time step, HMAC-SHA1
+// truncated to totpDigits.
+func totpCode(key []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", value%1000000)
+}
+
+// matchTOTP returns the time step a code of a secret is valid for at a time.
+// Steps up to lastStep were used already and do not match.
+func matchTOTP(secret string, code string, now time.Time, lastStep int64) (int64, bool) {
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+	current := now.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step > lastStep && subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// normalizeRecoveryCode lets recovery codes be typed in any case, with or
+// without the dash.
+func normalizeRecoveryCode(code string) string {
+	return strings.ReplaceAll(strings.ToUpper(strings.TrimSpace(code)), "-", "")
+}
+
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("recovery code generation failed: %w", err)
+		}
+		code := totpEncoding.EncodeToString(b)
+		codes[i] = code[:4] + "-" + code[4:]
+		hashes[i] = hashToken(code)
+	}
+	return codes, hashes, nil
+}
+
+// totpURI is the otpauth:// URI authenticator apps enroll a secret from.
+func totpURI(username string, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", TOTPIssuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", strconv.Itoa(totpDigits))
+	query.Set("period", strconv.Itoa(totpPeriod))
+	return "otpauth://totp/" + url.PathEscape(TOTPIssuer+":"+username) + "?" + query.Encode()
+}
+
+// EnrollTOTP gives an account a new TOTP secret and recovery codes, which
+// replace any unconfirmed ones. Accounts with a confirmed secret must have
+// it removed first and fail with ErrConflict.
+func EnrollTOTP(ctx context.Context, repo TwoFactorRepository, acc models.Account) (models.TOTPEnrollment, error) {
+	fmt.Printf("Attempting to enroll TOTP of account: %v...\n", acc.ID)
+	current, err := repo.GetTwoFactor(ctx, acc.ID)
+	if err == nil && current.Confirmed {
+		return models.TOTPEnrollment{}, ErrConflict
+	}
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return models.TOTPEnrollment{}, err
+	}
+
+	key := make([]byte, 20)
+	if _, err := rand.Read(key); err != nil {
+		return models.TOTPEnrollment{}, fmt.Errorf("secret generation failed: %w", err)
+	}
+	secret := totpEncoding.EncodeToString(key)
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return models.TOTPEnrollment{}, err
+	}
+	uri := totpURI(acc.Username, secret)
+	qr, err := barcode.QRCode(uri, 256)
+	if err != nil {
+		return models.TOTPEnrollment{}, err
+	}
+	if err := repo.SetTwoFactor(ctx, models.TwoFactor{AccountID: acc.ID, Secret: secret}, hashes); err != nil {
+		return models.TOTPEnrollment{}, err
+	}
+
+	fmt.Printf("Successfully enrolled TOTP of account: %v!\n", acc.ID)
+	return models.TOTPEnrollment{
+		Secret:        secret,
+		URI:           uri,
+		QRCode:        "data:image/png;base64," + base64.StdEncoding.EncodeToString(qr),
+		RecoveryCodes: codes,
+	}, nil
+}
+
+// ConfirmTOTP confirms the secret an account enrolled with a code from it.
+// Wrong codes fail with ErrCredentials, and secrets confirmed already with
+// ErrConflict.
+func ConfirmTOTP(ctx context.Context, repo TwoFactorRepository, accountID int64, code string) error {
+	secret, err := repo.GetTwoFactor(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	if secret.Confirmed {
+		return ErrConflict
+	}
+	return useTOTP(ctx, repo, secret, code)
+}
+
+func useTOTP(ctx context.Context, repo TwoFactorRepository, secret models.TwoFactor, code string) error {
+	step, ok := matchTOTP(secret.Secret, strings.TrimSpace(code), time.Now(), secret.LastStep)
+	if !ok {
+		return ErrCredentials
+	}
+	used, err := repo.UseTOTPStep(ctx, secret.AccountID, step)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrCredentials
+	}
+	return nil
+}
+
+// checkSecondFactor checks a code of an account's secret. Recovery codes
+// stand in for TOTP codes once the secret is confirmed.
+func checkSecondFactor(ctx context.Context, repo TwoFactorRepository, secret models.TwoFactor, code string) error {
+	err := useTOTP(ctx, repo, secret, code)
+	if !errors.Is(err, ErrCredentials) || !secret.Confirmed {
+		return err
+	}
+	used, err := repo.UseRecoveryCode(ctx, secret.AccountID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrCredentials
+	}
+	return nil
+}
+
+// secondFactorRequired reports whether an account logs in with a second
+// factor: it has a confirmed secret or its role requires one.
+func secondFactorRequired(ctx context.Context, repo TwoFactorRepository, acc models.Account) (bool, error) {
+	if slices.Contains(TwoFactorRoles, acc.Role.Value) {
+		return true, nil
+	}
+	secret, err := repo.GetTwoFactor(ctx, acc.ID)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return secret.Confirmed, err
+}
+
+// LoginChallenge returns the challenge of an account that logged in with its
+// password and must now enter a second factor, or nil if it need not.
+func LoginChallenge(ctx context.Context, repo Repository, acc models.Account) (*models.LoginChallenge, error) {
+	required, err := secondFactorRequired(ctx, repo, acc)
+	if err != nil || !required {
+		return nil, err
+	}
+	secret, err := repo.GetTwoFactor(ctx, acc.ID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	generation, err := repo.TokenGeneration(ctx, acc.ID)
+	if err != nil {
+		return nil, err
+	}
+	token, expires, err := signPreAuthToken(acc, generation)
+	if err != nil {
+		return nil, err
+	}
+	return &models.LoginChallenge{PreAuthToken: token, ExpiresAt: expires, Enroll: !secret.Confirmed}, nil
+}
+
+func signPreAuthToken(acc models.Account, generation int64) (string, time.Time, error) {
+	jti, err := randomToken(16)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	now := time.Now()
+	expires := now.Add(PreAuthTokenTTL)
+	claims := &models.JwtCustomClaims{
+		ID:         acc.ID,
+		Username:   acc.Username,
+		Role:       acc.Role,
+		Generation: generation,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   strconv.FormatInt(acc.ID, 10),
+			Audience:  jwt.ClaimStrings{PreAuthAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expires),
+		},
+	}
+	key, err := loadSigningKey()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	t, err := jwt.NewWithClaims(signingMethod, claims).SignedString(key)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return t, expires, nil
+}
+
+// preAuthAccount returns the account a pre-auth token was signed for, if the
+// token is valid, unused and the account still active. Others fail with
+// ErrCredentials.
+func preAuthAccount(ctx context.Context, repo Repository, token string) (*models.JwtCustomClaims, models.Account, error) {
+	key, err := loadSigningKey()
+	if err != nil {
+		return nil, models.Account{}, err
+	}
+	if private, ok := key.(*rsa.PrivateKey); ok {
+		key = &private.PublicKey
+	}
+	claims := new(models.JwtCustomClaims)
+	_, err = jwt.ParseWithClaims(token, claims, func(*jwt.Token) (any, error) { return key, nil },
+		jwt.WithValidMethods([]string{signingMethod.Alg()}),
+		jwt.WithAudience(PreAuthAudience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, models.Account{}, ErrCredentials
+	}
+	revoked, err := repo.TokenRevoked(ctx, claims.RegisteredClaims.ID, claims.ID, claims.Generation)
+	if err != nil {
+		return nil, models.Account{}, err
+	}
+	if revoked {
+		return nil, models.Account{}, ErrCredentials
+	}
+	acc, err := repo.GetAccount(ctx, int(claims.ID))
+	if err != nil || !acc.Active {
+		return nil, models.Account{}, ErrCredentials
+	}
+	return claims, acc, nil
+}
+
+// EnrollWithPreAuth enrolls the TOTP secret of an account that must log in
+// with one but has none, before it has an access token.
+func EnrollWithPreAuth(ctx context.Context, repo Repository, token string) (models.TOTPEnrollment, error) {
+	_, acc, err := preAuthAccount(ctx, repo, token)
+	if err != nil {
+		return models.TOTPEnrollment{}, err
+	}
+	return EnrollTOTP(ctx, repo, acc)
+}
+
+// VerifySecondFactor completes a login with a code of the account's secret,
+// confirming it when enrolled at login, or a recovery code. Wrong codes
+// count as failed logins of the username and address.
+func VerifySecondFactor(ctx context.Context, repo Repository, request models.SecondFactorRequest, address string) (models.TokenPair, error) {
+	claims, acc, err := preAuthAccount(ctx, repo, request.PreAuthToken)
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+	keys := loginKeys(acc.Username, address)
+	if err := countAttempt(ctx, repo, time.Now(), keys); err != nil {
+		return models.TokenPair{}, err
+	}
+
+	secret, err := repo.GetTwoFactor(ctx, acc.ID)
+	if errors.Is(err, ErrNotFound) {
+		return models.TokenPair{}, ErrCredentials
+	}
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+	if err := checkSecondFactor(ctx, repo, secret, request.Code); err != nil {
+		return models.TokenPair{}, err
+	}
+
+	if err := repo.RevokeAccessToken(ctx, claims.RegisteredClaims.ID, claims.ExpiresAt.Time); err != nil {
+		return models.TokenPair{}, err
+	}
+	if err := repo.ClearLoginFailures(ctx, usernameKey(acc.Username)); err != nil {
+		return models.TokenPair{}, err
+	}
+	if err := repo.ForgiveLoginFailure(ctx, addressKey(address)); err != nil {
+		return models.TokenPair{}, err
+	}
+	fmt.Println("Successfully [Verified] Second Factor Of Account:", acc.Username)
+	return IssueTokens(ctx, repo, acc)
+}
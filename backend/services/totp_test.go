@@ -0,0 +1,130 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/WMS/models"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 appendix B, SHA1, truncated to six digits
+	key := []byte("12345678901234567890")
+	for unix, code := range map[int64]string{59: "287082", 1111111109: "081804", 1234567890: "005924", 2000000000: "279037"} {
+		assert.Equal(t, code, totpCode(key, unix/totpPeriod), unix)
+	}
+
+	secret := totpEncoding.EncodeToString(key)
+	now := time.Unix(1111111109, 0)
+	step, ok := matchTOTP(secret, "081804", now, 0)
+	assert.True(t, ok)
+	assert.Equal(t, int64(1111111109/totpPeriod), step)
+	_, ok = matchTOTP(secret, "081804", now.Add(totpPeriod*time.Second), 0)
+	assert.True(t, ok, "Codes are accepted a step late")
+	_, ok = matchTOTP(secret, "081804", now.Add(3*totpPeriod*time.Second), 0)
+	assert.False(t, ok)
+	_, ok = matchTOTP(secret, "081804", now, step)
+	assert.False(t, ok, "Used steps do not match again")
+	_, ok = matchTOTP(secret, "81804", now, 0)
+	assert.False(t, ok)
+}
+
+func TestTOTPURI(t *testing.T) {
+	assert.Equal(t, "otpauth://totp/WMS:jo%20b?algorithm=SHA1&digits=6&issuer=WMS&period=30&secret=ABC", totpURI("jo b", "ABC"))
+}
+
+// currentCode is the code of a secret now.
+func currentCode(t *testing.T, secret string) string {
+	key, err := totpEncoding.DecodeString(secret)
+	assert.Nil(t, err)
+	return totpCode(key, time.Now().Unix()/totpPeriod)
+}
+
+func TestEnrollTOTP(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	account := models.Account{ID: 1, Username: "jo", Password: "secret", Active: true}
+	assert.Nil(t, store.AddAccount(ctx, account))
+
+	enrollment, err := EnrollTOTP(ctx, store, account)
+	assert.Nil(t, err)
+	assert.Len(t, enrollment.Secret, 32)
+	assert.Contains(t, enrollment.URI, "secret="+enrollment.Secret)
+	assert.True(t, strings.HasPrefix(enrollment.QRCode, "data:image/png;base64,"))
+	assert.Len(t, enrollment.RecoveryCodes, recoveryCodeCount)
+	assert.NotContains(t, store.recoveryCodes[1], normalizeRecoveryCode(enrollment.RecoveryCodes[0]), "Recovery codes are stored hashed")
+
+	assert.ErrorIs(t, ConfirmTOTP(ctx, store, 1, "000000"), ErrCredentials)
+	assert.Nil(t, ConfirmTOTP(ctx, store, 1, currentCode(t, enrollment.Secret)))
+	assert.ErrorIs(t, ConfirmTOTP(ctx, store, 1, currentCode(t, enrollment.Secret)), ErrConflict)
+	_, err = EnrollTOTP(ctx, store, account)
+	assert.ErrorIs(t, err, ErrConflict, "Confirmed secrets are removed before enrolling again")
+
+	assert.ErrorIs(t, ConfirmTOTP(ctx, store, 2, "000000"), ErrNotFound)
+	assert.Nil(t, store.DeleteTwoFactor(ctx, 1))
+	assert.ErrorIs(t, store.DeleteTwoFactor(ctx, 1), ErrNotFound)
+}
+
+func TestSecondFactorLogin(t *testing.T) {
+	UseSigningKey([]byte("totp-test-key"), jwt.SigningMethodHS256)
+	store := NewMemoryStore()
+	ctx := context.Background()
+	admin := models.Account{ID: 1, Username: "boss", Password: "secret", Role: models.Role{Value: models.RoleAdmin}, Active: true}
+	employee := models.Account{ID: 2, Username: "jo", Password: "secret", Role: models.Role{Value: models.RoleEmployee}, Active: true}
+	assert.Nil(t, store.AddAccount(ctx, admin))
+	assert.Nil(t, store.AddAccount(ctx, employee))
+
+	challenge, err := LoginChallenge(ctx, store, employee)
+	assert.Nil(t, err)
+	assert.Nil(t, challenge, "Employees need no second factor until they enroll")
+
+	challenge, err = LoginChallenge(ctx, store, admin)
+	assert.Nil(t, err)
+	if !assert.NotNil(t, challenge) {
+		return
+	}
+	assert.True(t, challenge.Enroll)
+
+	_, err = VerifySecondFactor(ctx, store, models.SecondFactorRequest{PreAuthToken: challenge.PreAuthToken, Code: "123456"}, "10.0.0.1")
+	assert.ErrorIs(t, err, ErrCredentials, "Admins without a secret must enroll")
+	store.loginAttempts = map[string]models.LoginAttempts{}
+
+	enrollment, err := EnrollWithPreAuth(ctx, store, challenge.PreAuthToken)
+	assert.Nil(t, err)
+	_, err = VerifySecondFactor(ctx, store, models.SecondFactorRequest{PreAuthToken: challenge.PreAuthToken, Code: "not a code"}, "10.0.0.1")
+	assert.ErrorIs(t, err, ErrCredentials)
+	assert.Equal(t, 1, store.loginAttempts[usernameKey("boss")].Failures, "Wrong codes count as failed logins")
+	store.loginAttempts = map[string]models.LoginAttempts{}
+
+	tokens, err := VerifySecondFactor(ctx, store, models.SecondFactorRequest{PreAuthToken: challenge.PreAuthToken, Code: currentCode(t, enrollment.Secret)}, "10.0.0.1")
+	assert.Nil(t, err)
+	assert.NotEmpty(t, tokens.Token)
+	secret, err := store.GetTwoFactor(ctx, 1)
+	assert.Nil(t, err)
+	assert.True(t, secret.Confirmed, "The first code confirms a secret enrolled at login")
+
+	_, err = VerifySecondFactor(ctx, store, models.SecondFactorRequest{PreAuthToken: challenge.PreAuthToken, Code: enrollment.RecoveryCodes[0]}, "10.0.0.1")
+	assert.ErrorIs(t, err, ErrCredentials, "Pre-auth tokens are used once")
+
+	challenge, err = LoginChallenge(ctx, store, admin)
+	assert.Nil(t, err)
+	assert.False(t, challenge.Enroll)
+	request := models.SecondFactorRequest{PreAuthToken: challenge.PreAuthToken, Code: strings.ToLower(enrollment.RecoveryCodes[0])}
+	_, err = VerifySecondFactor(ctx, store, request, "10.0.0.1")
+	assert.Nil(t, err, "Recovery codes stand in for codes")
+
+	challenge, err = LoginChallenge(ctx, store, admin)
+	assert.Nil(t, err)
+	request.PreAuthToken = challenge.PreAuthToken
+	_, err = VerifySecondFactor(ctx, store, request, "10.0.0.1")
+	assert.ErrorIs(t, err, ErrCredentials, "Recovery codes are used once")
+
+	_, err = VerifySecondFactor(ctx, store, models.SecondFactorRequest{PreAuthToken: "forged"}, "10.0.0.1")
+	assert.ErrorIs(t, err, ErrCredentials)
+}
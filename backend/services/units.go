@@ -0,0 +1,82 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/WMS/barcode"
+	"github.com/WMS/models"
+)
+
+// checkDimensions validates dimensions and fills in inches when no unit
+// was given.
+func checkDimensions(d *models.Dimensions) error {
+	d.Unit = strings.ToLower(strings.TrimSpace(d.Unit))
+	if d.Unit == "" {
+		d.Unit = models.UnitInch
+	}
+	if !slices.Contains(models.LengthUnits, d.Unit) {
+		return invalid("dimensions", "unknown length unit %q", d.Unit)
+	}
+	for _, side := range []float64{d.Length, d.Width, d.Height} {
+		if side <= 0 || math.IsInf(side, 0) || math.IsNaN(side) {
+			return invalid("dimensions", "length, width and height must be positive")
+		}
+	}
+	return nil
+}
+
+// checkWeight validates a weight and fills in pounds when no unit was
+// given.
+func checkWeight(field string, w *models.Weight) error {
+	w.Unit = strings.ToLower(strings.TrimSpace(w.Unit))
+	if w.Unit == "" {
+		w.Unit = models.UnitPound
+	}
+	if !slices.Contains(models.WeightUnits, w.Unit) {
+		return invalid(field, "unknown weight unit %q", w.Unit)
+	}
+	if w.Value < 0 || math.IsInf(w.Value, 0) || math.IsNaN(w.Value) {
+		return invalid(field, "weight cannot be negative")
+	}
+	return nil
+}
+
+// checkBarcode validates a product code and stores it as a GTIN-14. Empty
+// codes are allowed where a code is optional.
+func checkBarcode(field string, code *string, required bool) error {
+	if strings.TrimSpace(*code) == "" {
+		if required {
+			return invalid(field, "barcode is required")
+		}
+		*code = ""
+		return nil
+	}
+	gtin, err := barcode.Normalize(*code)
+	if err != nil {
+		return invalid(field, "%s", err.Error())
+	}
+	*code = gtin
+	return nil
+}
+
+// NormalizeItem validates the UPC and weight of an item before it is
+// stored.
+func NormalizeItem(item *models.Item) error {
+	if err := checkBarcode("upc", &item.UPC, true); err != nil {
+		return err
+	}
+	return checkWeight("weight", &item.Weight)
+}
+
+// NormalizeBox validates the UPC and dimensions of a box before it is
+// stored.
+func NormalizeBox(box *models.Box) error {
+	if err := checkBarcode("upc", &box.UPC, false); err != nil {
+		return err
+	}
+	return checkDimensions(&box.Dimensions)
+}
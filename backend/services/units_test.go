@@ -0,0 +1,98 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/WMS/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDimensions(t *testing.T) {
+	d, err := models.ParseDimensions("2x2x4")
+	assert.Nil(t, err)
+	assert.Equal(t, models.Dimensions{Length: 2, Width: 2, Height: 4, Unit: models.UnitInch}, d)
+
+	d, err = models.ParseDimensions(" 30 x 20.5 X 10 cm")
+	assert.Nil(t, err)
+	assert.Equal(t, models.Dimensions{Length: 30, Width: 20.5, Height: 10, Unit: models.UnitCentimetre}, d)
+
+	for _, s := range []string{"", "2x2", "2x2x0", "ax2x2", "2x2x2 ft"} {
+		_, err := models.ParseDimensions(s)
+		assert.Error(t, err, s)
+	}
+
+	// Metric sides are compared in inches
+	assert.Equal(t, [3]float64{4, 2, 1}, inches(models.Dimensions{Length: 25.4, Width: 101.6, Height: 50.8, Unit: models.UnitMillimetre}))
+}
+
+func TestUnitConversion(t *testing.T) {
+	d := models.Dimensions{Length: 10, Width: 4, Height: 1, Unit: models.UnitInch}
+	assert.Equal(t, models.Dimensions{Length: 25.4, Width: 10.16, Height: 2.54, Unit: models.UnitCentimetre}, d.System(models.SystemMetric))
+	assert.Equal(t, d, d.System(models.SystemMetric).System(models.SystemImperial))
+	assert.Equal(t, d, d.System(""))
+
+	w := models.Weight{Value: 500, Unit: models.UnitGram}
+	assert.Equal(t, models.Weight{Value: 0.5, Unit: models.UnitKilogram}, w.System(models.SystemMetric))
+	assert.Equal(t, models.Weight{Value: 1.102311, Unit: models.UnitPound}, w.System(models.SystemImperial))
+	assert.Equal(t, models.Weight{Value: 2.2, Unit: "st"}, models.Weight{Value: 2.2, Unit: "st"}.In(models.UnitKilogram), "Unknown units are left alone")
+
+	item := models.Item{ID: 1, Weight: models.Weight{Value: 2, Unit: models.UnitPound}}
+	order := models.Order{Payload: []models.ItemGroup{{Item: item, Count: 1}}}
+	metric := order.System(models.SystemMetric)
+	assert.Equal(t, models.Weight{Value: 0.907185, Unit: models.UnitKilogram}, metric.Payload[0].Item.Weight)
+	assert.Equal(t, models.UnitPound, order.Payload[0].Item.Weight.Unit, "Converting copies the payload")
+}
+
+func TestLegacyUnits(t *testing.T) {
+	// Weights used to be plain numbers and box dimensions free text
+	var box models.Box
+	assert.Nil(t, json.Unmarshal([]byte(`{"item":{"id":1,"weight":1.5},"dimensions":"2x2x4","count":6}`), &box))
+	assert.Equal(t, models.Weight{Value: 1.5, Unit: models.UnitPound}, box.Item.Weight)
+	assert.Equal(t, models.Dimensions{Length: 2, Width: 2, Height: 4, Unit: models.UnitInch}, box.Dimensions)
+
+	assert.Nil(t, json.Unmarshal([]byte(`{"item":{"weight":"250 g"},"dimensions":{"length":10,"width":5,"height":5,"unit":"cm"}}`), &box))
+	assert.Equal(t, models.Weight{Value: 250, Unit: models.UnitGram}, box.Item.Weight)
+	assert.Equal(t, models.Dimensions{Length: 10, Width: 5, Height: 5, Unit: models.UnitCentimetre}, box.Dimensions)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"dimensions":"2 by 4"}`), &box))
+	assert.Error(t, json.Unmarshal([]byte(`{"item":{"weight":"heavy"}}`), &box))
+}
+
+func TestNormalizeUnits(t *testing.T) {
+	item := models.Item{UPC: "036000291452", Weight: models.Weight{Value: 3}}
+	assert.Nil(t, NormalizeItem(&item))
+	assert.Equal(t, models.UnitPound, item.Weight.Unit, "Weights default to pounds")
+	assert.Equal(t, "00036000291452", item.UPC, "UPCs are stored as GTIN-14")
+
+	var validation *ValidationError
+	item.UPC = "036000291453"
+	assert.ErrorAs(t, NormalizeItem(&item), &validation, "Wrong check digit")
+	assert.Equal(t, "upc", validation.Field)
+	item.UPC = " "
+	assert.ErrorAs(t, NormalizeItem(&item), &validation, "Items need a UPC")
+	item.UPC = "036000291452"
+	item.Weight = models.Weight{Value: 3, Unit: "st"}
+	assert.ErrorAs(t, NormalizeItem(&item), &validation)
+	item.Weight = models.Weight{Value: -1, Unit: models.UnitKilogram}
+	assert.ErrorAs(t, NormalizeItem(&item), &validation)
+
+	box := models.Box{Dimensions: models.Dimensions{Length: 1, Width: 2, Height: 3, Unit: " CM"}}
+	assert.Nil(t, NormalizeBox(&box))
+	assert.Equal(t, models.UnitCentimetre, box.Dimensions.Unit)
+	assert.Empty(t, box.UPC, "Boxes need no UPC")
+	box.UPC = "96385075"
+	assert.ErrorAs(t, NormalizeBox(&box), &validation)
+	box.UPC = "96385074"
+	assert.Nil(t, NormalizeBox(&box))
+	assert.Equal(t, "00000096385074", box.UPC)
+	box.Dimensions.Height = 0
+	assert.ErrorAs(t, NormalizeBox(&box), &validation)
+
+	carton := models.Carton{Name: "S", Dimensions: box.Dimensions, MaxWeight: models.Weight{Value: 10, Unit: models.UnitKilogram}, TareWeight: models.Weight{Value: 500, Unit: models.UnitGram}}
+	carton.Dimensions.Height = 3
+	assert.Nil(t, NormalizeCarton(&carton))
+	assert.Equal(t, models.Weight{Value: 0.5, Unit: models.UnitKilogram}, carton.TareWeight, "Tare is kept in the unit of the max weight")
+}
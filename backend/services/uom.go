@@ -0,0 +1,180 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/WMS/models"
+)
+
+// uomLevel returns the position of a unit of measure in the hierarchy, or
+// -1 when it is unknown.
+func uomLevel(uom string) int {
+	return slices.Index(models.UOMLevels, uom)
+}
+
+// NormalizeUnitsOfMeasure validates the pack levels defined for an item
+// and sorts them smallest first. The each is implied and cannot be
+// defined, and every level has to hold more eaches than the one below it.
+func NormalizeUnitsOfMeasure(itemID int64, units []models.UnitOfMeasure) error {
+	for i := range units {
+		unit := &units[i]
+		unit.ItemID = itemID
+		unit.UOM = strings.ToUpper(strings.TrimSpace(unit.UOM))
+		if uomLevel(unit.UOM) <= 0 {
+			return invalid("uom", "unknown unit of measure %q", unit.UOM)
+		}
+		if err := checkBarcode("upc", &unit.UPC, false); err != nil {
+			return err
+		}
+		if unit.Quantity <= 1 {
+			return invalid("quantity", "a %s must hold more than one each", unit.UOM)
+		}
+	}
+	slices.SortFunc(units, func(a, b models.UnitOfMeasure) int {
+		return cmp.Compare(uomLevel(a.UOM), uomLevel(b.UOM))
+	})
+	for i := 1; i < len(units); i++ {
+		if units[i].UOM == units[i-1].UOM {
+			return invalid("uom", "%s is defined more than once", units[i].UOM)
+		}
+		if units[i].Quantity <= units[i-1].Quantity {
+			return invalid("quantity", "a %s must hold more than a %s", units[i].UOM, units[i-1].UOM)
+		}
+	}
+	return nil
+}
+
+// itemUnits completes the units of measure of an item. The each is always
+// there with the item UPC. An item without a case level ships its smallest
+// box as the case, and a case without a UPC takes the UPC of the box of the
+// same size.
+func itemUnits(item models.Item, defined []models.UnitOfMeasure, boxes []models.Box) []models.UnitOfMeasure {
+	units := []models.UnitOfMeasure{{ItemID: item.ID, UOM: models.UOMEach, Quantity: 1, UPC: item.UPC}}
+	var smallest *models.Box
+	for i, box := range boxes {
+		if box.Item.ID == item.ID && box.Count > 1 && (smallest == nil || box.Count < smallest.Count) {
+			smallest = &boxes[i]
+		}
+	}
+
+	hasCase := false
+	for _, unit := range defined {
+		if unit.UOM == models.UOMCase {
+			hasCase = true
+			if unit.UPC == "" {
+				for _, box := range boxes {
+					if box.Item.ID == item.ID && box.Count == unit.Quantity {
+						unit.UPC = box.UPC
+						break
+					}
+				}
+			}
+		}
+		units = append(units, unit)
+	}
+	if !hasCase && smallest != nil {
+		boxCase := models.UnitOfMeasure{ItemID: item.ID, UOM: models.UOMCase, Quantity: smallest.Count, UPC: smallest.UPC}
+		// The box only stands in for the case where it fits the levels
+		// defined around it.
+		fits := true
+		for _, unit := range units {
+			below := uomLevel(unit.UOM) < uomLevel(models.UOMCase)
+			if below && unit.Quantity >= boxCase.Quantity || !below && unit.Quantity <= boxCase.Quantity {
+				fits = false
+			}
+		}
+		if fits {
+			units = append(units, boxCase)
+		}
+	}
+	slices.SortFunc(units, func(a, b models.UnitOfMeasure) int {
+		return cmp.Compare(uomLevel(a.UOM), uomLevel(b.UOM))
+	})
+	return units
+}
+
+// ItemIDs lists the distinct items of item lines.
+func ItemIDs(groups []models.ItemGroup) []int64 {
+	var ids []int64
+	for _, group := range groups {
+		ids = append(ids, group.Item.ID)
+	}
+	slices.Sort(ids)
+	return slices.Compact(ids)
+}
+
+// toBaseUnits converts a count in a unit of measure of an item to eaches.
+// Counts without a unit of measure are already in eaches.
+func toBaseUnits(units map[int64][]models.UnitOfMeasure, itemID int64, uom string, count int64) (int64, error) {
+	uom = strings.ToUpper(strings.TrimSpace(uom))
+	if uom == "" || uom == models.UOMEach {
+		return count, nil
+	}
+	if uomLevel(uom) < 0 {
+		return 0, invalid("uom", "unknown unit of measure %q", uom)
+	}
+	for _, unit := range units[itemID] {
+		if unit.UOM != uom {
+			continue
+		}
+		if count > math.MaxInt64/unit.Quantity || count < math.MinInt64/unit.Quantity {
+			return 0, invalid("count", "%d %s of item %d is out of range", count, uom, itemID)
+		}
+		return count * unit.Quantity, nil
+	}
+	return 0, invalid("uom", "item %d has no %s unit of measure", itemID, uom)
+}
+
+// GroupsToBase converts item lines given in a unit of measure to eaches.
+func GroupsToBase(groups []models.ItemGroup, units map[int64][]models.UnitOfMeasure) error {
+	for i := range groups {
+		count, err := toBaseUnits(units, groups[i].Item.ID, groups[i].UOM, groups[i].Count)
+		if err != nil {
+			return err
+		}
+		groups[i].Count, groups[i].UOM = count, ""
+	}
+	return nil
+}
+
+// LocationsToBase converts the location counts of an item given in a unit
+// of measure to eaches.
+func LocationsToBase(itemID int64, locations []models.LocationData, units map[int64][]models.UnitOfMeasure) error {
+	for i := range locations {
+		count, err := toBaseUnits(units, itemID, locations[i].UOM, locations[i].Count)
+		if err != nil {
+			return err
+		}
+		locations[i].Count, locations[i].UOM = count, ""
+	}
+	return nil
+}
+
+// ReceiptToBase converts received and damaged counts given in a unit of
+// measure to eaches, reading the item of each line from the shipment
+// payload. Lines the shipment does not have are left to receiving to
+// reject.
+func ReceiptToBase(shipment models.Shipment, lines []models.ReceiptLine, units map[int64][]models.UnitOfMeasure) error {
+	for i := range lines {
+		line := &lines[i]
+		if line.Line < 0 || line.Line >= len(shipment.Payload) {
+			continue
+		}
+		itemID := shipment.Payload[line.Line].Item.ID
+		received, err := toBaseUnits(units, itemID, line.UOM, line.Received)
+		if err != nil {
+			return err
+		}
+		damaged, err := toBaseUnits(units, itemID, line.UOM, line.Damaged)
+		if err != nil {
+			return err
+		}
+		line.Received, line.Damaged, line.UOM = received, damaged, ""
+	}
+	return nil
+}
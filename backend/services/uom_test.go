@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: GPL-3.0
+
+package services
+
+import (
+	"testing"
+
+	"github.com/WMS/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeUnitsOfMeasure(t *testing.T) {
+	units := []models.UnitOfMeasure{
+		{UOM: "pallet", Quantity: 480},
+		{UOM: " inner ", Quantity: 6, UPC: " 036000291452 "},
+	}
+	assert.Nil(t, NormalizeUnitsOfMeasure(1, units))
+	assert.Equal(t, []models.UnitOfMeasure{
+		{ItemID: 1, UOM: models.UOMInner, Quantity: 6, UPC: "00036000291452"},
+		{ItemID: 1, UOM: models.UOMPallet, Quantity: 480},
+	}, units)
+
+	var validation *ValidationError
+	for _, units := range [][]models.UnitOfMeasure{
+		{{UOM: models.UOMEach, Quantity: 2}},
+		{{UOM: "DOZEN", Quantity: 12}},
+		{{UOM: models.UOMCase, Quantity: 1}},
+		{{UOM: models.UOMCase, Quantity: 12}, {UOM: models.UOMCase, Quantity: 24}},
+		{{UOM: models.UOMCase, Quantity: 12}, {UOM: models.UOMInner, Quantity: 12}},
+	} {
+		assert.ErrorAs(t, NormalizeUnitsOfMeasure(1, units), &validation, units)
+	}
+}
+
+func TestItemUnits(t *testing.T) {
+	item := models.Item{ID: 1, UPC: "100"}
+	boxes := []models.Box{
+		{UPC: "124", Item: item, Count: 24},
+		{UPC: "112", Item: item, Count: 12},
+		{UPC: "201", Item: models.Item{ID: 2}, Count: 2},
+	}
+
+	// The smallest box is the case
+	assert.Equal(t, []models.UnitOfMeasure{
+		{ItemID: 1, UOM: models.UOMEach, Quantity: 1, UPC: "100"},
+		{ItemID: 1, UOM: models.UOMCase, Quantity: 12, UPC: "112"},
+		{ItemID: 1, UOM: models.UOMPallet, Quantity: 480},
+	}, itemUnits(item, []models.UnitOfMeasure{{ItemID: 1, UOM: models.UOMPallet, Quantity: 480}}, boxes))
+
+	// A defined case takes the UPC of the box it matches
+	units := itemUnits(item, []models.UnitOfMeasure{{ItemID: 1, UOM: models.UOMCase, Quantity: 24}}, boxes)
+	assert.Equal(t, models.UnitOfMeasure{ItemID: 1, UOM: models.UOMCase, Quantity: 24, UPC: "124"}, units[1])
+
+	// A box that does not fit the defined levels is no case
+	units = itemUnits(item, []models.UnitOfMeasure{{ItemID: 1, UOM: models.UOMInner, Quantity: 12}}, boxes)
+	assert.Len(t, units, 2)
+}
+
+func TestToBaseUnits(t *testing.T) {
+	units := map[int64][]models.UnitOfMeasure{
+		1: itemUnits(models.Item{ID: 1}, []models.UnitOfMeasure{{ItemID: 1, UOM: models.UOMPallet, Quantity: 480}}, []models.Box{{Item: models.Item{ID: 1}, Count: 12}}),
+	}
+
+	groups := []models.ItemGroup{
+		{Item: models.Item{ID: 1}, Count: 2, UOM: "case"},
+		{Item: models.Item{ID: 1}, Count: 1, UOM: models.UOMPallet},
+		{Item: models.Item{ID: 2}, Count: 5},
+	}
+	assert.Nil(t, GroupsToBase(groups, units))
+	assert.Equal(t, []models.ItemGroup{
+		{Item: models.Item{ID: 1}, Count: 24},
+		{Item: models.Item{ID: 1}, Count: 480},
+		{Item: models.Item{ID: 2}, Count: 5},
+	}, groups)
+
+	locations := []models.LocationData{{LocationID: 1, Count: 3, UOM: models.UOMCase}}
+	assert.Nil(t, LocationsToBase(1, locations, units))
+	assert.Equal(t, []models.LocationData{{LocationID: 1, Count: 36}}, locations)
+
+	shipment := models.Shipment{Payload: []models.ItemGroup{{Item: models.Item{ID: 1}, Count: 480}}}
+	lines := []models.ReceiptLine{{Line: 0, Received: 39, Damaged: 1, UOM: models.UOMCase}}
+	assert.Nil(t, ReceiptToBase(shipment, lines, units))
+	assert.Equal(t, []models.ReceiptLine{{Line: 0, Received: 468, Damaged: 12}}, lines)
+
+	var validation *ValidationError
+	assert.ErrorAs(t, GroupsToBase([]models.ItemGroup{{Item: models.Item{ID: 1}, Count: 1, UOM: models.UOMInner}}, units), &validation)
+	assert.ErrorAs(t, GroupsToBase([]models.ItemGroup{{Item: models.Item{ID: 2}, Count: 1, UOM: models.UOMCase}}, units), &validation)
+	assert.ErrorAs(t, GroupsToBase([]models.ItemGroup{{Item: models.Item{ID: 1}, Count: 1, UOM: "crate"}}, units), &validation)
+	assert.ErrorAs(t, GroupsToBase([]models.ItemGroup{{Item: models.Item{ID: 1}, Count: 1 << 62, UOM: models.UOMPallet}}, units), &validation)
+}
@@ -15,6 +15,26 @@ export interface JwtObject extends JwtPayload {
   username: string;
   role: Role;
   orig_iat: number;
+  gen: number;
+}
+
+export interface TokenPair {
+  token: string;
+  refreshToken: string;
+  expiresAt: string;
+}
+
+export interface LoginChallenge {
+  preAuthToken: string;
+  expiresAt: string;
+  enroll: boolean;
+}
+
+export interface TOTPEnrollment {
+  secret: string;
+  uri: string;
+  qrCode: string;
+  recoveryCodes: string[];
 }
 
 export interface Account {
@@ -24,12 +44,21 @@ export interface Account {
   email: string;
   phone: string;
   username: string;
-  password: string;
   role: Role;
   active: boolean;
   created: Date | string;
 }
 
+// The password is only ever sent, when an account is created
+export interface NewAccount extends Account {
+  password: string;
+}
+
+export interface PasswordChange {
+  currentPassword: string;
+  newPassword: string;
+}
+
 export interface Role {
   ADMIN: string;
   MANAGER: string;
@@ -44,10 +73,15 @@ export interface Item {
   upc: string;
   name: string;
   description: string;
-  weight: number;
+  weight: Weight;
   image: ImageInfo | null;
 }
 
+export interface Weight {
+  value: number;
+  unit: string;
+}
+
 export interface ItemInfo {
   id: number;
   name: string;
@@ -63,7 +97,7 @@ export interface Box {
   id: number | null;
   upc: string;
   item: Item;
-  dimensions: string;
+  dimensions: Dimensions;
   count: number;
 }
 
@@ -71,6 +105,7 @@ export interface Dimensions {
   length: number;
   width: number;
   height: number;
+  unit: string;
 }
 
 export interface Inventory {
@@ -105,3 +140,16 @@ export interface ItemGroup {
   item: Item;
   count: number;
 }
+
+export interface Page<T> {
+  data: T[];
+  page: PageInfo;
+}
+
+export interface PageInfo {
+  limit: number;
+  offset: number;
+  total: number;
+  next?: number;
+  sort: string[];
+}
@@ -6,10 +6,6 @@ import { selectAppActive } from "../features/appSlice";
 import { selectAppUser } from "../features/appSlice";
 import { selectErrorActive } from "../features/errors/errorSlice";
 import { useNavigate } from "react-router";
-import { InitInvAPI } from "../services/inventoryApi";
-import { InitAccAPI } from "../services/accountApi";
-import { InitItemAPI } from "../services/itemApi";
-import { InitBoxAPI } from "../services/boxApi";
 
 export default function Home() {
   const appActive = useAppSelector(selectAppActive);
@@ -17,11 +13,6 @@ export default function Home() {
   const errorState = useAppSelector(selectErrorActive);
   const navigate = useNavigate();
 
-  InitAccAPI();
-  InitInvAPI();
-  InitItemAPI();
-  InitBoxAPI();
-
   useEffect(() => {
     if (!appActive || !userState.userActive) {
       navigate("/login");
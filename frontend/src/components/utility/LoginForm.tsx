@@ -4,6 +4,7 @@ import { useState } from "react";
 import { useAppDispatch } from "../../app/hooks";
 import {
   insertJWT,
+  insertRefreshToken,
   insertRole,
   activateUser,
   insertUsername,
@@ -11,7 +12,7 @@ import {
 } from "../../features/accounts/accountSlice";
 import { insertAppUser, activateApp } from "../../features/appSlice";
 import { useNavigate } from "react-router";
-import type { JwtObject } from "../../app/models";
+import type { JwtObject, TokenPair } from "../../app/models";
 import { AuthorizeUser } from "../../services/utilityApi";
 import DOMPurify from "dompurify";
 
@@ -22,7 +23,7 @@ export default function LoginForm() {
   const navigate = useNavigate();
 
   let exists: boolean;
-  let token: string;
+  let tokens: TokenPair;
   let payload: JwtObject;
 
   const handleLogin = async () => {
@@ -34,14 +35,15 @@ export default function LoginForm() {
 
     console.log("Attempting to login as: ", usernameIn);
     try {
-      [exists, token, payload] = await AuthorizeUser(usernameIn, passwordIn);
+      [exists, tokens, payload] = await AuthorizeUser(usernameIn, passwordIn);
       console.log("Exists:", exists, "Token Payload:", payload);
 
-      if (!exists || token.length < 1 || payload === null) {
+      if (!exists || tokens.token.length < 1 || payload === null) {
         alert("Account not found in database...");
         throw new Error("Account not found in database!");
       }
-      dispatch(insertJWT(token));
+      dispatch(insertJWT(tokens.token));
+      dispatch(insertRefreshToken(tokens.refreshToken));
       dispatch(insertID(payload.id));
       dispatch(insertUsername(payload.username));
       dispatch(insertRole(payload.role.Value));
@@ -49,7 +51,8 @@ export default function LoginForm() {
 
       dispatch(
         insertAppUser({
-          jwt: token,
+          jwt: tokens.token,
+          refreshToken: tokens.refreshToken,
           id: payload.id,
           username: payload.username,
           role: payload.role.Value,
@@ -7,7 +7,7 @@ import { selectAppActive } from "../appSlice";
 import { selectErrorActive, insertError } from "../errors/errorSlice";
 import DOMPurify from "dompurify";
 import { selectRole, selectUserState } from "./accountSlice";
-import type { Account } from "../../app/models";
+import type { Account, NewAccount } from "../../app/models";
 import { CreateAccount } from "../../services/accountApi";
 
 export default function CreateAccountForm() {
@@ -46,7 +46,7 @@ export default function CreateAccountForm() {
   const handleCreate = async () => {
     let success: boolean;
     let responseAccount: Account;
-    const updatedAccount: Account = {
+    const updatedAccount: NewAccount = {
       id: null,
       firstname: firstnameIn,
       lastname: lastnameIn,
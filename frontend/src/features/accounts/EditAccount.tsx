@@ -8,6 +8,7 @@ import { insertError, selectErrorActive } from "../errors/errorSlice";
 import type { Account } from "../../app/models";
 import { selectRole, selectUserState } from "./accountSlice";
 import {
+  ChangePassword,
   DeleteAccount,
   GetAccount,
   UpdateAccount,
@@ -23,6 +24,7 @@ export default function EditAccount() {
   const navigate = useNavigate();
   const [account, setAccount] = useState<Account | null>();
   const [usernameIn, setUsernameValue] = useState("");
+  const [currentPasswordIn, setCurrentPasswordValue] = useState("");
   const [passwordIn, setPasswordValue] = useState("");
   const [firstnameIn, setFirstnameValue] = useState("");
   const [lastnameIn, setLastnameValue] = useState("");
@@ -129,7 +131,6 @@ export default function EditAccount() {
       email: emailIn,
       phone: phoneIn,
       username: usernameIn,
-      password: passwordIn,
       role: {
         ADMIN: "",
         CUSTOMER: "",
@@ -154,8 +155,8 @@ export default function EditAccount() {
       alert("Username cannot be empty!");
       return;
     }
-    if (updatedAccount.password.trim() === "") {
-      alert("Password cannot be empty!");
+    if (passwordIn.trim() !== "" && currentPasswordIn.trim() === "") {
+      alert("Enter the current password to change it!");
       return;
     }
     if (updatedAccount.role.Value.trim() === "") {
@@ -179,6 +180,12 @@ export default function EditAccount() {
       console.log(
         `success: ${success ? "True" : "False"} , account: ${responseAccount}`,
       );
+      if (passwordIn.trim() !== "") {
+        await ChangePassword(+id!, userState, {
+          currentPassword: currentPasswordIn,
+          newPassword: passwordIn,
+        });
+      }
       navigate(-1);
     } catch (err) {
       console.error("ApiService Failed To Create Account: " + err);
@@ -220,12 +227,26 @@ export default function EditAccount() {
               />
             </div>
           ) : null}
+          <div id="input-current-password">
+            <label htmlFor="current-password-area">Current Password:</label>
+            <input
+              className="border-2 rounded text-center"
+              type="password"
+              aria-label="current password"
+              placeholder="..."
+              value={currentPasswordIn}
+              onChange={(e) => {
+                const sanitizedValue = DOMPurify.sanitize(e.target.value);
+                setCurrentPasswordValue(sanitizedValue);
+              }}
+            />
+          </div>
           <div id="input-password">
-            <label htmlFor="password-area">*Password:</label>
+            <label htmlFor="password-area">New Password:</label>
             <input
               className="border-2 rounded text-center"
               type="password"
-              aria-label="password"
+              aria-label="new password"
               placeholder="..."
               value={passwordIn}
               onChange={(e) => {
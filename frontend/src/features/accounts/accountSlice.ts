@@ -5,6 +5,7 @@ import { createAppSlice } from "../../app/createAppSlice";
 
 export type AccountSliceState = {
   jwt: string;
+  refreshToken: string;
   id: number;
   username: string;
   role: string;
@@ -13,6 +14,7 @@ export type AccountSliceState = {
 
 const initialState: AccountSliceState = {
   jwt: "",
+  refreshToken: "",
   id: 0,
   username: "",
   role: "",
@@ -32,6 +34,14 @@ export const accountSlice = createAppSlice({
     resetJWT: create.reducer((state) => {
       state.jwt = "";
     }),
+    insertRefreshToken: create.reducer(
+      (state, action: PayloadAction<string>) => {
+        state.refreshToken = action.payload;
+      },
+    ),
+    resetRefreshToken: create.reducer((state) => {
+      state.refreshToken = "";
+    }),
     insertID: create.reducer((state, action: PayloadAction<number>) => {
       state.id = action.payload;
     }),
@@ -61,6 +71,7 @@ export const accountSlice = createAppSlice({
   // state as their first argument.
   selectors: {
     selectJWT: (account: AccountSliceState) => account.jwt,
+    selectRefreshToken: (account: AccountSliceState) => account.refreshToken,
     selectID: (account: AccountSliceState) => account.id,
     selectUsername: (account: AccountSliceState) => account.username,
     selectRole: (account: AccountSliceState) => account.role,
@@ -73,6 +84,8 @@ export const accountSlice = createAppSlice({
 export const {
   insertJWT,
   resetJWT,
+  insertRefreshToken,
+  resetRefreshToken,
   insertID,
   resetID,
   insertUsername,
@@ -86,6 +99,7 @@ export const {
 // Selectors returned by `slice.selectors` take the root state as their first argument.
 export const {
   selectJWT,
+  selectRefreshToken,
   selectID,
   selectUsername,
   selectRole,
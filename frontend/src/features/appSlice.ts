@@ -32,6 +32,7 @@ export const appSlice = createAppSlice({
     resetAppUser: create.reducer((state) => {
       state.user = {
         jwt: "",
+        refreshToken: "",
         id: 0,
         username: "",
         role: "",
@@ -7,7 +7,7 @@ import { selectAppActive } from "../appSlice";
 import { insertError, selectErrorActive } from "../errors/errorSlice";
 import type { Box } from "../../app/models";
 import { selectRole, selectUserState } from "../accounts/accountSlice";
-import { DeleteBox, GetAllBoxes } from "../../services/boxApi";
+import { DeleteBox, FormatDimensions, GetAllBoxes } from "../../services/boxApi";
 
 export default function Boxes() {
   const userRole = useAppSelector(selectRole);
@@ -146,7 +146,7 @@ export default function Boxes() {
                               {mapBox.item.name}
                             </Link>
                           </td>
-                          <td>{mapBox.dimensions}</td>
+                          <td>{FormatDimensions(mapBox.dimensions)}</td>
                           <td>{mapBox.count}</td>
                           {userRole === "ADMIN" || userRole === "MANAGER" ? (
                             <td className="flex">
@@ -179,7 +179,7 @@ export default function CreateBoxForm() {
       id: null,
       upc: upcIn,
       item: item!,
-      dimensions: "",
+      dimensions: dimensionsIn!,
       count: countIn,
     };
 
@@ -286,6 +286,7 @@ export default function CreateBoxForm() {
                   length: lengthIn,
                   width: widthIn,
                   height: heightIn,
+                  unit: "in",
                 })
               }
             >
@@ -8,7 +8,7 @@ import { insertError, selectErrorActive } from "../errors/errorSlice";
 import type { ItemInfo, Dimensions, Item, Box } from "../../app/models";
 import { GetItemsList, GetItem } from "../../services/itemApi";
 import { selectRole, selectUserState } from "../accounts/accountSlice";
-import { ConvertDimensions, GetBox, UpdateBox } from "../../services/boxApi";
+import { GetBox, UpdateBox } from "../../services/boxApi";
 import DOMPurify from "dompurify";
 
 export default function EditBox() {
@@ -216,7 +216,7 @@ export default function EditBox() {
       id: +id!,
       upc: upcIn,
       item: item!,
-      dimensions: ConvertDimensions(dimensionsIn!),
+      dimensions: dimensionsIn!,
       count: countIn,
     };
 
@@ -284,7 +284,7 @@ export default function EditBox() {
                 className="border-2 rounded text-center"
                 type="number"
                 aria-label="length"
-                placeholder={box?.dimensions.split("x").at(0)}
+                placeholder={box?.dimensions.length.toString()}
                 onChange={(e) => {
                   setLengthValue(e.target.valueAsNumber);
                 }}
@@ -294,7 +294,7 @@ export default function EditBox() {
                 className="border-2 rounded text-center"
                 type="number"
                 aria-label="width"
-                placeholder={box?.dimensions.split("x").at(1)}
+                placeholder={box?.dimensions.width.toString()}
                 onChange={(e) => {
                   setWidthValue(e.target.valueAsNumber);
                 }}
@@ -304,7 +304,7 @@ export default function EditBox() {
                 className="border-2 rounded text-center"
                 type="number"
                 aria-label="height"
-                placeholder={box?.dimensions.split("x").at(2)}
+                placeholder={box?.dimensions.height.toString()}
                 onChange={(e) => {
                   setHeightValue(e.target.valueAsNumber);
                 }}
@@ -316,6 +316,7 @@ export default function EditBox() {
                   length: lengthIn,
                   width: widthIn,
                   height: heightIn,
+                  unit: box?.dimensions.unit ?? "in",
                 })
               }
             >
@@ -92,7 +92,7 @@ export default function CreateItemForm() {
       upc: upcIn,
       name: nameIn,
       description: descriptionIn,
-      weight: weightIn,
+      weight: { value: weightIn, unit: "lb" },
       image: { name: "", data: convertToBytea(imgBinIn!), valid: true },
     };
 
@@ -160,7 +160,7 @@ export default function EditItem() {
       upc: upcIn,
       name: nameIn,
       description: descriptionIn,
-      weight: weightIn,
+      weight: { value: weightIn, unit: "lb" },
       image: { name: "", data: convertToBytea(imgBinIn!), valid: true },
     };
 
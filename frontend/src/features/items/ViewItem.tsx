@@ -7,7 +7,7 @@ import { selectAppActive } from "../appSlice";
 import { insertError, selectErrorActive } from "../errors/errorSlice";
 import { GetItem } from "../../services/itemApi";
 import { selectUserState } from "../accounts/accountSlice";
-import type { Item } from "../../app/models";
+import type { Item, Weight } from "../../app/models";
 
 export default function ViewItem() {
   const appActive = useAppSelector(selectAppActive);
@@ -24,12 +24,8 @@ export default function ViewItem() {
     return `data:image/png;base64,${imageData}`;
   };
 
-  const convertWeight = (input: number) => {
-    if (input < 1) {
-      setWeightDisplay(`${input} oz.`);
-    } else {
-      setWeightDisplay(`${input} lb.`);
-    }
+  const convertWeight = (input: Weight) => {
+    setWeightDisplay(`${input.value} ${input.unit}`);
   };
 
   useEffect(() => {
@@ -1,12 +1,15 @@
 // SPDX-License-Identifier: GPL-3.0
 
 import axios, { HttpStatusCode } from "axios";
-import type { Account, Role } from "../app/models";
-import {
-  selectJWT,
-  type AccountSliceState,
-} from "../features/accounts/accountSlice";
-import { useAppSelector } from "../app/hooks";
+import type {
+  Account,
+  NewAccount,
+  Page,
+  PasswordChange,
+  Role,
+} from "../app/models";
+import type { AccountSliceState } from "../features/accounts/accountSlice";
+import { UseSession } from "./utilityApi";
 
 const apiHost: string =
   import.meta.env.VITE_API_URL || "https://localhost:1323";
@@ -20,15 +23,7 @@ const api = axios.create({
   },
 });
 
-export function InitAccAPI() {
-  const token = useAppSelector(selectJWT);
-  api.interceptors.request.use((config) => {
-    if (token) {
-      config.headers.Authorization = `Bearer ${token}`;
-    }
-    return config;
-  });
-}
+UseSession(api);
 
 export async function CreateAccount(
   initiatorAccount: AccountSliceState,
@@ -43,7 +38,7 @@ export async function CreateAccount(
 ): Promise<[boolean, Account]> {
   let successful: boolean;
   const timestamp = new Date().toISOString();
-  const newAccount: Account = {
+  const newAccount: NewAccount = {
     id: id,
     firstname: firstname,
     lastname: lastname,
@@ -72,7 +67,7 @@ export async function CreateAccount(
         phone: newAccount.phone,
         username: newAccount.username,
         password: newAccount.password,
-        role: newAccount.role.Value,
+        role: newAccount.role,
         active: newAccount.active,
         created: newAccount.created,
       },
@@ -107,8 +102,9 @@ export async function GetAccounts(
       alert("You Do Have Have Permission To View All Accounts");
       throw new Error("Initiator's Account Is Not Privileged");
     }
-    const response = await api.get<Account[]>(apiHost + "/api/accounts", {
+    const response = await api.get<Page<Account>>(apiHost + "/api/accounts", {
       //withCredentials: true,
+      params: { limit: 500 },
     });
     const data = response.data;
     console.log("Raw API Response: ", data);
@@ -117,7 +113,7 @@ export async function GetAccounts(
       throw new Error("Response Status: NOT 'Ok'");
     }
     received = true;
-    accounts = data;
+    accounts = data.data;
     return [received, accounts];
   } catch (err) {
     console.error(err);
@@ -190,10 +186,8 @@ export async function UpdateAccount(
         email: newAccount.email,
         phone: newAccount.phone,
         username: newAccount.username,
-        password: newAccount.password,
         role: newAccount.role,
         active: newAccount.active,
-        created: newAccount.created,
       },
       {
         //withCredentials: true,
@@ -214,6 +208,31 @@ export async function UpdateAccount(
   }
 }
 
+export async function ChangePassword(
+  id: number,
+  initiatorAccount: AccountSliceState,
+  change: PasswordChange,
+): Promise<boolean> {
+  try {
+    if (initiatorAccount.id !== id && initiatorAccount.role !== "ADMIN") {
+      alert("You Do Have Have Permission To Update This Account");
+      throw new Error("Initiator's Account Is Not Privileged");
+    }
+    const response = await api.put<number>(
+      apiHost + `/api/accounts/${id}/password`,
+      change,
+    );
+    if (response.status !== HttpStatusCode.Accepted) {
+      throw new Error(`Unexpected Response Status`);
+    }
+    return true;
+  } catch (err) {
+    console.error(err);
+    alert(`Error: Failed To Change Password Of Account [${id}]: ` + err);
+    throw new Error("Failed To Query RESTapi: " + err);
+  }
+}
+
 export async function DeleteAccount(
   initiatorAccount: AccountSliceState,
   id: number,
@@ -1,12 +1,9 @@
 // SPDX-License-Identifier: GPL-3.0
 
 import axios, { HttpStatusCode } from "axios";
-import type { Box, Dimensions, Item } from "../app/models";
-import {
-  selectJWT,
-  type AccountSliceState,
-} from "../features/accounts/accountSlice";
-import { useAppSelector } from "../app/hooks";
+import type { Box, Dimensions, Item, Page } from "../app/models";
+import type { AccountSliceState } from "../features/accounts/accountSlice";
+import { UseSession } from "./utilityApi";
 
 const apiHost: string =
   import.meta.env.VITE_API_URL || "https://localhost:1323";
@@ -20,18 +17,10 @@ const api = axios.create({
   },
 });
 
-export function ConvertDimensions(dims: Dimensions): string {
-  return `${dims.length}x${dims.width}x${dims.height}`;
-}
+UseSession(api);
 
-export function InitBoxAPI() {
-  const token = useAppSelector(selectJWT);
-  api.interceptors.request.use((config) => {
-    if (token) {
-      config.headers.Authorization = `Bearer ${token}`;
-    }
-    return config;
-  });
+export function FormatDimensions(dims: Dimensions): string {
+  return `${dims.length}x${dims.width}x${dims.height} ${dims.unit}`;
 }
 
 export async function CreateBox(
@@ -47,7 +36,7 @@ export async function CreateBox(
     id: id,
     upc: upc,
     item: item,
-    dimensions: ConvertDimensions(dimensions),
+    dimensions: dimensions,
     count: count,
   };
 
@@ -100,8 +89,9 @@ export async function GetAllBoxes(
       alert("User Account Is Not Active!");
       throw new Error("Initiator's Account Is Not Privileged");
     }
-    const response = await api.get<Box[]>(apiHost + "/api/boxes", {
+    const response = await api.get<Page<Box>>(apiHost + "/api/boxes", {
       //withCredentials: true,
+      params: { limit: 500 },
     });
     const data = response.data;
     console.log("Raw API Response: ", data);
@@ -110,7 +100,7 @@ export async function GetAllBoxes(
       throw new Error("Response Status: NOT 'Ok'");
     }
     received = true;
-    allBoxes = data;
+    allBoxes = data.data;
     return [received, allBoxes];
   } catch (err) {
     console.error(err);
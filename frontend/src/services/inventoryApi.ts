@@ -1,12 +1,9 @@
 // SPDX-License-Identifier: GPL-3.0
 
 import axios, { HttpStatusCode } from "axios";
-import type { Inventory, Item, LocationData } from "../app/models";
-import {
-  selectJWT,
-  type AccountSliceState,
-} from "../features/accounts/accountSlice";
-import { useAppSelector } from "../app/hooks";
+import type { Inventory, Item, LocationData, Page } from "../app/models";
+import type { AccountSliceState } from "../features/accounts/accountSlice";
+import { UseSession } from "./utilityApi";
 
 const apiHost: string =
   import.meta.env.VITE_API_URL || "https://localhost:1323";
@@ -20,15 +17,7 @@ const api = axios.create({
   },
 });
 
-export function InitInvAPI() {
-  const token = useAppSelector(selectJWT);
-  api.interceptors.request.use((config) => {
-    if (token) {
-      config.headers.Authorization = `Bearer ${token}`;
-    }
-    return config;
-  });
-}
+UseSession(api);
 
 export async function CreateInventory(
   initiatorAccount: AccountSliceState,
@@ -93,8 +82,9 @@ export async function GetAllInventory(
       alert("User Account Is Not Active!");
       throw new Error("Initiator's Account Is Not Privileged");
     }
-    const response = await api.get<Inventory[]>(apiHost + "/api/inventory", {
+    const response = await api.get<Page<Inventory>>(apiHost + "/api/inventory", {
       //withCredentials: true,
+      params: { limit: 500 },
     });
     const data = response.data;
     console.log("Raw API Response: ", data);
@@ -103,7 +93,7 @@ export async function GetAllInventory(
       throw new Error("Response Status: NOT 'Ok'");
     }
     received = true;
-    allInventory = data;
+    allInventory = data.data;
     return [received, allInventory];
   } catch (err) {
     console.error(err);
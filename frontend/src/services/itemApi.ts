@@ -1,12 +1,9 @@
 // SPDX-License-Identifier: GPL-3.0
 
 import axios, { HttpStatusCode } from "axios";
-import type { ImageInfo, Item, ItemInfo } from "../app/models";
-import {
-  selectJWT,
-  type AccountSliceState,
-} from "../features/accounts/accountSlice";
-import { useAppSelector } from "../app/hooks";
+import type { ImageInfo, Item, ItemInfo, Page, Weight } from "../app/models";
+import type { AccountSliceState } from "../features/accounts/accountSlice";
+import { UseSession } from "./utilityApi";
 
 const apiHost: string =
   import.meta.env.VITE_API_URL || "https://localhost:1323";
@@ -20,15 +17,7 @@ const api = axios.create({
   },
 });
 
-export function InitItemAPI() {
-  const token = useAppSelector(selectJWT);
-  api.interceptors.request.use((config) => {
-    if (token) {
-      config.headers.Authorization = `Bearer ${token}`;
-    }
-    return config;
-  });
-}
+UseSession(api);
 
 export async function GetItemsList(
   initiatorAccount: AccountSliceState,
@@ -42,8 +31,9 @@ export async function GetItemsList(
       alert("User Account Is Not Active!");
       throw new Error("Initiator's Account Is Not Privileged");
     }
-    const response = await api.get<ItemInfo[]>(apiHost + "/api/items/list", {
+    const response = await api.get<Page<ItemInfo>>(apiHost + "/api/items/list", {
       //withCredentials: true,
+      params: { limit: 500 },
     });
     const data = response.data;
     console.log("Raw API Response: ", data);
@@ -52,7 +42,7 @@ export async function GetItemsList(
       throw new Error("Response Status: NOT 'Ok'");
     }
     received = true;
-    itemsInfo = data;
+    itemsInfo = data.data;
     return [received, itemsInfo];
   } catch (err) {
     console.error(err);
@@ -67,7 +57,7 @@ export async function CreateItem(
   upc: string,
   name: string,
   description: string,
-  weight: number,
+  weight: Weight,
   image: ImageInfo,
 ): Promise<[boolean, Item]> {
   let successful: boolean;
@@ -130,8 +120,9 @@ export async function GetItems(
       alert("User Account Is Not Active!");
       throw new Error("Initiator's Account Is Not Privileged");
     }
-    const response = await api.get<Item[]>(apiHost + "/api/items", {
+    const response = await api.get<Page<Item>>(apiHost + "/api/items", {
       //withCredentials: true,
+      params: { limit: 500 },
     });
     const data = response.data;
     console.log("Raw API Response: ", data);
@@ -140,7 +131,7 @@ export async function GetItems(
       throw new Error("Response Status: NOT 'Ok'");
     }
     received = true;
-    items = data;
+    items = data.data;
     return [received, items];
   } catch (err) {
     console.error(err);
@@ -1,22 +1,34 @@
 // SPDX-License-Identifier: GPL-3.0
 
-import axios, { HttpStatusCode } from "axios";
+import axios, {
+  HttpStatusCode,
+  type AxiosInstance,
+  type InternalAxiosRequestConfig,
+} from "axios";
 import { jwtDecode } from "jwt-decode";
-import type { JwtObject } from "../app/models";
-import { selectJWT } from "../features/accounts/accountSlice";
-import { useAppSelector } from "../app/hooks";
+import type {
+  JwtObject,
+  LoginChallenge,
+  TokenPair,
+  TOTPEnrollment,
+} from "../app/models";
+import { store } from "../app/store";
+import {
+  deactivateUser,
+  insertJWT,
+  insertRefreshToken,
+  resetJWT,
+  resetRefreshToken,
+} from "../features/accounts/accountSlice";
+import { deactivateApp } from "../features/appSlice";
 
 const apiHost: string =
   import.meta.env.VITE_API_URL || "https://localhost:1323";
 
-const api = axios.create({
-  baseURL: apiHost,
-  timeout: 10000,
-  headers: {
-    "Content-Type": "application/json",
-    Accept: "application/json",
-  },
-});
+// Marks A Request That Was Already Retried After Refreshing The Session
+type SessionRequestConfig = InternalAxiosRequestConfig & { retried?: boolean };
+
+let refreshing: Promise<string> | null = null;
 
 const decodeToken = (token: string): JwtObject | null => {
   try {
@@ -27,14 +39,59 @@ const decodeToken = (token: string): JwtObject | null => {
   }
 };
 
-export function InitAPI() {
-  const token = useAppSelector(selectJWT);
+// Sends The Current Access Token With Every Request, Refreshing The Session
+// And Retrying Once When The Access Token Has Expired
+export function UseSession(api: AxiosInstance) {
   api.interceptors.request.use((config) => {
+    const token = store.getState().account.jwt;
     if (token) {
       config.headers.Authorization = `Bearer ${token}`;
     }
     return config;
   });
+  api.interceptors.response.use(undefined, async (error) => {
+    const config: SessionRequestConfig | undefined = error.config;
+    if (
+      !axios.isAxiosError(error) ||
+      error.response?.status !== HttpStatusCode.Unauthorized ||
+      config === undefined ||
+      config.retried ||
+      store.getState().account.refreshToken === ""
+    ) {
+      return Promise.reject(error);
+    }
+    config.retried = true;
+    await RefreshSession();
+    return api.request(config);
+  });
+}
+
+// Trades The Refresh Token For A New Pair, Sharing One Request Between All
+// Callers Waiting On It As Refresh Tokens Only Work Once
+export function RefreshSession(): Promise<string> {
+  if (refreshing === null) {
+    refreshing = axios
+      .post<TokenPair>(apiHost + "/auth/refresh", {
+        refreshToken: store.getState().account.refreshToken,
+      })
+      .then((response) => {
+        store.dispatch(insertJWT(response.data.token));
+        store.dispatch(insertRefreshToken(response.data.refreshToken));
+        return response.data.token;
+      })
+      .catch((err) => {
+        console.error("Session refresh failed: ", err);
+        store.dispatch(resetJWT());
+        store.dispatch(resetRefreshToken());
+        store.dispatch(deactivateUser());
+        store.dispatch(deactivateApp());
+        throw err;
+      })
+      .finally(() => {
+        refreshing = null;
+      });
+  }
+  return refreshing;
 }
 
 export async function PingHealth(): Promise<boolean> {
@@ -58,13 +115,12 @@ export async function PingHealth(): Promise<boolean> {
 export async function AuthorizeUser(
   username: string,
   password: string,
-): Promise<[boolean, string, JwtObject]> {
+): Promise<[boolean, TokenPair, JwtObject]> {
   let exists: boolean;
-  let token: string;
   let jwtPayload: JwtObject = {} as JwtObject;
 
   try {
-    const response = await axios.post<{ token: string }>(
+    const response = await axios.post<TokenPair | LoginChallenge>(
       apiHost + "/login",
       {
         username,
@@ -85,23 +141,43 @@ export async function AuthorizeUser(
     }
 
     exists = true;
-    token = response.data["token"];
-    jwtPayload = decodeToken(token)!;
-    api.interceptors.request.use(
-      (config) => {
-        config.headers.Authorization = `Bearer ${token}`;
-        return config;
-      },
-      (error) => {
-        console.error("Request interceptor error: ", error);
-        return Promise.reject(error);
-      },
-    );
+    const tokens =
+      "preAuthToken" in response.data
+        ? await SecondFactor(response.data)
+        : response.data;
+    jwtPayload = decodeToken(tokens.token)!;
 
-    return [exists, token, jwtPayload];
+    return [exists, tokens, jwtPayload];
   } catch (err) {
     console.error(err);
     alert(`Error: ${err}`);
     throw new Error("Failed To Query RESTapi: " + err);
   }
 }
+
+// Completes A Login That Asks For A Second Factor, Enrolling First If Needed
+async function SecondFactor(challenge: LoginChallenge): Promise<TokenPair> {
+  if (challenge.enroll) {
+    const enrollment = await axios.post<TOTPEnrollment>(
+      apiHost + "/auth/totp/enroll",
+      { preAuthToken: challenge.preAuthToken },
+    );
+    alert(
+      "Two-factor authentication is required for this account.\n" +
+        "Add this key to your authenticator app: " +
+        enrollment.data.secret +
+        "\n\nKeep these recovery codes somewhere safe:\n" +
+        enrollment.data.recoveryCodes.join("\n"),
+    );
+  }
+
+  const code = prompt("Enter the code from your authenticator app:") ?? "";
+  const response = await axios.post<TokenPair>(apiHost + "/auth/totp", {
+    preAuthToken: challenge.preAuthToken,
+    code: code.trim(),
+  });
+  if (response.status !== HttpStatusCode.Accepted) {
+    throw new Error("Second factor was not accepted");
+  }
+  return response.data;
+}